@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseAnalyzeArgs parses `iosdumper analyze --from <dir> [--only a,b]`,
+// which re-runs the analyzers over the directory an earlier scan extracted
+// the IPA to. It sets analysisOpts.only and returns the directory to scan.
+func parseAnalyzeArgs(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	from := fs.String("from", "", "Directory an earlier scan extracted the IPA to")
+	only := fs.String("only", "", "Comma-separated analyzers to run, e.g. secret,tracker")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *from == "" || fs.NArg() > 0 {
+		return nil, trError("ErrAnalyzeUsage")
+	}
+	if *only != "" {
+		known := make(map[string]bool)
+		for _, name := range analyzerNames() {
+			known[analyzerKey(name)] = true
+		}
+		analysisOpts.only = make(map[string]bool)
+		for _, name := range strings.Split(*only, ",") {
+			key := analyzerKey(strings.ToLower(strings.TrimSpace(name)))
+			if !known[key] {
+				return nil, trError("ErrUnknownAnalyzer", "Name", name, "Names", strings.Join(analyzerNames(), ", "))
+			}
+			analysisOpts.only[key] = true
+		}
+	}
+	return []string{filepath.Clean(*from)}, nil
+}
+
+// analyzeExtraction reports on an IPA extracted by an earlier scan, skipping
+// the unzip, or on an .app directory in place
+func analyzeExtraction(ctx context.Context, dir string, prog *progressReporter) *scanResult {
+	if isAppDir(dir) {
+		return analyzeAppDir(ctx, dir, prog)
+	}
+	return reanalyze(ctx, newScanResult(dir), dir, "", prog)
+}
+
+// reanalyze reports on the extraction in dir. The IPA it was extracted from,
+// when still present, supplies the checksum and the entries outside Payload;
+// with no archive given that is the IPA beside dir, or the copy older
+// versions left inside it.
+func reanalyze(ctx context.Context, result *scanResult, dir, archive string, prog *progressReporter) *scanResult {
+	result.out = prog.output()
+	if st, err := os.Stat(filepath.Join(dir, "Payload")); err != nil || !st.IsDir() {
+		return failScan(ctx, result, errCodeInvalidInput, "input", trError("ErrNoExtraction", "Dir", dir))
+	}
+	if archive == "" {
+		archive = sourceArchive(dir)
+	}
+	var extraEntries []string
+	if archive != "" {
+		if result.SHA256 == "" {
+			result.SHA256, _ = fileSHA256(archive)
+		}
+		var err error
+		if extraEntries, err = unexpectedArchiveEntries(archive); err != nil {
+			result.addError(extractErrorCode(err), "extract", "", err, false)
+		}
+	}
+	// A scan reusing its workspace extraction verified the IPA already
+	if result.ExpectedSHA256 == "" {
+		if err := verifyInputSHA256(result); err != nil {
+			return failScan(ctx, result, errCodeInvalidInput, "verify", err)
+		}
+	}
+	return reportExtraction(ctx, result, dir, extraEntries, prog)
+}
+
+// sourceArchive finds the IPA the extraction in dir was made from, or ""
+func sourceArchive(dir string) string {
+	for _, path := range []string{dir + ".ipa", filepath.Join(dir, filepath.Base(dir)+".zip")} {
+		if st, err := os.Stat(path); err == nil && st.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// analyzerName is how --only names a string analyzer, report step or check:
+// its function name without the new, analyze or check prefix and the
+// Analyzer or Resources suffix, lower-cased. Related steps share a name, so
+// sql selects newSQLAnalyzer and analyzeSQLResources.
+func analyzerName(fn interface{}) string {
+	name := funcName(fn)
+	for _, prefix := range []string{"new", "analyze", "check"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	for _, suffix := range []string{"Analyzer", "Resources"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != "" {
+			name = trimmed
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// analyzerKey ignores a plural s, so secrets selects the secret analyzer and
+// featureflag both analyzeFeatureFlagResources and checkFeatureFlags
+func analyzerKey(name string) string {
+	return strings.TrimSuffix(name, "s")
+}
+
+// analyzerNames lists the names --only accepts, one per key
+func analyzerNames() []string {
+	var fns []interface{}
+	for _, a := range stringAnalyzers {
+		fns = append(fns, a)
+	}
+	for _, a := range malwareAnalyzers {
+		fns = append(fns, a)
+	}
+	for _, step := range reportSteps {
+		fns = append(fns, step)
+	}
+	for _, c := range reportChecks {
+		fns = append(fns, c)
+	}
+	byKey := make(map[string]string)
+	for _, fn := range fns {
+		name := analyzerName(fn)
+		if shortest, ok := byKey[analyzerKey(name)]; !ok || len(name) < len(shortest) {
+			byKey[analyzerKey(name)] = name
+		}
+	}
+	names := make(map[string]bool)
+	for _, name := range byKey {
+		names[name] = true
+	}
+	return sortedSet(names)
+}
+
+// analyzerLabel is the name analyzerNames lists for fn, shared by every step
+// and check that --only selects together with it
+func analyzerLabel(fn interface{}) string {
+	key := analyzerKey(analyzerName(fn))
+	for _, name := range analyzerNames() {
+		if analyzerKey(name) == key {
+			return name
+		}
+	}
+	return analyzerName(fn)
+}
+
+// analyzerSelected reports whether fn runs: always, unless --only leaves it out
+func analyzerSelected(fn interface{}) bool {
+	return analysisOpts.only == nil || analysisOpts.only[analyzerKey(analyzerName(fn))]
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// arm64 supervisor call instructions used to enter the kernel directly
+const (
+	arm64SVC0    = 0xd4000001 // svc #0
+	arm64SVC0x80 = 0xd4001001 // svc #0x80, the Darwin convention
+)
+
+// maxTextScanSize bounds how much of __TEXT,__text is scanned for syscall instructions
+const maxTextScanSize = 256 << 20
+
+// antiDebugTechnique describes one way apps detect or block debuggers, with
+// the imported symbols and strings that give it away
+type antiDebugTechnique struct {
+	name    string
+	imports []string // imported symbols, any of which is evidence
+	strings []string // exact strings, any of which is evidence (symbols resolved with dlsym)
+}
+
+var antiDebugTechniques = []antiDebugTechnique{
+	{"ptrace(PT_DENY_ATTACH)", []string{"_ptrace"}, []string{"ptrace", "PT_DENY_ATTACH"}},
+	{"sysctl kinfo_proc P_TRACED check", []string{"_sysctl"}, []string{"P_TRACED", "kinfo_proc"}},
+	{"Parent process check", []string{"_getppid"}, []string{"getppid"}},
+	{"Exception port check", []string{"_task_get_exception_ports"}, []string{"task_get_exception_ports"}},
+	{"Raw syscall", []string{"_syscall"}, nil},
+}
+
+// antiDebugMeasure is a debugger detection technique the app appears to use
+type antiDebugMeasure struct {
+	Technique string   `json:"technique"`
+	Evidence  []string `json:"evidence"`
+}
+
+// antiDebugAnalyzer records strings naming debugger detection APIs. Together
+// with imported symbols and inline syscall instructions they identify the
+// anti-debugging measures an app employs.
+type antiDebugAnalyzer struct {
+	seen map[string]bool
+}
+
+func newAntiDebugAnalyzer() stringAnalyzer {
+	return &antiDebugAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *antiDebugAnalyzer) visit(s string, offset int64) {
+	for _, t := range antiDebugTechniques {
+		for _, name := range t.strings {
+			if s == name {
+				a.seen[s] = true
+			}
+		}
+	}
+}
+
+func (a *antiDebugAnalyzer) findings(r *appReport) []finding {
+	imports := make(map[string]bool, len(r.Imports))
+	for _, sym := range r.Imports {
+		imports[sym] = true
+	}
+
+	var measures []antiDebugMeasure
+	for _, t := range antiDebugTechniques {
+		var evidence []string
+		for _, sym := range t.imports {
+			if imports[sym] {
+				evidence = append(evidence, "import "+sym)
+			}
+		}
+		for _, s := range t.strings {
+			if a.seen[s] {
+				evidence = append(evidence, "string "+strconv.Quote(s))
+			}
+		}
+		if len(evidence) > 0 {
+			measures = append(measures, antiDebugMeasure{Technique: t.name, Evidence: evidence})
+		}
+	}
+
+	if n, err := countSyscallInstructions(r.BinaryPath); err == nil && n > 0 {
+		measures = append(measures, antiDebugMeasure{
+			Technique: "Inline svc syscall",
+			Evidence:  []string{strconv.Itoa(n) + " svc instructions in __text"},
+		})
+	}
+	r.AntiDebug = measures
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, m := range measures {
+		findings = append(findings, finding{
+			Rule:     "anti-debug",
+			Severity: severityInfo,
+			Title:    "Anti-debugging: " + m.Technique,
+			Evidence: strings.Join(m.Evidence, ", "),
+			Location: location,
+		})
+	}
+	return findings
+}
+
+// readImportedSymbols returns the sorted undefined symbols the first slice of the binary imports
+func readImportedSymbols(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(slices) == 0 {
+		return nil, nil
+	}
+	syms, err := slices[0].ImportedSymbols()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(syms)
+	return syms, nil
+}
+
+// countSyscallInstructions counts svc instructions in the __text section of
+// the arm64 slices. System calls normally go through libSystem, so app code
+// issuing them directly is usually hiding a ptrace or sysctl call.
+func countSyscallInstructions(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range slices {
+		if s.Cpu != macho.CpuArm64 {
+			continue
+		}
+		text := s.Section("__text")
+		if text == nil || text.Size > maxTextScanSize {
+			continue
+		}
+		code, err := text.Data()
+		if err != nil {
+			return 0, err
+		}
+		count := 0
+		for i := 0; i+4 <= len(code); i += 4 {
+			switch binary.LittleEndian.Uint32(code[i:]) {
+			case arm64SVC0, arm64SVC0x80:
+				count++
+			}
+		}
+		return count, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+)
+
+// apiUsageRequirement pairs a framework class with the Info.plist usage
+// strings App Review requires once the app links against it
+type apiUsageRequirement struct {
+	class    string
+	infoKeys []string // at least one must be present
+}
+
+var apiUsageRequirements = []apiUsageRequirement{
+	{"AVCaptureDevice", []string{"NSCameraUsageDescription"}},
+	{"AVAudioRecorder", []string{"NSMicrophoneUsageDescription"}},
+	{"CLLocationManager", []string{"NSLocationWhenInUseUsageDescription", "NSLocationAlwaysAndWhenInUseUsageDescription"}},
+	{"PHPhotoLibrary", []string{"NSPhotoLibraryUsageDescription", "NSPhotoLibraryAddUsageDescription"}},
+	{"CNContactStore", []string{"NSContactsUsageDescription"}},
+	{"EKEventStore", []string{"NSCalendarsUsageDescription", "NSCalendarsFullAccessUsageDescription", "NSCalendarsWriteOnlyAccessUsageDescription", "NSRemindersUsageDescription", "NSRemindersFullAccessUsageDescription"}},
+	{"CBCentralManager", []string{"NSBluetoothAlwaysUsageDescription"}},
+	{"CBPeripheralManager", []string{"NSBluetoothAlwaysUsageDescription"}},
+	{"LAContext", []string{"NSFaceIDUsageDescription"}},
+	{"ATTrackingManager", []string{"NSUserTrackingUsageDescription"}},
+	{"HKHealthStore", []string{"NSHealthShareUsageDescription", "NSHealthUpdateUsageDescription"}},
+	{"CMMotionActivityManager", []string{"NSMotionUsageDescription"}},
+	{"CMPedometer", []string{"NSMotionUsageDescription"}},
+	{"SFSpeechRecognizer", []string{"NSSpeechRecognitionUsageDescription"}},
+	{"MPMediaLibrary", []string{"NSAppleMusicUsageDescription"}},
+	{"NFCNDEFReaderSession", []string{"NFCReaderUsageDescription"}},
+	{"NFCTagReaderSession", []string{"NFCReaderUsageDescription"}},
+	{"HMHomeManager", []string{"NSHomeKitUsageDescription"}},
+	{"INPreferences", []string{"NSSiriUsageDescription"}},
+	{"NWBrowser", []string{"NSLocalNetworkUsageDescription"}},
+}
+
+// apiUsagePlatforms limits requirements to the platforms that have the
+// feature: watchOS and tvOS have no biometrics, and only iPhones read NFC
+var apiUsagePlatforms = map[string][]string{
+	"LAContext":            {platformIOS, platformVisionOS},
+	"NFCNDEFReaderSession": {platformIOS},
+	"NFCTagReaderSession":  {platformIOS},
+}
+
+// checkAppReview flags Info.plist gaps that get builds rejected or held at
+// upload time, so the scan can double as a pre-submission validator
+func checkAppReview(r *appReport) []finding {
+	var findings []finding
+	add := func(rule, severity, title, evidence, remediation string) {
+		findings = append(findings, finding{
+			Rule:        rule,
+			Severity:    severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: remediation,
+		})
+	}
+	info := r.InfoPlist
+
+	// The App Store filters on UIRequiredDeviceCapabilities only for iPhone and iPad apps
+	if _, ok := info["UIRequiredDeviceCapabilities"]; !ok && r.Platform == platformIOS {
+		add("missing-device-capabilities", severityLow, "UIRequiredDeviceCapabilities not declared", "UIRequiredDeviceCapabilities",
+			"Declare the device capabilities the app needs (at least arm64) so the App Store does not offer it to unsupported devices.")
+	} else if ok {
+		for _, capability := range requiredCapabilities(info) {
+			if (capability == "armv7" || capability == "armv6") && !hasSliceArch(r.Slices, capability) {
+				add("missing-device-capabilities", severityLow, "Required device capability has no matching slice", capability,
+					"Replace "+capability+" with arm64 in UIRequiredDeviceCapabilities.")
+			}
+		}
+	}
+
+	switch v := info["ITSAppUsesNonExemptEncryption"].(type) {
+	case nil:
+		add("export-compliance", severityLow, "Export compliance key missing", "ITSAppUsesNonExemptEncryption",
+			"Set ITSAppUsesNonExemptEncryption so uploads are not held for the export compliance questionnaire.")
+	case bool:
+		if v && plistString(info, "ITSEncryptionExportComplianceCode") == "" {
+			add("export-compliance", severityMedium, "Non-exempt encryption declared without a compliance code", "ITSAppUsesNonExemptEncryption: true",
+				"Add ITSEncryptionExportComplianceCode with the code issued for the app's encryption registration.")
+		}
+	}
+
+	imported := make(map[string]bool)
+	for _, sym := range r.Imports {
+		if strings.HasPrefix(sym, "_OBJC_CLASS_$_") {
+			imported[strings.TrimPrefix(sym, "_OBJC_CLASS_$_")] = true
+		}
+	}
+	for _, req := range apiUsageRequirements {
+		if !imported[req.class] || !onPlatform(r.Platform, apiUsagePlatforms[req.class]) {
+			continue
+		}
+		declared := false
+		for _, key := range req.infoKeys {
+			if plistString(info, key) != "" {
+				declared = true
+			}
+		}
+		if !declared {
+			findings = append(findings, finding{
+				Rule:        "missing-usage-description",
+				Severity:    severityMedium,
+				Title:       "API used without a privacy usage description",
+				Evidence:    req.class + " requires " + strings.Join(req.infoKeys, " or "),
+				Location:    bundleLocation(r, r.BinaryPath),
+				Remediation: "Add " + req.infoKeys[0] + " to Info.plist; the app is terminated when the API is first used and rejected at review without it.",
+			})
+		}
+	}
+	return findings
+}
+
+// requiredCapabilities returns UIRequiredDeviceCapabilities, which may be an
+// array of names or a dictionary of name to required flag
+func requiredCapabilities(info map[string]interface{}) []string {
+	if caps := plistStrings(info, "UIRequiredDeviceCapabilities"); len(caps) > 0 {
+		return caps
+	}
+	var caps []string
+	dict := plistDict(info, "UIRequiredDeviceCapabilities")
+	for _, key := range sortedKeys(dict) {
+		if required, _ := dict[key].(bool); required {
+			caps = append(caps, key)
+		}
+	}
+	return caps
+}
+
+// hasSliceArch reports whether one of the slices is built for arch
+func hasSliceArch(slices []sliceInfo, arch string) bool {
+	for _, s := range slices {
+		if s.Arch == arch {
+			return true
+		}
+	}
+	return false
+}
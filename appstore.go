@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// itunesLookupURL is the public iTunes Search API endpoint that resolves a
+// bundle ID to its App Store listing; it needs no credentials
+const itunesLookupURL = "https://itunes.apple.com/lookup"
+
+// storefrontPattern matches the two-letter country codes --app-store takes
+var storefrontPattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+// appStoreInfo is the published listing of an app, from --app-store
+type appStoreInfo struct {
+	Country       string `json:"country"`
+	Listed        bool   `json:"listed"` // false when the storefront has no app with the bundle ID
+	TrackID       int64  `json:"track_id,omitempty"`
+	URL           string `json:"url,omitempty"`
+	Version       string `json:"version,omitempty"`
+	Seller        string `json:"seller,omitempty"`
+	ContentRating string `json:"content_rating,omitempty"` // e.g. 12+
+	ReleaseDate   string `json:"release_date,omitempty"`   // of the current version, YYYY-MM-DD
+	ReleaseNotes  string `json:"release_notes,omitempty"`
+	NewerBuild    bool   `json:"newer_build,omitempty"` // the analyzed version is ahead of the store's
+}
+
+func (s *appStoreInfo) String() string {
+	switch {
+	case s == nil:
+		return ""
+	case !s.Listed:
+		return "not listed in " + strings.ToUpper(s.Country)
+	}
+	parts := []string{s.Version}
+	if s.Seller != "" {
+		parts = append(parts, "by "+s.Seller)
+	}
+	if s.ContentRating != "" {
+		parts = append(parts, "rated "+s.ContentRating)
+	}
+	if s.ReleaseDate != "" {
+		parts = append(parts, "released "+s.ReleaseDate)
+	}
+	summary := strings.Join(parts, ", ")
+	if s.NewerBuild {
+		summary += " (analyzed build is newer)"
+	}
+	return summary
+}
+
+// parseStorefront normalizes the --app-store country code
+func parseStorefront(country string) (string, error) {
+	country = strings.ToLower(strings.TrimSpace(country))
+	if !storefrontPattern.MatchString(country) {
+		return "", trError("ErrAppStoreCountry", "Country", country)
+	}
+	return country, nil
+}
+
+// runAppStorePass looks the app's bundle ID up in the country's storefront
+// and compares the published version with the analyzed one
+func runAppStorePass(ctx context.Context, r *appReport, country string) error {
+	if r.Metadata.BundleID == "" {
+		return errors.New("no bundle ID to look up")
+	}
+	info, err := lookupAppStore(ctx, r.Metadata.BundleID, country)
+	if err != nil {
+		return err
+	}
+	if info.Listed && r.Metadata.Version != "" && info.Version != "" {
+		info.NewerBuild = compareVersions(r.Metadata.Version, info.Version) > 0
+	}
+	r.AppStore = info
+	r.Findings = append(r.Findings, appStoreFindings(r)...)
+	return nil
+}
+
+// lookupAppStore queries the iTunes Lookup API for bundleID
+func lookupAppStore(ctx context.Context, bundleID, country string) (*appStoreInfo, error) {
+	query := url.Values{"bundleId": {bundleID}, "country": {country}, "entity": {"software"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, itunesLookupURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("iTunes lookup: %s", resp.Status)
+	}
+	var page struct {
+		Results []struct {
+			BundleID                  string    `json:"bundleId"`
+			TrackID                   int64     `json:"trackId"`
+			TrackViewURL              string    `json:"trackViewUrl"`
+			Version                   string    `json:"version"`
+			SellerName                string    `json:"sellerName"`
+			ContentAdvisoryRating     string    `json:"contentAdvisoryRating"`
+			TrackContentRating        string    `json:"trackContentRating"`
+			CurrentVersionReleaseDate time.Time `json:"currentVersionReleaseDate"`
+			ReleaseNotes              string    `json:"releaseNotes"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("iTunes lookup: %v", err)
+	}
+	info := &appStoreInfo{Country: country}
+	for _, res := range page.Results {
+		// The lookup matches bundle IDs case-insensitively
+		if !strings.EqualFold(res.BundleID, bundleID) {
+			continue
+		}
+		info.Listed = true
+		info.TrackID = res.TrackID
+		info.URL = strings.SplitN(res.TrackViewURL, "?", 2)[0]
+		info.Version = res.Version
+		info.Seller = res.SellerName
+		info.ContentRating = res.TrackContentRating
+		if info.ContentRating == "" {
+			info.ContentRating = res.ContentAdvisoryRating
+		}
+		if !res.CurrentVersionReleaseDate.IsZero() {
+			info.ReleaseDate = res.CurrentVersionReleaseDate.Format("2006-01-02")
+		}
+		info.ReleaseNotes = strings.TrimSpace(res.ReleaseNotes)
+		break
+	}
+	return info, nil
+}
+
+// appStoreFindings flags a build that is ahead of the published version
+func appStoreFindings(r *appReport) []finding {
+	s := r.AppStore
+	if s == nil || !s.NewerBuild {
+		return nil
+	}
+	return []finding{{
+		Rule:     "appstore-unreleased-build",
+		Severity: severityLow,
+		Title:    "Analyzed build is newer than the App Store version",
+		Evidence: fmt.Sprintf("analyzed %s, %s store has %s", versionString(r.Metadata), strings.ToUpper(s.Country), s.Version),
+		Location: bundleLocation(r, bundleInfoPlist(r.Path)),
+		Remediation: "Pre-release builds often carry debug settings, test endpoints and unannounced features; confirm this build was meant to " +
+			"leave the team and re-test the release candidate before it ships.",
+	}}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on what an IPA may expand to. Hostile archives are an expected
+// input, so extraction refuses anything no real app could need before
+// writing a byte. The size limits are set by --max-extract-size and
+// --max-file-size.
+const (
+	// maxArchiveEntries bounds the number of files and directories
+	maxArchiveEntries = 200000
+	// maxCompressionRatio is the largest uncompressed to compressed ratio
+	// accepted for entries of at least minBombCheckSize bytes. Deflate cannot
+	// exceed about 1032:1, so a higher ratio means the header lies.
+	maxCompressionRatio = 1032
+	minBombCheckSize    = 1 << 20
+)
+
+var (
+	errArchiveTooManyEntries = errors.New("archive has too many entries")
+	errArchiveTooLarge       = errors.New("archive expands past the size limit")
+	errArchiveBomb           = errors.New("archive looks like a decompression bomb")
+	errArchiveUnsafePath     = errors.New("unsafe path in archive")
+)
+
+// archiveLimitErrors are caused by a hostile or oversized archive rather than by the filesystem
+var archiveLimitErrors = []error{errArchiveTooManyEntries, errArchiveTooLarge, errArchiveBomb, errArchiveUnsafePath}
+
+// extractLimits bound how much of an archive is written to disk
+type extractLimits struct {
+	maxTotal byteSize // --max-extract-size: total bytes extracted, 0 for no limit
+	maxFile  byteSize // --max-file-size: bytes of a single entry, 0 for no limit
+}
+
+// extractOpts is set from --max-extract-size and --max-file-size
+var extractOpts = extractLimits{maxTotal: 16 << 30, maxFile: 4 << 30}
+
+// byteSize is a flag.Value taking a number of bytes with an optional K, M, G
+// or T suffix in binary units, e.g. 512M
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return formatBytes(int64(*b))
+}
+
+func (b *byteSize) Set(value string) error {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	shift := 0
+	if i := strings.IndexAny(s, "KMGT"); i >= 0 && i == len(s)-1 {
+		shift = 10 * (strings.IndexByte("KMGT", s[i]) + 1)
+		s = s[:i]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)>>shift {
+		return trError("ErrBadSize", "Value", value)
+	}
+	*b = byteSize(n << shift)
+	return nil
+}
+
+// extractLimitError lists the entries left out of an extraction because they
+// were over the size limits. Everything else was extracted, so the report
+// goes ahead without them.
+type extractLimitError struct {
+	tooLarge []string // over --max-file-size
+	overflow []string // would have taken the total past --max-extract-size
+}
+
+func (e *extractLimitError) Error() string {
+	var parts []string
+	if len(e.tooLarge) > 0 {
+		parts = append(parts, tr("ExtractFileLimit", "Count", len(e.tooLarge), "Limit", formatBytes(int64(extractOpts.maxFile)), "Files", sampleList(e.tooLarge)))
+	}
+	if len(e.overflow) > 0 {
+		parts = append(parts, tr("ExtractTotalLimit", "Count", len(e.overflow), "Limit", formatBytes(int64(extractOpts.maxTotal)), "Files", sampleList(e.overflow)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *extractLimitError) Unwrap() error { return errArchiveTooLarge }
+
+// admit decides whether an entry of size bytes is extracted given the bytes
+// extracted so far, recording it when it is left out
+func (e *extractLimitError) admit(name string, size, extracted uint64) bool {
+	if extractOpts.maxFile > 0 && size > uint64(extractOpts.maxFile) {
+		e.tooLarge = append(e.tooLarge, name)
+		return false
+	}
+	if extractOpts.maxTotal > 0 && size > uint64(extractOpts.maxTotal)-extracted {
+		e.overflow = append(e.overflow, name)
+		return false
+	}
+	return true
+}
+
+// checkArchive rejects archives that exceed the entry count limit, have
+// entries that escape the extraction directory, or look like a decompression
+// bomb: an implausible compression ratio, or entries whose compressed data
+// overlaps so one stream is expanded many times. Sizes come from the headers;
+// archive/zip fails any entry that decompresses to more than its header says.
+func checkArchive(files []*zip.File) error {
+	if len(files) > maxArchiveEntries {
+		return fmt.Errorf("%w: %d entries, limit %d", errArchiveTooManyEntries, len(files), maxArchiveEntries)
+	}
+	type span struct {
+		name       string
+		start, end int64
+	}
+	spans := make([]span, 0, len(files))
+	for _, f := range files {
+		if !safeArchivePath(f.Name) {
+			return fmt.Errorf("%w: %s", errArchiveUnsafePath, f.Name)
+		}
+		if f.UncompressedSize64 >= minBombCheckSize && f.UncompressedSize64/maxCompressionRatio > f.CompressedSize64 {
+			return fmt.Errorf("%w: %s expands %d bytes to %d", errArchiveBomb, f.Name, f.CompressedSize64, f.UncompressedSize64)
+		}
+		if f.CompressedSize64 == 0 {
+			continue
+		}
+		start, err := f.DataOffset()
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span{f.Name, start, start + int64(f.CompressedSize64)})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start < spans[i-1].end {
+			return fmt.Errorf("%w: %s overlaps %s", errArchiveBomb, spans[i].name, spans[i-1].name)
+		}
+	}
+	return nil
+}
+
+// safeArchivePath reports whether an entry name stays inside the directory it
+// is extracted to
+func safeArchivePath(name string) bool {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	return !filepath.IsAbs(clean) && !strings.HasPrefix(name, "/") && clean != ".." &&
+		!strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// isArchiveLimitError reports whether err came from checkArchive
+func isArchiveLimitError(err error) bool {
+	for _, target := range archiveLimitErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+// zipSeed builds an archive holding the named files
+func zipSeed(files ...string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range files {
+		f, _ := w.Create(name)
+		f.Write([]byte("<plist><dict/></plist>\n"))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func FuzzCheckArchive(f *testing.F) {
+	f.Add(zipSeed("Payload/", "Payload/App.app/Info.plist", "Payload/App.app/App"))
+	f.Add(zipSeed("../Info.plist"))
+	f.Add(zipSeed("/Payload/App.app/Info.plist"))
+	f.Add([]byte("PK\x05\x06" + string(make([]byte, 18))))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		if err := checkArchive(zr.File); err != nil {
+			return
+		}
+		for _, file := range zr.File {
+			if !safeArchivePath(file.Name) {
+				t.Fatalf("unsafe path %q passed checkArchive", file.Name)
+			}
+			rc, err := file.Open()
+			if err != nil {
+				continue
+			}
+			io.Copy(io.Discard, rc)
+			rc.Close()
+		}
+	})
+}
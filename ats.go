@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// atsPosture summarizes the App Transport Security configuration from Info.plist
+type atsPosture struct {
+	ArbitraryLoads           bool     `json:"arbitrary_loads"`
+	ArbitraryLoadsInWeb      bool     `json:"arbitrary_loads_in_web_content,omitempty"`
+	LocalNetworking          bool     `json:"local_networking,omitempty"`
+	ExceptionDomains         []string `json:"exception_domains,omitempty"`
+	InsecureExceptionDomains []string `json:"insecure_exception_domains,omitempty"` // domains allowing cleartext HTTP
+	NotApplicable            bool     `json:"not_applicable,omitempty"`             // the bundle has no ATS of its own, see atsApplies
+}
+
+// readATSPosture reads NSAppTransportSecurity from Info.plist
+func readATSPosture(info map[string]interface{}) atsPosture {
+	ats := plistDict(info, "NSAppTransportSecurity")
+	p := atsPosture{
+		ArbitraryLoads:      plistBool(ats, "NSAllowsArbitraryLoads"),
+		ArbitraryLoadsInWeb: plistBool(ats, "NSAllowsArbitraryLoadsInWebContent"),
+		LocalNetworking:     plistBool(ats, "NSAllowsLocalNetworking"),
+	}
+	domains := plistDict(ats, "NSExceptionDomains")
+	for _, domain := range sortedKeys(domains) {
+		p.ExceptionDomains = append(p.ExceptionDomains, domain)
+		exception, _ := domains[domain].(map[string]interface{})
+		if plistBool(exception, "NSExceptionAllowsInsecureHTTPLoads") || plistBool(exception, "NSThirdPartyExceptionAllowsInsecureHTTPLoads") {
+			p.InsecureExceptionDomains = append(p.InsecureExceptionDomains, domain)
+		}
+	}
+	return p
+}
+
+// String describes the posture for tables, e.g. "arbitrary loads" or "2 exception domains (1 insecure)"
+func (p atsPosture) String() string {
+	if p.NotApplicable {
+		return "not applicable"
+	}
+	var parts []string
+	if p.ArbitraryLoads {
+		parts = append(parts, "arbitrary loads")
+	}
+	if p.ArbitraryLoadsInWeb {
+		parts = append(parts, "arbitrary web loads")
+	}
+	if n := len(p.ExceptionDomains); n > 0 {
+		s := fmt.Sprintf("%d exception domains", n)
+		if m := len(p.InsecureExceptionDomains); m > 0 {
+			s += fmt.Sprintf(" (%d insecure)", m)
+		}
+		parts = append(parts, s)
+	}
+	if len(parts) == 0 {
+		return "strict"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// usageDescriptions returns the privacy usage description keys declared in Info.plist
+func usageDescriptions(info map[string]interface{}) []string {
+	var keys []string
+	for _, key := range sortedKeys(info) {
+		if strings.HasSuffix(key, "UsageDescription") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
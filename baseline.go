@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// fpDemoteAfter is how many of a rule's findings analysts mark as false
+// positives before the rule's other findings lose a confidence level
+const fpDemoteAfter = 3
+
+// fingerprintPattern matches the fingerprints findings carry, see fingerprint
+var fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// baseline is the analyst feedback that tunes future scans, kept as a JSON
+// file that can be reviewed and committed next to the app's sources
+type baseline struct {
+	FalsePositives []falsePositive `json:"false_positives"`
+
+	rules map[string]int // marks per rule
+}
+
+// falsePositive is a finding an analyst marked as a false positive with
+// `iosdumper fp add`, which copies its rule, bundle ID and title from the
+// report it was found in. Entries written by hand may lack them; those
+// without a rule still hide their finding but lower no rule's confidence.
+type falsePositive struct {
+	Fingerprint string    `json:"fingerprint"`
+	Rule        string    `json:"rule,omitempty"`
+	BundleID    string    `json:"bundle_id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// baselinePath is the baseline file, from --baseline or IOSDUMPER_BASELINE.
+// Scans apply no feedback when it is empty.
+var baselinePath = os.Getenv("IOSDUMPER_BASELINE")
+
+// activeBaseline is applied by applyConfidence; nil without a baseline
+var activeBaseline *baseline
+
+// loadBaseline reads the baseline at path; a missing file is an empty one
+func loadBaseline(path string) (*baseline, error) {
+	b := &baseline{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, trError("ErrBaselineParse", "Path", path, "Err", err)
+	}
+	return b, nil
+}
+
+// save writes the baseline to path, replacing it only once fully written
+func (b *baseline) save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// marked reports whether the finding with fingerprint is a false positive
+func (b *baseline) marked(fingerprint string) bool {
+	for _, fp := range b.FalsePositives {
+		if fp.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleMarks counts the false positives recorded against rule
+func (b *baseline) ruleMarks(rule string) int {
+	if b.rules == nil {
+		b.rules = make(map[string]int)
+		for _, fp := range b.FalsePositives {
+			if fp.Rule != "" {
+				b.rules[fp.Rule]++
+			}
+		}
+	}
+	return b.rules[rule]
+}
+
+// runFP implements `iosdumper fp add --report <file> [--note <text>]
+// <fingerprint>...`, which records findings as false positives in the
+// baseline. A fingerprint does not tell which rule raised the finding, so
+// the fingerprints are looked up in the JSON report of the scan that found
+// them. It returns the process exit code.
+func runFP(args []string) int {
+	if len(args) == 0 || args[0] != "add" {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrFPUsage")))
+		return exitBadInput
+	}
+	fs := flag.NewFlagSet("fp add", flag.ContinueOnError)
+	path := fs.String("baseline", baselinePath, "Baseline file to record the feedback in")
+	reportPath := fs.String("report", "", "JSON report to look the fingerprints' rule, app and title up in (required)")
+	note := fs.String("note", "", "Why the findings are false positives")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() == 0 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrFPUsage")))
+		return exitBadInput
+	}
+	if *path == "" {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrBaselineRequired")))
+		return exitBadInput
+	}
+	if *reportPath == "" {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrFPReportRequired")))
+		return exitBadInput
+	}
+	for _, fingerprint := range fs.Args() {
+		if !fingerprintPattern.MatchString(fingerprint) {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrFingerprint", "Fingerprint", fingerprint)))
+			return exitBadInput
+		}
+	}
+
+	known, err := reportFindings(*reportPath)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitBadInput
+	}
+	b, err := loadBaseline(*path)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitBadInput
+	}
+	added := 0
+	now := time.Now().UTC()
+	for _, fingerprint := range fs.Args() {
+		fp, ok := known[fingerprint]
+		if !ok {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrFPNotInReport", "Fingerprint", fingerprint, "Path", *reportPath)))
+			return exitBadInput
+		}
+		if b.marked(fingerprint) {
+			fmt.Fprintln(stdout, tr("FPAlreadyMarked", "Fingerprint", fingerprint))
+			continue
+		}
+		fp.Fingerprint = fingerprint
+		fp.Note = *note
+		fp.AddedAt = now
+		b.FalsePositives = append(b.FalsePositives, fp)
+		added++
+	}
+	if added == 0 {
+		return exitClean
+	}
+	if err := b.save(*path); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitToolFailure
+	}
+	activeTheme.success.Fprintln(stdout, tr("FPAdded", "Count", added, "Path", *path))
+	return exitClean
+}
+
+// reportFindings indexes the findings of a --json report by fingerprint
+func reportFindings(path string) (map[string]falsePositive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report struct {
+		Apps []struct {
+			Metadata appMetadata `json:"metadata"`
+			Findings []finding   `json:"findings"`
+		} `json:"apps"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, trError("ErrFPReport", "Path", path, "Err", err)
+	}
+	found := make(map[string]falsePositive)
+	for _, app := range report.Apps {
+		for _, f := range app.Findings {
+			found[f.Fingerprint] = falsePositive{Rule: f.Rule, BundleID: app.Metadata.BundleID, Title: f.Title}
+		}
+	}
+	return found, nil
+}
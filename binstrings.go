@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// minStringLength matches the strings(1) default
+	minStringLength = 4
+	// maxStringLength caps a single extracted string; longer runs are cut
+	maxStringLength = 4096
+)
+
+// stringAnalyzer inspects every printable string of a binary and turns what
+// it collected into findings once the scan is over
+type stringAnalyzer interface {
+	visit(s string, offset int64)
+	findings(r *appReport) []finding
+}
+
+// stringAnalyzers builds a fresh set of analyzers for each binary scanned
+var stringAnalyzers = []func() stringAnalyzer{
+	newBuildPathAnalyzer,
+	newAntiDebugAnalyzer,
+	newPrivateAPIAnalyzer,
+	newDynamicCodeAnalyzer,
+	newOTAAnalyzer,
+	newReceiptAnalyzer,
+	newPaywallAnalyzer,
+	newFeatureFlagAnalyzer,
+	newMemoryAnalyzer,
+	newKeychainAnalyzer,
+	newBiometricAnalyzer,
+	newPasteboardAnalyzer,
+	newSnapshotAnalyzer,
+	newClipboardAnalyzer,
+	newRadioAnalyzer,
+	newLocalNetworkAnalyzer,
+	newLoggingAnalyzer,
+	newInjectionAnalyzer,
+	newSQLAnalyzer,
+	newWebViewAnalyzer,
+	newEndpointAnalyzer,
+	newOAuthAnalyzer,
+	newDeepLinkAnalyzer,
+	newGraphQLAnalyzer,
+	newSecretAnalyzer,
+	newPushKeyAnalyzer,
+	newTrackerAnalyzer,
+	newTelemetryAnalyzer,
+	newBlobAnalyzer,
+	newObfuscationAnalyzer,
+}
+
+// analyzeBinaryStrings streams the printable strings of path through every
+// registered string analyzer and records their findings on the report
+func analyzeBinaryStrings(r *appReport, path string) error {
+	analyzers := stringAnalyzers
+	if analysisOpts.malware {
+		analyzers = append(analyzers[:len(analyzers):len(analyzers)], malwareAnalyzers...)
+	}
+	if analysisOpts.only != nil {
+		var selected []func() stringAnalyzer
+		for _, a := range analyzers {
+			if analyzerSelected(a) {
+				selected = append(selected, a)
+			}
+		}
+		analyzers = selected
+	}
+	findings, err := analyzeStrings(r, path, analyzers)
+	if err != nil {
+		return err
+	}
+	r.Findings = append(r.Findings, findings...)
+	return nil
+}
+
+// analyzeStrings runs a fresh instance of each analyzer over the printable
+// strings of the file at path and returns what they found
+func analyzeStrings(r *appReport, path string, newAnalyzers []func() stringAnalyzer) ([]finding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	analyzers := make([]stringAnalyzer, len(newAnalyzers))
+	for i, newAnalyzer := range newAnalyzers {
+		analyzers[i] = newAnalyzer()
+	}
+
+	// With --profile each analyzer's visits and findings are timed
+	spent := make([]time.Duration, len(analyzers))
+	visit := func(s string, offset int64) {
+		for _, a := range analyzers {
+			a.visit(s, offset)
+		}
+	}
+	if p := profiler; p != nil {
+		visit = func(s string, offset int64) {
+			for i, a := range analyzers {
+				start := time.Now()
+				a.visit(s, offset)
+				spent[i] += time.Since(start)
+			}
+		}
+		defer func() {
+			for i, a := range analyzers {
+				p.add("analyzer:"+typeName(a), spent[i])
+			}
+		}()
+	}
+	// The analyzers read ASCII strings; the main binary's strings of every
+	// encoding are counted for the report
+	scan := func(s string, offset int64, encoding string) {
+		if encoding == encodingASCII {
+			visit(s, offset)
+		}
+	}
+	if path == r.BinaryPath {
+		r.Strings = stringStats{Encodings: make(map[string]int), Scripts: make(map[string]int)}
+		scan = func(s string, offset int64, encoding string) {
+			r.Strings.add(s, encoding)
+			if encoding == encodingASCII {
+				visit(s, offset)
+			}
+		}
+	}
+	if err := scanStrings(f, minStringLength, scan); err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	for i, a := range analyzers {
+		start := time.Now()
+		findings = append(findings, a.findings(r)...)
+		spent[i] += time.Since(start)
+	}
+	return findings, nil
+}
+
+// scanPrintableStrings calls fn for every run of at least minLen printable
+// ASCII characters in r, like strings(1), along with the run's byte offset
+func scanPrintableStrings(r io.Reader, minLen int, fn func(s string, offset int64)) error {
+	br := bufio.NewReaderSize(r, 1<<20)
+	run := make([]byte, 0, 256)
+	var offset, start int64
+	runLen := 0
+
+	flush := func() {
+		if runLen >= minLen {
+			fn(string(run), start)
+		}
+		run = run[:0]
+		runLen = 0
+	}
+
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			flush()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if c == '\t' || (c >= 0x20 && c < 0x7f) {
+			if runLen == 0 {
+				start = offset
+			}
+			if len(run) < maxStringLength {
+				run = append(run, c)
+			}
+			runLen++
+		} else {
+			flush()
+		}
+		offset++
+	}
+}
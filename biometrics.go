@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+)
+
+// Selectors and symbols that reveal how an app uses biometric authentication
+const (
+	selEvaluatePolicy             = "evaluatePolicy:localizedReason:reply:"
+	selEvaluatedPolicyDomainState = "evaluatedPolicyDomainState"
+	symLAContextClass             = "_OBJC_CLASS_$_LAContext"
+)
+
+// keychainAccessControlSymbols bind a keychain item to a user presence or
+// biometry check that the Secure Enclave enforces
+var keychainAccessControlSymbols = []string{
+	"_SecAccessControlCreateWithFlags",
+	"_kSecAttrAccessControl",
+	"_kSecUseAuthenticationContext",
+}
+
+// biometricReport describes how the app authenticates the user with Face ID or Touch ID
+type biometricReport struct {
+	LocalAuthentication bool `json:"local_authentication"` // LAContext evaluatePolicy is called
+	AccessControl       bool `json:"keychain_access_control"`
+	DomainState         bool `json:"domain_state"` // evaluatedPolicyDomainState is read to notice enrollment changes
+}
+
+// biometricAnalyzer records LocalAuthentication selectors. With the imported
+// Security symbols they show whether a biometric prompt actually protects a
+// keychain item or only gates UI in a callback that can be hooked.
+type biometricAnalyzer struct {
+	evaluate    bool
+	domainState bool
+}
+
+func newBiometricAnalyzer() stringAnalyzer {
+	return &biometricAnalyzer{}
+}
+
+func (a *biometricAnalyzer) visit(s string, offset int64) {
+	switch s {
+	case selEvaluatePolicy:
+		a.evaluate = true
+	case selEvaluatedPolicyDomainState:
+		a.domainState = true
+	}
+}
+
+func (a *biometricAnalyzer) findings(r *appReport) []finding {
+	laContext := false
+	for _, sym := range r.Imports {
+		if sym == symLAContextClass {
+			laContext = true
+		}
+		if containsString(keychainAccessControlSymbols, sym) {
+			r.Biometrics.AccessControl = true
+		}
+	}
+	r.Biometrics.LocalAuthentication = laContext && a.evaluate
+	r.Biometrics.DomainState = a.domainState
+	if !r.Biometrics.LocalAuthentication {
+		return nil
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	if !r.Biometrics.AccessControl {
+		return []finding{{
+			Rule:     "biometric-lacontext-only",
+			Severity: severityMedium,
+			Title:    "Biometric authentication relies on LAContext alone",
+			Evidence: strings.Join([]string{"LAContext " + selEvaluatePolicy, "no SecAccessControl keychain item"}, ", "),
+			Location: location,
+			Remediation: "The evaluatePolicy reply is a boolean in the app's own process and is trivially hooked on a jailbroken device. " +
+				"Store the secret being unlocked in the keychain with SecAccessControlCreateWithFlags(.biometryCurrentSet) " +
+				"so the Secure Enclave performs the check.",
+		}}
+	}
+	if !r.Biometrics.DomainState {
+		return []finding{{
+			Rule:     "biometric-enrollment-change",
+			Severity: severityInfo,
+			Title:    "Biometric enrollment changes are not tracked",
+			Evidence: selEvaluatedPolicyDomainState + " not referenced",
+			Location: location,
+			Remediation: "Use the biometryCurrentSet access control flag, or compare evaluatedPolicyDomainState, " +
+				"so adding a new fingerprint or face does not grant access to existing secrets.",
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// minBase64BlobLength and minHexBlobLength skip short constants that are
+	// mostly identifiers, selectors and hashes
+	minBase64BlobLength = 24
+	minHexBlobLength    = 32
+	// maxDecodedBlobs bounds how many decoded blobs are reported per binary
+	maxDecodedBlobs = 25
+	// maxBlobPreview is how much of a decoded blob is quoted as evidence
+	maxBlobPreview = 120
+)
+
+var (
+	base64BlobPattern = regexp.MustCompile(`^(?:[A-Za-z0-9+/]{4})+(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?$|^[A-Za-z0-9_-]+$`)
+	hexBlobPattern    = regexp.MustCompile(`^(?:[0-9a-fA-F]{2})+$`)
+	decodedURLPattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*://[^\s]+`)
+)
+
+// decodedBlob is an encoded constant that decodes to something meaningful
+type decodedBlob struct {
+	Encoding string `json:"encoding"` // base64 or hex
+	Kind     string `json:"kind"`     // url, json, plist, private key, public key or certificate
+	Offset   int64  `json:"offset"`   // byte offset of the encoded string in the binary
+	Preview  string `json:"preview"`  // start of the decoded value; redacted for private keys
+}
+
+// blobAnalyzer decodes long base64 and hex constants and keeps those whose
+// contents are URLs, JSON, property lists or key material, catching lightly
+// obfuscated configuration and secrets that plain string matching misses
+type blobAnalyzer struct {
+	blobs []decodedBlob
+	seen  map[string]bool
+}
+
+func newBlobAnalyzer() stringAnalyzer {
+	return &blobAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *blobAnalyzer) visit(s string, offset int64) {
+	if len(a.blobs) >= maxDecodedBlobs || len(s) < minBase64BlobLength || a.seen[s] {
+		return
+	}
+	var encoding string
+	var data []byte
+	switch {
+	case len(s) >= minHexBlobLength && hexBlobPattern.MatchString(s):
+		encoding = "hex"
+		data, _ = hex.DecodeString(s)
+	case base64BlobPattern.MatchString(s) && looksEncoded(s):
+		encoding = "base64"
+		data = decodeBase64(s)
+	default:
+		return
+	}
+	if kind, preview := classifyDecoded(data); kind != "" {
+		a.seen[s] = true
+		a.blobs = append(a.blobs, decodedBlob{Encoding: encoding, Kind: kind, Offset: offset, Preview: preview})
+	}
+}
+
+func (a *blobAnalyzer) findings(r *appReport) []finding {
+	r.DecodedBlobs = a.blobs
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, b := range a.blobs {
+		severity := severityLow
+		switch b.Kind {
+		case "private key":
+			severity = severityHigh
+		case "public key", "certificate":
+			severity = severityInfo
+		}
+		findings = append(findings, finding{
+			Rule:        "encoded-blob",
+			Severity:    severity,
+			Title:       "Encoded constant decodes to " + b.Kind,
+			Evidence:    fmt.Sprintf("%s at 0x%x: %s", b.Encoding, b.Offset, b.Preview),
+			Location:    location,
+			Remediation: "Encoding is not protection. Fetch configuration and credentials from a server after authentication instead of shipping them in the binary.",
+		})
+	}
+	return findings
+}
+
+// looksEncoded rejects identifiers that happen to be valid base64 by requiring
+// both letter cases and a digit or symbol, as random encodings almost always have
+func looksEncoded(s string) bool {
+	var upper, lower, other bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		default:
+			other = true
+		}
+	}
+	return upper && lower && other
+}
+
+// decodeBase64 tries the standard and URL-safe alphabets, padded or not
+func decodeBase64(s string) []byte {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(s); err == nil {
+			return data
+		}
+	}
+	return nil
+}
+
+// classifyDecoded names what data is, or returns "" when it is nothing recognizable
+func classifyDecoded(data []byte) (kind, preview string) {
+	if len(data) < 8 {
+		return "", ""
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("bplist00")):
+		return "plist", "binary property list"
+	case bytes.HasPrefix(data, []byte("-----BEGIN")):
+		header := data[:min(len(data), 40)]
+		switch {
+		case bytes.Contains(header, []byte("PRIVATE KEY")):
+			return "private key", "PEM private key"
+		case bytes.Contains(header, []byte("PUBLIC KEY")):
+			return "public key", "PEM public key"
+		}
+		return "certificate", previewText(header)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		return "private key", "PKCS#8 private key"
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return "private key", "PKCS#1 RSA private key"
+	}
+	if _, err := x509.ParseECPrivateKey(data); err == nil {
+		return "private key", "EC private key"
+	}
+	if _, err := x509.ParsePKIXPublicKey(data); err == nil {
+		return "public key", "DER public key"
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return "certificate", cert.Subject.String()
+	}
+
+	if !utf8.Valid(data) {
+		return "", ""
+	}
+	text := strings.TrimSpace(string(data))
+	switch {
+	case decodedURLPattern.MatchString(text):
+		return "url", previewText([]byte(text))
+	case (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) && json.Valid([]byte(text)):
+		return "json", previewText([]byte(text))
+	case strings.Contains(text, "<plist"):
+		return "plist", previewText([]byte(text))
+	}
+	return "", ""
+}
+
+// previewText quotes the start of a decoded value on one line
+func previewText(data []byte) string {
+	s := []rune(strings.Join(strings.Fields(string(data)), " "))
+	if len(s) > maxBlobPreview {
+		return string(s[:maxBlobPreview]) + "…"
+	}
+	return string(s)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxBuildPathSamples bounds how many distinct paths are kept for the report
+const maxBuildPathSamples = 50
+
+var (
+	// userHomePattern captures the account name from macOS home directory paths
+	userHomePattern = regexp.MustCompile(`/Users/([A-Za-z0-9._\-]+)/`)
+	// absoluteBuildPathPattern matches absolute paths typical of build machines
+	absoluteBuildPathPattern = regexp.MustCompile(`(?:/Users/|/Volumes/|/home/|/private/var/|/var/lib/|/builds/|/opt/|/bitrise/|/tmp/)[^\s"'<>]*`)
+)
+
+// ciRunnerSignatures identify CI systems from the paths their runners build in
+var ciRunnerSignatures = []struct {
+	name   string
+	marker string
+}{
+	{"GitHub Actions", "/Users/runner/work/"},
+	{"GitHub Actions", "/home/runner/work/"},
+	{"GitLab CI", "/builds/"},
+	{"Jenkins", "/jenkins/workspace/"},
+	{"Jenkins", "/var/lib/jenkins/"},
+	{"CircleCI", "/Users/distiller/"},
+	{"Bitrise", "/Users/vagrant/git/"},
+	{"Bitrise", "/bitrise/"},
+	{"Azure Pipelines", "/Users/runner/work/1/"},
+	{"Azure Pipelines", "/agent/_work/"},
+	{"TeamCity", "/BuildAgent/work/"},
+	{"Xcode Cloud", "/Volumes/workspace/"},
+	{"Buildkite", "/buildkite/builds/"},
+}
+
+// systemUsers are path components under /Users that are not real accounts
+var systemUsers = map[string]bool{"Shared": true, "runner": true, "distiller": true, "vagrant": true}
+
+// buildPathReport is the build environment information leaked through absolute paths
+type buildPathReport struct {
+	Usernames []string `json:"usernames,omitempty"`
+	CIRunners []string `json:"ci_runners,omitempty"`
+	Paths     []string `json:"paths,omitempty"` // distinct sample of leaked paths
+	Total     int      `json:"total"`           // number of distinct leaked paths
+}
+
+// buildPathAnalyzer collects absolute build paths, usernames and CI runner
+// paths from binary strings, including the object file paths of the debug map
+type buildPathAnalyzer struct {
+	paths     map[string]bool
+	usernames map[string]bool
+	runners   map[string]bool
+}
+
+func newBuildPathAnalyzer() stringAnalyzer {
+	return &buildPathAnalyzer{
+		paths:     make(map[string]bool),
+		usernames: make(map[string]bool),
+		runners:   make(map[string]bool),
+	}
+}
+
+func (a *buildPathAnalyzer) visit(s string, offset int64) {
+	if !strings.Contains(s, "/") {
+		return
+	}
+	for _, path := range absoluteBuildPathPattern.FindAllString(s, -1) {
+		if len(strings.Split(path, "/")) < 4 {
+			continue // too short to be a build path, e.g. /tmp/x
+		}
+		a.paths[path] = true
+		for _, m := range userHomePattern.FindAllStringSubmatch(path, -1) {
+			if !systemUsers[m[1]] {
+				a.usernames[m[1]] = true
+			}
+		}
+		for _, ci := range ciRunnerSignatures {
+			if strings.Contains(path, ci.marker) {
+				a.runners[ci.name] = true
+			}
+		}
+	}
+}
+
+func (a *buildPathAnalyzer) findings(r *appReport) []finding {
+	report := buildPathReport{
+		Usernames: sortedSet(a.usernames),
+		CIRunners: sortedSet(a.runners),
+		Total:     len(a.paths),
+	}
+	report.Paths = sortedSet(a.paths)
+	if len(report.Paths) > maxBuildPathSamples {
+		report.Paths = report.Paths[:maxBuildPathSamples]
+	}
+	r.BuildPaths = report
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, user := range report.Usernames {
+		findings = append(findings, finding{
+			Rule:        "build-username-leak",
+			Severity:    severityLow,
+			Title:       "macOS username leaked through build paths",
+			Evidence:    user + " (" + samplePath(a.paths, "/Users/"+user+"/") + ")",
+			Location:    location,
+			Remediation: "Build release artifacts on a CI machine or with -fdebug-prefix-map / -debug-prefix-map so home directories are not recorded.",
+		})
+	}
+	for _, runner := range report.CIRunners {
+		findings = append(findings, finding{
+			Rule:     "build-ci-runner",
+			Severity: severityInfo,
+			Title:    "Built on CI runner",
+			Evidence: runner,
+			Location: location,
+		})
+	}
+	if report.Total > 0 {
+		findings = append(findings, finding{
+			Rule:        "build-path-leak",
+			Severity:    severityInfo,
+			Title:       "Absolute build paths embedded in the binary",
+			Evidence:    fmt.Sprintf("%d distinct paths, e.g. %s", report.Total, report.Paths[0]),
+			Location:    location,
+			Remediation: "Strip debug symbols from release builds (STRIP_INSTALLED_PRODUCT, DEPLOYMENT_POSTPROCESSING) and remap source prefixes.",
+		})
+	}
+	return findings
+}
+
+// samplePath returns the shortest collected path containing marker
+func samplePath(paths map[string]bool, marker string) string {
+	best := ""
+	for p := range paths {
+		if strings.Contains(p, marker) && (best == "" || len(p) < len(best)) {
+			best = p
+		}
+	}
+	return best
+}
+
+// sortedSet returns the members of a string set in lexical order
+func sortedSet(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nestedBundleDirs are where an app embeds extensions, watch apps and App Clips
+var nestedBundleDirs = []string{"PlugIns", "Extensions", "Watch", "AppClips"}
+
+// appBundle is an .app or app extension found in the payload. Each one gets
+// its own Info.plist, binary and report.
+type appBundle struct {
+	Dir        string // bundle directory
+	Rel        string // path relative to Payload, e.g. Foo.app/PlugIns/Share.appex
+	BinaryPath string // CFBundleExecutable, or the directory name without extension
+	Parent     string // Rel of the containing bundle, empty for top-level apps
+}
+
+// Name returns the bundle directory name, e.g. Share.appex
+func (b appBundle) Name() string {
+	return filepath.Base(b.Dir)
+}
+
+// plistCopyName names the XML copy of the bundle's Info.plist written next to
+// the extracted IPA. The first app keeps the historical Info.plist name.
+func (b appBundle) plistCopyName(first bool) string {
+	if first {
+		return "Info.plist"
+	}
+	return strings.ReplaceAll(b.Rel, "/", "_") + ".Info.plist"
+}
+
+// findBundles lists the .app bundles in payloadDir followed, for each, by the
+// bundles nested in it, parents before children. An app linked into the
+// payload, as analyzeAppDir does, is listed by its real directory.
+func findBundles(payloadDir string) ([]appBundle, error) {
+	apps, err := filepath.Glob(filepath.Join(payloadDir, "*.app"))
+	if err != nil {
+		return nil, err
+	}
+	var bundles []appBundle
+	var add func(dir, rel, parent string)
+	add = func(dir, rel, parent string) {
+		bundles = append(bundles, appBundle{Dir: dir, Rel: rel, BinaryPath: bundleBinary(dir), Parent: parent})
+		contents := bundleContents(dir)
+		for _, sub := range nestedBundleDirs {
+			entries, err := os.ReadDir(filepath.Join(contents, sub))
+			if err != nil {
+				continue
+			}
+			subRel := sub
+			if contents != dir {
+				subRel = "Contents/" + sub
+			}
+			for _, e := range entries {
+				if ext := filepath.Ext(e.Name()); e.IsDir() && (ext == ".app" || ext == ".appex") {
+					add(filepath.Join(contents, sub, e.Name()), rel+"/"+subRel+"/"+e.Name(), rel)
+				}
+			}
+		}
+	}
+	for _, app := range apps {
+		if real, err := filepath.EvalSymlinks(app); err == nil {
+			app = real
+		}
+		add(app, filepath.Base(app), "")
+	}
+	return bundles, nil
+}
+
+// bundleBinary returns the main executable of the bundle at dir, falling back
+// to the directory name when Info.plist does not name one
+func bundleBinary(dir string) string {
+	if exe, err := bundleExecutable(dir); err == nil {
+		return exe
+	}
+	name := filepath.Base(dir)
+	return filepath.Join(bundleExecutableDir(dir), strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// Mac Catalyst apps and their extensions use the macOS bundle layout: the
+// Info.plist, code signature, Frameworks and PlugIns are in Contents, the
+// executable in Contents/MacOS and resources in Contents/Resources. Their
+// frameworks keep the Info.plist in Resources. iOS bundles are flat.
+
+// bundleContents returns the directory holding the Info.plist, code
+// signature and nested bundles of the bundle at dir
+func bundleContents(dir string) string {
+	contents := filepath.Join(dir, "Contents")
+	if _, err := os.Stat(filepath.Join(contents, "Info.plist")); err == nil {
+		return contents
+	}
+	return dir
+}
+
+// bundleInfoPlist returns the path of the Info.plist of the bundle at dir
+func bundleInfoPlist(dir string) string {
+	if contents := bundleContents(dir); contents != dir {
+		return filepath.Join(contents, "Info.plist")
+	}
+	path := filepath.Join(dir, "Info.plist")
+	if _, err := os.Stat(path); err != nil {
+		resources := filepath.Join(dir, "Resources", "Info.plist")
+		if _, err := os.Stat(resources); err == nil {
+			return resources
+		}
+	}
+	return path
+}
+
+// bundleResources returns the directory holding the resources of the bundle
+// at dir, such as its privacy manifest
+func bundleResources(dir string) string {
+	if contents := bundleContents(dir); contents != dir {
+		return filepath.Join(contents, "Resources")
+	}
+	return filepath.Dir(bundleInfoPlist(dir))
+}
+
+// bundleExecutableDir returns the directory holding the executable of the
+// bundle at dir
+func bundleExecutableDir(dir string) string {
+	if contents := bundleContents(dir); contents != dir {
+		return filepath.Join(contents, "MacOS")
+	}
+	return dir
+}
+
+// isNestedBundle reports whether rel, relative to a bundle, is an extension,
+// watch app or App Clip that is analyzed as a bundle of its own
+func isNestedBundle(rel string) bool {
+	dir, name := filepath.Split(strings.TrimPrefix(rel, "Contents/"))
+	ext := filepath.Ext(name)
+	return (ext == ".app" || ext == ".appex") && containsString(nestedBundleDirs, strings.TrimSuffix(dir, "/"))
+}
@@ -0,0 +1,122 @@
+package main
+
+import "strings"
+
+// capabilityRule declares a capability and every way an app can opt into it
+type capabilityRule struct {
+	Name            string
+	Entitlements    []string // entitlement keys that grant it
+	InfoKeys        []string // Info.plist keys that declare it
+	BackgroundModes []string // UIBackgroundModes values that enable it
+	InfoValues      []string // Info.plist arrays whose values each declare it, e.g. accessory protocols
+	SceneRoles      []string // UISceneConfigurations session roles that declare it
+	ExtensionPoints []string // NSExtensionPointIdentifier values of app extensions providing it
+	Platforms       []string // platforms that have it; empty for all
+}
+
+// capabilityRules is the capability matrix, in display order
+var capabilityRules = []capabilityRule{
+	{Name: "Push notifications", Entitlements: []string{"aps-environment"}, BackgroundModes: []string{"remote-notification"}},
+	{Name: "Associated domains", Entitlements: []string{"com.apple.developer.associated-domains"}},
+	{Name: "App groups", Entitlements: []string{"com.apple.security.application-groups"}},
+	{Name: "Keychain sharing", Entitlements: []string{"keychain-access-groups"}},
+	{Name: "iCloud", Entitlements: []string{"com.apple.developer.icloud-services", "com.apple.developer.ubiquity-kvstore-identifier"}},
+	{Name: "Sign in with Apple", Entitlements: []string{"com.apple.developer.applesignin"}},
+	{Name: "Apple Pay", Entitlements: []string{"com.apple.developer.in-app-payments"}},
+	{Name: "Wallet passes", Entitlements: []string{"com.apple.developer.pass-type-identifiers"}},
+	{Name: "HealthKit", Entitlements: []string{"com.apple.developer.healthkit"}, InfoKeys: []string{"NSHealthShareUsageDescription", "NSHealthUpdateUsageDescription"}},
+	{Name: "HomeKit", Entitlements: []string{"com.apple.developer.homekit"}, InfoKeys: []string{"NSHomeKitUsageDescription"}},
+	{Name: "Siri", Entitlements: []string{"com.apple.developer.siri"}, InfoKeys: []string{"NSSiriUsageDescription", "INIntentsSupported"},
+		ExtensionPoints: []string{"com.apple.intents-service", "com.apple.intents-ui-service"}},
+	{Name: "CarPlay", Platforms: []string{platformIOS}, Entitlements: []string{
+		"com.apple.developer.carplay-audio", "com.apple.developer.carplay-charging", "com.apple.developer.carplay-communication",
+		"com.apple.developer.carplay-driving-task", "com.apple.developer.carplay-fueling", "com.apple.developer.carplay-maps",
+		"com.apple.developer.carplay-parking", "com.apple.developer.carplay-public-safety", "com.apple.developer.carplay-quick-ordering",
+	}, SceneRoles: []string{
+		"CPTemplateApplicationSceneSessionRoleApplication", "CPTemplateApplicationDashboardSceneSessionRoleApplication",
+		"CPTemplateApplicationInstrumentClusterSceneSessionRoleApplication",
+	}},
+	{Name: "External accessories (MFi)", Entitlements: []string{"com.apple.external-accessory.wireless-configuration"},
+		InfoValues: []string{"UISupportedExternalAccessoryProtocols"}, BackgroundModes: []string{"external-accessory"}},
+	{Name: "NFC tag reading", Entitlements: []string{"com.apple.developer.nfc.readersession.formats"}, InfoKeys: []string{"NFCReaderUsageDescription"},
+		Platforms: []string{platformIOS}},
+	{Name: "Network extensions", Entitlements: []string{"com.apple.developer.networking.networkextension"}},
+	{Name: "Camera", InfoKeys: []string{"NSCameraUsageDescription"}},
+	{Name: "Microphone", InfoKeys: []string{"NSMicrophoneUsageDescription"}},
+	{Name: "Photo library", InfoKeys: []string{"NSPhotoLibraryUsageDescription", "NSPhotoLibraryAddUsageDescription"}},
+	{Name: "Contacts", InfoKeys: []string{"NSContactsUsageDescription"}},
+	{Name: "Location", InfoKeys: []string{"NSLocationWhenInUseUsageDescription", "NSLocationAlwaysAndWhenInUseUsageDescription", "NSLocationAlwaysUsageDescription"}, BackgroundModes: []string{"location"}},
+	{Name: "Bluetooth", InfoKeys: []string{"NSBluetoothAlwaysUsageDescription", "NSBluetoothPeripheralUsageDescription"}, BackgroundModes: []string{"bluetooth-central", "bluetooth-peripheral"}},
+	{Name: "Face ID", InfoKeys: []string{"NSFaceIDUsageDescription"}, Platforms: []string{platformIOS, platformVisionOS}}, // Optic ID on visionOS
+	{Name: "App tracking", InfoKeys: []string{"NSUserTrackingUsageDescription"}},
+	{Name: "Background audio", BackgroundModes: []string{"audio"}},
+	{Name: "Background fetch", BackgroundModes: []string{"fetch", "processing"}},
+	{Name: "VoIP", BackgroundModes: []string{"voip"}},
+	{Name: "Main camera access", Entitlements: []string{"com.apple.developer.arkit.main-camera-access.allow"},
+		InfoKeys: []string{"NSEnterpriseMCAMUsageDescription"}, Platforms: []string{platformVisionOS}},
+	{Name: "World sensing", InfoKeys: []string{"NSWorldSensingUsageDescription"}, Platforms: []string{platformVisionOS}},
+	{Name: "Hand tracking", InfoKeys: []string{"NSHandsTrackingUsageDescription"}, Platforms: []string{platformVisionOS}},
+	{Name: "Custom URL schemes", InfoKeys: []string{"CFBundleURLTypes"}},
+	{Name: "Document types", InfoKeys: []string{"CFBundleDocumentTypes"}},
+}
+
+// capability is one evaluated row of the capability matrix
+type capability struct {
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	Sources []string `json:"sources,omitempty"` // the entitlements, Info.plist keys or background modes that enabled it
+}
+
+// evaluateCapabilities checks every capability rule of the platform against
+// the app's entitlements and Info.plist
+func evaluateCapabilities(info, entitlements map[string]interface{}, platform string) []capability {
+	backgroundModes := plistStrings(info, "UIBackgroundModes")
+	sceneConfigs := plistDict(plistDict(info, "UIApplicationSceneManifest"), "UISceneConfigurations")
+	extensionPoint := plistString(plistDict(info, "NSExtension"), "NSExtensionPointIdentifier")
+
+	caps := make([]capability, 0, len(capabilityRules))
+	for _, rule := range capabilityRules {
+		if !onPlatform(platform, rule.Platforms) {
+			continue
+		}
+		c := capability{Name: rule.Name}
+		for _, key := range rule.Entitlements {
+			if _, ok := entitlements[key]; ok {
+				c.Sources = append(c.Sources, key)
+			}
+		}
+		for _, key := range rule.InfoKeys {
+			if _, ok := info[key]; ok {
+				c.Sources = append(c.Sources, key)
+			}
+		}
+		for _, mode := range rule.BackgroundModes {
+			for _, declared := range backgroundModes {
+				if declared == mode {
+					c.Sources = append(c.Sources, "UIBackgroundModes:"+mode)
+				}
+			}
+		}
+		for _, key := range rule.InfoValues {
+			for _, value := range plistStrings(info, key) {
+				c.Sources = append(c.Sources, key+":"+value)
+			}
+		}
+		for _, role := range rule.SceneRoles {
+			if _, ok := sceneConfigs[role]; ok {
+				c.Sources = append(c.Sources, "UISceneConfigurations:"+role)
+			}
+		}
+		if extensionPoint != "" && containsString(rule.ExtensionPoints, extensionPoint) {
+			c.Sources = append(c.Sources, "NSExtensionPointIdentifier:"+extensionPoint)
+		}
+		c.Enabled = len(c.Sources) > 0
+		caps = append(caps, c)
+	}
+	return caps
+}
+
+// sourceList renders the sources of a capability for the matrix
+func (c capability) sourceList() string {
+	return strings.Join(c.Sources, ", ")
+}
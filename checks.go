@@ -0,0 +1,44 @@
+package main
+
+// check is a rule evaluated against an app report once its plists and
+// entitlements have been parsed. Checks return findings and never fail.
+type check func(r *appReport) []finding
+
+// reportChecks run, in order, at the end of buildAppReport
+var reportChecks = []check{
+	checkEntitlementConsistency,
+	checkWildcardEntitlements,
+	checkForeignSlices,
+	checkToolchain,
+	checkFrameworkSignatures,
+	checkTampering,
+	checkInstrumentation,
+	checkDataProtection,
+	checkGraphQL,
+	checkHiddenExecutables,
+	checkEnterpriseDistribution,
+	checkPaywall,
+	checkFeatureFlags,
+	checkConsent,
+	checkRequiredReasonAPIs,
+	checkLocalizations,
+	checkMediaMetadata,
+	checkResources,
+	checkVPN,
+	checkKnownHashes,
+	checkAppReview,
+	checkPolicy,
+}
+
+// runChecks appends the findings of every registered check to the report
+func runChecks(r *appReport) {
+	for _, c := range reportChecks {
+		if !analyzerSelected(c) {
+			continue
+		}
+		stop := profiler.time("check:" + funcName(c))
+		r.Findings = append(r.Findings, c(r)...)
+		stop()
+		r.recorder.record(analyzerLabel(c), r)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+)
+
+// Entitlements and Info.plist keys that share data with the user's other
+// devices or with the developer's other apps
+const (
+	entAppGroups          = "com.apple.security.application-groups"
+	entAssociatedDomains  = "com.apple.developer.associated-domains"
+	keyUserActivityTypes  = "NSUserActivityTypes"
+	symUserActivityClass  = "_OBJC_CLASS_$_NSUserActivity"
+	symPasteboardLocalKey = "_UIPasteboardOptionLocalOnly"
+)
+
+// namedPasteboardSelectors create or open a pasteboard other than the general
+// one; any app from the same team can open it by name
+var namedPasteboardSelectors = []string{
+	"pasteboardWithName:create:",
+	"pasteboardWithUniqueName",
+	"removePasteboardWithName:",
+}
+
+// handoffSelectors publish an NSUserActivity to the user's other devices
+var handoffSelectors = []string{
+	"becomeCurrent",
+	"setEligibleForHandoff:",
+	"setUserActivity:",
+	"updateUserActivityState:",
+}
+
+// clipboardReport is the app's cross-device and cross-app data sharing:
+// Universal Clipboard, Handoff and pasteboards shared through app groups
+type clipboardReport struct {
+	UniversalClipboard  bool     `json:"universal_clipboard"` // general pasteboard written without UIPasteboardOptionLocalOnly
+	NamedPasteboards    []string `json:"named_pasteboards,omitempty"`
+	AppGroups           []string `json:"app_groups,omitempty"`
+	Handoff             []string `json:"handoff,omitempty"` // NSUserActivity selectors referenced
+	ActivityTypes       []string `json:"activity_types,omitempty"`
+	ContinuationDomains []string `json:"continuation_domains,omitempty"`
+}
+
+// clipboardAnalyzer records named pasteboard and Handoff selectors. It runs
+// after pasteboardAnalyzer, whose general pasteboard result it reuses.
+type clipboardAnalyzer struct {
+	seen map[string]bool
+}
+
+func newClipboardAnalyzer() stringAnalyzer {
+	return &clipboardAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *clipboardAnalyzer) visit(s string, offset int64) {
+	if containsString(namedPasteboardSelectors, s) || containsString(handoffSelectors, s) {
+		a.seen[s] = true
+	}
+}
+
+func (a *clipboardAnalyzer) findings(r *appReport) []finding {
+	c := &r.Clipboard
+	c.UniversalClipboard = r.PrivacyHygiene.Pasteboard && !containsString(r.Imports, symPasteboardLocalKey)
+	for _, sel := range namedPasteboardSelectors {
+		if a.seen[sel] {
+			c.NamedPasteboards = append(c.NamedPasteboards, sel)
+		}
+	}
+	c.AppGroups = plistStrings(r.Entitlements, entAppGroups)
+	if containsString(r.Imports, symUserActivityClass) {
+		for _, sel := range handoffSelectors {
+			if a.seen[sel] {
+				c.Handoff = append(c.Handoff, sel)
+			}
+		}
+	}
+	c.ActivityTypes = plistStrings(r.InfoPlist, keyUserActivityTypes)
+	for _, domain := range plistStrings(r.Entitlements, entAssociatedDomains) {
+		if strings.HasPrefix(domain, "activitycontinuation:") {
+			c.ContinuationDomains = append(c.ContinuationDomains, domain)
+		}
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if c.UniversalClipboard {
+		findings = append(findings, finding{
+			Rule:     "universal-clipboard",
+			Severity: severityLow,
+			Title:    "General pasteboard contents can sync to the user's other devices",
+			Evidence: "UIPasteboard generalPasteboard written, UIPasteboardOptionLocalOnly not referenced",
+			Location: location,
+			Remediation: "Copy passwords, codes and account numbers with setItems:options: and UIPasteboardOptionLocalOnly so Universal Clipboard " +
+				"does not hand them to nearby Macs and iPads signed in to the same Apple ID.",
+		})
+	}
+	if len(c.NamedPasteboards) > 0 && len(c.AppGroups) > 0 {
+		findings = append(findings, finding{
+			Rule:     "shared-pasteboard",
+			Severity: severityLow,
+			Title:    "Named pasteboards shared with the developer's other apps",
+			Evidence: sampleList(c.NamedPasteboards) + "; app groups: " + sampleList(c.AppGroups),
+			Location: location,
+			Remediation: "Named pasteboards are readable by every app from the same team and persist until removed; " +
+				"exchange sensitive data through the app group container or a shared keychain group instead, and clear the pasteboard after use.",
+		})
+	}
+	if len(c.Handoff) > 0 && len(c.ActivityTypes) > 0 {
+		evidence := sampleList(c.ActivityTypes) + "; " + sampleList(c.Handoff)
+		if len(c.ContinuationDomains) > 0 {
+			evidence += "; " + sampleList(c.ContinuationDomains)
+		}
+		findings = append(findings, finding{
+			Rule:     "handoff-activity",
+			Severity: severityInfo,
+			Title:    "User activities are published for Handoff",
+			Evidence: evidence,
+			Location: bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: "Check what each activity's userInfo and webpageURL carry; Handoff sends them to the user's other devices. " +
+				"Set eligibleForHandoff to NO on activities describing sensitive screens.",
+		})
+	}
+	return findings
+}
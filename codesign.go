@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Code signature blob magics and CodeDirectory fields
+const (
+	csMagicCodeDirectory = 0xfade0c02
+	csMagicBlobWrapper   = 0xfade0b01
+
+	csFlagAdhoc         = 0x2
+	csHashTypeSHA1      = 1
+	csHashTypeSHA384    = 4
+	csSlotInfo          = 1
+	csSlotResources     = 3
+	csSupportsTeamID    = 0x20200
+	cdHashSize          = 20
+	codeDirectoryMinLen = 44
+)
+
+// signatureInfo summarizes the code signature of a Mach-O binary
+type signatureInfo struct {
+	Signed     bool     `json:"signed"`
+	AdHoc      bool     `json:"ad_hoc,omitempty"`
+	Identifier string   `json:"identifier,omitempty"`
+	TeamID     string   `json:"team_id,omitempty"`
+	CDHash     string   `json:"cdhash,omitempty"`   // of the strongest CodeDirectory, as codesign reports it
+	CDHashes   []string `json:"cdhashes,omitempty"` // of every CodeDirectory
+
+	// Hashes of the Info.plist and sealed resources bound into the signature
+	infoPlistHash []byte
+	resourcesHash []byte
+	hashType      byte
+}
+
+// String describes the signature for tables: the team ID, "ad-hoc" or "unsigned"
+func (s signatureInfo) String() string {
+	switch {
+	case !s.Signed:
+		return "unsigned"
+	case s.AdHoc:
+		return "ad-hoc"
+	case s.TeamID == "":
+		return "signed"
+	}
+	return s.TeamID
+}
+
+// readSignature parses the CodeDirectories of the first signed slice of the binary at path
+func readSignature(path string) (signatureInfo, error) {
+	var info signatureInfo
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return info, err
+	}
+
+	for _, s := range slices {
+		cds, err := codeSignatureBlobs(f, s, csMagicCodeDirectory)
+		if err != nil {
+			return info, err
+		}
+		if len(cds) == 0 {
+			continue
+		}
+		cms, err := codeSignatureBlob(f, s, csMagicBlobWrapper)
+		if err != nil {
+			return info, err
+		}
+
+		var strongest byte
+		for i, cd := range cds {
+			parsed := parseCodeDirectory(cd)
+			if i == 0 {
+				info = parsed
+			}
+			info.CDHashes = append(info.CDHashes, parsed.CDHash)
+			if parsed.hashType > strongest {
+				strongest = parsed.hashType
+				info.CDHash = parsed.CDHash
+			}
+		}
+		// Ad-hoc signatures either set the flag or carry no CMS signature
+		info.AdHoc = info.AdHoc || len(cms) == 0
+		return info, nil
+	}
+	return info, nil
+}
+
+// parseCodeDirectory reads the identifier, team ID, flags, special slots and
+// CDHash from a CodeDirectory payload (the blob without its 8-byte magic/length
+// header). Offsets inside the CodeDirectory are relative to the start of the blob.
+func parseCodeDirectory(cd []byte) signatureInfo {
+	info := signatureInfo{Signed: true}
+	if len(cd)+8 < codeDirectoryMinLen {
+		return info
+	}
+	be := binary.BigEndian
+	blob := make([]byte, 8, len(cd)+8)
+	be.PutUint32(blob, csMagicCodeDirectory)
+	be.PutUint32(blob[4:], uint32(len(cd)+8))
+	blob = append(blob, cd...)
+
+	version := be.Uint32(blob[8:])
+	info.AdHoc = be.Uint32(blob[12:])&csFlagAdhoc != 0
+	info.Identifier = cString(blob, be.Uint32(blob[20:]))
+	if version >= csSupportsTeamID && len(blob) >= 52 {
+		info.TeamID = cString(blob, be.Uint32(blob[48:]))
+	}
+
+	hashOffset := int(be.Uint32(blob[16:]))
+	specialSlots := int(be.Uint32(blob[24:]))
+	hashSize := int(blob[36])
+	info.hashType = blob[37]
+	info.infoPlistHash = specialSlot(blob, hashOffset, hashSize, specialSlots, csSlotInfo)
+	info.resourcesHash = specialSlot(blob, hashOffset, hashSize, specialSlots, csSlotResources)
+
+	info.CDHash = hex.EncodeToString(codeHash(info.hashType, blob)[:cdHashSize])
+	return info
+}
+
+// specialSlot returns the hash stored in special slot n, which precedes the
+// code slots at hashOffset. It returns nil for absent or all-zero slots.
+func specialSlot(blob []byte, hashOffset, hashSize, count, n int) []byte {
+	start := hashOffset - n*hashSize
+	if n > count || hashSize == 0 || start < 0 || hashOffset > len(blob) {
+		return nil
+	}
+	hash := blob[start : start+hashSize]
+	for _, b := range hash {
+		if b != 0 {
+			return hash
+		}
+	}
+	return nil
+}
+
+// codeHash hashes data with the CodeDirectory hash type
+func codeHash(hashType byte, data []byte) []byte {
+	switch hashType {
+	case csHashTypeSHA1:
+		h := sha1.Sum(data)
+		return h[:]
+	case csHashTypeSHA384:
+		h := sha512.Sum384(data)
+		return h[:]
+	}
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+// cString returns the NUL-terminated string at off in b, or "" if off is out of range
+func cString(b []byte, off uint32) string {
+	if off == 0 || int(off) >= len(b) {
+		return ""
+	}
+	s := b[off:]
+	if i := strings.IndexByte(string(s), 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+// checkFrameworkSignatures compares every embedded framework's signature with
+// the main app's. On a genuine build all of them are signed by the same team;
+// unsigned, ad-hoc or foreign-team frameworks point at repackaging or an
+// injected library.
+func checkFrameworkSignatures(r *appReport) []finding {
+	var findings []finding
+	for _, fw := range r.Frameworks {
+		location, err := filepath.Rel(r.Path, fw.BinaryPath)
+		if err != nil {
+			location = fw.Name
+		}
+		sig := fw.Signature
+
+		switch {
+		case !sig.Signed:
+			findings = append(findings, finding{
+				Rule:        "framework-unsigned",
+				Severity:    severityHigh,
+				Title:       "Embedded framework is not code signed",
+				Evidence:    fw.Name,
+				Location:    location,
+				Remediation: "Sign every embedded framework during export (Embed & Sign); an unsigned framework will not load on device and suggests the bundle was modified.",
+			})
+		case sig.AdHoc && !r.Signature.AdHoc:
+			findings = append(findings, finding{
+				Rule:        "framework-adhoc",
+				Severity:    severityHigh,
+				Title:       "Embedded framework is ad-hoc signed",
+				Evidence:    fw.Name + " (" + sig.Identifier + ")",
+				Location:    location,
+				Remediation: "Re-sign the framework with the app's distribution identity; ad-hoc signed frameworks in a distribution build indicate tampering.",
+			})
+		case r.Signature.TeamID != "" && sig.TeamID != "" && sig.TeamID != r.Signature.TeamID:
+			findings = append(findings, finding{
+				Rule:        "framework-team-mismatch",
+				Severity:    severityHigh,
+				Title:       "Embedded framework signed by a different team",
+				Evidence:    fw.Name + ": " + sig.TeamID + " (app: " + r.Signature.TeamID + ")",
+				Location:    location,
+				Remediation: "Verify where the framework came from and re-sign it with the app's team; a foreign team ID is a common sign of repackaging.",
+			})
+		}
+	}
+	return findings
+}
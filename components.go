@@ -0,0 +1,85 @@
+package main
+
+import "path/filepath"
+
+// componentAnalyzers run over the binary of every embedded framework and
+// dylib. They report what the code contains; checks about the app's overall
+// posture, which would misfire on a library, run on the main binary only.
+var componentAnalyzers = []func() stringAnalyzer{
+	newBuildPathAnalyzer,
+	newPrivateAPIAnalyzer,
+	newDynamicCodeAnalyzer,
+	newEndpointAnalyzer,
+	newSecretAnalyzer,
+	newPushKeyAnalyzer,
+	newBlobAnalyzer,
+}
+
+// analyzeComponents analyzes each embedded framework and dylib as a bundle of
+// its own: its Info.plist, its load commands and its binary's strings. The
+// findings are added to the app's report, attributed to the component, and
+// the endpoints found are merged into the app's inventory.
+func analyzeComponents(r *appReport) error {
+	for i := range r.Frameworks {
+		fw := &r.Frameworks[i]
+		component := fw.Name
+		dir := filepath.Dir(fw.BinaryPath)
+		if fw.Kind == "framework" {
+			component += ".framework"
+		} else {
+			dir = r.Path
+		}
+		rel, err := filepath.Rel(r.Path, fw.BinaryPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		c := &appReport{Name: component, Path: dir, BinaryPath: fw.BinaryPath, Schemes: r.Schemes}
+		if fw.Kind == "framework" {
+			if info, err := readPlistFile(bundleInfoPlist(dir)); err == nil {
+				c.InfoPlist = info
+				c.Metadata = metadataFromInfoPlist(info)
+			}
+		}
+		// A framework whose binary cannot be parsed is reported by the
+		// signature and slice checks; skip it here
+		if c.Libraries, err = readLinkedLibraries(fw.BinaryPath); err != nil {
+			continue
+		}
+		if c.Imports, err = readImportedSymbols(fw.BinaryPath); err != nil {
+			continue
+		}
+		fw.Libraries = c.Libraries
+
+		findings := loadCommandFindings(c.Libraries, rel)
+		stringFindings, err := analyzeStrings(c, fw.BinaryPath, componentAnalyzers)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, stringFindings...)
+		// The analyzers locate the binary relative to the component
+		local := bundleLocation(c, fw.BinaryPath)
+		for j := range findings {
+			findings[j].Component = component
+			if findings[j].Location == local {
+				findings[j].Location = rel
+			}
+		}
+		r.Findings = append(r.Findings, findings...)
+		r.Endpoints = mergeEndpoints(r.Endpoints, c.Endpoints)
+	}
+	return nil
+}
+
+// mergeEndpoints returns the sorted union of two endpoint lists
+func mergeEndpoints(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	set := make(map[string]bool, len(a)+len(b))
+	for _, u := range append(append([]string(nil), a...), b...) {
+		set[u] = true
+	}
+	return sortedSet(set)
+}
@@ -0,0 +1,97 @@
+package main
+
+// Confidence levels of heuristic findings: how likely a finding is to be a
+// true positive rather than a string that merely looks like one
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+)
+
+// confidenceLevels orders the levels from most to least confident
+var confidenceLevels = []string{confidenceHigh, confidenceMedium, confidenceLow}
+
+// ruleConfidence is the confidence of the rules that infer behavior from
+// strings in the binary rather than read it from a plist, entitlement or
+// signature. Findings of other rules have no confidence level; analyzers
+// may set a finding's own, as the secret and obfuscation analyzers do.
+var ruleConfidence = map[string]string{
+	"hardcoded-secret":               confidenceHigh,
+	"push-credential":                confidenceHigh,
+	"telemetry-credential":           confidenceHigh,
+	"obfuscated-string":              confidenceMedium,
+	"private-api":                    confidenceMedium,
+	"remote-code-update":             confidenceMedium,
+	"anti-debug":                     confidenceMedium,
+	"sensitive-logging":              confidenceMedium,
+	"sql-concatenation":              confidenceMedium,
+	"keychain-default-accessibility": confidenceMedium,
+	"keychain-weak-accessibility":    confidenceMedium,
+	"pasteboard-no-expiration":       confidenceMedium,
+	"biometric-lacontext-only":       confidenceMedium,
+	"receipt-verify-from-device":     confidenceMedium,
+	"dynamic-link-open-redirect":     confidenceMedium,
+	"malware-hidden-executable":      confidenceMedium,
+	"receipt-naive-validation":       confidenceLow,
+	"receipt-not-validated":          confidenceLow,
+	"sql-format-string":              confidenceLow,
+	"js-format-string":               confidenceLow,
+	"predicate-format":               confidenceLow,
+	"tamper-suspicion":               confidenceLow,
+	"encoded-blob":                   confidenceLow,
+	"feature-flag-sensitive":         confidenceLow,
+	"paywall-local-flag":             confidenceLow,
+	"paywall-config-flag":            confidenceLow,
+	"snapshot-sensitive-screen":      confidenceLow,
+	"state-restoration-sensitive":    confidenceLow,
+	"locale-debug-text":              confidenceLow,
+}
+
+// confidenceLevel maps a 0-1 score onto a level
+func confidenceLevel(score float64) string {
+	switch {
+	case score >= 0.8:
+		return confidenceHigh
+	case score >= 0.6:
+		return confidenceMedium
+	}
+	return confidenceLow
+}
+
+// lowerConfidence drops level by steps, stopping at low. A finding without
+// a level counts as high.
+func lowerConfidence(level string, steps int) string {
+	i := 0
+	for i < len(confidenceLevels) && confidenceLevels[i] != level {
+		i++
+	}
+	if i == len(confidenceLevels) {
+		i = 0
+	}
+	return confidenceLevels[min(i+steps, len(confidenceLevels)-1)]
+}
+
+// applyConfidence gives r's heuristic findings their confidence level and
+// applies the analyst feedback recorded in b: findings marked as false
+// positives are left out, and every fpDemoteAfter marks a rule collected
+// lower the confidence of its other findings by one level. Findings must be
+// fingerprinted, see dedupeFindings.
+func applyConfidence(r *appReport, b *baseline) {
+	findings := r.Findings[:0]
+	for _, f := range r.Findings {
+		if f.Confidence == "" {
+			f.Confidence = ruleConfidence[f.Rule]
+		}
+		if b != nil {
+			if b.marked(f.Fingerprint) {
+				r.FalsePositives = append(r.FalsePositives, f.Fingerprint)
+				continue
+			}
+			if steps := b.ruleMarks(f.Rule) / fpDemoteAfter; steps > 0 {
+				f.Confidence = lowerConfidence(f.Confidence, steps)
+			}
+		}
+		findings = append(findings, f)
+	}
+	r.Findings = findings
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// trackingCategories are tracker categories Apple counts as tracking:
+// advertising, profiling and linking users across apps
+var trackingCategories = []string{"Advertisement", "Profiling", "Identification"}
+
+// consentReport combines what the app declares about tracking with the
+// tracking code it ships
+type consentReport struct {
+	ATTPrompt       string   `json:"att_prompt,omitempty"` // NSUserTrackingUsageDescription
+	ATTRequested    bool     `json:"att_requested"`        // calls ATTrackingManager
+	IDFA            bool     `json:"idfa"`                 // reads the advertising identifier
+	Manifests       []string `json:"manifests,omitempty"`  // PrivacyInfo.xcprivacy files in the bundle
+	Tracking        bool     `json:"tracking"`             // a manifest sets NSPrivacyTracking
+	TrackingDomains []string `json:"tracking_domains,omitempty"`
+	TrackingSDKs    []string `json:"tracking_sdks,omitempty"`
+}
+
+// String summarizes the consent surface, e.g. "ATT prompt, IDFA, manifest (tracking), 2 tracking SDKs"
+func (c consentReport) String() string {
+	var parts []string
+	switch {
+	case c.ATTPrompt != "" && c.ATTRequested:
+		parts = append(parts, "ATT prompt")
+	case c.ATTPrompt != "":
+		parts = append(parts, "ATT string only")
+	}
+	if c.IDFA {
+		parts = append(parts, "IDFA")
+	}
+	if len(c.Manifests) > 0 {
+		manifest := "privacy manifest"
+		if c.Tracking {
+			manifest += " (tracking)"
+		}
+		parts = append(parts, manifest)
+	}
+	if len(c.TrackingSDKs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d tracking SDKs", len(c.TrackingSDKs)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// analyzeConsent reads the privacy manifests of the app and its frameworks and
+// combines them with the ATT prompt, IDFA access and the trackers found by
+// trackerAnalyzer
+func analyzeConsent(r *appReport) error {
+	c := consentReport{ATTPrompt: plistString(r.InfoPlist, "NSUserTrackingUsageDescription")}
+	for _, sym := range r.Imports {
+		switch sym {
+		case "_OBJC_CLASS_$_ATTrackingManager":
+			c.ATTRequested = true
+		case "_OBJC_CLASS_$_ASIdentifierManager":
+			c.IDFA = true
+		}
+	}
+
+	domains := make(map[string]bool)
+	err := walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() != "PrivacyInfo.xcprivacy" {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		manifest, err := readPlistFile(path)
+		if err != nil {
+			return nil
+		}
+		c.Manifests = append(c.Manifests, filepath.ToSlash(rel))
+		c.Tracking = c.Tracking || plistBool(manifest, "NSPrivacyTracking")
+		for _, d := range plistStrings(manifest, "NSPrivacyTrackingDomains") {
+			domains[strings.ToLower(d)] = true
+		}
+		return nil
+	})
+	sort.Strings(c.Manifests)
+	c.TrackingDomains = sortedSet(domains)
+
+	for _, t := range r.Trackers {
+		for _, category := range t.Categories {
+			if containsString(trackingCategories, category) {
+				c.TrackingSDKs = append(c.TrackingSDKs, t.Name)
+				break
+			}
+		}
+	}
+	r.Consent = c
+	return err
+}
+
+// checkConsent flags inconsistencies between the tracking code the app ships
+// and what it declares: the ATT prompt string and the privacy manifests
+func checkConsent(r *appReport) []finding {
+	c := r.Consent
+	tracks := c.IDFA || len(c.TrackingSDKs) > 0
+	var evidence []string
+	if c.IDFA {
+		evidence = append(evidence, "ASIdentifierManager")
+	}
+	evidence = append(evidence, c.TrackingSDKs...)
+
+	var findings []finding
+	if tracks && c.ATTPrompt == "" {
+		findings = append(findings, finding{
+			Rule:     "att-prompt-missing",
+			Severity: severityMedium,
+			Title:    "Tracking SDKs or IDFA access without an App Tracking Transparency prompt",
+			Evidence: sampleList(evidence),
+			Location: bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: "Add NSUserTrackingUsageDescription and call ATTrackingManager before tracking; without consent the IDFA is zeroed, " +
+				"and tracking by other means violates App Review guideline 5.1.2 and GDPR consent requirements.",
+		})
+	}
+	if c.ATTPrompt != "" && !c.ATTRequested {
+		findings = append(findings, finding{
+			Rule:        "att-not-requested",
+			Severity:    severityInfo,
+			Title:       "ATT usage description without a call to ATTrackingManager",
+			Evidence:    "NSUserTrackingUsageDescription: " + snippet(c.ATTPrompt, 0, 80),
+			Location:    bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: "Remove the usage description if the app no longer asks for tracking consent, or check that an SDK requests it.",
+		})
+	}
+	if tracks && len(c.Manifests) == 0 {
+		findings = append(findings, finding{
+			Rule:        "privacy-manifest-missing",
+			Severity:    severityLow,
+			Title:       "Tracking code without a privacy manifest",
+			Evidence:    sampleList(evidence),
+			Location:    filepath.Base(r.Path),
+			Remediation: "Ship a PrivacyInfo.xcprivacy declaring NSPrivacyTracking, the tracking domains and the collected data types; App Store Connect requires one for apps using tracking SDKs.",
+		})
+	}
+	if len(c.Manifests) > 0 && tracks && !c.Tracking {
+		findings = append(findings, finding{
+			Rule:        "privacy-manifest-tracking-undeclared",
+			Severity:    severityMedium,
+			Title:       "Privacy manifest does not declare tracking",
+			Evidence:    sampleList(evidence),
+			Location:    c.Manifests[0],
+			Remediation: "Set NSPrivacyTracking to true in the privacy manifest, or remove the advertising and attribution SDKs.",
+		})
+	}
+
+	var undeclared []string
+	for _, t := range r.Trackers {
+		if !containsString(c.TrackingSDKs, t.Name) {
+			continue
+		}
+		for _, d := range t.Domains {
+			if !isDeclaredTrackingDomain(d, c.TrackingDomains) {
+				undeclared = append(undeclared, d)
+			}
+		}
+	}
+	if len(undeclared) > 0 && len(c.Manifests) > 0 {
+		sort.Strings(undeclared)
+		findings = append(findings, finding{
+			Rule:        "tracking-domain-undeclared",
+			Severity:    severityLow,
+			Title:       "Tracker domains missing from NSPrivacyTrackingDomains",
+			Evidence:    sampleList(undeclared),
+			Location:    c.Manifests[0],
+			Remediation: "List tracking domains in NSPrivacyTrackingDomains so iOS blocks them until the user grants tracking permission.",
+		})
+	}
+	return findings
+}
+
+// isDeclaredTrackingDomain reports whether host is one of the declared
+// domains or a subdomain of one
+func isDeclaredTrackingDomain(host string, declared []string) bool {
+	for _, d := range declared {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+)
+
+// usageRequirement pairs an entitlement with the Info.plist usage strings the
+// system requires before the capability it grants can be used
+type usageRequirement struct {
+	entitlement string
+	infoKeys    []string // at least one must be present
+}
+
+var usageRequirements = []usageRequirement{
+	{"com.apple.developer.healthkit", []string{"NSHealthShareUsageDescription", "NSHealthUpdateUsageDescription"}},
+	{"com.apple.developer.homekit", []string{"NSHomeKitUsageDescription"}},
+	{"com.apple.developer.siri", []string{"NSSiriUsageDescription"}},
+	{"com.apple.developer.nfc.readersession.formats", []string{"NFCReaderUsageDescription"}},
+	{"com.apple.developer.arkit.main-camera-access.allow", []string{"NSEnterpriseMCAMUsageDescription"}}, // visionOS
+}
+
+// checkEntitlementConsistency cross-validates the signed entitlements against
+// what Info.plist declares. Mismatches usually mean the two were edited
+// independently and have drifted, or that the app was re-signed.
+func checkEntitlementConsistency(r *appReport) []finding {
+	if r.Entitlements == nil {
+		return nil
+	}
+
+	var findings []finding
+	add := func(severity, title, evidence string) {
+		findings = append(findings, finding{
+			Rule:     "entitlement-consistency",
+			Severity: severity,
+			Title:    title,
+			Evidence: evidence,
+			Location: bundleLocation(r, r.BinaryPath),
+		})
+	}
+
+	info := r.InfoPlist
+	backgroundModes := plistStrings(info, "UIBackgroundModes")
+	hasBackgroundMode := func(mode string) bool {
+		for _, m := range backgroundModes {
+			if m == mode {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Push: the background mode does nothing without the entitlement, and a
+	// development APNs environment should never reach a distributed build
+	aps := plistString(r.Entitlements, "aps-environment")
+	switch {
+	case aps == "" && hasBackgroundMode("remote-notification"):
+		add(severityLow, "remote-notification background mode declared without aps-environment entitlement", "UIBackgroundModes: remote-notification")
+	case aps != "" && !hasBackgroundMode("remote-notification"):
+		add(severityInfo, "Push entitlement present without remote-notification background mode", "aps-environment: "+aps)
+	}
+	if aps == "development" {
+		add(severityMedium, "Build signed with development APNs environment", "aps-environment: development")
+	}
+
+	// Handoff entries in associated domains need activity types to be useful
+	for _, domain := range plistStrings(r.Entitlements, "com.apple.developer.associated-domains") {
+		if strings.HasPrefix(domain, "activitycontinuation:") && len(plistStrings(info, "NSUserActivityTypes")) == 0 {
+			add(severityLow, "Handoff associated domain without NSUserActivityTypes", domain)
+		}
+	}
+
+	for _, req := range usageRequirements {
+		if _, ok := r.Entitlements[req.entitlement]; !ok {
+			continue
+		}
+		declared := false
+		for _, key := range req.infoKeys {
+			if plistString(info, key) != "" {
+				declared = true
+			}
+		}
+		if !declared {
+			add(severityLow, "Entitlement without matching usage description ("+strings.Join(req.infoKeys, " or ")+")", req.entitlement)
+		}
+	}
+
+	if hasBackgroundMode("location") && plistString(info, "NSLocationAlwaysAndWhenInUseUsageDescription") == "" && plistString(info, "NSLocationAlwaysUsageDescription") == "" {
+		add(severityLow, "Background location mode without an always-location usage description", "UIBackgroundModes: location")
+	}
+
+	// application-identifier is TEAMID.bundle-id; a different bundle ID means
+	// the Info.plist was edited after signing or the app was re-signed
+	if appID := plistString(r.Entitlements, "application-identifier"); appID != "" && r.Metadata.BundleID != "" {
+		if i := strings.Index(appID, "."); i >= 0 {
+			signedID := appID[i+1:]
+			if signedID != r.Metadata.BundleID && !strings.HasSuffix(signedID, "*") {
+				add(severityMedium, "Signed application-identifier does not match CFBundleIdentifier", appID+" vs "+r.Metadata.BundleID)
+			}
+		}
+	}
+
+	if groups, ok := r.Entitlements["com.apple.security.application-groups"]; ok && formatPlistValue(groups) == "" {
+		add(severityInfo, "App groups entitlement declares no groups", "com.apple.security.application-groups")
+	}
+
+	return findings
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// containerRoot holds the data containers of installed apps
+	containerRoot = "/var/mobile/Containers/Data/Application"
+	// containerMetadata names the file recording a container's bundle ID
+	containerMetadata = ".com.apple.mobile_container_manager.metadata.plist"
+	// maxContainerFileSize skips pulled files too large to scan
+	maxContainerFileSize = 32 << 20
+)
+
+// containerDirs are the runtime data directories pulled from the container
+var containerDirs = []string{"Documents", "Library/Preferences", "Library/Caches", "Library/Application Support"}
+
+var (
+	// bundleIDPattern matches valid bundle identifiers, which are passed to the remote shell
+	bundleIDPattern = regexp.MustCompile(`^[A-Za-z0-9.-]+$`)
+	// sensitiveKeyPattern matches plist keys that usually hold credentials
+	sensitiveKeyPattern = regexp.MustCompile(`(?i)token|password|passwd|secret|api_?key|session|credential|cookie|auth`)
+)
+
+// sshTarget is a jailbroken device reached with ssh(1)
+type sshTarget struct {
+	host     string // [user@]host
+	port     string
+	identity string
+}
+
+// command returns an ssh invocation running remote on the device
+func (t sshTarget) command(ctx context.Context, remote string) *exec.Cmd {
+	args := []string{"-o", "BatchMode=yes", "-p", t.port}
+	if t.identity != "" {
+		args = append(args, "-i", t.identity)
+	}
+	return exec.CommandContext(ctx, "ssh", append(args, t.host, remote)...)
+}
+
+// output runs remote on the device and returns its standard output
+func (t sshTarget) output(ctx context.Context, remote string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := t.command(ctx, remote)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+// runContainer implements the container command: it pulls an installed app's
+// data container from a jailbroken device over SSH and scans the runtime data
+func runContainer(ctx context.Context, args []string, jsonPath string) int {
+	fs := flag.NewFlagSet("container", flag.ContinueOnError)
+	target := sshTarget{}
+	fs.StringVar(&target.host, "ssh", "", "Device to connect to, as [user@]host")
+	fs.StringVar(&target.port, "port", "22", "SSH port")
+	fs.StringVar(&target.identity, "identity", "", "SSH private key")
+	out := fs.String("out", "", "Directory for the pulled files (default <bundle-id>.container)")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if target.host == "" || fs.NArg() != 1 || !bundleIDPattern.MatchString(fs.Arg(0)) {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrContainerUsage")))
+		return exitBadInput
+	}
+	bundleID := fs.Arg(0)
+	if *out == "" {
+		*out = bundleID + ".container"
+	}
+
+	result := newScanResult("ssh://" + target.host + "/" + bundleID)
+	fail := func(code string, err error) int {
+		if errors.Is(err, exec.ErrNotFound) {
+			code = errCodeToolMissing
+		}
+		if ctx.Err() != nil {
+			code = errCodeInterrupted
+		}
+		result.addError(code, "container", bundleID, err, true)
+		result.finish()
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		if jsonPath != "" {
+			writeJSONReport(jsonPath, result)
+		}
+		return result.ExitCode
+	}
+
+	container, err := findDataContainer(ctx, target, bundleID)
+	if err != nil {
+		return fail(errCodeToolFailed, trError("ErrSSH", "Host", target.host, "Err", err))
+	}
+	if container == "" {
+		return fail(errCodeInvalidInput, trError("ErrContainerNotFound", "App", bundleID, "Host", target.host))
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return fail(errCodeIO, trError("ErrCreateDir", "Err", err))
+	}
+	files, err := pullDataContainer(ctx, target, container, *out)
+	if err != nil {
+		return fail(errCodeToolFailed, trError("ErrSSH", "Host", target.host, "Err", err))
+	}
+	activeTheme.success.Fprintln(stdout, tr("ContainerPulled", "Files", files, "Container", container, "Dir", *out))
+
+	r := &appReport{Name: bundleID, Path: *out, Metadata: appMetadata{BundleID: bundleID}}
+	if err := analyzeContainer(r); err != nil {
+		result.addError(errCodeIO, "container", bundleID, err, false)
+	}
+	attachEvidenceContext(r)
+	r.Findings = dedupeFindings(r.Findings)
+	applyConfidence(r, activeBaseline)
+	redactReport(r)
+	result.Apps = append(result.Apps, r)
+	result.finish()
+
+	var rows [][]string
+	for _, f := range r.Findings {
+		rows = append(rows, []string{f.Severity, f.Rule, f.Title, f.Location, findingEvidence(f)})
+	}
+	printTable(stdout, tr("TableFindings", "App", bundleID), []string{tr("ColSeverity"), tr("ColRule"), tr("ColTitle"), tr("ColLocation"), tr("ColEvidence")}, rows)
+	printEvidenceContext(stdout, r.Findings)
+	if jsonPath != "" {
+		if err := writeJSONReport(jsonPath, result); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			return exitToolFailure
+		}
+	}
+	return result.ExitCode
+}
+
+// findDataContainer returns the path of the data container of bundleID on the
+// device, or "" if the app is not installed. Candidates are found with grep
+// and confirmed by parsing their metadata plist, so a bundle ID that is a
+// prefix of another does not match it.
+func findDataContainer(ctx context.Context, target sshTarget, bundleID string) (string, error) {
+	out, err := target.output(ctx, fmt.Sprintf("grep -l -F -- '%s' %s/*/%s; true", bundleID, containerRoot, containerMetadata))
+	if err != nil {
+		return "", err
+	}
+	for _, path := range strings.Fields(string(out)) {
+		data, err := target.output(ctx, "cat '"+path+"'")
+		if err != nil {
+			return "", err
+		}
+		value, err := parsePlist(data)
+		if err != nil {
+			continue
+		}
+		dict, _ := value.(map[string]interface{})
+		if plistString(dict, "MCMMetadataIdentifier") == bundleID {
+			return filepath.Dir(path), nil
+		}
+	}
+	return "", nil
+}
+
+// pullDataContainer streams the runtime data directories of container from
+// the device as a tar archive and extracts them into dir, returning the
+// number of files written
+func pullDataContainer(ctx context.Context, target sshTarget, container, dir string) (int, error) {
+	var quoted []string
+	for _, d := range containerDirs {
+		quoted = append(quoted, "'"+d+"'")
+	}
+	remote := fmt.Sprintf(`cd '%s' && set -- && for d in %s; do [ -e "$d" ] && set -- "$@" "$d"; done; [ $# -gt 0 ] && tar -cf - "$@"`,
+		container, strings.Join(quoted, " "))
+	cmd := target.command(ctx, remote)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	files, extractErr := extractTar(pipe, dir)
+	io.Copy(io.Discard, pipe)
+	if err := cmd.Wait(); err != nil && extractErr == nil {
+		// the remote command exits 1 when the container has none of the directories
+		if files == 0 && stderr.Len() == 0 {
+			return 0, nil
+		}
+		return files, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return files, extractErr
+}
+
+// extractTar writes the regular files and directories of a tar stream under
+// dir, rejecting entries that would land outside it
+func extractTar(r io.Reader, dir string) (int, error) {
+	archive := tar.NewReader(r)
+	files := 0
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return files, fmt.Errorf("unsafe path in container archive: %s", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return files, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, err
+			}
+			f, err := os.Create(target)
+			if err != nil {
+				return files, err
+			}
+			_, err = io.Copy(f, archive)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return files, err
+			}
+			files++
+		}
+	}
+}
+
+// containerAnalyzers run over every pulled file
+var containerAnalyzers = []func() stringAnalyzer{
+	newSecretAnalyzer,
+}
+
+// analyzeContainer scans pulled runtime data for credentials, unencrypted
+// databases and secrets in preferences, adding findings to the report
+func analyzeContainer(r *appReport) error {
+	var databases []string
+	var plistSecrets []string
+	err := filepath.Walk(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() > maxContainerFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		findings, err := analyzeStrings(r, path, containerAnalyzers)
+		if err != nil {
+			return err
+		}
+		for i := range findings {
+			findings[i].Rule = "container-secret"
+			findings[i].Title = strings.TrimPrefix(findings[i].Title, "Hardcoded ") + " stored in app data"
+			findings[i].Location = rel
+			findings[i].Remediation = "Keep credentials in the Keychain; files in the data container are readable from backups and on jailbroken devices."
+		}
+		r.Findings = append(r.Findings, findings...)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+			databases = append(databases, rel)
+		}
+		if strings.HasSuffix(rel, ".plist") {
+			if value, err := parsePlist(data); err == nil {
+				walkDecodedValue(value, func(key string, v interface{}) {
+					if s, ok := v.(string); ok && len(s) >= 8 && sensitiveKeyPattern.MatchString(key) {
+						plistSecrets = append(plistSecrets, fmt.Sprintf("%s: %s = %s", rel, key, redactSecret(s)))
+					}
+				})
+			}
+		}
+		return nil
+	})
+
+	if len(plistSecrets) > 0 {
+		sort.Strings(plistSecrets)
+		r.Findings = append(r.Findings, finding{
+			Rule:        "container-plist-secret",
+			Severity:    severityMedium,
+			Title:       "Credential-like values in property lists",
+			Evidence:    sampleList(plistSecrets),
+			Location:    strings.SplitN(plistSecrets[0], ":", 2)[0],
+			Remediation: "Move tokens and passwords from UserDefaults and other plists to the Keychain.",
+		})
+	}
+	if len(databases) > 0 {
+		r.Findings = append(r.Findings, finding{
+			Rule:        "container-plaintext-db",
+			Severity:    severityLow,
+			Title:       "Unencrypted SQLite databases in the data container",
+			Evidence:    sampleList(databases),
+			Location:    databases[0],
+			Remediation: "Encrypt databases holding personal data (SQLCipher, or Data Protection complete) and keep them out of Caches.",
+		})
+	}
+	return err
+}
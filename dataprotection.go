@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+)
+
+// dataProtectionEntitlement sets the protection class of every file the app creates
+const dataProtectionEntitlement = "com.apple.developer.default-data-protection"
+
+// defaultDataProtection is the class iOS applies when the entitlement is absent
+const defaultDataProtection = "NSFileProtectionCompleteUntilFirstUserAuthentication"
+
+// fileProtectionNoneSymbols write individual files without data protection
+var fileProtectionNoneSymbols = []string{
+	"_NSFileProtectionNone",
+	"_NSURLFileProtectionNone",
+}
+
+// dataProtectionReport describes the file protection classes the app uses
+type dataProtectionReport struct {
+	Default   string   `json:"default"`  // protection class for new files
+	Explicit  bool     `json:"explicit"` // set by the entitlement rather than the system default
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// String summarizes the default class, e.g. "Complete (entitlement)"
+func (d dataProtectionReport) String() string {
+	s := strings.TrimPrefix(d.Default, "NSFileProtection")
+	if d.Explicit {
+		return s + " (entitlement)"
+	}
+	return s + " (system default)"
+}
+
+// checkDataProtection reports the default data protection class and flags
+// apps that opt their files out of encryption at rest
+func checkDataProtection(r *appReport) []finding {
+	d := dataProtectionReport{Default: defaultDataProtection}
+	if class := plistString(r.Entitlements, dataProtectionEntitlement); class != "" {
+		d.Default, d.Explicit = class, true
+	}
+	for _, sym := range r.Imports {
+		if containsString(fileProtectionNoneSymbols, sym) {
+			d.Overrides = append(d.Overrides, strings.TrimPrefix(sym, "_"))
+		}
+	}
+	r.DataProtection = d
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if d.Default == "NSFileProtectionNone" {
+		findings = append(findings, finding{
+			Rule:        "data-protection-none",
+			Severity:    severityHigh,
+			Title:       "App opts out of data protection by default",
+			Evidence:    dataProtectionEntitlement + " = NSFileProtectionNone",
+			Location:    location,
+			Remediation: "Remove the entitlement or set it to NSFileProtectionComplete so files are encrypted with a key that is unavailable while the device is locked.",
+		})
+	}
+	if len(d.Overrides) > 0 {
+		findings = append(findings, finding{
+			Rule:        "data-protection-none",
+			Severity:    severityLow,
+			Title:       "Files written without data protection",
+			Evidence:    strings.Join(d.Overrides, ", "),
+			Location:    location,
+			Remediation: "Write sensitive files with .completeFileProtection and reserve NSFileProtectionNone for data that must be readable before first unlock.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxLinkTemplates bounds how many dynamic link templates are kept
+const maxLinkTemplates = 20
+
+var (
+	// dynamicLinkHostPattern matches Firebase Dynamic Links and Branch hosts
+	dynamicLinkHostPattern = regexp.MustCompile(`\b[a-z0-9][a-z0-9\-]*\.(?:page\.link|app\.goo\.gl|app\.link|test-app\.link)\b`)
+	// linkTemplatePattern matches a dynamic link whose target is filled in at
+	// run time: a long link's link= parameter or one of Branch's redirect
+	// parameters followed by a format specifier
+	linkTemplatePattern = regexp.MustCompile(`(?:[?&]link=|\$(?:fallback_url|desktop_url|ios_url|ipad_url|android_url)=?)(?:%@|%s|%[0-9]*\$@|\{[^}]*\})`)
+	// branchKeyPattern matches a Branch live or test key
+	branchKeyPattern = regexp.MustCompile(`\bkey_(?:live|test)_[A-Za-z0-9]{20,}`)
+)
+
+// dynamicLinkProviders maps dynamic link host suffixes to their service
+var dynamicLinkProviders = []struct {
+	Suffix   string
+	Provider string
+}{
+	{".page.link", "Firebase Dynamic Links"},
+	{".app.goo.gl", "Firebase Dynamic Links"},
+	{".test-app.link", "Branch"},
+	{".app.link", "Branch"},
+}
+
+// deepLinkReport is the app's Firebase Dynamic Links and Branch setup
+type deepLinkReport struct {
+	Domains   []deepLinkDomain `json:"domains,omitempty"`
+	Templates []string         `json:"templates,omitempty"` // links built from run-time values
+	BranchKey string           `json:"branch_key,omitempty"`
+}
+
+// deepLinkDomain is a dynamic link domain and whether the app claims it
+type deepLinkDomain struct {
+	Domain           string `json:"domain"`
+	Provider         string `json:"provider"`
+	AssociatedDomain bool   `json:"associated_domain"` // applinks: entry present
+}
+
+// deepLinkAnalyzer collects dynamic link hosts, link templates and Branch
+// keys. It runs after endpointAnalyzer and also reads the endpoint hosts.
+type deepLinkAnalyzer struct {
+	hosts     map[string]bool
+	templates map[string]bool
+	keys      map[string]bool
+}
+
+func newDeepLinkAnalyzer() stringAnalyzer {
+	return &deepLinkAnalyzer{hosts: make(map[string]bool), templates: make(map[string]bool), keys: make(map[string]bool)}
+}
+
+func (a *deepLinkAnalyzer) visit(s string, offset int64) {
+	if len(s) > maxFormatStringLength {
+		return
+	}
+	if strings.Contains(s, ".link") || strings.Contains(s, ".goo.gl") {
+		for _, host := range dynamicLinkHostPattern.FindAllString(s, -1) {
+			a.hosts[host] = true
+		}
+	}
+	if (strings.Contains(s, "link=") || strings.Contains(s, "_url")) && linkTemplatePattern.MatchString(s) && len(a.templates) < maxLinkTemplates {
+		a.templates[s] = true
+	}
+	if strings.Contains(s, "key_") {
+		for _, key := range branchKeyPattern.FindAllString(s, -1) {
+			a.keys[key] = true
+		}
+	}
+}
+
+func (a *deepLinkAnalyzer) findings(r *appReport) []finding {
+	d := &r.DeepLinks
+	for _, u := range r.Endpoints {
+		if host := endpointHost(u); dynamicLinkProvider(host) != "" {
+			a.hosts[host] = true
+		}
+	}
+	// Custom domains are declared in Info.plist
+	custom := make(map[string]string)
+	for _, c := range []struct{ Key, Provider string }{
+		{"FirebaseDynamicLinksCustomDomains", "Firebase Dynamic Links"},
+		{"branch_universal_link_domains", "Branch"},
+	} {
+		for _, v := range plistStrings(r.InfoPlist, c.Key) {
+			host := v
+			if u, err := url.Parse(v); err == nil && u.Host != "" {
+				host = u.Host
+			}
+			host = strings.ToLower(host)
+			a.hosts[host] = true
+			custom[host] = c.Provider
+		}
+	}
+	branchKey := plistString(r.InfoPlist, "branch_key")
+	if branchKeys := plistDict(r.InfoPlist, "branch_key"); branchKeys != nil {
+		branchKey = plistString(branchKeys, "live")
+		if test := plistString(branchKeys, "test"); test != "" {
+			a.keys[test] = true
+		}
+	}
+	if branchKey != "" {
+		a.keys[branchKey] = true
+	}
+
+	claimed := make(map[string]bool)
+	for _, entry := range plistStrings(r.Entitlements, entAssociatedDomains) {
+		if host, ok := strings.CutPrefix(entry, "applinks:"); ok {
+			host, _, _ = strings.Cut(host, "?")
+			claimed[strings.ToLower(host)] = true
+		}
+	}
+	for _, host := range sortedSet(a.hosts) {
+		provider := dynamicLinkProvider(host)
+		if provider == "" {
+			provider = custom[host]
+		}
+		d.Domains = append(d.Domains, deepLinkDomain{Domain: host, Provider: provider, AssociatedDomain: claimed[host] || claimed["*."+parentDomain(host)]})
+	}
+	d.Templates = sortedSet(a.templates)
+	var testKeys []string
+	for _, key := range sortedSet(a.keys) {
+		if strings.HasPrefix(key, "key_test_") {
+			testKeys = append(testKeys, redactSecret(key))
+		} else if d.BranchKey == "" {
+			d.BranchKey = redactSecret(key)
+		}
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	var unclaimed, firebase []string
+	for _, dom := range d.Domains {
+		if !dom.AssociatedDomain {
+			unclaimed = append(unclaimed, dom.Domain)
+		}
+		if dom.Provider == "Firebase Dynamic Links" {
+			firebase = append(firebase, dom.Domain)
+		}
+	}
+	if len(unclaimed) > 0 {
+		findings = append(findings, finding{
+			Rule:     "dynamic-link-unclaimed",
+			Severity: severityLow,
+			Title:    "Dynamic link domain missing from associated domains",
+			Evidence: sampleList(unclaimed),
+			Location: location,
+			Remediation: "Add an applinks: entry for each link domain the app handles; otherwise links open in the browser and rely on " +
+				"the provider's redirect, which can be steered to another app or site.",
+		})
+	}
+	if len(d.Templates) > 0 {
+		findings = append(findings, finding{
+			Rule:     "dynamic-link-open-redirect",
+			Severity: severityMedium,
+			Title:    "Dynamic links built from run-time redirect targets",
+			Evidence: sampleList(d.Templates),
+			Location: location,
+			Remediation: "Restrict the allowed link and fallback URL patterns in the Firebase or Branch console, and build links only " +
+				"from trusted destinations so they cannot be used as an open redirect on the app's domain.",
+		})
+	}
+	if len(firebase) > 0 {
+		findings = append(findings, finding{
+			Rule:     "firebase-dynamic-links",
+			Severity: severityLow,
+			Title:    "Uses Firebase Dynamic Links, which has been shut down",
+			Evidence: sampleList(firebase),
+			Location: location,
+			Remediation: "Firebase Dynamic Links stopped serving links in August 2025; move deep links to universal links on a domain " +
+				"the developer controls.",
+		})
+	}
+	if len(testKeys) > 0 {
+		findings = append(findings, finding{
+			Rule:        "branch-test-key",
+			Severity:    severityLow,
+			Title:       "Branch test key shipped in the app",
+			Evidence:    sampleList(testKeys),
+			Location:    location,
+			Remediation: "Ship only the live Branch key in release builds; test-mode links and data are not meant for production users.",
+		})
+	}
+	return findings
+}
+
+// dynamicLinkProvider returns the service serving links on host, or "" for
+// other hosts
+func dynamicLinkProvider(host string) string {
+	for _, p := range dynamicLinkProviders {
+		if strings.HasSuffix(host, p.Suffix) {
+			return p.Provider
+		}
+	}
+	return ""
+}
+
+// parentDomain drops the first label of host
+func parentDomain(host string) string {
+	_, parent, _ := strings.Cut(host, ".")
+	return parent
+}
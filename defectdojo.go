@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defectDojoReport is DefectDojo's Generic Findings Import document, written
+// by --defectdojo for import as the "Generic Findings Import" scan type
+type defectDojoReport struct {
+	Findings []defectDojoFinding `json:"findings"`
+}
+
+// defectDojoFinding is one finding in DefectDojo's generic format
+type defectDojoFinding struct {
+	Title            string `json:"title"`
+	Severity         string `json:"severity"` // Info, Low, Medium, High or Critical
+	Description      string `json:"description"`
+	Mitigation       string `json:"mitigation,omitempty"`
+	Date             string `json:"date"` // scan date, YYYY-MM-DD
+	FilePath         string `json:"file_path,omitempty"`
+	Line             int    `json:"line,omitempty"`
+	ComponentName    string `json:"component_name,omitempty"`
+	ComponentVersion string `json:"component_version,omitempty"`
+	UniqueID         string `json:"unique_id_from_tool"` // DefectDojo deduplicates on this, see ticketKey
+	VulnID           string `json:"vuln_id_from_tool"`   // the rule
+	StaticFinding    bool   `json:"static_finding"`
+	DynamicFinding   bool   `json:"dynamic_finding"`
+}
+
+// newDefectDojoReport converts the findings of every scan. Findings in an
+// embedded framework name it as the component; the others name the app.
+func newDefectDojoReport(results []*scanResult) defectDojoReport {
+	out := defectDojoReport{Findings: []defectDojoFinding{}}
+	for _, scan := range results {
+		date := scan.StartedAt.Format("2006-01-02")
+		for _, r := range scan.Apps {
+			for _, f := range r.Findings {
+				d := defectDojoFinding{
+					Title:            r.Name + ": " + f.Title,
+					Severity:         strings.ToUpper(f.Severity[:1]) + f.Severity[1:],
+					Description:      findingMarkdown(scan, r, f),
+					Mitigation:       f.Remediation,
+					Date:             date,
+					FilePath:         f.Location,
+					ComponentName:    r.Metadata.BundleID,
+					ComponentVersion: versionString(r.Metadata),
+					UniqueID:         ticketKey(r, f),
+					VulnID:           f.Rule,
+					StaticFinding:    true,
+				}
+				if f.Component != "" {
+					d.ComponentName, d.ComponentVersion = f.Component, ""
+				}
+				if f.Context != nil {
+					d.Line = f.Context.Line
+				}
+				out.Findings = append(out.Findings, d)
+			}
+		}
+	}
+	return out
+}
+
+// findingMarkdown renders a finding's details as Markdown, the description
+// format of DefectDojo and GitLab findings
+func findingMarkdown(scan *scanResult, r *appReport, f finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**App:** %s (%s) %s\n\n", r.Name, r.Metadata.BundleID, versionString(r.Metadata))
+	fmt.Fprintf(&b, "**Input:** %s\n\n", scan.Input)
+	fmt.Fprintf(&b, "**Rule:** %s\n\n", f.Rule)
+	fmt.Fprintf(&b, "**Evidence:** `%s`\n\n", strings.ReplaceAll(f.Evidence, "`", "'"))
+	for _, name := range sortedKeysOf(f.Fields) {
+		fmt.Fprintf(&b, "**%s:** `%s`\n\n", name, strings.ReplaceAll(f.Fields[name], "`", "'"))
+	}
+	if f.Occurrences > 1 {
+		fmt.Fprintf(&b, "**Occurrences:** %d\n\n", f.Occurrences)
+	}
+	if c := f.Context; c != nil {
+		fmt.Fprintf(&b, "**Found at:** %s offset 0x%x\n\n", c.File, c.Offset)
+	}
+	fmt.Fprintf(&b, "**Fingerprint:** %s\n", f.Fingerprint)
+	return b.String()
+}
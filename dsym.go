@@ -0,0 +1,194 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// analysisOptions configures optional analysis inputs
+type analysisOptions struct {
+	dsymPath string          // --dsym: a .dSYM bundle, a DWARF file, or a directory of dSYMs
+	malware  bool            // --malware: also run the heuristics for suspicious sideloaded apps
+	frida    string          // --frida: device whose running app is compared with the static results
+	appStore string          // --app-store: storefront country the published listing is looked up in
+	noWrite  bool            // --no-write: extract to a temporary directory and leave the input's directory untouched
+	only     map[string]bool // analyze --only: the analyzers to run, see analyzerName; nil runs them all
+
+	expectSHA256 string // --expect-sha256: digest the input IPA must have, lower-case hex
+}
+
+// analysisOpts is set from the command line
+var analysisOpts analysisOptions
+
+// dsymMatch records whether a binary slice has a matching dSYM
+type dsymMatch struct {
+	Binary string `json:"binary"` // path relative to the .app
+	Arch   string `json:"arch"`
+	UUID   string `json:"uuid"`
+	DSYM   string `json:"dsym,omitempty"` // matching DWARF file, empty if none matched
+}
+
+// objcClass is an Objective-C class and the methods recovered for it
+type objcClass struct {
+	Name    string   `json:"name"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// indexDSYMs maps slice UUIDs to the DWARF files found under path
+func indexDSYMs(path string) (map[string]string, error) {
+	index := make(map[string]string)
+	add := func(file string) {
+		slices, err := readSlices(file)
+		if err != nil {
+			return // not a Mach-O, skip it
+		}
+		for _, s := range slices {
+			if s.UUID != "" {
+				index[s.UUID] = file
+			}
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		add(path)
+		return index, nil
+	}
+
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Base(filepath.Dir(p)) == "DWARF" {
+			add(p)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// correlateDSYMs matches the UUIDs of the app binary and frameworks against
+// the dSYMs in --dsym. When the main binary matches, its dSYM symbol table
+// is used to recover Objective-C method names missing from the stripped binary.
+func correlateDSYMs(r *appReport) error {
+	if analysisOpts.dsymPath == "" {
+		return nil
+	}
+
+	index, err := indexDSYMs(analysisOpts.dsymPath)
+	if err != nil {
+		return trError("ErrDSYM", "Path", analysisOpts.dsymPath, "Err", err)
+	}
+
+	binaries := []string{r.BinaryPath}
+	for _, fw := range r.Frameworks {
+		binaries = append(binaries, fw.BinaryPath)
+	}
+
+	mainDSYM := ""
+	for _, bin := range binaries {
+		slices, err := readSlices(bin)
+		if err != nil {
+			continue
+		}
+		rel, _ := filepath.Rel(r.Path, bin)
+		for _, s := range slices {
+			if s.UUID == "" {
+				continue
+			}
+			m := dsymMatch{Binary: rel, Arch: s.Arch, UUID: s.UUID, DSYM: index[s.UUID]}
+			r.DSYM = append(r.DSYM, m)
+			if bin == r.BinaryPath && m.DSYM != "" && mainDSYM == "" {
+				mainDSYM = m.DSYM
+			}
+		}
+	}
+
+	if mainDSYM != "" {
+		classes, err := readObjCSymbols(mainDSYM)
+		if err != nil {
+			return trError("ErrDSYM", "Path", mainDSYM, "Err", err)
+		}
+		r.Classes = mergeClasses(r.Classes, classes)
+	}
+	return nil
+}
+
+// readObjCSymbols collects Objective-C classes and methods from the symbol
+// table of the first slice of a Mach-O binary or dSYM
+func readObjCSymbols(path string) ([]objcClass, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(slices) == 0 || slices[0].Symtab == nil {
+		return nil, nil
+	}
+
+	methods := make(map[string]map[string]bool)
+	addClass := func(name string) map[string]bool {
+		if methods[name] == nil {
+			methods[name] = make(map[string]bool)
+		}
+		return methods[name]
+	}
+
+	for _, sym := range slices[0].Symtab.Syms {
+		name := sym.Name
+		switch {
+		case strings.HasPrefix(name, "_OBJC_CLASS_$_"):
+			addClass(strings.TrimPrefix(name, "_OBJC_CLASS_$_"))
+		case (strings.HasPrefix(name, "-[") || strings.HasPrefix(name, "+[")) && strings.HasSuffix(name, "]"):
+			body := name[2 : len(name)-1]
+			if i := strings.Index(body, " "); i > 0 {
+				class := body[:i]
+				// Category methods are written -[Class(Category) sel]
+				if j := strings.Index(class, "("); j > 0 {
+					class = class[:j]
+				}
+				addClass(class)[name[:1]+body[i+1:]] = true
+			}
+		}
+	}
+
+	classes := make([]objcClass, 0, len(methods))
+	for name, set := range methods {
+		classes = append(classes, objcClass{Name: name, Methods: sortedSet(set)})
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+	return classes, nil
+}
+
+// mergeClasses combines two class lists, uniting the methods of classes present in both
+func mergeClasses(a, b []objcClass) []objcClass {
+	methods := make(map[string]map[string]bool)
+	for _, list := range [][]objcClass{a, b} {
+		for _, c := range list {
+			if methods[c.Name] == nil {
+				methods[c.Name] = make(map[string]bool)
+			}
+			for _, m := range c.Methods {
+				methods[c.Name][m] = true
+			}
+		}
+	}
+
+	merged := make([]objcClass, 0, len(methods))
+	for name, set := range methods {
+		merged = append(merged, objcClass{Name: name, Methods: sortedSet(set)})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dynamicLoadImports load native code at runtime
+var dynamicLoadImports = []string{"_dlopen", "_dlsym", "_NSCreateObjectFileImageFromMemory", "_NSLinkModule"}
+
+// bundleOpenSelectors open an NSBundle at a path the app chooses; its code
+// is loaded by loadAndReturnError:
+var bundleOpenSelectors = []string{"bundleWithPath:", "bundleWithURL:"}
+
+// hotPatchSignatures are classes of frameworks that download and run code
+// to patch an app after release
+var hotPatchSignatures = map[string]string{
+	"JPEngine":          "JSPatch",
+	"JPBoxing":          "JSPatch",
+	"ROXCore":           "Rollout.io",
+	"DCBridge":          "DynamicCocoa",
+	"WaxPatch":          "Wax",
+	"wax_start":         "Wax",
+	"CodePush":          "CodePush",
+	"CodePushPackage":   "CodePush",
+	"RCTHotUpdateModel": "React Native hot update",
+}
+
+var (
+	// writableCodePattern matches libraries and bundles under the container's
+	// writable directories, where downloaded code ends up
+	writableCodePattern = regexp.MustCompile(`(?i)(?:/Documents/|/Library/Caches/|/Library/Application Support/|/tmp/|/private/var/mobile/)\S*\.(?:dylib|framework|bundle)\b`)
+	// remoteCodePattern matches native libraries and scripts fetched over the network
+	remoteCodePattern = regexp.MustCompile(`(?i)https?://\S+?\.(?:dylib|framework|bundle|js|jsbundle)\b`)
+)
+
+// dynamicCodeReport records how the app can load code that App Review never saw
+type dynamicCodeReport struct {
+	Loaders       []string `json:"loaders,omitempty"`        // dlopen, dlsym and NSBundle loading APIs
+	WritablePaths []string `json:"writable_paths,omitempty"` // code paths inside writable container directories
+	RemoteCode    []string `json:"remote_code,omitempty"`    // URLs of libraries and scripts
+	HotPatch      []string `json:"hot_patch,omitempty"`      // hot-patching frameworks
+	JavaScript    bool     `json:"javascript"`               // JavaScriptCore evaluates scripts
+}
+
+// dynamicCodeAnalyzer looks for code loaded from outside the signed bundle:
+// native libraries opened from the container, NSBundles loaded from
+// Documents or tmp, and JavaScriptCore running scripts from the network
+type dynamicCodeAnalyzer struct {
+	selectors map[string]bool
+	writable  map[string]bool
+	remote    map[string]bool
+	hotPatch  map[string]bool
+}
+
+func newDynamicCodeAnalyzer() stringAnalyzer {
+	return &dynamicCodeAnalyzer{
+		selectors: make(map[string]bool),
+		writable:  make(map[string]bool),
+		remote:    make(map[string]bool),
+		hotPatch:  make(map[string]bool),
+	}
+}
+
+func (a *dynamicCodeAnalyzer) visit(s string, offset int64) {
+	if containsString(bundleOpenSelectors, s) || s == "loadAndReturnError:" || s == "evaluateScript:" || s == "evaluateScript:withSourceURL:" {
+		a.selectors[s] = true
+		return
+	}
+	if framework, ok := hotPatchSignatures[s]; ok {
+		a.hotPatch[framework] = true
+		return
+	}
+	if m := writableCodePattern.FindString(s); m != "" {
+		addSample(a.writable, m)
+	}
+	if m := remoteCodePattern.FindString(s); m != "" {
+		addSample(a.remote, m)
+	}
+}
+
+func (a *dynamicCodeAnalyzer) findings(r *appReport) []finding {
+	loaders := make(map[string]bool)
+	javaScriptCore := false
+	for _, sym := range r.Imports {
+		switch {
+		case containsString(dynamicLoadImports, sym):
+			loaders[strings.TrimPrefix(sym, "_")] = true
+		case sym == "_OBJC_CLASS_$_JSContext", sym == "_JSEvaluateScript":
+			javaScriptCore = true
+		}
+	}
+	if a.selectors["loadAndReturnError:"] {
+		for _, sel := range bundleOpenSelectors {
+			if a.selectors[sel] {
+				loaders["NSBundle "+sel] = true
+			}
+		}
+	}
+
+	d := dynamicCodeReport{
+		Loaders:       sortedSet(loaders),
+		WritablePaths: sortedSet(a.writable),
+		RemoteCode:    sortedSet(a.remote),
+		HotPatch:      sortedSet(a.hotPatch),
+		JavaScript:    javaScriptCore && (a.selectors["evaluateScript:"] || a.selectors["evaluateScript:withSourceURL:"]),
+	}
+	r.DynamicCode = d
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	add := func(severity, title string, evidence []string, remediation string) {
+		findings = append(findings, finding{
+			Rule:        "remote-code-update",
+			Severity:    severity,
+			Title:       title,
+			Evidence:    sampleList(evidence),
+			Location:    location,
+			Remediation: remediation,
+		})
+	}
+
+	var remoteNative, remoteScripts []string
+	for _, u := range d.RemoteCode {
+		if ext := strings.ToLower(filepath.Ext(u)); ext == ".js" || ext == ".jsbundle" {
+			remoteScripts = append(remoteScripts, u)
+		} else {
+			remoteNative = append(remoteNative, u)
+		}
+	}
+	if len(d.Loaders) > 0 && len(d.WritablePaths)+len(remoteNative) > 0 {
+		add(severityHigh, "Native code loaded from outside the app bundle", append(d.Loaders, append(d.WritablePaths, remoteNative...)...),
+			"Ship all native code inside the signed bundle. Code loaded from the container or the network is not covered by App Review and can be replaced by anyone who can write there.")
+	}
+	if len(d.HotPatch) > 0 {
+		add(severityHigh, "Hot-patching framework downloads and runs code", d.HotPatch,
+			"Remove the hot-patching SDK; App Review guideline 2.5.2 forbids downloading code that changes app behavior, and the patch channel is a remote code execution path.")
+	}
+	if d.JavaScript && len(remoteScripts) > 0 {
+		add(severityMedium, "JavaScriptCore evaluates scripts that may come from the network", remoteScripts,
+			"Bundle the scripts the app evaluates, or verify a signature over downloaded scripts before passing them to evaluateScript:.")
+	}
+	return findings
+}
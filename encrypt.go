@@ -0,0 +1,150 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// outputRecipient is the public key an encrypted evidence archive is written for
+type outputRecipient struct {
+	path string // --encrypt-output key file
+	tool string // age or gpg
+	ext  string // suffix of the encrypted archive
+}
+
+// loadRecipient reads the key file given to --encrypt-output and picks the
+// tool that encrypts to it: an age or SSH public key uses age, an OpenPGP
+// public key, armored or binary, uses gpg
+func loadRecipient(keyPath string) (*outputRecipient, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	r := &outputRecipient{path: keyPath}
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "age1") || strings.HasPrefix(text, "ssh-ed25519 ") || strings.HasPrefix(text, "ssh-rsa "):
+		r.tool, r.ext = "age", ".age"
+	case strings.Contains(text, "-----BEGIN PGP PUBLIC KEY BLOCK-----") || len(data) > 0 && data[0]&0x80 != 0:
+		r.tool, r.ext = "gpg", ".gpg"
+	default:
+		return nil, trError("ErrRecipientFormat", "Path", keyPath)
+	}
+	if _, err := exec.LookPath(r.tool); err != nil {
+		return nil, trError("ErrRecipientTool", "Tool", r.tool, "Err", err)
+	}
+	return r, nil
+}
+
+// command returns the encryption command reading the archive on stdin
+func (r *outputRecipient) command(ctx context.Context, out string) *exec.Cmd {
+	if r.tool == "age" {
+		return exec.CommandContext(ctx, "age", "--encrypt", "-R", r.path, "-o", out)
+	}
+	return exec.CommandContext(ctx, "gpg", "--batch", "--yes", "--trust-model", "always",
+		"--recipient-file", r.path, "--output", out, "--encrypt")
+}
+
+// evidenceArchivePath names the encrypted archive for an input: next to a
+// local IPA, or in the working directory for a downloaded one
+func evidenceArchivePath(input, ext string) string {
+	if isRemoteInput(input) {
+		input = path.Base(input)
+	}
+	return strings.TrimSuffix(input, filepath.Ext(input)) + ".evidence.tar.gz" + ext
+}
+
+// writeEncryptedArchive packs the scan's JSON report and the artifacts it
+// wrote (XML Info.plist copies, GraphQL exports) into a gzipped tar and
+// encrypts it for the recipient. The tar streams straight into the
+// encryption tool, so no unencrypted copy reaches the disk. An artifact
+// that no longer exists, such as one written to the scratch directory of a
+// downloaded IPA, is recorded as an error on the result and its report.
+func writeEncryptedArchive(ctx context.Context, result *scanResult, r *outputRecipient) (string, error) {
+	var artifacts []string
+	for _, artifact := range result.Artifacts {
+		if _, err := os.Stat(artifact); err != nil {
+			result.addError(errCodeIO, "encrypt", "", trError("ErrArtifactSkipped", "Path", artifact, "Err", err), false)
+			continue
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	result.finish()
+	report, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	out := evidenceArchivePath(result.Input, r.ext)
+	cmd := r.command(ctx, out)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	writeErr := writeEvidenceTar(stdin, append(report, '\n'), artifacts)
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		os.Remove(out)
+		return "", fmt.Errorf("%s: %v: %s", r.tool, err, strings.TrimSpace(stderr.String()))
+	}
+	if writeErr != nil {
+		os.Remove(out)
+		return "", writeErr
+	}
+	return out, nil
+}
+
+// writeEvidenceTar writes report.json and the artifacts to a gzipped tar stream
+func writeEvidenceTar(w io.Writer, report []byte, artifacts []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	if err := tw.WriteHeader(&tar.Header{Name: "report.json", Mode: 0644, Size: int64(len(report)), ModTime: now}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(report); err != nil {
+		return err
+	}
+	for _, artifact := range artifacts {
+		if err := addTarFile(tw, artifact, "artifacts/"+filepath.Base(artifact)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addTarFile copies the file at path into the archive under name
+func addTarFile(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// otaInstallPattern matches configuration profile, MDM enrollment and
+// over-the-air app installation strings
+var otaInstallPattern = regexp.MustCompile(`(?i)\.mobileconfig\b|application/x-apple-aspen-config|itms-services:|path=ManagedConfigurationList|com\.apple\.mdm`)
+
+// otaResourceExtensions are bundle files searched for OTA install strings
+var otaResourceExtensions = map[string]bool{
+	".plist": true, ".json": true, ".html": true, ".htm": true, ".js": true, ".txt": true, ".strings": true,
+}
+
+// maxResourceScanSize skips bundle resources too large to be configuration
+const maxResourceScanSize = 4 << 20
+
+// provisioningProfile is the subset of embedded.mobileprovision that tells how
+// the app is distributed
+type provisioningProfile struct {
+	Name       string    `json:"name"`
+	TeamName   string    `json:"team_name,omitempty"`
+	Type       string    `json:"type"` // development, ad-hoc, enterprise or app-store
+	Devices    int       `json:"devices,omitempty"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// String summarizes the profile, e.g. "enterprise (Acme Inc), expires 2027-01-31"
+func (p *provisioningProfile) String() string {
+	if p == nil {
+		return ""
+	}
+	s := p.Type
+	if p.TeamName != "" {
+		s += " (" + p.TeamName + ")"
+	}
+	if !p.Expiration.IsZero() {
+		s += ", expires " + p.Expiration.Format("2006-01-02")
+	}
+	return s
+}
+
+// readProvisioningProfile reads the app's embedded.mobileprovision, or the
+// Contents/embedded.provisionprofile of a Mac Catalyst app, returning nil
+// when there is none, as in builds downloaded from the App Store. The
+// profile is a CMS signed message; the plist is read from its content
+// without verifying the signature.
+func readProvisioningProfile(appDir string) (*provisioningProfile, error) {
+	name := "embedded.mobileprovision"
+	if contents := bundleContents(appDir); contents != appDir {
+		name = filepath.Join("Contents", "embedded.provisionprofile")
+	}
+	data, err := os.ReadFile(filepath.Join(appDir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	start := bytes.Index(data, []byte("<?xml"))
+	end := bytes.Index(data, []byte("</plist>"))
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("embedded.mobileprovision: no property list found")
+	}
+	value, err := parsePlist(data[start : end+len("</plist>")])
+	if err != nil {
+		return nil, fmt.Errorf("embedded.mobileprovision: %v", err)
+	}
+	dict, _ := value.(map[string]interface{})
+
+	p := &provisioningProfile{
+		Name:     plistString(dict, "Name"),
+		TeamName: plistString(dict, "TeamName"),
+		Devices:  len(plistStrings(dict, "ProvisionedDevices")),
+	}
+	p.Expiration, _ = dict["ExpirationDate"].(time.Time)
+	switch {
+	case plistBool(dict, "ProvisionsAllDevices"):
+		p.Type = "enterprise"
+	case p.Devices > 0 && plistBool(plistDict(dict, "Entitlements"), "get-task-allow"):
+		p.Type = "development"
+	case p.Devices > 0:
+		p.Type = "ad-hoc"
+	default:
+		p.Type = "app-store"
+	}
+	return p, nil
+}
+
+// otaAnalyzer collects configuration profile and OTA install strings from the binary
+type otaAnalyzer struct {
+	matches map[string]bool
+}
+
+func newOTAAnalyzer() stringAnalyzer {
+	return &otaAnalyzer{matches: make(map[string]bool)}
+}
+
+func (a *otaAnalyzer) visit(s string, offset int64) {
+	if len(s) <= maxFormatStringLength && otaInstallPattern.MatchString(s) {
+		addSample(a.matches, s)
+	}
+}
+
+func (a *otaAnalyzer) findings(r *appReport) []finding {
+	r.OTAIndicators = sortedSet(a.matches)
+	return nil
+}
+
+// analyzeOTAResources adds bundled configuration profiles, and OTA install
+// strings in text resources, to the report's OTA indicators
+func analyzeOTAResources(r *appReport) error {
+	infoPlist := bundleInfoPlist(r.Path)
+	return walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext == ".mobileconfig" {
+			r.OTAIndicators = append(r.OTAIndicators, rel)
+			return nil
+		}
+		if !otaResourceExtensions[ext] || info.Size() > maxResourceScanSize || path == infoPlist {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		found := false
+		scanErr := scanPrintableStrings(f, minStringLength, func(s string, offset int64) {
+			if !found && otaInstallPattern.MatchString(s) {
+				r.OTAIndicators = append(r.OTAIndicators, rel+": "+snippet(s, 0, 80))
+				found = true
+			}
+		})
+		return scanErr
+	})
+}
+
+// checkEnterpriseDistribution flags enterprise-signed apps and, more
+// severely, enterprise-signed apps that install profiles or other apps over
+// the air, the usual shape of grey-market distribution
+func checkEnterpriseDistribution(r *appReport) []finding {
+	if r.Provisioning == nil || r.Provisioning.Type != "enterprise" {
+		return nil
+	}
+	if len(r.OTAIndicators) == 0 {
+		return []finding{{
+			Rule:        "enterprise-signed",
+			Severity:    severityInfo,
+			Title:       "Signed with an enterprise distribution profile",
+			Evidence:    r.Provisioning.String(),
+			Location:    "embedded.mobileprovision",
+			Remediation: "Enterprise profiles are for apps used inside the organization; make sure this build is not distributed to the public.",
+		}}
+	}
+	return []finding{{
+		Rule:        "enterprise-ota-distribution",
+		Severity:    severityHigh,
+		Title:       "Enterprise-signed app installs profiles or apps over the air",
+		Evidence:    r.Provisioning.String() + "; " + sampleList(r.OTAIndicators),
+		Location:    "embedded.mobileprovision",
+		Remediation: "Enterprise signing combined with MDM profile, itms-services or OTA install strings is typical of grey-market app stores; verify who distributes the app and what it installs.",
+	}}
+}
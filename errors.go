@@ -0,0 +1,81 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"os/exec"
+)
+
+// Process exit codes. These are part of the CLI contract, see README.
+const (
+	exitClean       = 0   // analysis completed, nothing above info severity
+	exitFindings    = 1   // analysis completed with low-or-higher findings
+	exitBadInput    = 2   // the input or the command line was unusable
+	exitToolFailure = 3   // an external tool or the filesystem failed and no report could be produced
+	exitPartial     = 4   // a report was produced but some analyzers failed
+	exitInterrupted = 130 // cancelled by SIGINT/SIGTERM
+)
+
+// Machine-readable codes for the errors[] array of the JSON report
+const (
+	errCodeInvalidInput = "invalid-input"
+	errCodeToolMissing  = "tool-missing"
+	errCodeToolFailed   = "tool-failed"
+	errCodeIO           = "io-error"
+	errCodeFetch        = "fetch-failed"
+	errCodeParse        = "parse-error"
+	errCodeInterrupted  = "interrupted"
+)
+
+// scanError is a problem that stopped part or all of the analysis
+type scanError struct {
+	Code    string `json:"code"`
+	Stage   string `json:"stage"`
+	App     string `json:"app,omitempty"`
+	Message string `json:"message"`
+	Fatal   bool   `json:"fatal"` // true when the scan stopped here
+}
+
+// toolErrorCode distinguishes a missing external tool from one that ran and failed
+func toolErrorCode(err error) string {
+	if errors.Is(err, exec.ErrNotFound) {
+		return errCodeToolMissing
+	}
+	return errCodeToolFailed
+}
+
+// extractErrorCode distinguishes a malformed archive from a filesystem problem
+func extractErrorCode(err error) string {
+	if errors.Is(err, zip.ErrFormat) || errors.Is(err, zip.ErrAlgorithm) || errors.Is(err, zip.ErrChecksum) || isArchiveLimitError(err) {
+		return errCodeInvalidInput
+	}
+	for _, target := range append(zipPasswordErrors, wrapperErrors...) {
+		if errors.Is(err, target) {
+			return errCodeInvalidInput
+		}
+	}
+	return errCodeIO
+}
+
+// exitCodeForErrors maps recorded errors to an exit code, or -1 if none of them decide it
+func exitCodeForErrors(errs []scanError) int {
+	partial := false
+	for _, e := range errs {
+		if !e.Fatal {
+			partial = true
+			continue
+		}
+		switch e.Code {
+		case errCodeInterrupted:
+			return exitInterrupted
+		case errCodeInvalidInput:
+			return exitBadInput
+		default:
+			return exitToolFailure
+		}
+	}
+	if partial {
+		return exitPartial
+	}
+	return -1
+}
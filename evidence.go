@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// contextWidth is how many bytes of context are kept on each side of a match
+	contextWidth = 40
+	// maxContextFileSize skips files too large to load for context
+	maxContextFileSize = 256 << 20
+	// minAnchorLength is the shortest evidence worth searching for
+	minAnchorLength = 4
+	// maxRedactedLine bounds how far --redact looks on each side of a match
+	// for the rest of its line
+	maxRedactedLine = 4096
+)
+
+// evidenceContext locates a finding's evidence in its source file
+type evidenceContext struct {
+	File   string `json:"file"`           // relative to the app bundle
+	Offset int64  `json:"offset"`         // byte offset of the match
+	Line   int    `json:"line,omitempty"` // 1-based line of the match, for text files
+	Before string `json:"before,omitempty"`
+	Match  string `json:"match"`
+	After  string `json:"after,omitempty"`
+}
+
+// secretContextRules raise findings whose surroundings are likely to hold
+// more of the credential, such as the secret access key next to an AWS
+// access key ID; they get an offset but never context
+var secretContextRules = map[string]bool{"hardcoded-secret": true, "container-secret": true}
+
+// attachEvidenceContext finds where each finding's evidence occurs in the
+// file named by its location and records the offset and the surrounding
+// bytes. Redacted evidence and secret findings get an offset but no context,
+// so the report does not print the secret the evidence leaves out.
+func attachEvidenceContext(r *appReport) {
+	files := make(map[string][]byte)
+	load := func(rel string) []byte {
+		if data, ok := files[rel]; ok {
+			return data
+		}
+		path := filepath.Join(r.Path, filepath.FromSlash(rel))
+		var data []byte
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Size() <= maxContextFileSize {
+			data, _ = os.ReadFile(path)
+		}
+		files[rel] = data
+		return data
+	}
+	for i := range r.Findings {
+		f := &r.Findings[i]
+		if f.Location == "" || f.Evidence == "" {
+			continue
+		}
+		data := load(f.Location)
+		if len(data) == 0 {
+			continue
+		}
+		for _, anchor := range evidenceAnchors(f.Evidence) {
+			anchor, _, masked := strings.Cut(anchor, "…")
+			if len(anchor) < minAnchorLength {
+				continue
+			}
+			at := bytes.Index(data, []byte(anchor))
+			if at < 0 {
+				continue
+			}
+			f.Context = newEvidenceContext(f.Location, data, at, len(anchor), masked || secretContextRules[f.Rule])
+			break
+		}
+	}
+}
+
+// evidenceAnchors returns the strings to search for: the whole evidence,
+// then each item of a list, then the value after a "label: " prefix
+func evidenceAnchors(evidence string) []string {
+	anchors := []string{evidence}
+	for _, part := range strings.FieldsFunc(evidence, func(r rune) bool { return r == ',' || r == ';' }) {
+		part = strings.TrimSpace(part)
+		anchors = append(anchors, part)
+		if _, value, ok := strings.Cut(part, ": "); ok {
+			anchors = append(anchors, strings.TrimSpace(value))
+		}
+	}
+	return anchors
+}
+
+func newEvidenceContext(file string, data []byte, at, n int, redacted bool) *evidenceContext {
+	c := &evidenceContext{File: file, Offset: int64(at), Match: string(data[at : at+n])}
+	head := data
+	if len(head) > 8192 {
+		head = head[:8192]
+	}
+	if bytes.IndexByte(head, 0) < 0 {
+		c.Line = bytes.Count(data[:at], []byte("\n")) + 1
+	}
+	if redacted {
+		return c
+	}
+	if redactOutput {
+		return redactEvidenceContext(c, data, at, n)
+	}
+	start, end := at-contextWidth, at+n+contextWidth
+	if start < 0 {
+		start = 0
+	}
+	if end > len(data) {
+		end = len(data)
+	}
+	c.Before = printableContext(data[start:at])
+	c.After = printableContext(data[at+n : end])
+	return c
+}
+
+// redactEvidenceContext cuts the context out of the redacted line holding
+// the match rather than out of the raw bytes, so a credential the window
+// would cut partway through is still recognised and masked. A match that is
+// itself part of a credential is masked and gets no context.
+func redactEvidenceContext(c *evidenceContext, data []byte, at, n int) *evidenceContext {
+	isBreak := func(b byte) bool { return b == '\n' || b == '\r' || b == 0 }
+	lineStart, lineEnd := at, at+n
+	for lineStart > 0 && at-lineStart < maxRedactedLine && !isBreak(data[lineStart-1]) {
+		lineStart--
+	}
+	for lineEnd < len(data) && lineEnd-at-n < maxRedactedLine && !isBreak(data[lineEnd]) {
+		lineEnd++
+	}
+	line := string(data[lineStart:lineEnd])
+	masked := redactText(line)
+
+	// The redacted regions lie between the unchanged prefix and suffix
+	prefix := 0
+	for prefix < len(line) && prefix < len(masked) && line[prefix] == masked[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(line)-prefix && suffix < len(masked)-prefix && line[len(line)-1-suffix] == masked[len(masked)-1-suffix] {
+		suffix++
+	}
+	from, to := at-lineStart, at-lineStart+n
+	if line != masked && to > prefix && from < len(line)-suffix {
+		c.Match = maskSecret(c.Match)
+		return c
+	}
+	if from >= len(line)-suffix {
+		from, to = from+len(masked)-len(line), to+len(masked)-len(line)
+	}
+	start, end := from-contextWidth, to+contextWidth
+	if start < 0 {
+		start = 0
+	}
+	if end > len(masked) {
+		end = len(masked)
+	}
+	c.Before = printableContext([]byte(masked[start:from]))
+	c.After = printableContext([]byte(masked[to:end]))
+	return c
+}
+
+// printableContext replaces control and non-ASCII bytes with dots so binary
+// context prints on one line
+func printableContext(b []byte) string {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		out[i] = c
+	}
+	return string(out)
+}
+
+// printEvidenceContext lists where each finding's evidence was found, with
+// the match highlighted in its context
+func printEvidenceContext(w io.Writer, findings []finding) {
+	printed := false
+	for _, f := range findings {
+		c := f.Context
+		if c == nil {
+			continue
+		}
+		if !printed {
+			activeTheme.title.Fprintln(w, tr("EvidenceContext"))
+			printed = true
+		}
+		where := fmt.Sprintf("%s@0x%x", c.File, c.Offset)
+		if c.Line > 0 {
+			where = fmt.Sprintf("%s:%d", c.File, c.Line)
+		}
+		fmt.Fprintf(w, "  %s %s %s%s%s\n", activeTheme.option.Sprint(f.Rule+":"), where, c.Before, activeTheme.match.Sprint(c.Match), c.After)
+	}
+	if printed {
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+// fastlaneSchemaVersion identifies the --fastlane output contract. Keys are
+// only ever added within a version; renaming or removing one bumps it.
+const fastlaneSchemaVersion = 1
+
+// fastlaneOutput is the document --fastlane writes to stdout. It is a small,
+// stable projection of the full report for wrapping iOSDumper in a fastlane
+// action: every key is always present and severities are always all listed.
+type fastlaneOutput struct {
+	SchemaVersion int             `json:"schema_version"`
+	Tool          string          `json:"tool"`
+	Version       string          `json:"version"`
+	Status        string          `json:"status"` // clean, findings, partial, failed or interrupted
+	ExitCode      int             `json:"exit_code"`
+	Counts        map[string]int  `json:"counts"` // findings per severity across all apps
+	Apps          []fastlaneApp   `json:"apps"`
+	Errors        []fastlaneError `json:"errors"`
+}
+
+// fastlaneApp is one scanned .app bundle
+type fastlaneApp struct {
+	Input    string            `json:"input"`
+	Name     string            `json:"name"`
+	BundleID string            `json:"bundle_id"`
+	Version  string            `json:"version"`
+	Build    string            `json:"build"`
+	Counts   map[string]int    `json:"counts"`
+	Findings []fastlaneFinding `json:"findings"`
+}
+
+// fastlaneFinding is one finding of a fastlaneApp
+type fastlaneFinding struct {
+	Rule        string            `json:"rule"`
+	Severity    string            `json:"severity"`
+	Title       string            `json:"title"`
+	Evidence    string            `json:"evidence"`
+	Location    string            `json:"location"`
+	Component   string            `json:"component"` // embedded framework or dylib, empty for the app itself
+	Remediation string            `json:"remediation"`
+	Fields      map[string]string `json:"fields,omitempty"`     // named values the rule's pattern captured
+	Confidence  string            `json:"confidence,omitempty"` // high, medium or low for heuristic rules
+	Fingerprint string            `json:"fingerprint"`
+	Occurrences int               `json:"occurrences"`
+}
+
+// fastlaneError is a problem that stopped part or all of a scan
+type fastlaneError struct {
+	Input   string `json:"input"`
+	Code    string `json:"code"`
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+	Fatal   bool   `json:"fatal"`
+}
+
+// severityCounts returns a count for every severity level, all starting at zero
+func severityCounts() map[string]int {
+	return map[string]int{severityInfo: 0, severityLow: 0, severityMedium: 0, severityHigh: 0, severityCritical: 0}
+}
+
+// newFastlaneOutput projects finished scans onto the fastlane contract
+func newFastlaneOutput(b *batchResult) fastlaneOutput {
+	out := fastlaneOutput{
+		SchemaVersion: fastlaneSchemaVersion,
+		Tool:          b.Tool,
+		Version:       b.Version,
+		Status:        b.Status,
+		ExitCode:      b.ExitCode,
+		Counts:        severityCounts(),
+		Apps:          []fastlaneApp{},
+		Errors:        []fastlaneError{},
+	}
+	for _, scan := range b.Scans {
+		for _, r := range scan.Apps {
+			app := fastlaneApp{
+				Input:    scan.Input,
+				Name:     r.Name,
+				BundleID: r.Metadata.BundleID,
+				Version:  r.Metadata.Version,
+				Build:    r.Metadata.Build,
+				Counts:   severityCounts(),
+				Findings: []fastlaneFinding{},
+			}
+			for _, f := range r.Findings {
+				app.Counts[f.Severity]++
+				out.Counts[f.Severity]++
+				app.Findings = append(app.Findings, fastlaneFinding{
+					Rule:        f.Rule,
+					Severity:    f.Severity,
+					Title:       f.Title,
+					Evidence:    f.Evidence,
+					Location:    f.Location,
+					Component:   f.Component,
+					Remediation: f.Remediation,
+					Fields:      f.Fields,
+					Confidence:  f.Confidence,
+					Fingerprint: f.Fingerprint,
+					Occurrences: f.Occurrences,
+				})
+			}
+			out.Apps = append(out.Apps, app)
+		}
+		for _, e := range scan.Errors {
+			out.Errors = append(out.Errors, fastlaneError{Input: scan.Input, Code: e.Code, Stage: e.Stage, Message: e.Message, Fatal: e.Fatal})
+		}
+	}
+	return out
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxFeatureFlagKeys bounds how many flag keys are kept
+const maxFeatureFlagKeys = 200
+
+// featureFlagSDKs are classes of remote feature flag and configuration SDKs,
+// mapped to the service name
+var featureFlagSDKs = map[string]string{
+	"LDClient":             "LaunchDarkly",
+	"LDConfig":             "LaunchDarkly",
+	"FIRRemoteConfig":      "Firebase Remote Config",
+	"RemoteConfigSettings": "Firebase Remote Config",
+	"OPTLYClient":          "Optimizely",
+	"OptimizelyClient":     "Optimizely",
+	"SplitFactoryBuilder":  "Split",
+	"ConfigCatClient":      "ConfigCat",
+	"StatsigOptions":       "Statsig",
+	"FlagsmithClient":      "Flagsmith",
+	"UnleashClient":        "Unleash",
+	"GrowthBookSDK":        "GrowthBook",
+}
+
+// featureFlagHosts are API hosts of the same services
+var featureFlagHosts = map[string]string{
+	"launchdarkly.com":                    "LaunchDarkly",
+	"firebaseremoteconfig.googleapis.com": "Firebase Remote Config",
+	"cdn.optimizely.com":                  "Optimizely",
+	"sdk.split.io":                        "Split",
+	"cdn-global.configcat.com":            "ConfigCat",
+	"featuregates.org":                    "Statsig",
+	"statsigapi.net":                      "Statsig",
+	"api.flagsmith.com":                   "Flagsmith",
+	"cdn.growthbook.io":                   "GrowthBook",
+}
+
+var (
+	// flagKeyPattern matches the usual shapes of flag keys: ff_new_checkout,
+	// feature.dark-mode, chat_enabled, isNewOnboardingEnabled
+	flagKeyPattern = regexp.MustCompile(`^(?:(?:ff|feature|features|flag|flags|toggle|exp|experiment|kill)[_.-][a-z0-9]+(?:[_.-][a-z0-9]+)*|[a-z0-9]+(?:[_-][a-z0-9]+)*[_-](?:enabled|disabled|flag|toggle|killswitch|kill_switch|rollout)|(?:is|enable|should)[A-Z][A-Za-z0-9]*(?:Enabled|Flag|Feature))$`)
+	// flagFilePattern matches the normalized names of bundled flag and remote config defaults files
+	flagFilePattern = regexp.MustCompile(`remoteconfig|featureflag|featuretoggle|^flags|^features|^toggles|^experiments`)
+)
+
+// sensitiveFlagWords are words in a flag key that suggest it gates debug,
+// internal or security-relevant behavior
+var sensitiveFlagWords = map[string]bool{
+	"debug": true, "admin": true, "internal": true, "staff": true, "employee": true, "beta": true,
+	"bypass": true, "skip": true, "qa": true, "override": true, "insecure": true, "unlock": true,
+	"hidden": true, "secret": true, "god": true, "dev": true, "developer": true, "test": true,
+	"staging": true, "sandbox": true, "cheat": true, "root": true, "jailbreak": true, "pinning": true,
+	"ssl": true, "tls": true, "tamper": true,
+}
+
+// featureFlagReport lists remote flag services and the flag keys the app knows about
+type featureFlagReport struct {
+	Services  []string `json:"services,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	Sensitive []string `json:"sensitive,omitempty"` // keys naming debug, internal or security switches
+	Files     []string `json:"files,omitempty"`     // bundled flag and remote config defaults
+}
+
+// String summarizes the flags, e.g. "LaunchDarkly, 14 keys"
+func (f featureFlagReport) String() string {
+	parts := append([]string(nil), f.Services...)
+	if len(f.Keys) > 0 {
+		parts = append(parts, fmt.Sprintf("%d keys", len(f.Keys)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// featureFlagAnalyzer collects flag SDKs, their hosts and flag key strings from the binary
+type featureFlagAnalyzer struct {
+	services map[string]bool
+	keys     map[string]bool
+}
+
+func newFeatureFlagAnalyzer() stringAnalyzer {
+	return &featureFlagAnalyzer{services: make(map[string]bool), keys: make(map[string]bool)}
+}
+
+func (a *featureFlagAnalyzer) visit(s string, offset int64) {
+	if service, ok := featureFlagSDKs[s]; ok {
+		a.services[service] = true
+		return
+	}
+	if strings.Contains(s, ".") {
+		for host, service := range featureFlagHosts {
+			if strings.Contains(s, host) {
+				a.services[service] = true
+				return
+			}
+		}
+	}
+	if len(s) <= 64 && len(a.keys) < maxFeatureFlagKeys && flagKeyPattern.MatchString(s) {
+		a.keys[s] = true
+	}
+}
+
+func (a *featureFlagAnalyzer) findings(r *appReport) []finding {
+	for _, sym := range r.Imports {
+		if service, ok := featureFlagSDKs[strings.TrimPrefix(sym, "_OBJC_CLASS_$_")]; ok {
+			a.services[service] = true
+		}
+	}
+	r.FeatureFlags = featureFlagReport{Services: sortedSet(a.services), Keys: sortedSet(a.keys)}
+	return nil
+}
+
+// analyzeFeatureFlagResources adds the keys of bundled flag and remote config
+// defaults files, and flag-shaped boolean keys in other plists and JSON, to
+// the report
+func analyzeFeatureFlagResources(r *appReport) error {
+	keys := make(map[string]bool)
+	for _, k := range r.FeatureFlags.Keys {
+		keys[k] = true
+	}
+	infoPlist := bundleInfoPlist(r.Path)
+	err := walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() > maxResourceScanSize {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil || path == infoPlist {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".plist" && ext != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var value interface{}
+		if ext == ".json" {
+			err = json.Unmarshal(data, &value)
+		} else {
+			value, err = parsePlist(data)
+		}
+		if err != nil {
+			return nil
+		}
+
+		name := strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(filepath.Base(path)))
+		if dict, ok := value.(map[string]interface{}); ok && flagFilePattern.MatchString(name) {
+			r.FeatureFlags.Files = append(r.FeatureFlags.Files, filepath.ToSlash(rel))
+			for k := range dict {
+				if len(keys) < maxFeatureFlagKeys {
+					keys[k] = true
+				}
+			}
+			return nil
+		}
+		walkDecodedValue(value, func(key string, v interface{}) {
+			if _, ok := v.(bool); ok && len(keys) < maxFeatureFlagKeys && flagKeyPattern.MatchString(key) {
+				keys[key] = true
+			}
+		})
+		return nil
+	})
+	r.FeatureFlags.Keys = sortedSet(keys)
+	sort.Strings(r.FeatureFlags.Files)
+	r.FeatureFlags.Sensitive = nil
+	for _, k := range r.FeatureFlags.Keys {
+		if isSensitiveFlag(k) {
+			r.FeatureFlags.Sensitive = append(r.FeatureFlags.Sensitive, k)
+		}
+	}
+	return err
+}
+
+// isSensitiveFlag reports whether one of the words of a snake, kebab or camel
+// case key is in sensitiveFlagWords
+func isSensitiveFlag(key string) bool {
+	var words []string
+	start := 0
+	for i, c := range key {
+		switch {
+		case c == '_' || c == '-' || c == '.':
+			words = append(words, key[start:i])
+			start = i + 1
+		case unicode.IsUpper(c) && i > start:
+			words = append(words, key[start:i])
+			start = i
+		}
+	}
+	words = append(words, key[start:])
+	for _, w := range words {
+		if sensitiveFlagWords[strings.ToLower(w)] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFeatureFlags lists the flag inventory and flags keys that look like
+// switches for debug, internal or security-relevant behavior
+func checkFeatureFlags(r *appReport) []finding {
+	f := r.FeatureFlags
+	if len(f.Services)+len(f.Keys) == 0 {
+		return nil
+	}
+	location := bundleLocation(r, r.BinaryPath)
+	if len(f.Files) > 0 && len(f.Services) == 0 {
+		location = f.Files[0]
+	}
+	title := fmt.Sprintf("%d feature flag keys", len(f.Keys))
+	if len(f.Services) > 0 {
+		title += " (" + strings.Join(f.Services, ", ") + ")"
+	}
+	findings := []finding{{
+		Rule:        "feature-flags",
+		Severity:    severityInfo,
+		Title:       title,
+		Evidence:    sampleList(append(append([]string(nil), f.Files...), f.Keys...)),
+		Location:    location,
+		Remediation: "Review what each flag gates; code behind a disabled flag still ships in the binary and can be reached by patching the flag.",
+	}}
+	if len(f.Sensitive) > 0 {
+		findings = append(findings, finding{
+			Rule:     "feature-flag-sensitive",
+			Severity: severityLow,
+			Title:    "Feature flags gate debug, internal or security functionality",
+			Evidence: sampleList(f.Sensitive),
+			Location: location,
+			Remediation: "Compile debug and internal features out of release builds rather than hiding them behind a flag, and never let a flag " +
+				"turn off certificate pinning, jailbreak detection or server-side authorization.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isRemoteInput reports whether input names an artifact to download rather than a local file
+func isRemoteInput(input string) bool {
+	u, err := url.Parse(input)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "s3", "gs", "artifactory", "nexus":
+		return true
+	}
+	return false
+}
+
+// httpClient downloads artifacts, uploads reports and calls webhooks. The
+// worker gives it a timeout, as nobody is there to interrupt a stalled server.
+var httpClient = http.DefaultClient
+
+// fetchArtifact downloads the artifact at rawURL into dir and returns its local path
+func fetchArtifact(ctx context.Context, rawURL, dir string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "s3":
+		// The AWS CLI resolves credentials from the environment, shared
+		// config, SSO or instance metadata the same way every other AWS tool does
+		return fetchWithCommand(exec.CommandContext(ctx, "aws", "s3", "cp", "--only-show-errors", rawURL, "-"), dir, path.Base(u.Path))
+	case "gs":
+		// gcloud uses the active account or application default credentials
+		return fetchWithCommand(exec.CommandContext(ctx, "gcloud", "storage", "cat", rawURL), dir, path.Base(u.Path))
+	case "artifactory", "nexus":
+		return fetchFromRepository(ctx, rawURL, dir)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	return downloadHTTP(req, dir, path.Base(u.Path))
+}
+
+// downloadHTTP performs req and saves the response body into dir/name
+func downloadHTTP(req *http.Request, dir, name string) (string, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return saveArtifact(resp.Body, dir, name)
+}
+
+// fetchWithCommand streams the standard output of cmd into dir/name
+func fetchWithCommand(cmd *exec.Cmd, dir, name string) (string, error) {
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	local, saveErr := saveArtifact(out, dir, name)
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return local, saveErr
+}
+
+// saveArtifact writes r to dir under name, which gets an .ipa suffix if it lacks one
+// so the download is accepted as scan input
+func saveArtifact(r io.Reader, dir, name string) (string, error) {
+	if name == "" || name == "/" || name == "." {
+		name = "artifact"
+	}
+	if !strings.HasSuffix(name, ".ipa") {
+		name += ".ipa"
+	}
+	local := filepath.Join(dir, name)
+	f, err := os.Create(local)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	return local, f.Close()
+}
+
+// scanInput scans a local IPA, or downloads a remote one into a scratch
+// directory first. An IPA delivered inside zip, tar or gzip wrappers is
+// unwrapped there too. An .app directory is analyzed in place. The result
+// keeps the original input in its Input field.
+func scanInput(ctx context.Context, input string, prog *progressReporter) *scanResult {
+	remote := isRemoteInput(input)
+	if !remote && strings.HasSuffix(input, ".ipa") {
+		return run(ctx, input, prog)
+	}
+	if !remote && isAppDir(input) {
+		return analyzeAppDir(ctx, input, prog)
+	}
+
+	dir, err := newScratchDir()
+	if err != nil {
+		result := newScanResult(input)
+		result.addError(errCodeIO, "fetch", "", trError("ErrCreateDir", "Err", err), true)
+		return result
+	}
+	defer os.RemoveAll(dir)
+
+	local := input
+	if remote {
+		prog.stageStart("fetch", 0)
+		if local, err = fetchArtifact(ctx, input, dir); err != nil {
+			result := newScanResult(input)
+			code := errCodeFetch
+			if errors.Is(err, exec.ErrNotFound) {
+				code = errCodeToolMissing
+			}
+			if ctx.Err() != nil {
+				code = errCodeInterrupted
+			}
+			result.addError(code, "fetch", "", trError("ErrFetch", "Input", input, "Err", err), true)
+			return result
+		}
+	}
+	prog.stageStart("unwrap", 0)
+	if inner, err := unwrapIPA(ctx, local, dir); err != nil {
+		result := newScanResult(input)
+		result.addError(extractErrorCode(err), "unwrap", "", trError("ErrUnwrap", "Input", input, "Err", err), true)
+		return result
+	} else if inner != "" {
+		local = inner
+	}
+	result := run(ctx, local, prog)
+	result.Input = input
+	return result
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Severity levels, from least to most urgent
+const (
+	severityInfo     = "info"
+	severityLow      = "low"
+	severityMedium   = "medium"
+	severityHigh     = "high"
+	severityCritical = "critical"
+)
+
+// finding is a single observation an analyzer wants to surface to the reader
+type finding struct {
+	Rule        string            `json:"rule"` // stable identifier of the check that produced it
+	Severity    string            `json:"severity"`
+	Confidence  string            `json:"confidence,omitempty"` // high, medium or low for heuristic findings, see applyConfidence
+	Title       string            `json:"title"`
+	Evidence    string            `json:"evidence"`              // the matched value, e.g. a domain or string from the binary
+	Fields      map[string]string `json:"fields,omitempty"`      // named capture groups of the rule's pattern, e.g. an AWS key's type and ID
+	Location    string            `json:"location,omitempty"`    // file the evidence came from, relative to the app bundle, see bundleLocation
+	Component   string            `json:"component,omitempty"`   // embedded framework or dylib the evidence came from, empty for the bundle itself
+	Remediation string            `json:"remediation,omitempty"` // how to fix it, for findings that are actionable
+	Context     *evidenceContext  `json:"context,omitempty"`     // where the evidence sits in the file
+	Fingerprint string            `json:"fingerprint"`           // stable identity for baselines and diffs, see fingerprint
+	Occurrences int               `json:"occurrences"`           // how many identical findings were folded into this one
+}
+
+// bundleLocation returns path relative to the app bundle, as a finding's
+// Location gives it: the main binary of a Mac Catalyst app is
+// Contents/MacOS/<exe>. A path outside the bundle is given by its base name.
+func bundleLocation(r *appReport, path string) string {
+	rel, err := filepath.Rel(r.Path, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// captureFields returns the named capture groups of pattern that took part
+// in match, a result of FindStringSubmatch, or nil when none did
+func captureFields(pattern *regexp.Regexp, match []string) map[string]string {
+	var fields map[string]string
+	for i, name := range pattern.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[name] = match[i]
+	}
+	return fields
+}
+
+// hexAddressPattern matches addresses and offsets, which change between builds
+var hexAddressPattern = regexp.MustCompile(`0x[0-9a-f]+`)
+
+// fingerprint identifies the finding across runs and app versions: a digest
+// of its rule, component and evidence, with case, whitespace and addresses
+// normalized. The location is left out so a finding keeps its fingerprint
+// when the file it was found in moves.
+func (f finding) fingerprint() string {
+	evidence := strings.Join(strings.Fields(strings.ToLower(f.Evidence)), " ")
+	evidence = hexAddressPattern.ReplaceAllString(evidence, "0x")
+	sum := sha256.Sum256([]byte(f.Rule + "\x00" + f.Component + "\x00" + evidence))
+	return hex.EncodeToString(sum[:8])
+}
+
+// dedupeFindings fingerprints each finding and folds findings sharing a
+// fingerprint into the first one, counting how often it occurred
+func dedupeFindings(findings []finding) []finding {
+	index := make(map[string]int, len(findings))
+	out := findings[:0]
+	for _, f := range findings {
+		f.Fingerprint = f.fingerprint()
+		if i, ok := index[f.Fingerprint]; ok {
+			out[i].Occurrences++
+			continue
+		}
+		f.Occurrences = 1
+		index[f.Fingerprint] = len(out)
+		out = append(out, f)
+	}
+	return out
+}
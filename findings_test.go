@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprintStable(t *testing.T) {
+	base := finding{Rule: "hardcoded-url", Severity: severityLow, Title: "Hardcoded URL", Evidence: "https://api.example.com/v1", Location: "App"}
+	want := base.fingerprint()
+	// Fingerprints are stored in baselines and suppressions, so they must not
+	// change between releases of iosdumper either
+	if want != "5d6b29d397a28750" {
+		t.Fatalf("fingerprint %s, want 5d6b29d397a28750 as recorded in existing baselines", want)
+	}
+
+	same := []struct {
+		name   string
+		change func(*finding)
+	}{
+		{"another run", func(*finding) {}},
+		{"moved file", func(f *finding) { f.Location = "Frameworks/Net.framework/Net" }},
+		{"no location", func(f *finding) { f.Location = "" }},
+		{"other severity", func(f *finding) { f.Severity = severityHigh }},
+		{"other title", func(f *finding) { f.Title = "URL" }},
+		{"case", func(f *finding) { f.Evidence = "HTTPS://API.example.com/v1" }},
+		{"whitespace", func(f *finding) { f.Evidence = "  https://api.example.com/v1\n" }},
+		{"occurrences", func(f *finding) { f.Occurrences = 7 }},
+	}
+	for _, tt := range same {
+		t.Run(tt.name, func(t *testing.T) {
+			f := base
+			tt.change(&f)
+			if got := f.fingerprint(); got != want {
+				t.Errorf("fingerprint %s, want %s", got, want)
+			}
+		})
+	}
+
+	different := []struct {
+		name   string
+		change func(*finding)
+	}{
+		{"rule", func(f *finding) { f.Rule = "insecure-url" }},
+		{"evidence", func(f *finding) { f.Evidence = "https://api.example.com/v2" }},
+		{"component", func(f *finding) { f.Component = "Net.framework" }},
+	}
+	for _, tt := range different {
+		t.Run(tt.name, func(t *testing.T) {
+			f := base
+			tt.change(&f)
+			if got := f.fingerprint(); got == want {
+				t.Errorf("fingerprint %s, want it to differ", got)
+			}
+		})
+	}
+}
+
+func TestFingerprintIgnoresAddresses(t *testing.T) {
+	a := finding{Rule: "anti-debug", Evidence: "ptrace call at 0x100004a2c"}
+	b := finding{Rule: "anti-debug", Evidence: "ptrace call at 0x1000051f0"}
+	if a.fingerprint() != b.fingerprint() {
+		t.Errorf("fingerprints %s and %s differ, want addresses ignored", a.fingerprint(), b.fingerprint())
+	}
+}
+
+func TestDedupeFindings(t *testing.T) {
+	findings := []finding{
+		{Rule: "hardcoded-url", Evidence: "https://a.example.com", Location: "App"},
+		{Rule: "hardcoded-url", Evidence: "https://b.example.com", Location: "App"},
+		{Rule: "hardcoded-url", Evidence: "https://a.example.com", Location: "Frameworks/X.framework/X"},
+		{Rule: "secret", Evidence: "https://a.example.com", Location: "App"},
+		{Rule: "hardcoded-url", Evidence: "HTTPS://A.example.com", Location: "main.js"},
+	}
+	got := dedupeFindings(findings)
+	want := []struct {
+		rule, evidence, location string
+		occurrences              int
+	}{
+		{"hardcoded-url", "https://a.example.com", "App", 3},
+		{"hardcoded-url", "https://b.example.com", "App", 1},
+		{"secret", "https://a.example.com", "App", 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d findings, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		f := got[i]
+		if f.Rule != w.rule || f.Evidence != w.evidence || f.Location != w.location || f.Occurrences != w.occurrences {
+			t.Errorf("finding %d is %+v, want %+v", i, f, w)
+		}
+		if f.Fingerprint != f.fingerprint() {
+			t.Errorf("finding %d has fingerprint %q, want %q", i, f.Fingerprint, f.fingerprint())
+		}
+	}
+
+	// Deduplicating the result again changes nothing but restarting the counts
+	again := dedupeFindings(append([]finding(nil), got...))
+	for i := range again {
+		if again[i].Fingerprint != got[i].Fingerprint {
+			t.Errorf("finding %d fingerprint changed from %s to %s", i, got[i].Fingerprint, again[i].Fingerprint)
+		}
+	}
+}
+
+func TestBundleLocation(t *testing.T) {
+	r := &appReport{Path: filepath.Join("tmp", "Payload", "App.app")}
+	tests := []struct {
+		path, want string
+	}{
+		{filepath.Join(r.Path, "App"), "App"},
+		{filepath.Join(r.Path, "Frameworks", "X.framework", "X"), "Frameworks/X.framework/X"},
+		{filepath.Join("tmp", "Payload", "Other.app", "Other"), "Other"},
+		{filepath.Join("tmp", "Payload", "App.apple"), "App.apple"},
+	}
+	for _, tt := range tests {
+		if got := bundleLocation(r, tt.path); got != tt.want {
+			t.Errorf("bundleLocation(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// fragmentsDir is set by --fragments: each scan also writes what every
+// analyzer found there as a separate JSON fragment, plus the report merged
+// back from them
+var fragmentsDir string
+
+// fragmentReport names the merged report written next to the fragments; merge
+// skips it when reading a directory
+const fragmentReport = "report.json"
+
+// bundleAnalyzer owns what buildAppReport records before any analyzer runs:
+// the Info.plist, signature, slices and linked libraries
+const bundleAnalyzer = "bundle"
+
+// reportFragment is what one analyzer contributed to the report on one app.
+// Fragments produced on different machines, e.g. static analysis on Linux
+// and a Frida pass on macOS, are combined by `iosdumper merge`.
+type reportFragment struct {
+	Tool      string                     `json:"tool"`
+	Version   string                     `json:"version"`
+	Analyzer  string                     `json:"analyzer"` // see analyzerName
+	Host      string                     `json:"host,omitempty"`
+	OS        string                     `json:"os"`
+	Input     string                     `json:"input"`
+	SHA256    string                     `json:"sha256,omitempty"`
+	StartedAt time.Time                  `json:"started_at"`
+	App       string                     `json:"app"`    // bundle path inside Payload, e.g. Foo.app
+	Fields    map[string]json.RawMessage `json:"fields"` // top-level report fields the analyzer set
+	Findings  []finding                  `json:"findings"`
+	Errors    []scanError                `json:"errors,omitempty"` // the scan's, on the first app's bundle fragment
+}
+
+// fragmentRecorder attributes the top-level fields and finding rules of an
+// appReport to the analyzer that last changed them, by comparing the report
+// before and after each one runs. A nil recorder records nothing, which keeps
+// scans without --fragments from paying for the comparisons.
+type fragmentRecorder struct {
+	last     map[string]json.RawMessage
+	fields   map[string]string // JSON field name to analyzer
+	rules    map[string]string // finding rule to the analyzer that first reported it
+	order    []string          // analyzers in the order they ran
+	findings int
+}
+
+// newFragmentRecorder returns a recorder when --fragments is set
+func newFragmentRecorder() *fragmentRecorder {
+	if fragmentsDir == "" {
+		return nil
+	}
+	// Fields still at their zero value are left for the analyzer named after them
+	return &fragmentRecorder{last: reportFields(&appReport{}), fields: make(map[string]string), rules: make(map[string]string)}
+}
+
+// record credits analyzer with everything that changed since the last call
+func (fr *fragmentRecorder) record(analyzer string, r *appReport) {
+	if fr == nil {
+		return
+	}
+	current := reportFields(r)
+	for key, value := range current {
+		if key != "findings" && !bytes.Equal(value, fr.last[key]) {
+			fr.fields[key] = analyzer
+		}
+	}
+	fr.last = current
+	for _, f := range r.Findings[min(fr.findings, len(r.Findings)):] {
+		if _, ok := fr.rules[f.Rule]; !ok {
+			fr.rules[f.Rule] = analyzer
+		}
+	}
+	fr.findings = len(r.Findings)
+	if !containsString(fr.order, analyzer) {
+		fr.order = append(fr.order, analyzer)
+	}
+}
+
+// reportFields splits an appReport into its top-level JSON fields
+func reportFields(r *appReport) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	data, err := json.Marshal(r)
+	if err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// appFragments splits the finished report on one app into fragments, one per
+// analyzer that ran. A field no analyzer changed goes to the analyzer named
+// after it, e.g. sql, and anything else unclaimed to the bundle.
+func appFragments(result *scanResult, r *appReport) []*reportFragment {
+	host, _ := os.Hostname()
+	var order []string
+	if r.recorder != nil {
+		order = r.recorder.order
+	}
+	owner := func(m map[string]string, key string) string {
+		if analyzer, ok := m[key]; ok {
+			return analyzer
+		}
+		for _, analyzer := range order {
+			if analyzerKey(analyzer) == analyzerKey(strings.ReplaceAll(key, "_", "")) {
+				return analyzer
+			}
+		}
+		return bundleAnalyzer
+	}
+	byAnalyzer := make(map[string]*reportFragment)
+	var fragments []*reportFragment
+	fragment := func(analyzer string) *reportFragment {
+		if f, ok := byAnalyzer[analyzer]; ok {
+			return f
+		}
+		f := &reportFragment{
+			Tool:      "iosdumper",
+			Version:   version,
+			Analyzer:  analyzer,
+			Host:      host,
+			OS:        runtime.GOOS,
+			Input:     result.Input,
+			SHA256:    result.SHA256,
+			StartedAt: result.StartedAt,
+			App:       appKey(r.Bundle, r.Name),
+			Fields:    make(map[string]json.RawMessage),
+			Findings:  []finding{},
+		}
+		byAnalyzer[analyzer] = f
+		fragments = append(fragments, f)
+		return f
+	}
+
+	fragment(bundleAnalyzer)
+	for _, analyzer := range order {
+		fragment(analyzer)
+	}
+	var fields, rules map[string]string
+	if r.recorder != nil {
+		fields, rules = r.recorder.fields, r.recorder.rules
+	}
+	for key, value := range reportFields(r) {
+		if key != "findings" {
+			fragment(owner(fields, key)).Fields[key] = value
+		}
+	}
+	for _, f := range r.Findings {
+		frag := fragment(owner(rules, f.Rule))
+		frag.Findings = append(frag.Findings, f)
+	}
+	return fragments
+}
+
+// appKey identifies an app across fragments and reports: its bundle path
+// inside Payload, or its name in reports that predate it
+func appKey(bundle, name string) string {
+	if bundle != "" {
+		return bundle
+	}
+	return name
+}
+
+// writeFragments writes the fragments of every app in result to
+// dir/<ipa>/<app>/<analyzer>.json and the report merged back from them to
+// dir/<ipa>/report.json, returning the merged report's path
+func writeFragments(dir string, result *scanResult) (string, error) {
+	if len(result.Apps) == 0 {
+		return "", nil
+	}
+	name := filepath.Base(result.Extraction)
+	if result.Extraction == "" {
+		name = strings.TrimSuffix(filepath.Base(result.Input), filepath.Ext(result.Input))
+	}
+	root := filepath.Join(dir, name)
+	var paths []string
+	for i, app := range result.Apps {
+		appDir := filepath.Join(root, strings.ReplaceAll(appKey(app.Bundle, app.Name), "/", "_"))
+		if err := os.MkdirAll(appDir, 0755); err != nil {
+			return "", err
+		}
+		for _, f := range appFragments(result, app) {
+			if i == 0 && f.Analyzer == bundleAnalyzer {
+				f.Errors = result.Errors
+			}
+			path := filepath.Join(appDir, f.Analyzer+".json")
+			if err := writeJSONReport(path, f); err != nil {
+				return "", err
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	merged, err := mergeSources(paths)
+	if err != nil {
+		return "", err
+	}
+	merged.Input = result.Input
+	merged.finish()
+	path := filepath.Join(root, fragmentReport)
+	return path, writeJSONReport(path, merged)
+}
+
+// mergedApp gathers one app's fields and findings across sources
+type mergedApp struct {
+	fields   map[string]json.RawMessage
+	findings map[string][]finding // by analyzer, or by report for whole reports
+	sources  []string             // keys of findings, in the order first seen
+}
+
+// reportSource is a whole JSON report given to merge rather than a fragment
+type reportSource struct {
+	Input     string                       `json:"input"`
+	SHA256    string                       `json:"sha256"`
+	StartedAt time.Time                    `json:"started_at"`
+	Apps      []map[string]json.RawMessage `json:"apps"`
+	Errors    []scanError                  `json:"errors"`
+}
+
+// mergeSources combines fragments and whole --json reports, read in order,
+// into one scan result. Directories are searched for fragments. A later
+// source wins on the fields it shares with an earlier one, and replaces the
+// findings of an analyzer both ran; findings are otherwise combined.
+func mergeSources(paths []string) (*scanResult, error) {
+	files, err := mergeFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	result := newScanResult("")
+	result.StartedAt = time.Time{}
+	apps := make(map[string]*mergedApp)
+	var order []string
+	app := func(key string) *mergedApp {
+		if a, ok := apps[key]; ok {
+			return a
+		}
+		a := &mergedApp{fields: make(map[string]json.RawMessage), findings: make(map[string][]finding)}
+		apps[key] = a
+		order = append(order, key)
+		return a
+	}
+	// scan records what identifies the scanned artifact, which must agree
+	scan := func(path, input, sha string, started time.Time, errs []scanError) error {
+		if result.Input == "" {
+			result.Input = input
+		}
+		if sha != "" && result.SHA256 != "" && sha != result.SHA256 {
+			return trError("ErrMergeMismatch", "Path", path, "Expected", result.SHA256, "Actual", sha)
+		}
+		if sha != "" {
+			result.SHA256 = sha
+		}
+		if !started.IsZero() && (result.StartedAt.IsZero() || started.Before(result.StartedAt)) {
+			result.StartedAt = started
+		}
+		result.Errors = append(result.Errors, errs...)
+		return nil
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var probe struct {
+			Analyzer string          `json:"analyzer"`
+			Apps     json.RawMessage `json:"apps"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, trError("ErrMergeSource", "Path", path, "Err", err)
+		}
+
+		switch {
+		case probe.Analyzer != "":
+			var f reportFragment
+			if err := json.Unmarshal(data, &f); err != nil {
+				return nil, trError("ErrMergeSource", "Path", path, "Err", err)
+			}
+			if err := scan(path, f.Input, f.SHA256, f.StartedAt, f.Errors); err != nil {
+				return nil, err
+			}
+			a := app(f.App)
+			for key, value := range f.Fields {
+				a.fields[key] = value
+			}
+			a.addFindings(f.Analyzer, f.Findings)
+		case probe.Apps != nil:
+			var r reportSource
+			if err := json.Unmarshal(data, &r); err != nil {
+				return nil, trError("ErrMergeSource", "Path", path, "Err", err)
+			}
+			if err := scan(path, r.Input, r.SHA256, r.StartedAt, r.Errors); err != nil {
+				return nil, err
+			}
+			for _, fields := range r.Apps {
+				var id struct{ Name, Bundle string }
+				var findings []finding
+				for key, value := range fields {
+					switch key {
+					case "findings":
+						json.Unmarshal(value, &findings)
+					case "name":
+						json.Unmarshal(value, &id.Name)
+					case "bundle":
+						json.Unmarshal(value, &id.Bundle)
+					}
+				}
+				a := app(appKey(id.Bundle, id.Name))
+				for key, value := range fields {
+					if key != "findings" {
+						a.fields[key] = value
+					}
+				}
+				a.addFindings("report", findings)
+			}
+		default:
+			return nil, trError("ErrMergeSource", "Path", path, "Err", trError("ErrMergeUnknown"))
+		}
+	}
+
+	for _, key := range order {
+		a := apps[key]
+		// The same finding reported by several sources is kept once
+		seen := make(map[string]bool)
+		findings := []finding{}
+		for _, source := range a.sources {
+			for _, f := range a.findings[source] {
+				if f.Fingerprint == "" {
+					f.Fingerprint = f.fingerprint()
+				}
+				if !seen[f.Fingerprint] {
+					seen[f.Fingerprint] = true
+					findings = append(findings, f)
+				}
+			}
+		}
+		data, err := json.Marshal(a.fields)
+		if err != nil {
+			return nil, err
+		}
+		report := &appReport{}
+		if err := json.Unmarshal(data, report); err != nil {
+			return nil, trError("ErrMergeSource", "Path", key, "Err", err)
+		}
+		// Only the bundle fragment carries the app's name; others know its key
+		if report.Name == "" && report.Bundle == "" {
+			report.Name, report.Bundle = path.Base(key), key
+		}
+		report.Findings = findings
+		result.Apps = append(result.Apps, report)
+	}
+	if result.StartedAt.IsZero() {
+		result.StartedAt = time.Now().UTC()
+	}
+	return result, nil
+}
+
+// addFindings records what source reported, replacing what it reported
+// before
+func (a *mergedApp) addFindings(source string, findings []finding) {
+	if _, ok := a.findings[source]; !ok {
+		a.sources = append(a.sources, source)
+	}
+	a.findings[source] = findings
+}
+
+// mergeFiles expands directories to the JSON files in them, except merged
+// reports, in lexical order
+func mergeFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		st, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(p, ".json") && d.Name() != fragmentReport {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// runMerge implements `iosdumper merge <fragment|report|dir>...`: it combines
+// results produced on different machines into one report, printed like a
+// scan's and written with --json
+func runMerge(args []string, jsonPath string) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() == 0 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrMergeUsage")))
+		return exitBadInput
+	}
+	result, err := mergeSources(fs.Args())
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitBadInput
+	}
+	if len(result.Apps) == 0 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrMergeEmpty")))
+		return exitBadInput
+	}
+	result.finish()
+
+	for _, app := range result.Apps {
+		printReport(stdout, app)
+	}
+	if jsonPath != "" {
+		if err := writeJSONReport(jsonPath, result); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			return exitToolFailure
+		}
+	}
+	activeTheme.success.Fprintln(stdout, tr("MergeSummary", "Apps", len(result.Apps), "Input", result.Input))
+	return result.ExitCode
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withFragments runs the test with --fragments set, so reports record which
+// analyzer set what
+func withFragments(t *testing.T) {
+	t.Helper()
+	saved := fragmentsDir
+	fragmentsDir = t.TempDir()
+	t.Cleanup(func() { fragmentsDir = saved })
+}
+
+// writeFragment writes f as a fragment file in dir
+func writeFragment(t *testing.T, dir string, f reportFragment) string {
+	t.Helper()
+	if f.Tool == "" {
+		f.Tool, f.Version, f.OS = "iosdumper", version, "linux"
+	}
+	path := filepath.Join(dir, strings.ReplaceAll(f.App, "/", "_")+"-"+f.Analyzer+".json")
+	if err := writeJSONReport(path, f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// mergedFindings returns the rules and evidence of each merged app's findings, by app
+func mergedFindings(result *scanResult) map[string][]string {
+	apps := make(map[string][]string)
+	for _, app := range result.Apps {
+		key := appKey(app.Bundle, app.Name)
+		apps[key] = []string{}
+		for _, f := range app.Findings {
+			apps[key] = append(apps[key], f.Rule+": "+f.Evidence)
+		}
+	}
+	return apps
+}
+
+func TestFragmentsRoundTrip(t *testing.T) {
+	withFragments(t)
+	r := &appReport{Name: "App.app", Bundle: "App.app", recorder: newFragmentRecorder()}
+	r.Metadata = appMetadata{BundleID: "com.example.app", Version: "1.2"}
+	r.Platform = "iOS"
+	r.recorder.record(bundleAnalyzer, r)
+	secret := finding{
+		Rule:     "aws-access-key",
+		Severity: severityHigh,
+		Title:    "AWS access key ID",
+		Evidence: "AKIA************MPLE",
+		Fields:   map[string]string{"id": testAWSKeyID},
+		Location: "App",
+	}
+	r.Findings = append(r.Findings, secret)
+	r.recorder.record("secret", r)
+	r.Findings = append(r.Findings, finding{Rule: "ats-arbitrary-loads", Severity: severityMedium, Title: "ATS disabled", Evidence: "NSAllowsArbitraryLoads"})
+	r.recorder.record("ats", r)
+	r.Findings = dedupeFindings(r.Findings)
+
+	result := newScanResult("App.ipa")
+	result.SHA256 = "ab12"
+	result.Apps = []*appReport{r}
+	path, err := writeFragments(fragmentsDir, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret lands in the fragment of the analyzer that found it, and
+	// only there
+	appDir := filepath.Join(fragmentsDir, "App", "App.app")
+	for analyzer, want := range map[string][]string{bundleAnalyzer: {}, "secret": {"aws-access-key"}, "ats": {"ats-arbitrary-loads"}} {
+		var f reportFragment
+		data, err := os.ReadFile(filepath.Join(appDir, analyzer+".json"))
+		if err == nil {
+			err = json.Unmarshal(data, &f)
+		}
+		if err != nil {
+			t.Fatalf("%s fragment: %v", analyzer, err)
+		}
+		got := []string{}
+		for _, finding := range f.Findings {
+			got = append(got, finding.Rule)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s fragment has findings %q, want %q", analyzer, got, want)
+		}
+		if _, ok := f.Fields["metadata"]; ok != (analyzer == bundleAnalyzer) {
+			t.Errorf("%s fragment has metadata: %v", analyzer, ok)
+		}
+	}
+
+	// Merged back, the report is whole again, the secret's fields included
+	merged, err := mergeSources([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Apps) != 1 {
+		t.Fatalf("merged %d apps, want 1", len(merged.Apps))
+	}
+	got := merged.Apps[0]
+	if !reflect.DeepEqual(got.Metadata, r.Metadata) || got.Platform != "iOS" || got.Bundle != "App.app" {
+		t.Errorf("merged app %+v, want the fields of %+v", got, r)
+	}
+	if !reflect.DeepEqual(got.Findings, r.Findings) {
+		t.Errorf("merged findings %+v, want %+v", got.Findings, r.Findings)
+	}
+	if merged.SHA256 != "ab12" || merged.Input != "App.ipa" {
+		t.Errorf("merged input %s (%s), want App.ipa (ab12)", merged.Input, merged.SHA256)
+	}
+}
+
+func TestMergeFragments(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := finding{Rule: "aws-access-key", Severity: severityHigh, Evidence: "AKIA************MPLE"}
+	ats := finding{Rule: "ats-arbitrary-loads", Severity: severityMedium, Evidence: "NSAllowsArbitraryLoads"}
+	hook := finding{Rule: "frida-class", Severity: severityInfo, Evidence: "LoadedAtRuntime"}
+
+	tests := []struct {
+		name      string
+		fragments []reportFragment
+		want      map[string][]string
+	}{
+		{"analyzers of one app are joined", []reportFragment{
+			{Analyzer: "secret", App: "App.app", SHA256: "ab12", Findings: []finding{key}},
+			{Analyzer: "ats", App: "App.app", SHA256: "ab12", Findings: []finding{ats}},
+		}, map[string][]string{"App.app": {"aws-access-key: AKIA************MPLE", "ats-arbitrary-loads: NSAllowsArbitraryLoads"}}},
+		{"machines of one app are joined", []reportFragment{
+			{Analyzer: "secret", App: "App.app", OS: "linux", SHA256: "ab12", Findings: []finding{key}},
+			{Analyzer: "frida", App: "App.app", OS: "darwin", Tool: "iosdumper", Findings: []finding{hook}},
+		}, map[string][]string{"App.app": {"aws-access-key: AKIA************MPLE", "frida-class: LoadedAtRuntime"}}},
+		{"apps side by side stay apart", []reportFragment{
+			{Analyzer: "secret", App: "App.app", Findings: []finding{key}},
+			{Analyzer: "secret", App: "App.app/PlugIns/Share.appex", Findings: []finding{key}},
+			{Analyzer: "ats", App: "App.app/PlugIns/Share.appex", Findings: []finding{ats}},
+		}, map[string][]string{
+			"App.app":                     {"aws-access-key: AKIA************MPLE"},
+			"App.app/PlugIns/Share.appex": {"aws-access-key: AKIA************MPLE", "ats-arbitrary-loads: NSAllowsArbitraryLoads"},
+		}},
+		{"a finding two analyzers report is kept once", []reportFragment{
+			{Analyzer: "secret", App: "App.app", Findings: []finding{key}},
+			{Analyzer: "web-content", App: "App.app", Findings: []finding{key, ats}},
+		}, map[string][]string{"App.app": {"aws-access-key: AKIA************MPLE", "ats-arbitrary-loads: NSAllowsArbitraryLoads"}}},
+		{"a rerun of an analyzer replaces its findings", []reportFragment{
+			{Analyzer: "secret", App: "App.app", Findings: []finding{key, ats}},
+			{Analyzer: "secret", App: "App.app", Findings: []finding{key}},
+		}, map[string][]string{"App.app": {"aws-access-key: AKIA************MPLE"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var paths []string
+			for i, f := range tt.fragments {
+				f.Input, f.StartedAt = "App.ipa", started
+				sub := filepath.Join(dir, string(rune('a'+i)))
+				if err := os.Mkdir(sub, 0755); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, writeFragment(t, sub, f))
+			}
+			result, err := mergeSources(paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mergedFindings(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged %q, want %q", got, tt.want)
+			}
+			if !result.StartedAt.Equal(started) {
+				t.Errorf("merged scan started at %v, want %v", result.StartedAt, started)
+			}
+		})
+	}
+}
+
+func TestMergeFragmentsOfDifferentIPAs(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFragment(t, dir, reportFragment{Analyzer: "secret", App: "App.app", Input: "App.ipa", SHA256: "ab12"})
+	b := writeFragment(t, dir, reportFragment{Analyzer: "ats", App: "App.app", Input: "App.ipa", SHA256: "cd34"})
+	if _, err := mergeSources([]string{a, b}); err == nil {
+		t.Error("merged fragments of two IPAs, want an error")
+	}
+}
+
+func TestMergeFilesSkipsMergedReport(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.json", fragmentReport, "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := mergeFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got %q, want %q", files, want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxRuntimeOnlyClasses bounds how many runtime-only class names are kept
+const maxRuntimeOnlyClasses = 200
+
+// fridaClassScript prints the loaded Objective-C classes as one JSON object
+// mapping each image path to its class names
+const fridaClassScript = `console.log(JSON.stringify(ObjC.available ? ObjC.enumerateLoadedClassesSync() : {}));`
+
+// systemImagePrefixes are where the OS's own libraries load from
+var systemImagePrefixes = []string{"/System/", "/usr/lib/", "/usr/libexec/", "/Developer/", "/private/preboot/", "/Library/Developer/"}
+
+// injectedImageMarkers identify tweak loaders and Frida's own agent, which are
+// part of the analyst's jailbreak rather than the app
+var injectedImageMarkers = []string{"MobileSubstrate", "TweakInject", "substitute", "ellekit", "libhooker", "frida", "/var/jb/"}
+
+// runtimeReport is the result of the --frida pass
+type runtimeReport struct {
+	Device         string   `json:"device"`
+	AppClasses     int      `json:"app_classes"`               // classes loaded from the main binary
+	RuntimeOnly    []string `json:"runtime_only,omitempty"`    // main binary classes missing from its symbol table
+	ExternalImages []string `json:"external_images,omitempty"` // images loaded from outside the bundle and the OS
+	Injected       []string `json:"injected,omitempty"`        // tweak and instrumentation images, ignored
+}
+
+// fridaDeviceArgs maps the --frida value to frida's device selection: usb,
+// a remote host:port, or a device ID
+func fridaDeviceArgs(device string) []string {
+	switch {
+	case device == "usb":
+		return []string{"-U"}
+	case strings.Contains(device, ":"):
+		return []string{"-H", device}
+	default:
+		return []string{"-D", device}
+	}
+}
+
+// runFridaPass attaches Frida to the running app, enumerates its loaded
+// classes and compares them with the static analysis
+func runFridaPass(ctx context.Context, r *appReport, device string) error {
+	if r.Metadata.BundleID == "" {
+		return errors.New("no bundle ID to attach to")
+	}
+	args := append(fridaDeviceArgs(device), "-N", r.Metadata.BundleID, "-q", "-e", fridaClassScript)
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "frida", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+
+	var loaded map[string][]string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "{") {
+			if err := json.Unmarshal([]byte(line), &loaded); err != nil {
+				return fmt.Errorf("parsing frida output: %v", err)
+			}
+			break
+		}
+	}
+	if loaded == nil {
+		return errors.New("frida printed no class list")
+	}
+	r.Runtime = diffRuntimeClasses(r, loaded)
+	r.Runtime.Device = device
+	r.Findings = append(r.Findings, runtimeFindings(r)...)
+	return nil
+}
+
+// diffRuntimeClasses sorts loaded images into the app's, the OS's, injected
+// and external ones, and finds main binary classes that static analysis missed
+func diffRuntimeClasses(r *appReport, loaded map[string][]string) *runtimeReport {
+	rt := &runtimeReport{}
+	static := make(map[string]bool, len(r.Classes))
+	for _, c := range r.Classes {
+		static[c.Name] = true
+	}
+	binary := "/" + r.Name + "/" + filepath.Base(r.BinaryPath)
+	bundle := "/" + r.Name + "/"
+	var runtimeOnly []string
+	for _, image := range sortedKeysOf(loaded) {
+		switch {
+		case strings.HasSuffix(image, binary):
+			rt.AppClasses += len(loaded[image])
+			if len(static) == 0 {
+				continue
+			}
+			for _, name := range loaded[image] {
+				if !static[name] && !strings.HasPrefix(name, "NSKVONotifying_") {
+					runtimeOnly = append(runtimeOnly, name)
+				}
+			}
+		case strings.Contains(image, bundle):
+		case containsAny(image, injectedImageMarkers):
+			rt.Injected = append(rt.Injected, image)
+		case hasAnyPrefix(image, systemImagePrefixes):
+		default:
+			rt.ExternalImages = append(rt.ExternalImages, image)
+		}
+	}
+	sort.Strings(runtimeOnly)
+	if len(runtimeOnly) > maxRuntimeOnlyClasses {
+		runtimeOnly = runtimeOnly[:maxRuntimeOnlyClasses]
+	}
+	rt.RuntimeOnly = runtimeOnly
+	return rt
+}
+
+// containsAny reports whether s contains one of substrs
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeFindings reports code the running app loaded that static analysis
+// could not see
+func runtimeFindings(r *appReport) []finding {
+	rt := r.Runtime
+	var findings []finding
+	if len(rt.ExternalImages) > 0 {
+		findings = append(findings, finding{
+			Rule:        "runtime-external-image",
+			Severity:    severityHigh,
+			Title:       "Running app loaded code from outside its bundle",
+			Evidence:    sampleList(rt.ExternalImages),
+			Location:    bundleLocation(r, r.BinaryPath),
+			Remediation: "Find out what loads these images; code from the data container or a download is not covered by the code signature App Review saw.",
+		})
+	}
+	if len(rt.RuntimeOnly) > 0 {
+		findings = append(findings, finding{
+			Rule:     "runtime-only-classes",
+			Severity: severityMedium,
+			Title:    fmt.Sprintf("%d classes in the running binary are missing from its symbol table", len(rt.RuntimeOnly)),
+			Evidence: sampleList(rt.RuntimeOnly),
+			Location: bundleLocation(r, r.BinaryPath),
+			Remediation: "Classes registered at runtime can come from decrypted or unpacked code; reverse the code that creates them before trusting the " +
+				"static results.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// gitLabSASTSchemaVersion is the version of GitLab's security report schema
+// the --gitlab-sast document follows
+const gitLabSASTSchemaVersion = "15.0.7"
+
+// gitLabTimeLayout is the timestamp format the schema requires, in UTC without a zone
+const gitLabTimeLayout = "2006-01-02T15:04:05"
+
+// gitLabReport is a GitLab SAST security report. Declared as
+// artifacts:reports:sast in a pipeline job, it feeds the Security Dashboard
+// and merge request widget.
+type gitLabReport struct {
+	Version         string                `json:"version"`
+	Scan            gitLabScan            `json:"scan"`
+	Vulnerabilities []gitLabVulnerability `json:"vulnerabilities"`
+}
+
+type gitLabScan struct {
+	Analyzer  gitLabTool `json:"analyzer"`
+	Scanner   gitLabTool `json:"scanner"`
+	Type      string     `json:"type"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	Status    string     `json:"status"` // success or failure
+}
+
+type gitLabTool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  gitLabVendor `json:"vendor"`
+}
+
+type gitLabVendor struct {
+	Name string `json:"name"`
+}
+
+type gitLabVulnerability struct {
+	ID          string             `json:"id"` // stable across scans, see gitLabID
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Severity    string             `json:"severity"` // Info, Low, Medium, High or Critical
+	Solution    string             `json:"solution,omitempty"`
+	Location    gitLabLocation     `json:"location"`
+	Identifiers []gitLabIdentifier `json:"identifiers"`
+}
+
+type gitLabLocation struct {
+	File      string `json:"file,omitempty"`
+	StartLine int    `json:"start_line,omitempty"`
+}
+
+type gitLabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// newGitLabReport converts the findings of every scan. The report fails when
+// any scan stopped early, so GitLab does not treat missing findings as fixed.
+func newGitLabReport(results []*scanResult) gitLabReport {
+	tool := gitLabTool{ID: "iosdumper", Name: "iOSDumper", Version: version, Vendor: gitLabVendor{Name: "iOSDumper"}}
+	out := gitLabReport{
+		Version: gitLabSASTSchemaVersion,
+		Scan: gitLabScan{
+			Analyzer:  tool,
+			Scanner:   tool,
+			Type:      "sast",
+			StartTime: results[0].StartedAt.Format(gitLabTimeLayout),
+			EndTime:   time.Now().UTC().Format(gitLabTimeLayout),
+			Status:    "success",
+		},
+		Vulnerabilities: []gitLabVulnerability{},
+	}
+	for _, scan := range results {
+		for _, e := range scan.Errors {
+			if e.Fatal {
+				out.Scan.Status = "failure"
+			}
+		}
+		for _, r := range scan.Apps {
+			for _, f := range r.Findings {
+				v := gitLabVulnerability{
+					ID:          gitLabID(ticketKey(r, f)),
+					Name:        f.Title,
+					Description: findingMarkdown(scan, r, f),
+					Severity:    strings.ToUpper(f.Severity[:1]) + f.Severity[1:],
+					Solution:    f.Remediation,
+					Location:    gitLabLocation{File: gitLabPath(r, f)},
+					Identifiers: []gitLabIdentifier{{Type: "iosdumper_rule", Name: "iOSDumper " + f.Rule, Value: f.Rule}},
+				}
+				if f.Context != nil {
+					v.Location.StartLine = f.Context.Line
+				}
+				out.Vulnerabilities = append(out.Vulnerabilities, v)
+			}
+		}
+	}
+	return out
+}
+
+// gitLabID formats a digest of key as a UUID, which the schema expects for
+// vulnerability IDs
+func gitLabID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// gitLabPath locates a finding within the IPA's payload, e.g.
+// Payload/App.app/Frameworks/Foo.framework/Foo
+func gitLabPath(r *appReport, f finding) string {
+	if f.Location == "" {
+		return ""
+	}
+	bundle := r.Bundle
+	if bundle == "" {
+		bundle = r.Name
+	}
+	return "Payload/" + bundle + "/" + f.Location
+}
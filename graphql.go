@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxGraphQLOperations bounds how many definitions are kept per app
+	maxGraphQLOperations = 200
+	// maxPersistedQueries bounds how many persisted query hashes are kept
+	maxPersistedQueries = 50
+)
+
+var (
+	// graphQLEndpointPattern matches endpoint URLs that serve GraphQL
+	graphQLEndpointPattern = regexp.MustCompile(`(?i)/(?:graphql|gql)\b|//graphql\.`)
+	// graphQLDefinitionPattern matches the start of a named definition; the
+	// executable kinds come from the binary, schema kinds only from .graphql files
+	graphQLDefinitionPattern = regexp.MustCompile(`\b(query|mutation|subscription|fragment|type|input|interface|enum|extend\s+type)\s+([_A-Za-z][_0-9A-Za-z]*)[^{}]*\{`)
+	// persistedQueryPattern matches the SHA-256 hashes Apollo uses as
+	// persisted query and operation identifiers
+	persistedQueryPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// graphQLExecutableKinds are the definitions an app sends to the server
+var graphQLExecutableKinds = map[string]bool{"query": true, "mutation": true, "subscription": true, "fragment": true}
+
+// graphQLMarkers show the binary talks GraphQL even without a recognizable endpoint
+var graphQLMarkers = []string{"persistedQuery", "sha256Hash", "operationName", "ApolloAPI", "__typename"}
+
+// graphQLOperation is one named GraphQL definition found in the app
+type graphQLOperation struct {
+	Kind     string `json:"kind"` // query, mutation, subscription, fragment or a schema kind such as type
+	Name     string `json:"name"`
+	Source   string `json:"source"` // binary or bundle-relative .graphql file
+	Document string `json:"document"`
+}
+
+// graphQLReport is the GraphQL surface discovered in the app
+type graphQLReport struct {
+	Endpoints        []string           `json:"endpoints,omitempty"`
+	PersistedQueries []string           `json:"persisted_queries,omitempty"`
+	Operations       []graphQLOperation `json:"operations,omitempty"`
+}
+
+// String summarizes the discovery, e.g. "2 endpoints, 14 operations, 3 persisted queries"
+func (g graphQLReport) String() string {
+	if len(g.Endpoints)+len(g.Operations)+len(g.PersistedQueries) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d endpoints, %d operations, %d persisted queries", len(g.Endpoints), len(g.Operations), len(g.PersistedQueries))
+}
+
+// graphQLAnalyzer collects GraphQL documents compiled into the binary, such as
+// the operation text apollo-ios generates, and persisted query hashes
+type graphQLAnalyzer struct {
+	operations []graphQLOperation
+	hashes     map[string]bool
+	markers    bool
+	seen       map[string]bool
+}
+
+func newGraphQLAnalyzer() stringAnalyzer {
+	return &graphQLAnalyzer{hashes: make(map[string]bool), seen: make(map[string]bool)}
+}
+
+func (a *graphQLAnalyzer) visit(s string, offset int64) {
+	switch {
+	case persistedQueryPattern.MatchString(s):
+		if len(a.hashes) < maxPersistedQueries {
+			a.hashes[s] = true
+		}
+		return
+	case containsString(graphQLMarkers, s):
+		a.markers = true
+		return
+	case !strings.Contains(s, "{") || a.seen[s]:
+		return
+	}
+	a.seen[s] = true
+	for _, op := range splitGraphQLDefinitions(s, "binary") {
+		if graphQLExecutableKinds[op.Kind] && len(a.operations) < maxGraphQLOperations {
+			a.operations = append(a.operations, op)
+		}
+	}
+}
+
+// findings records the GraphQL surface; checkGraphQL reports it once bundled
+// documents are added. It runs after the endpoint analyzer so the report's
+// endpoints are already known.
+func (a *graphQLAnalyzer) findings(r *appReport) []finding {
+	g := graphQLReport{Operations: a.operations}
+	for _, u := range r.Endpoints {
+		if graphQLEndpointPattern.MatchString(u) {
+			g.Endpoints = append(g.Endpoints, u)
+		}
+	}
+	// A 64-digit hex string alone is as likely a checksum as a query hash
+	if len(g.Endpoints) > 0 || len(g.Operations) > 0 || a.markers {
+		g.PersistedQueries = sortedSet(a.hashes)
+	}
+	r.GraphQL = g
+	return nil
+}
+
+// checkGraphQL inventories the GraphQL API and flags shipped introspection
+// queries; testers export the documents to replay the operations the app sends
+func checkGraphQL(r *appReport) []finding {
+	g := r.GraphQL
+	if len(g.Endpoints) == 0 && len(g.Operations) == 0 {
+		return nil
+	}
+	evidence := g.String()
+	if len(g.Endpoints) > 0 {
+		evidence += ": " + sampleList(g.Endpoints)
+	}
+	var findings []finding
+	findings = append(findings, finding{
+		Rule:        "graphql-api",
+		Severity:    severityInfo,
+		Title:       "GraphQL API in use",
+		Evidence:    evidence,
+		Location:    bundleLocation(r, r.BinaryPath),
+		Remediation: "Enforce authorization per field on the server, disable introspection in production and accept only persisted queries if the app is the only client.",
+	})
+	for _, op := range g.Operations {
+		if strings.Contains(op.Document, "__schema") {
+			location := op.Source
+			if location == "binary" {
+				location = bundleLocation(r, r.BinaryPath)
+			}
+			findings = append(findings, finding{
+				Rule:        "graphql-introspection",
+				Severity:    severityLow,
+				Title:       "App ships a GraphQL introspection query",
+				Evidence:    op.Name,
+				Location:    location,
+				Remediation: "Remove introspection from release builds; the server answering it hands out the full schema.",
+			})
+			break
+		}
+	}
+	return findings
+}
+
+// analyzeGraphQLResources adds the definitions in bundled .graphql and .gql
+// files, including schema types, to the report's GraphQL section
+func analyzeGraphQLResources(r *appReport) error {
+	var files []string
+	walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if ext := strings.ToLower(filepath.Ext(path)); err == nil && !info.IsDir() && (ext == ".graphql" || ext == ".gql") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	for _, path := range files {
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, op := range splitGraphQLDefinitions(string(data), filepath.ToSlash(rel)) {
+			if len(r.GraphQL.Operations) < maxGraphQLOperations {
+				r.GraphQL.Operations = append(r.GraphQL.Operations, op)
+			}
+		}
+	}
+	return nil
+}
+
+// splitGraphQLDefinitions returns each named definition in text, cut at the
+// brace that closes it. Unbalanced text, such as a document split across
+// strings, yields nothing.
+func splitGraphQLDefinitions(text, source string) []graphQLOperation {
+	var ops []graphQLOperation
+	for len(text) > 0 {
+		m := graphQLDefinitionPattern.FindStringSubmatchIndex(text)
+		if m == nil {
+			break
+		}
+		depth, end := 0, -1
+		for i := m[1] - 1; i < len(text) && end < 0; i++ {
+			switch text[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i + 1
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+		ops = append(ops, graphQLOperation{
+			Kind:     strings.Join(strings.Fields(text[m[2]:m[3]]), " "),
+			Name:     text[m[4]:m[5]],
+			Source:   source,
+			Document: strings.TrimSpace(text[m[0]:end]),
+		})
+		text = text[end:]
+	}
+	return ops
+}
+
+// exportGraphQL writes every discovered definition to <app>.graphql in dir so
+// API testers can load it into their client, and returns the path written,
+// or "" when the app has no GraphQL documents
+func exportGraphQL(r *appReport, dir string) (string, error) {
+	g := r.GraphQL
+	if len(g.Operations) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# GraphQL documents discovered in %s by iosdumper\n", r.Name)
+	for _, u := range g.Endpoints {
+		fmt.Fprintf(&b, "# endpoint: %s\n", u)
+	}
+	for _, h := range g.PersistedQueries {
+		fmt.Fprintf(&b, "# persisted query: %s\n", h)
+	}
+	source := ""
+	for _, op := range g.Operations {
+		if op.Source != source {
+			source = op.Source
+			fmt.Fprintf(&b, "\n# source: %s\n", source)
+		}
+		fmt.Fprintf(&b, "\n%s\n", op.Document)
+	}
+
+	path := filepath.Join(dir, strings.TrimSuffix(r.Name, filepath.Ext(r.Name))+".graphql")
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
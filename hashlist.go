@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hashList maps lowercase hex SHA-256 digests to a label for the file
+type hashList map[string]string
+
+// knownHashes are loaded from --known-good and --known-bad
+var knownHashes struct {
+	good hashList
+	bad  hashList
+}
+
+// knownFile is a bundle file whose digest is on a hash list
+type knownFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	List   string `json:"list"` // good or bad
+	Label  string `json:"label,omitempty"`
+}
+
+// loadHashList reads a hash list: one SHA-256 digest per line, optionally
+// followed by a label, so the output of sha256sum or shasum -a 256 can be
+// used as is. Blank lines and lines starting with # are skipped.
+func loadHashList(file string) (hashList, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list := make(hashList)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest, label, _ := strings.Cut(line, " ")
+		digest = strings.ToLower(digest)
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("hash list %s:%d: not a SHA-256 digest: %q", file, n, digest)
+		}
+		// sha256sum marks binary mode with a * before the file name
+		list[digest] = strings.TrimPrefix(strings.TrimSpace(label), "*")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("hash list %s: no digests", file)
+	}
+	return list, nil
+}
+
+// analyzeKnownHashes hashes every bundle file and records those on the
+// known-good or known-bad list. It ignores --include and --exclude so a
+// known-bad file cannot be hidden by the scan scope; nested bundles are
+// hashed in their own reports.
+func analyzeKnownHashes(r *appReport) error {
+	if knownHashes.good == nil && knownHashes.bad == nil {
+		return nil
+	}
+	err := filepath.Walk(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if isNestedBundle(filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		sum := r.BinarySHA256
+		if path != r.BinaryPath || sum == "" {
+			if sum, err = fileSHA256(path); err != nil {
+				return err
+			}
+		}
+		if label, ok := knownHashes.bad[sum]; ok {
+			r.KnownFiles = append(r.KnownFiles, knownFile{Path: filepath.ToSlash(rel), SHA256: sum, List: "bad", Label: label})
+		} else if label, ok := knownHashes.good[sum]; ok {
+			r.KnownFiles = append(r.KnownFiles, knownFile{Path: filepath.ToSlash(rel), SHA256: sum, List: "good", Label: label})
+		}
+		return nil
+	})
+	sort.Slice(r.KnownFiles, func(i, j int) bool { return r.KnownFiles[i].Path < r.KnownFiles[j].Path })
+	return err
+}
+
+// checkKnownHashes alerts on every known-bad file and notes the known-good ones
+func checkKnownHashes(r *appReport) []finding {
+	var findings []finding
+	var good []string
+	for _, f := range r.KnownFiles {
+		name := f.Path
+		if f.Label != "" {
+			name += " (" + f.Label + ")"
+		}
+		if f.List == "good" {
+			good = append(good, name)
+			continue
+		}
+		findings = append(findings, finding{
+			Rule:        "known-bad-file",
+			Severity:    severityCritical,
+			Title:       "File matches a known-bad hash",
+			Evidence:    name + " sha256:" + f.SHA256,
+			Location:    f.Path,
+			Remediation: "Treat the app as compromised until the file is explained; it is byte-for-byte identical to an entry on the known-bad list.",
+		})
+	}
+	if len(good) > 0 {
+		findings = append(findings, finding{
+			Rule:        "known-good-file",
+			Severity:    severityInfo,
+			Title:       "Files matching known-good hashes",
+			Evidence:    sampleList(good),
+			Location:    r.Name,
+			Remediation: "No action needed; these files are identical to approved builds and can be skipped in manual review.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// historyRecord is one app scan recorded in the results history. The history
+// is an SQLite database, written and queried through the sqlite3 CLI, so CI
+// jobs can share one file without a database server and other tools can run
+// their own queries on it.
+type historyRecord struct {
+	ScannedAt   time.Time      `json:"scanned_at"`
+	Input       string         `json:"input"`
+	BundleID    string         `json:"bundle_id"`
+	Name        string         `json:"name"`
+	Version     string         `json:"version"`
+	Build       string         `json:"build,omitempty"`
+	TeamID      string         `json:"team_id,omitempty"`   // of the code signature
+	TeamName    string         `json:"team_name,omitempty"` // from the provisioning profile
+	BinarySize  int64          `json:"binary_size"`
+	SDKs        int            `json:"sdks"`        // embedded frameworks
+	Permissions int            `json:"permissions"` // usage description keys
+	Findings    map[string]int `json:"findings"`    // per severity
+}
+
+// historyPath is the results history database, from --history or
+// IOSDUMPER_HISTORY. Scans are not recorded when it is empty.
+var historyPath = os.Getenv("IOSDUMPER_HISTORY")
+
+// historySchema creates the scans table of a new history database. Findings
+// holds a JSON object of finding counts per severity.
+const historySchema = `CREATE TABLE IF NOT EXISTS scans (
+	id INTEGER PRIMARY KEY,
+	scanned_at TEXT NOT NULL,
+	input TEXT NOT NULL,
+	bundle_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	version TEXT NOT NULL,
+	build TEXT NOT NULL,
+	team_id TEXT NOT NULL,
+	team_name TEXT NOT NULL,
+	binary_size INTEGER NOT NULL,
+	sdks INTEGER NOT NULL,
+	permissions INTEGER NOT NULL,
+	findings TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS scans_bundle_id ON scans (bundle_id);
+`
+
+// historyBusyTimeout is how long a write waits for another process holding
+// the database lock, in milliseconds
+const historyBusyTimeout = 10000
+
+// newHistoryRecord summarizes an app report for the history
+func newHistoryRecord(input string, scannedAt time.Time, r *appReport) historyRecord {
+	h := historyRecord{
+		ScannedAt:   scannedAt,
+		Input:       input,
+		BundleID:    r.Metadata.BundleID,
+		Name:        r.Name,
+		Version:     r.Metadata.Version,
+		Build:       r.Metadata.Build,
+		TeamID:      r.Signature.TeamID,
+		BinarySize:  r.BinarySize,
+		SDKs:        len(r.Frameworks),
+		Permissions: len(r.Permissions),
+		Findings:    make(map[string]int),
+	}
+	if r.Provisioning != nil {
+		h.TeamName = r.Provisioning.TeamName
+	}
+	for _, f := range r.Findings {
+		h.Findings[f.Severity]++
+	}
+	return h
+}
+
+// signerHistoryFindings compares the team that signed r with the team that
+// signed the latest recorded scan of the same bundle ID. A different team
+// shipping a version is how repackaged and hijacked builds usually show up.
+// Unsigned and ad-hoc signed builds, such as apps dumped from a device, are
+// not compared.
+func signerHistoryFindings(path string, r *appReport) ([]finding, error) {
+	if r.Signature.TeamID == "" || r.Metadata.BundleID == "" {
+		return nil, nil
+	}
+	records, err := readHistory(path, r.Metadata.BundleID)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var last *historyRecord
+	for i := range records {
+		if records[i].TeamID != "" && (last == nil || !records[i].ScannedAt.Before(last.ScannedAt)) {
+			last = &records[i]
+		}
+	}
+	if last == nil || last.TeamID == r.Signature.TeamID {
+		return nil, nil
+	}
+	name := ""
+	if r.Provisioning != nil {
+		name = r.Provisioning.TeamName
+	}
+	return []finding{{
+		Rule:     "signer-changed",
+		Severity: severityHigh,
+		Title:    "App is signed by a different team than its previous version",
+		Evidence: fmt.Sprintf("%s was signed by %s, this build by %s", historyLabel(*last), teamLabel(last.TeamID, last.TeamName), teamLabel(r.Signature.TeamID, name)),
+		Location: bundleLocation(r, r.BinaryPath),
+		Remediation: "Confirm the change of developer account with the vendor before trusting this build; an unexpected signer usually means a " +
+			"repackaged app or a compromised release pipeline.",
+	}}, nil
+}
+
+// historyLabel names the version of a recorded scan, e.g. "1.2 (34)", or its date
+func historyLabel(h historyRecord) string {
+	if label := versionString(appMetadata{Version: h.Version, Build: h.Build}); label != "" {
+		return label
+	}
+	return h.ScannedAt.Format("2006-01-02")
+}
+
+// teamLabel formats a team ID with the team name when known
+func teamLabel(id, name string) string {
+	if name == "" {
+		return id
+	}
+	return id + " (" + name + ")"
+}
+
+// appendHistory records every app of a finished scan in the history
+// database, creating it on first use
+func appendHistory(path string, result *scanResult) error {
+	if len(result.Apps) == 0 {
+		return nil
+	}
+	var script strings.Builder
+	script.WriteString(historySchema + "BEGIN;\n")
+	for _, app := range result.Apps {
+		h := newHistoryRecord(result.Input, result.StartedAt, app)
+		findings, err := json.Marshal(h.Findings)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&script, "INSERT INTO scans (scanned_at, input, bundle_id, name, version, build, team_id, team_name, binary_size, sdks, permissions, findings) "+
+			"VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %d, %d, %d, %s);\n",
+			sqlQuote(h.ScannedAt.UTC().Format(time.RFC3339Nano)), sqlQuote(h.Input), sqlQuote(h.BundleID), sqlQuote(h.Name),
+			sqlQuote(h.Version), sqlQuote(h.Build), sqlQuote(h.TeamID), sqlQuote(h.TeamName),
+			h.BinarySize, h.SDKs, h.Permissions, sqlQuote(string(findings)))
+	}
+	script.WriteString("COMMIT;\n")
+	_, err := runSQLite(path, script.String())
+	return err
+}
+
+// readHistory loads the records of the history database, optionally only
+// those of one bundle ID, oldest first. A missing database is reported as
+// the os.IsNotExist error of opening it rather than created.
+func readHistory(path, bundleID string) ([]historyRecord, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	query := "SELECT json_object('scanned_at', scanned_at, 'input', input, 'bundle_id', bundle_id, 'name', name, " +
+		"'version', version, 'build', build, 'team_id', team_id, 'team_name', team_name, 'binary_size', binary_size, " +
+		"'sdks', sdks, 'permissions', permissions, 'findings', json(findings)) FROM scans"
+	if bundleID != "" {
+		query += " WHERE bundle_id = " + sqlQuote(bundleID)
+	}
+	out, err := runSQLite(path, historySchema+query+" ORDER BY scanned_at, id;\n")
+	if err != nil {
+		return nil, err
+	}
+
+	var records []historyRecord
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var h historyRecord
+		if err := json.Unmarshal(sc.Bytes(), &h); err != nil {
+			return nil, err
+		}
+		records = append(records, h)
+	}
+	return records, sc.Err()
+}
+
+// runSQLite runs script against the database at path with the sqlite3 CLI,
+// stopping at the first failing statement, and returns its output
+func runSQLite(path, script string) ([]byte, error) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, trError("ErrHistoryTool", "Err", err)
+	}
+	cmd := exec.Command("sqlite3", "-batch", "-bail", "-cmd", fmt.Sprintf(".timeout %d", historyBusyTimeout), path)
+	cmd.Stdin = strings.NewReader(script)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return out, nil
+}
+
+// sqlQuote returns s as an SQL string literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// localeFS holds one message file per supported language, named active.<lang>.json
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// localizer resolves message IDs for the selected language. It starts out
+// English so messages printed before flag parsing still render.
+var localizer = newLocalizer("en")
+
+// newLocalizer loads every embedded message file and returns a localizer
+// preferring lang, falling back to English for missing messages
+func newLocalizer(lang string) *i18n.Localizer {
+	bundle := i18n.NewBundle(language.English)
+	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+
+	entries, _ := localeFS.ReadDir("locales")
+	for _, entry := range entries {
+		bundle.LoadMessageFileFS(localeFS, path.Join("locales", entry.Name()))
+	}
+
+	return i18n.NewLocalizer(bundle, lang, "en")
+}
+
+// setLanguage switches output messages to lang, or to the language named by
+// the environment (LC_ALL, LC_MESSAGES, LANG) when lang is empty
+func setLanguage(lang string) {
+	if lang == "" {
+		lang = languageFromEnv()
+	}
+	localizer = newLocalizer(lang)
+}
+
+// languageFromEnv extracts a BCP 47 tag from POSIX locale variables,
+// e.g. "es_ES.UTF-8" becomes "es-ES"
+func languageFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if i := strings.IndexAny(value, ".@"); i >= 0 {
+			value = value[:i]
+		}
+		if value == "C" || value == "POSIX" {
+			return "en"
+		}
+		return strings.ReplaceAll(value, "_", "-")
+	}
+	return "en"
+}
+
+// tr returns the localized message for id. Template data is passed as
+// alternating key/value pairs, e.g. tr("WorkspaceReused", "Dir", d).
+// Unknown IDs are returned unchanged so a missing translation is visible but harmless.
+func tr(id string, kv ...interface{}) string {
+	data := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if key, ok := kv[i].(string); ok {
+			data[key] = kv[i+1]
+		}
+	}
+
+	msg, err := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+	if err != nil {
+		return id
+	}
+	return msg
+}
+
+// localizedError is a translated message that still unwraps to the error it describes,
+// so callers can classify it with errors.Is
+type localizedError struct {
+	msg   string
+	cause error
+}
+
+func (e *localizedError) Error() string { return e.msg }
+func (e *localizedError) Unwrap() error { return e.cause }
+
+// trError is tr for messages returned as errors. An error passed as template
+// data becomes the cause of the returned error.
+func trError(id string, kv ...interface{}) error {
+	for i := 1; i < len(kv); i += 2 {
+		if cause, ok := kv[i].(error); ok {
+			return &localizedError{msg: tr(id, kv...), cause: cause}
+		}
+	}
+	return errors.New(tr(id, kv...))
+}
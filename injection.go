@@ -0,0 +1,157 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxInjectionSamples bounds how many strings are kept per injection surface
+	maxInjectionSamples = 10
+	// maxFormatStringLength skips runs too long to be a single format string,
+	// such as string tables without NUL separators
+	maxFormatStringLength = 512
+)
+
+var (
+	// sqlStatementPattern matches SQL statements
+	sqlStatementPattern = regexp.MustCompile(`(?i)\b(?:select\b.+\bfrom|insert\s+into|update\b.+\bset|delete\s+from|replace\s+into)\b`)
+	// javaScriptPattern matches JavaScript snippets meant for a web view
+	javaScriptPattern = regexp.MustCompile(`document\.|window\.|javascript:|\.innerHTML|postMessage\(|function\s*\(|\beval\(`)
+	// objectFormatPattern matches printf verbs that splice strings or objects
+	objectFormatPattern = regexp.MustCompile(`%(?:\d+\$)?(?:@|s|ls)`)
+)
+
+// injectionSelectors are Objective-C selectors and Swift names of APIs that
+// interpret a string as code or a query language
+var injectionSelectors = map[string]string{
+	"predicateWithFormat:":                                                    "NSPredicate format",
+	"predicateWithFormat:arguments:":                                          "NSPredicate format",
+	"predicateWithFormat:argumentArray:":                                      "NSPredicate format",
+	"evaluateJavaScript:completionHandler:":                                   "JavaScript evaluation",
+	"evaluateJavaScript:inFrame:inContentWorld:completionHandler:":            "JavaScript evaluation",
+	"callAsyncJavaScript:arguments:inFrame:inContentWorld:completionHandler:": "JavaScript evaluation",
+	"stringByEvaluatingJavaScriptFromString:":                                 "JavaScript evaluation",
+	"evaluateScript:":                                                         "JavaScript evaluation",
+	"evaluateScript:withSourceURL:":                                           "JavaScript evaluation",
+}
+
+// injectionImports are C functions that execute SQL
+var injectionImports = map[string]string{
+	"_sqlite3_exec":       "SQLite",
+	"_sqlite3_prepare":    "SQLite",
+	"_sqlite3_prepare_v2": "SQLite",
+	"_sqlite3_prepare_v3": "SQLite",
+	"_sqlite3_mprintf":    "SQLite",
+}
+
+// injectionSurface is one group of evidence that the app builds queries or
+// scripts from strings
+type injectionSurface struct {
+	Category string   `json:"category"`
+	Evidence []string `json:"evidence"`
+}
+
+// injectionAnalyzer collects selectors of injection-prone APIs and format
+// strings that splice values into SQL or JavaScript
+type injectionAnalyzer struct {
+	selectors  map[string]bool
+	sqlFormats map[string]bool
+	jsFormats  map[string]bool
+}
+
+func newInjectionAnalyzer() stringAnalyzer {
+	return &injectionAnalyzer{
+		selectors:  make(map[string]bool),
+		sqlFormats: make(map[string]bool),
+		jsFormats:  make(map[string]bool),
+	}
+}
+
+func (a *injectionAnalyzer) visit(s string, offset int64) {
+	if _, ok := injectionSelectors[s]; ok {
+		a.selectors[s] = true
+		return
+	}
+	if len(s) > maxFormatStringLength || !strings.Contains(s, "%") || !objectFormatPattern.MatchString(s) {
+		return
+	}
+	switch {
+	case sqlStatementPattern.MatchString(s):
+		addSample(a.sqlFormats, s)
+	case javaScriptPattern.MatchString(s):
+		addSample(a.jsFormats, s)
+	}
+}
+
+func (a *injectionAnalyzer) findings(r *appReport) []finding {
+	groups := make(map[string][]string)
+	for sel := range a.selectors {
+		category := injectionSelectors[sel]
+		groups[category] = append(groups[category], sel)
+	}
+	for _, sym := range r.Imports {
+		if category, ok := injectionImports[sym]; ok {
+			groups[category] = append(groups[category], sym)
+		}
+	}
+	if len(a.sqlFormats) > 0 {
+		groups["SQL format string"] = sortedSet(a.sqlFormats)
+	}
+	if len(a.jsFormats) > 0 {
+		groups["JavaScript format string"] = sortedSet(a.jsFormats)
+	}
+
+	r.InjectionSurface = nil
+	for category, evidence := range groups {
+		sort.Strings(evidence)
+		r.InjectionSurface = append(r.InjectionSurface, injectionSurface{Category: category, Evidence: evidence})
+	}
+	sort.Slice(r.InjectionSurface, func(i, j int) bool { return r.InjectionSurface[i].Category < r.InjectionSurface[j].Category })
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if len(a.sqlFormats) > 0 && len(groups["SQLite"]) > 0 {
+		for _, s := range sortedSet(a.sqlFormats) {
+			findings = append(findings, finding{
+				Rule:        "sql-format-string",
+				Severity:    severityMedium,
+				Title:       "SQL statement built with a format string",
+				Evidence:    s,
+				Location:    location,
+				Remediation: "Bind values with sqlite3_bind_* or a query builder instead of splicing them into the statement with stringWithFormat.",
+			})
+		}
+	}
+	if len(a.jsFormats) > 0 && len(groups["JavaScript evaluation"]) > 0 {
+		for _, s := range sortedSet(a.jsFormats) {
+			findings = append(findings, finding{
+				Rule:        "js-format-string",
+				Severity:    severityMedium,
+				Title:       "JavaScript built with a format string and evaluated",
+				Evidence:    s,
+				Location:    location,
+				Remediation: "Pass values with callAsyncJavaScript(_:arguments:) or JSON-encode them; never splice untrusted strings into evaluated script.",
+			})
+		}
+	}
+	if len(groups["NSPredicate format"]) > 0 {
+		findings = append(findings, finding{
+			Rule:        "predicate-format",
+			Severity:    severityInfo,
+			Title:       "NSPredicate built from a format string",
+			Evidence:    strings.Join(groups["NSPredicate format"], ", "),
+			Location:    location,
+			Remediation: "Make sure user input only reaches predicates through %@ arguments, never as part of the format itself.",
+		})
+	}
+	return findings
+}
+
+// addSample adds s to set unless the set already holds maxInjectionSamples entries
+func addSample(set map[string]bool, s string) {
+	if len(set) < maxInjectionSamples {
+		set[s] = true
+	}
+}
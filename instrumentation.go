@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// instrumentationSignatures identify dynamic instrumentation and hooking
+// toolkits by the file or install name they ship as
+var instrumentationSignatures = []struct {
+	tool    string
+	pattern *regexp.Regexp
+}{
+	{"Frida", regexp.MustCompile(`(?i)frida[-_]?gadget|libfrida|frida-agent`)},
+	{"Cycript", regexp.MustCompile(`(?i)libcycript|cycript\.framework`)},
+	{"Cydia Substrate", regexp.MustCompile(`(?i)cydiasubstrate|libsubstrate|substrateloader|mobilesubstrate`)},
+	{"Substitute", regexp.MustCompile(`(?i)libsubstitute`)},
+	{"libhooker", regexp.MustCompile(`(?i)libhooker|libblackjack`)},
+	{"ElleKit", regexp.MustCompile(`(?i)ellekit`)},
+	{"Reveal", regexp.MustCompile(`(?i)revealserver|libreveal`)},
+	{"FLEX", regexp.MustCompile(`(?i)(?:^|/)flex(?:ing)?\.framework|libflex`)},
+}
+
+// instrumentationArtifact is one instrumentation toolkit trace found in the bundle
+type instrumentationArtifact struct {
+	Tool   string `json:"tool"`
+	Kind   string `json:"kind"` // "file", "load-command" or "config"
+	Path   string `json:"path"`
+	Config string `json:"config,omitempty"` // summary of a gadget configuration
+}
+
+// fridaGadgetConfig is the subset of a Frida gadget .config file worth reporting
+type fridaGadgetConfig struct {
+	Interaction struct {
+		Type    string `json:"type"`
+		Address string `json:"address"`
+		Port    int    `json:"port"`
+		Path    string `json:"path"`
+		OnLoad  string `json:"on_load"`
+	} `json:"interaction"`
+}
+
+// instrumentationTool returns the toolkit name matching name, or ""
+func instrumentationTool(name string) string {
+	for _, sig := range instrumentationSignatures {
+		if sig.pattern.MatchString(name) {
+			return sig.tool
+		}
+	}
+	return ""
+}
+
+// findInstrumentation walks the bundle for instrumentation libraries and their
+// configuration files, and checks the main binary's load commands for them
+func findInstrumentation(r *appReport) []instrumentationArtifact {
+	var artifacts []instrumentationArtifact
+
+	filepath.Walk(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		tool := instrumentationTool(rel)
+		if tool == "" {
+			return nil
+		}
+
+		switch {
+		case info.IsDir():
+			if strings.HasSuffix(rel, ".framework") && instrumentationTool(filepath.Base(rel)) != "" {
+				artifacts = append(artifacts, instrumentationArtifact{Tool: tool, Kind: "file", Path: rel})
+				return filepath.SkipDir
+			}
+		case strings.HasSuffix(rel, ".config"):
+			artifacts = append(artifacts, instrumentationArtifact{Tool: tool, Kind: "config", Path: rel, Config: summarizeGadgetConfig(path)})
+		case strings.HasSuffix(rel, ".dylib") || strings.HasSuffix(rel, ".js"):
+			artifacts = append(artifacts, instrumentationArtifact{Tool: tool, Kind: "file", Path: rel})
+		}
+		return nil
+	})
+
+	for _, lib := range r.Libraries {
+		if tool := instrumentationTool(lib); tool != "" {
+			artifacts = append(artifacts, instrumentationArtifact{Tool: tool, Kind: "load-command", Path: lib})
+		}
+	}
+
+	sort.SliceStable(artifacts, func(i, j int) bool { return artifacts[i].Tool < artifacts[j].Tool })
+	return artifacts
+}
+
+// summarizeGadgetConfig describes how a Frida gadget configuration makes the
+// gadget behave, e.g. "listen on 0.0.0.0:27042" or "script /path/to/agent.js"
+func summarizeGadgetConfig(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var cfg fridaGadgetConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "unparseable configuration"
+	}
+
+	in := cfg.Interaction
+	switch in.Type {
+	case "listen", "connect":
+		address := in.Address
+		if address == "" {
+			address = "127.0.0.1"
+		}
+		summary := in.Type + " on " + address
+		if in.Port != 0 {
+			summary += fmt.Sprintf(":%d", in.Port)
+		}
+		if in.OnLoad != "" {
+			summary += " (on_load " + in.OnLoad + ")"
+		}
+		return summary
+	case "script", "script-directory":
+		return in.Type + " " + in.Path
+	case "":
+		return "listen on 127.0.0.1:27042 (default)"
+	}
+	return in.Type
+}
+
+// checkInstrumentation reports instrumentation toolkits shipped in or loaded by the app
+func checkInstrumentation(r *appReport) []finding {
+	var findings []finding
+	for _, a := range r.Instrumentation {
+		f := finding{
+			Rule:        "instrumentation-framework",
+			Severity:    severityHigh,
+			Evidence:    a.Path,
+			Location:    a.Path,
+			Remediation: "Remove instrumentation toolkits from release builds; they expose the app to runtime inspection and modification.",
+		}
+		switch a.Kind {
+		case "load-command":
+			f.Severity = severityCritical
+			f.Title = a.Tool + " loaded by the app binary"
+			f.Location = bundleLocation(r, r.BinaryPath)
+		case "config":
+			f.Title = a.Tool + " gadget configuration bundled"
+			if a.Config != "" {
+				f.Evidence = a.Path + ": " + a.Config
+			}
+		default:
+			f.Title = a.Tool + " bundled in the app"
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
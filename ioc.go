@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// iocFormats are the feed formats --ioc-format accepts
+var iocFormats = []string{"stix", "csv"}
+
+// indicator is one observable extracted from a scan
+type indicator struct {
+	Type  string // domain, ipv4, ipv6, url or sha256
+	Value string
+	App   string // bundle ID, or the input for IPA hashes
+	Input string
+}
+
+// stixPatterns turn an indicator type into a STIX 2.1 pattern
+var stixPatterns = map[string]string{
+	"domain": "[domain-name:value = '%s']",
+	"ipv4":   "[ipv4-addr:value = '%s']",
+	"ipv6":   "[ipv6-addr:value = '%s']",
+	"url":    "[url:value = '%s']",
+	"sha256": "[file:hashes.'SHA-256' = '%s']",
+}
+
+// stixBundle is a STIX 2.1 bundle of indicators
+type stixBundle struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Objects []stixIndicator `json:"objects"`
+}
+
+// stixIndicator is a STIX 2.1 indicator object
+type stixIndicator struct {
+	Type           string   `json:"type"`
+	SpecVersion    string   `json:"spec_version"`
+	ID             string   `json:"id"`
+	Created        string   `json:"created"`
+	Modified       string   `json:"modified"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	IndicatorTypes []string `json:"indicator_types"`
+	Pattern        string   `json:"pattern"`
+	PatternType    string   `json:"pattern_type"`
+	ValidFrom      string   `json:"valid_from"`
+	Labels         []string `json:"labels,omitempty"`
+}
+
+// collectIndicators gathers the hashes of each scanned IPA and app binary and
+// the hosts and URLs of every network endpoint, without duplicates
+func collectIndicators(results []*scanResult) []indicator {
+	var out []indicator
+	seen := make(map[string]bool)
+	add := func(typ, value, app, input string) {
+		if key := typ + " " + value; !seen[key] {
+			seen[key] = true
+			out = append(out, indicator{Type: typ, Value: value, App: app, Input: input})
+		}
+	}
+
+	for _, result := range results {
+		if result.SHA256 != "" {
+			add("sha256", result.SHA256, result.Input, result.Input)
+		}
+		for _, app := range result.Apps {
+			id := app.Metadata.BundleID
+			if id == "" {
+				id = app.Name
+			}
+			if app.BinarySHA256 != "" {
+				add("sha256", app.BinarySHA256, id, result.Input)
+			}
+			for _, u := range app.Endpoints {
+				if _, ok := endpointSchemes[endpointScheme(u)]; !ok || isLoopbackURL(u) {
+					continue
+				}
+				add("url", u, id, result.Input)
+				host := endpointHost(u)
+				switch ip := net.ParseIP(host); {
+				case ip == nil:
+					add("domain", host, id, result.Input)
+				case ip.To4() != nil:
+					add("ipv4", host, id, result.Input)
+				default:
+					add("ipv6", host, id, result.Input)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// writeIOCFeed writes the indicators of every scan to path as a STIX 2.1
+// bundle or a CSV feed
+func writeIOCFeed(path, format string, results []*scanResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	indicators := collectIndicators(results)
+	if format == "csv" {
+		err = writeIOCCSV(f, indicators)
+	} else {
+		err = writeSTIXBundle(f, indicators)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeIOCCSV writes one indicator per row under a type,value,app,input header
+func writeIOCCSV(w io.Writer, indicators []indicator) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"type", "value", "app", "input"})
+	for _, ind := range indicators {
+		cw.Write([]string{ind.Type, ind.Value, ind.App, ind.Input})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeSTIXBundle writes the indicators as STIX 2.1 indicator objects. Their
+// type is "unknown": the tool extracts observables but cannot judge intent.
+func writeSTIXBundle(w io.Writer, indicators []indicator) error {
+	now := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	bundle := stixBundle{Type: "bundle", ID: "bundle--" + newUUID(), Objects: []stixIndicator{}}
+	for _, ind := range indicators {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(ind.Value)
+		bundle.Objects = append(bundle.Objects, stixIndicator{
+			Type:           "indicator",
+			SpecVersion:    "2.1",
+			ID:             "indicator--" + newUUID(),
+			Created:        now,
+			Modified:       now,
+			Name:           fmt.Sprintf("%s %s", ind.Type, ind.Value),
+			Description:    fmt.Sprintf("Extracted by iosdumper %s from %s (%s)", version, ind.App, ind.Input),
+			IndicatorTypes: []string{"unknown"},
+			Pattern:        fmt.Sprintf(stixPatterns[ind.Type], value),
+			PatternType:    "stix",
+			ValidFrom:      now,
+			Labels:         []string{"ios", "iosdumper"},
+		})
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// fileSHA256 returns the hex SHA-256 digest of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -4,14 +4,18 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"syscall"
 
 	"github.com/fatih/color"
 )
@@ -38,22 +42,47 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
-// unzip extracts the contents of the zip file to a directory of the same name
-func unzip(zipFile, targetDir string) error {
+// scratchRoot is the --scratch directory for intermediate files; empty uses
+// the system temporary directory, which honors TMPDIR
+var scratchRoot string
+
+// newScratchDir creates a private directory for a scan's intermediate files.
+// Callers remove it with a deferred os.RemoveAll, which also runs when an
+// interrupt cancels the scan.
+func newScratchDir() (string, error) {
+	return os.MkdirTemp(scratchRoot, "iosdumper-")
+}
+
+// unzip extracts the contents of the zip file to a directory of the same name.
+// Extraction stops between entries once ctx is cancelled.
+func unzip(ctx context.Context, zipFile, targetDir string, prog *progressReporter) error {
 	reader, err := zip.OpenReader(zipFile)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
+	if err := checkArchive(reader.File); err != nil {
+		return err
+	}
+
 	infoPlistFound := false // Flag to track if Info.plist is found
+	limits := &extractLimitError{}
+	var extracted uint64
+
+	for i, file := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-	for _, file := range reader.File {
 		path := filepath.Join(targetDir, file.Name)
+		prog.file(file.Name, progressExtractStart+(progressExtractEnd-progressExtractStart)*i/len(reader.File))
 
 		if strings.HasSuffix(path, "Info.plist") {
 			infoPlistFound = true
-			color.Green("Info.plist found at: %s", path)
+			if showSection(sectionLog) {
+				activeTheme.success.Fprintln(prog.output(), tr("InfoPlistFound", "Path", path))
+			}
 		}
 
 		if file.FileInfo().IsDir() {
@@ -61,64 +90,83 @@ func unzip(zipFile, targetDir string) error {
 			continue
 		}
 
-		fileReader, err := file.Open()
-		if err != nil {
-			return err
-		}
-		defer fileReader.Close()
-
-		targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
-			return err
+		if !limits.admit(file.Name, file.UncompressedSize64, extracted) {
+			continue
 		}
-		defer targetFile.Close()
-
-		if _, err := io.Copy(targetFile, fileReader); err != nil {
+		if err := extractFile(file, path); err != nil {
 			return err
 		}
+		extracted += file.UncompressedSize64
 	}
 
 	if !infoPlistFound {
-		color.Red("Info.plist not found within the zip file.")
+		activeTheme.failure.Fprintln(prog.output(), tr("InfoPlistNotInZip"))
 	}
 
+	if len(limits.tooLarge) > 0 || len(limits.overflow) > 0 {
+		return limits
+	}
 	return nil
 }
 
-// convertPlistToXML converts a binary plist file to XML format using plutil
-func convertPlistToXML(plistPath, targetDir string) error {
-	// Copy Info.plist to target directory before converting
-	targetPlistPath := filepath.Join(targetDir, "Info.plist")
+// extractFile writes a single zip entry to path, decrypting it if needed and
+// closing both ends before returning. archive/zip stops with an error if the entry decompresses to more
+// than the size in its header, which the extraction limits were checked against.
+func extractFile(file *zip.File, path string) error {
+	fileReader, err := openZipEntry(file)
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+
+	targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+	if err != nil {
+		return err
+	}
+	defer targetFile.Close()
+
+	_, err = io.Copy(targetFile, fileReader)
+	return err
+}
+
+// convertPlistToXML copies a plist file to targetPlistPath and converts the
+// copy to XML format using plutil, logging to w
+func convertPlistToXML(ctx context.Context, w io.Writer, plistPath, targetPlistPath string) error {
 	err := copyFile(plistPath, targetPlistPath)
 	if err != nil {
-		return fmt.Errorf("error copying Info.plist to target directory: %v", err)
+		return trError("ErrCopyPlist", "Err", err)
 	}
 
-	cmd := exec.Command("plutil", "-convert", "xml1", targetPlistPath)
+	cmd := exec.CommandContext(ctx, "plutil", "-convert", "xml1", targetPlistPath)
 	err = cmd.Run()
 	if err != nil {
-		return fmt.Errorf("error converting Info.plist to XML format: %v", err)
+		return trError("ErrConvertPlist", "Err", err)
+	}
+	if err := redactFile(targetPlistPath); err != nil {
+		return trError("ErrConvertPlist", "Err", err)
+	}
+	if showSection(sectionLog) {
+		activeTheme.success.Fprintln(w, tr("PlistConverted", "Path", targetPlistPath))
 	}
-	color.Green("Successfully converted %s to XML format.", targetPlistPath)
 	return nil
 }
 
-// highlightKeysInFile reads the file at the given path and prints its content with specific keys highlighted
-func highlightKeysInFile(filePath string) error {
+// highlightKeysInFile reads the file at the given path and prints its content to w with specific keys highlighted.
+func highlightKeysInFile(w io.Writer, filePath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file %s: %v", filePath, err)
+		return trError("ErrOpenFile", "Path", filePath, "Err", err)
 	}
 	defer file.Close()
 
-	// Define the keys to highlight and their respective colors
+	// Define the keys to highlight and their respective colors from the active theme
 	keysToHighlight := map[string]*color.Color{
-		"CFBundleURLSchemes":             color.New(color.FgCyan),
-		"CFBundleURLName":                color.New(color.FgGreen),
-		"CFBundleTypeRole":               color.New(color.FgYellow),
-		"CFBundleURLComponents":          color.New(color.FgMagenta),
-		"CFBundleComponentPath":          color.New(color.FgRed),
-		"CFBundleURLComponentQueryItems": color.New(color.FgBlue),
+		"CFBundleURLSchemes":             activeTheme.keys[0],
+		"CFBundleURLName":                activeTheme.keys[1],
+		"CFBundleTypeRole":               activeTheme.keys[2],
+		"CFBundleURLComponents":          activeTheme.keys[3],
+		"CFBundleComponentPath":          activeTheme.keys[4],
+		"CFBundleURLComponentQueryItems": activeTheme.keys[5],
 	}
 
 	// Compile a regular expression to match any of the keys
@@ -130,125 +178,166 @@ func highlightKeysInFile(filePath string) error {
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := redactText(scanner.Text())
 		matches := pattern.FindStringSubmatch(line)
 		if len(matches) > 0 {
 			// If the line contains one of the keys, highlight the matching part
 			key := matches[0]
-			keysToHighlight[key].Println(line)
+			keysToHighlight[key].Fprintln(w, line)
 		} else {
 			// Otherwise, print the line without color
-			fmt.Println(line)
+			fmt.Fprintln(w, line)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading file %s: %v", filePath, err)
+		return trError("ErrReadFile", "Path", filePath, "Err", err)
 	}
 
 	return nil
 }
 
-// highlightText searches for substrings and applies color highlighting
-func highlightText(input string, searchText string, colorize *color.Color) string {
-	var buffer bytes.Buffer
-	lines := strings.Split(input, "\n")
-	for _, line := range lines {
-		if strings.Contains(line, searchText) {
-			parts := strings.Split(line, searchText)
-			for i, part := range parts {
-				if i > 0 {
-					buffer.WriteString(colorize.Sprint(searchText))
-				}
-				buffer.WriteString(part)
-			}
-			buffer.WriteString("\n")
-		} else {
-			buffer.WriteString(line + "\n")
+// eachLine calls fn with every line read from r, without the newline. Lines
+// are read one at a time so memory stays bounded by the longest line rather
+// than the whole output.
+func eachLine(r io.Reader, fn func(line string)) error {
+	br := bufio.NewReaderSize(r, 64<<10)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			fn(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 	}
-	return buffer.String()
 }
 
-// runRadare2Command runs `r2 -qc 'izz~PropertyList'` on the specified binary within the .app directory
-func runRadare2Command(appDir string) error {
-	// Assuming the main binary has the same name as the .app directory
-	appName := filepath.Base(appDir)             // Get the directory name
-	binaryPath := filepath.Join(appDir, appName) // Construct the path to the binary
+// highlightLines copies the lines of r to w, coloring every occurrence of searchText
+func highlightLines(w io.Writer, r io.Reader, searchText string, colorize *color.Color) error {
+	return eachLine(r, func(line string) {
+		line = redactText(line)
+		fmt.Fprintln(w, strings.ReplaceAll(line, searchText, colorize.Sprint(searchText)))
+	})
+}
 
-	// Remove the .app extension from the binary name
-	binaryPath = strings.TrimSuffix(binaryPath, filepath.Ext(binaryPath))
+// runRadare2Command runs `r2 -qc 'izz~PropertyList'` on the main binary of a bundle,
+// streaming its output to out with "applinks:" highlighted.
+func runRadare2Command(ctx context.Context, out io.Writer, binaryPath string) error {
+	appName := filepath.Base(filepath.Dir(binaryPath))
 
-	cmd := exec.Command("r2", "-qc", "izz~PropertyList", binaryPath)
-	output, err := cmd.CombinedOutput()
+	cmd := exec.CommandContext(ctx, "r2", "-qc", "izz~PropertyList", binaryPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("error running r2 command on %s: %v, output: %s", appName, err, string(output))
+		return trError("ErrRadare2", "App", appName, "Err", err, "Output", "")
+	}
+	if err := cmd.Start(); err != nil {
+		return trError("ErrRadare2", "App", appName, "Err", err, "Output", stderr.String())
 	}
 
-	// Process the output to highlight "applinks:" in green
-	highlightedOutput := highlightText(string(output), "applinks:", color.New(color.FgGreen))
-	fmt.Printf("Results from r2 command on %s:\n%s", appName, highlightedOutput)
-	return nil
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, tr("Radare2Results", "App", appName))
+	readErr := highlightLines(w, output, "applinks:", activeTheme.match)
+	w.Flush()
+	if err := cmd.Wait(); err != nil {
+		return trError("ErrRadare2", "App", appName, "Err", err, "Output", stderr.String())
+	}
+	return readErr
 }
 
-// runStringsAndGrep runs `strings` on the app binary, then filters with `grep`
-func runStringsAndGrep(binaryPath string) error {
-	// First, execute the strings command and filter with grep
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("strings '%s' | grep -E '.*\\/.*'", binaryPath))
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error executing strings and grep command: %v", err)
-	}
-
-	// Exclude specific patterns
-	excludePatterns := []string{"https://", "/Users/", "/Volumes/", "http://", "BuildRoot/"}
-	var filteredLines []string
-	lines := strings.Split(out.String(), "\n")
-	for _, line := range lines {
-		exclude := false
-		for _, pattern := range excludePatterns {
-			if strings.Contains(line, pattern) {
-				exclude = true
-				break
-			}
+// stringsExcludePatterns drop strings output lines that are URLs or build
+// machine paths, which other sections already report
+var stringsExcludePatterns = []string{"https://", "/Users/", "/Volumes/", "http://", "BuildRoot/"}
+
+// printBinaryStrings streams the strings of the app binary that are at
+// least --strings-min-length characters long and in a --strings-encoding
+// encoding: the ASCII ones containing a slash, then every UTF-8 and UTF-16
+// one tagged with its encoding and script, up to --max-strings lines, to out.
+func printBinaryStrings(out io.Writer, binaryPath string) error {
+	f, err := os.Open(binaryPath)
+	if err != nil {
+		return trError("ErrStrings", "Err", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	printed, omitted := 0, 0
+	admit := func() bool {
+		if outputOpts.maxStrings > 0 && printed >= outputOpts.maxStrings {
+			omitted++
+			return false
+		}
+		printed++
+		return true
+	}
+	if outputOpts.stringsEncodings.shows(encodingASCII) {
+		fmt.Fprintln(w, tr("FilteredStrings"))
+	}
+	// Non-ASCII strings are rare enough to hold until the ASCII ones are out
+	var encoded []string
+	err = scanStrings(f, outputOpts.stringsMinLength, func(line string, offset int64, encoding string) {
+		if !outputOpts.stringsEncodings.shows(encoding) {
+			return
+		}
+		if encoding != encodingASCII {
+			encoded = append(encoded, line+"  ("+encodingLabel(line, encoding)+")")
+			return
 		}
-		if !exclude {
-			filteredLines = append(filteredLines, line)
+		if !strings.Contains(line, "/") || containsAny(line, stringsExcludePatterns) {
+			return
 		}
+		if admit() {
+			writeColorizedLine(w, line, slashPathPattern.MatchString(line))
+		}
+	})
+	if len(encoded) > 0 {
+		fmt.Fprintln(w, tr("EncodedStrings"))
+	}
+	for _, line := range encoded {
+		if admit() {
+			writeColorizedLine(w, line, false)
+		}
+	}
+	if omitted > 0 {
+		fmt.Fprintln(w, tr("StringsOmitted", "Count", omitted))
+	}
+	if err != nil {
+		return trError("ErrStrings", "Err", err)
 	}
-
-	// Combine filtered lines back into a single string
-	filteredOutput := strings.Join(filteredLines, "\n")
-
-	// Print the colored output
-	colorOutput := colorizeOutput(filteredOutput)
-	fmt.Println("Filtered strings with slashes:", colorOutput)
-
 	return nil
 }
 
-// colorizeOutput applies color only to lines matching the specific format: /something/something
-func colorizeOutput(input string) string {
-	var buffer bytes.Buffer
-	lines := strings.Split(input, "\n")
-	colorize := color.New(color.FgGreen) // Use cyan for highlighting
-	colorize2 := color.New(color.FgRed)  // Use BiugRed an for highlighting
-	// Compile a regex to match the specific pattern: /something/something
-	pattern := regexp.MustCompile(`\/[^\/\s]+\/[^\/\s]+`)
-
-	for _, line := range lines {
-		if pattern.MatchString(line) {
-			// If the line matches the pattern, apply color
-			buffer.WriteString(colorize.Sprintln(line))
-		} else {
-			// Otherwise, print the line without color
-			buffer.WriteString(colorize2.Sprintln(line))
-		}
+// encodingLabel tags a non-ASCII string with its encoding and script
+func encodingLabel(s, encoding string) string {
+	if script := stringScript(s); script != "" {
+		return encoding + ", " + script
 	}
-	return buffer.String()
+	return encoding
 }
 
+// slashPathPattern matches the specific format: /something/something
+var slashPathPattern = regexp.MustCompile(`\/[^\/\s]+\/[^\/\s]+`)
+
+// writeColorizedLine writes a strings output line, highlighting lines
+// matching the specific format: /something/something
+func writeColorizedLine(w io.Writer, line string, isPath bool) {
+	line = redactText(line)
+	if isPath {
+		activeTheme.match.Fprintln(w, line)
+	} else {
+		activeTheme.noMatch.Fprintln(w, line)
+	}
+}
+
+// version is the release reported in the banner
+const version = "1.0.0"
+
 // displayBanner
 func displayBanner() {
 	banner := `
@@ -269,133 +358,711 @@ func displayBanner() {
   ,;.          ,          E#t         :              j                                          
                           L:                                                                      
 
-iOSDumper - Find key information
-Version: 1.0.0
-	`
-	color.Yellow(banner)
+`
+	activeTheme.banner.Fprintln(stdout, banner)
+	activeTheme.banner.Fprintln(stdout, tr("BannerTagline"))
+	activeTheme.banner.Fprintln(stdout, tr("BannerVersion", "Version", version))
 }
 
 func displayHelp() {
-	title := color.New(color.FgCyan, color.Bold).SprintFunc()
-	option := color.New(color.FgYellow).SprintFunc()
+	title := activeTheme.title.SprintFunc()
+	option := activeTheme.option.SprintFunc()
 
-	fmt.Printf("%s\n", title("Usage: iosdumper <file.ipa>\n"))
-	fmt.Printf("%s\n", option("Options:"))
-	fmt.Printf("  %s\t%s\n", option("-h, --help"), "Show this help message and exit.")
+	fmt.Printf("%s\n", title(tr("HelpUsage")+"\n"))
+	fmt.Printf("%s\n", option(tr("HelpOptions")))
+	fmt.Printf("  %s\t%s\n", option("-h, --help"), tr("HelpHelp"))
+	fmt.Printf("  %s\t%s\n", option("--progress jsonl"), tr("HelpProgress"))
+	fmt.Printf("  %s\t%s\n", option("--lang <code>"), tr("HelpLang"))
+	fmt.Printf("  %s\t%s\n", option("--theme <name>"), tr("HelpTheme", "Themes", strings.Join(themeNames(), ", ")))
+	fmt.Printf("  %s\t%s\n", option("--wide"), tr("HelpWide"))
+	fmt.Printf("  %s\t%s\n", option("--truncate <n>"), tr("HelpTruncate"))
+	fmt.Printf("  %s\t%s\n", option("--max-strings <n>"), tr("HelpMaxStrings"))
+	fmt.Printf("  %s\t%s\n", option("--strings-min-length <n>"), tr("HelpStringsMinLength"))
+	fmt.Printf("  %s\t%s\n", option("--strings-encoding <list>"), tr("HelpStringsEncoding"))
+	fmt.Printf("  %s\t%s\n", option("--max-findings-per-rule <n>"), tr("HelpMaxFindingsPerRule"))
+	fmt.Printf("  %s\t%s\n", option("--pager"), tr("HelpPager"))
+	fmt.Printf("  %s\t%s\n", option("--summary"), tr("HelpSummary"))
+	fmt.Printf("  %s\t%s\n", option("--show <sections>"), tr("HelpShow", "Sections", strings.Join(allSections, ", ")))
+	fmt.Printf("  %s\t%s\n", option("--json <file>"), tr("HelpJSON"))
+	fmt.Printf("  %s\t%s\n", option("--fragments <dir>"), tr("HelpFragments"))
+	fmt.Printf("  %s\t%s\n", option("--dsym <path>"), tr("HelpDSYM"))
+	fmt.Printf("  %s\t%s\n", option("--malware"), tr("HelpMalware"))
+	fmt.Printf("  %s\t%s\n", option("--no-write"), tr("HelpNoWrite"))
+	fmt.Printf("  %s\t%s\n", option("--scratch <dir>"), tr("HelpScratch"))
+	fmt.Printf("  %s\t%s\n", option("--expect-sha256 <digest>"), tr("HelpExpectSHA256"))
+	fmt.Printf("  %s\t%s\n", option("--zip-password <password>"), tr("HelpZipPassword"))
+	fmt.Printf("  %s\t%s\n", option("--workspace <dir>"), tr("HelpWorkspace"))
+	fmt.Printf("  %s\t%s\n", option("--frida <device>"), tr("HelpFrida"))
+	fmt.Printf("  %s\t%s\n", option("--app-store <country>"), tr("HelpAppStore"))
+	fmt.Printf("  %s\t%s\n", option("--include <globs>"), tr("HelpInclude"))
+	fmt.Printf("  %s\t%s\n", option("--exclude <globs>"), tr("HelpExclude"))
+	fmt.Printf("  %s\t%s\n", option("--policy <file>"), tr("HelpPolicy"))
+	fmt.Printf("  %s\t%s\n", option("--trackers <file>"), tr("HelpTrackers"))
+	fmt.Printf("  %s\t%s\n", option("--known-good <file>"), tr("HelpKnownGood"))
+	fmt.Printf("  %s\t%s\n", option("--known-bad <file>"), tr("HelpKnownBad"))
+	fmt.Printf("  %s\t%s\n", option("--history <db>"), tr("HelpHistory"))
+	fmt.Printf("  %s\t%s\n", option("--baseline <file>"), tr("HelpBaseline"))
+	fmt.Printf("  %s\t%s\n", option("--publish-report"), tr("HelpPublishReport"))
+	fmt.Printf("  %s\t%s\n", option("--tickets jira|github"), tr("HelpTickets"))
+	fmt.Printf("  %s\t%s\n", option("--fastlane"), tr("HelpFastlane"))
+	fmt.Printf("  %s\t%s\n", option("--ioc <file>"), tr("HelpIOC"))
+	fmt.Printf("  %s\t%s\n", option("--ioc-format stix|csv"), tr("HelpIOCFormat"))
+	fmt.Printf("  %s\t%s\n", option("--defectdojo <file>"), tr("HelpDefectDojo"))
+	fmt.Printf("  %s\t%s\n", option("--gitlab-sast <file>"), tr("HelpGitLabSAST"))
+	fmt.Printf("  %s\t%s\n", option("--redact"), tr("HelpRedact"))
+	fmt.Printf("  %s\t%s\n", option("--encrypt-output <recipient.pub>"), tr("HelpEncryptOutput"))
+	fmt.Printf("  %s\t%s\n", option("--profile <dir>"), tr("HelpProfile"))
+	fmt.Printf("  %s\t%s\n", option("--max-extract-size <size>"), tr("HelpMaxExtractSize"))
+	fmt.Printf("  %s\t%s\n", option("--max-file-size <size>"), tr("HelpMaxFileSize"))
+	fmt.Printf("\n%s\n", option(tr("HelpCommands")))
+	fmt.Printf("  %s\t%s\n", option("trends [--history <db>] [--html <file>] [bundle-id]"), tr("HelpTrends"))
+	fmt.Printf("  %s\t%s\n", option("watch [--reports <dir>] [--webhook <url>] <dir>"), tr("HelpWatch"))
+	fmt.Printf("  %s\t%s\n", option("serve [--listen <addr>] [--root <dir>]"), tr("HelpServe"))
+	fmt.Printf("  %s\t%s\n", option("worker [--brokers <list>] [--jobs <topic>]"), tr("HelpWorker"))
+	fmt.Printf("  %s\t%s\n", option("testflight --app <id> [--build <n>] [file.ipa]"), tr("HelpTestFlight"))
+	fmt.Printf("  %s\t%s\n", option("container --ssh <[user@]host> <bundle-id>"), tr("HelpContainer"))
+	fmt.Printf("  %s\t%s\n", option("analyze --from <dir> [--only <analyzers>]"), tr("HelpAnalyze"))
+	fmt.Printf("  %s\t%s\n", option("open <workspace>"), tr("HelpOpen"))
+	fmt.Printf("  %s\t%s\n", option("merge <fragments|reports|dirs>..."), tr("HelpMerge"))
+	fmt.Printf("  %s\t%s\n", option("fp add --report <file> [--note <text>] <fingerprint>..."), tr("HelpFP"))
+	fmt.Printf("  %s\t%s\n", option("db update [--db <dir>] [--from <dir>]"), tr("HelpDB"))
+	fmt.Printf("  %s\t%s\n", option("macho sections [--arch <arch>] [--hex <segment,section>] <binary>"), tr("HelpMacho"))
+	fmt.Printf("  %s\t%s\n", option("macho otool -L | nm [-g] | codesign -d --entitlements - <binary>"), tr("HelpMachoCompat"))
 }
 
-func main() {
-	displayBanner()
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
 
+func main() {
 	helpFlag := flag.Bool("help", false, "Show help message")
 	flag.BoolVar(helpFlag, "h", false, "Show help message (shorthand)")
+	progressFlag := flag.String("progress", "", "Progress event format written to stderr (jsonl)")
+	langFlag := flag.String("lang", "", "Language for output messages (defaults to LANG)")
+	themeFlag := flag.String("theme", "default", "Color palette: default, colorblind, mono, high-contrast")
+	flag.BoolVar(&tableOpts.wide, "wide", false, "Never truncate table cells")
+	flag.IntVar(&tableOpts.maxCell, "truncate", 0, "Truncate table cells to N characters (0 fits the terminal)")
+	flag.IntVar(&outputOpts.maxStrings, "max-strings", 0, "Print at most N lines in the strings section (0 for all)")
+	flag.IntVar(&outputOpts.stringsMinLength, "strings-min-length", minStringLength, "Print strings of at least N characters in the strings section")
+	flag.Var(outputOpts.stringsEncodings, "strings-encoding", "Comma-separated encodings the strings section prints: ascii, utf-8, utf-16 (default all)")
+	flag.IntVar(&outputOpts.maxFindingsPerRule, "max-findings-per-rule", 0, "Print at most N findings of each rule (0 for all)")
+	flag.BoolVar(&outputOpts.pager, "pager", false, "Page the report through $PAGER (less -R by default)")
+	flag.BoolVar(&sectionOpts.summary, "summary", false, "Print only metadata, capabilities and finding counts")
+	flag.Var(sectionOpts.show, "show", "Comma-separated sections to print in addition to the summary")
+	jsonFlag := flag.String("json", "", "Write a JSON report to this file (- for stdout)")
+	flag.StringVar(&analysisOpts.dsymPath, "dsym", "", "A .dSYM bundle or directory of dSYMs to correlate with the binary")
+	flag.BoolVar(&analysisOpts.malware, "malware", false, "Also run heuristics for suspicious sideloaded apps")
+	flag.BoolVar(&analysisOpts.noWrite, "no-write", false, "Extract to a temporary directory and write nothing next to the input")
+	flag.StringVar(&zipPassword, "zip-password", os.Getenv("IOSDUMPER_ZIP_PASSWORD"), "Password for encrypted zip entries (ZipCrypto or AES); also IOSDUMPER_ZIP_PASSWORD")
+	flag.StringVar(&scratchRoot, "scratch", "", "Directory for temporary files such as downloads (defaults to TMPDIR)")
+	expectSHA256Flag := flag.String("expect-sha256", "", "Stop unless the input IPA has this SHA-256 digest, and record it in the report")
+	flag.StringVar(&fragmentsDir, "fragments", "", "Also write each analyzer's results to this directory as JSON fragments, with the report merged from them")
+	workspaceFlag := flag.String("workspace", "", "Extract, write artifacts and keep reports in this workspace directory")
+	flag.StringVar(&analysisOpts.frida, "frida", "", "Attach Frida to the running app on this device (usb, host:port or device ID) and diff its loaded classes")
+	flag.StringVar(&analysisOpts.appStore, "app-store", "", "Look each app up in this country's App Store (e.g. us) and compare the published version")
+	flag.Var(&scopeOpts.include, "include", "Comma-separated path globs limiting which bundle files are scanned (e.g. 'Frameworks/**')")
+	flag.Var(&scopeOpts.exclude, "exclude", "Comma-separated path globs of bundle files to skip (e.g. '*.png,*.car')")
+	policyFlag := flag.String("policy", "", "Evaluate apps against this organization policy file")
+	trackersFlag := flag.String("trackers", "", "Also match endpoints against this Exodus tracker database (JSON)")
+	knownGoodFlag := flag.String("known-good", "", "Annotate bundle files whose SHA-256 is listed in this file")
+	knownBadFlag := flag.String("known-bad", "", "Alert on bundle files whose SHA-256 is listed in this file")
+	flag.StringVar(&historyPath, "history", historyPath, "Record scan summaries in this results history database (SQLite)")
+	flag.StringVar(&baselinePath, "baseline", baselinePath, "Leave out findings marked as false positives in this baseline file and tune confidence from it")
+	flag.BoolVar(&publishReports, "publish-report", false, "Upload each report next to its Artifactory or Nexus artifact")
+	ticketsFlag := flag.String("tickets", "", "Open or update a Jira or GitHub issue for each high or critical finding: jira or github")
+	fastlaneFlag := flag.Bool("fastlane", false, "Write the stable fastlane JSON document to stdout, without banner or color")
+	iocFlag := flag.String("ioc", "", "Write the extracted domains, IPs, URLs and hashes to this file as an IOC feed")
+	iocFormatFlag := flag.String("ioc-format", "stix", "IOC feed format: stix (STIX 2.1 bundle) or csv")
+	flag.BoolVar(&redactOutput, "redact", false, "Mask secrets in every output, keeping a prefix, suffix and hash")
+	defectDojoFlag := flag.String("defectdojo", "", "Write the findings to this file in DefectDojo's Generic Findings Import format")
+	gitLabFlag := flag.String("gitlab-sast", "", "Write the findings to this file as a GitLab SAST security report")
+	encryptFlag := flag.String("encrypt-output", "", "Also write the report and artifacts as an archive encrypted to this age or PGP public key")
+	profileFlag := flag.String("profile", "", "Write CPU and heap profiles and per-analyzer timings to this directory")
+	flag.Var(&extractOpts.maxTotal, "max-extract-size", "Extract at most this many bytes of the IPA, e.g. 8G (0 for no limit)")
+	flag.Var(&extractOpts.maxFile, "max-file-size", "Skip IPA entries larger than this, e.g. 1G (0 for no limit)")
 
 	flag.Parse()
 
+	// IOSDUMPER_HISTORY is an implicit write; --no-write keeps only the outputs named on the command line
+	if analysisOpts.noWrite && !flagSet("history") {
+		historyPath = ""
+	}
+
+	setLanguage(*langFlag)
+
+	// Keep stdout clean for the JSON document
+	if *jsonFlag == "-" {
+		stdout = os.Stderr
+	}
+	if *fastlaneFlag {
+		if *jsonFlag == "-" {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrFastlaneStdout")))
+			os.Exit(exitBadInput)
+		}
+		color.NoColor = true
+		stdout = os.Stderr
+	}
+	if err := setTheme(*themeFlag); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		os.Exit(exitBadInput)
+	}
+	if !*fastlaneFlag && (flag.Arg(0) != "macho" || !containsString(machoCompatCommands, flag.Arg(1))) {
+		displayBanner()
+	}
+
+	if !containsString(iocFormats, *iocFormatFlag) {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrIOCFormat", "Format", *iocFormatFlag, "Formats", strings.Join(iocFormats, ", "))))
+		os.Exit(exitBadInput)
+	}
+
+	if outputOpts.stringsMinLength < 1 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrStringsMinLength", "Length", outputOpts.stringsMinLength)))
+		os.Exit(exitBadInput)
+	}
+
+	if *expectSHA256Flag != "" {
+		digest, err := parseExpectedSHA256(*expectSHA256Flag)
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+		analysisOpts.expectSHA256 = digest
+	}
+
 	if *helpFlag || len(flag.Args()) == 0 {
 		displayHelp()
-		os.Exit(0)
+		os.Exit(exitClean)
 	}
 
-	filePath := os.Args[1]
+	if flag.Arg(0) == "trends" {
+		os.Exit(runTrends(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "macho" {
+		os.Exit(runMacho(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "merge" {
+		os.Exit(runMerge(flag.Args()[1:], *jsonFlag))
+	}
+	if flag.Arg(0) == "fp" {
+		os.Exit(runFP(flag.Args()[1:]))
+	}
 
-	if !strings.HasSuffix(filePath, ".ipa") {
-		color.Red("Error: The specified file does not have an '.ipa' extension.")
-		os.Exit(1)
+	prog, err := newProgressReporter(*progressFlag, os.Stderr)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		os.Exit(exitBadInput)
 	}
 
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		color.Red("Error: The specified file does not exist.")
-		os.Exit(1)
+	if *policyFlag != "" {
+		if activePolicy, err = loadPolicy(*policyFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
 	}
+	if *trackersFlag != "" {
+		if activeTrackers, err = loadTrackers(*trackersFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	if baselinePath != "" {
+		if activeBaseline, err = loadBaseline(baselinePath); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	if *knownGoodFlag != "" {
+		if knownHashes.good, err = loadHashList(*knownGoodFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	if *ticketsFlag != "" {
+		if ticketExporter, err = trackerFor(*ticketsFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	var recipient *outputRecipient
+	if *encryptFlag != "" {
+		// The archive would be written next to the input
+		if analysisOpts.noWrite {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrEncryptNoWrite")))
+			os.Exit(exitBadInput)
+		}
+		if recipient, err = loadRecipient(*encryptFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	if *knownBadFlag != "" {
+		if knownHashes.bad, err = loadHashList(*knownBadFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+
+	// Cancel everything in flight on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	fileDir := strings.TrimSuffix(filePath, filepath.Ext(filePath))
-	if err := os.Mkdir(fileDir, 0755); err != nil {
-		color.Red("Error creating directory: %v", err)
-		os.Exit(1)
+	if flag.Arg(0) == "db" {
+		code := runDB(ctx, flag.Args()[1:])
+		stop()
+		os.Exit(code)
+	}
+	// Without a database vulnerable-component matching is skipped, not fatal
+	if activeVulnDB, err = loadVulnDB(vulnDBDir()); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrVulnDB", "Err", err))
 	}
 
-	newFilePath := filepath.Join(fileDir, filepath.Base(filePath))
-	if err := copyFile(filePath, newFilePath); err != nil {
-		color.Red("Error copying file: %v", err)
-		os.Exit(1)
+	if flag.Arg(0) == "watch" {
+		code := runWatch(ctx, flag.Args()[1:], prog)
+		stop()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "serve" {
+		code := runServe(ctx, flag.Args()[1:])
+		stop()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "worker" {
+		code := runWorker(ctx, flag.Args()[1:], prog)
+		stop()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "container" {
+		code := runContainer(ctx, flag.Args()[1:], *jsonFlag)
+		stop()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "testflight" {
+		code := runTestFlight(ctx, flag.Args()[1:], prog, *jsonFlag)
+		stop()
+		os.Exit(code)
 	}
 
-	zipFilePath := strings.TrimSuffix(newFilePath, filepath.Ext(newFilePath)) + ".zip"
-	if err := os.Rename(newFilePath, zipFilePath); err != nil {
-		color.Red("Error changing file extension: %v", err)
-		os.Exit(1)
+	inputs, scan := flag.Args(), scanInput
+	if flag.Arg(0) == "analyze" {
+		if inputs, err = parseAnalyzeArgs(flag.Args()[1:]); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+		scan = analyzeExtraction
+	}
+	if flag.Arg(0) == "open" {
+		if flag.NArg() != 2 {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrOpenUsage")))
+			os.Exit(exitBadInput)
+		}
+		*workspaceFlag = flag.Arg(1)
+	}
+	if scratchRoot != "" {
+		// Fail before scanning rather than in every scan that needs it
+		dir, err := newScratchDir()
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrScratch", "Dir", scratchRoot, "Err", err)))
+			os.Exit(exitBadInput)
+		}
+		os.Remove(dir)
+	}
+	if analysisOpts.appStore != "" {
+		if analysisOpts.appStore, err = parseStorefront(analysisOpts.appStore); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	if *workspaceFlag != "" {
+		if analysisOpts.noWrite {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrWorkspaceNoWrite")))
+			os.Exit(exitBadInput)
+		}
+		if activeWorkspace, err = openWorkspace(*workspaceFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+	}
+	if flag.Arg(0) == "open" {
+		if inputs, err = activeWorkspace.reopenInputs(); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
+		scan = activeWorkspace.reopen
 	}
 
-	color.Green("File successfully copied and renamed to: %s", zipFilePath)
+	// One digest identifies one artifact
+	if analysisOpts.expectSHA256 != "" && len(inputs) != 1 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrExpectSHA256Inputs")))
+		os.Exit(exitBadInput)
+	}
 
-	// Unzip the file
-	if err := unzip(zipFilePath, fileDir); err != nil {
-		color.Red("Error unzipping file: %v", err)
-		os.Exit(1)
+	if *profileFlag != "" {
+		if err := startProfiling(*profileFlag); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			os.Exit(exitBadInput)
+		}
 	}
+	startPager()
+	var results []*scanResult
+	for _, input := range inputs {
+		result := scan(ctx, input, prog)
+		result.finish()
+		results = append(results, result)
+
+		if historyPath != "" {
+			if err := appendHistory(historyPath, result); err != nil {
+				activeTheme.failure.Fprintln(stdout, tr("ErrHistory", "Err", err))
+			}
+		}
+		if activeWorkspace != nil {
+			if path, err := activeWorkspace.save(result); err != nil {
+				activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			} else if path != "" {
+				activeTheme.success.Fprintln(stdout, tr("WorkspaceReportWritten", "Path", path))
+			}
+		}
+		if fragmentsDir != "" {
+			if path, err := writeFragments(fragmentsDir, result); err != nil {
+				activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			} else if path != "" {
+				activeTheme.success.Fprintln(stdout, tr("FragmentsWritten", "Dir", filepath.Dir(path), "Path", path))
+			}
+		}
+		publishReport(ctx, result)
+		exportTickets(ctx, result)
 
-	// Search and convert Info.plist to XML format
-	infoPlistPath := filepath.Join(fileDir, "Payload", "*.app", "Info.plist") // Assuming standard IPA structure
-	matches, err := filepath.Glob(infoPlistPath)
-	if err != nil || len(matches) == 0 {
-		color.Red("Info.plist not found or error searching: %v", err)
-		os.Exit(1)
+		for _, e := range result.Errors {
+			if e.Fatal {
+				activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", e.Message))
+			}
+		}
+		if result.ExitCode == exitInterrupted {
+			activeTheme.failure.Fprintln(stdout, tr("Interrupted"))
+			break
+		}
 	}
 
-	// Convert the first matched Info.plist to XML format and copy to the initial directory
-	if err := convertPlistToXML(matches[0], fileDir); err != nil {
-		color.Red("Error converting Info.plist to XML format: %v", err)
-		os.Exit(1)
+	// Batch scans compare every app side by side and report as one document
+	var report interface{} = results[0]
+	exitCode := results[0].ExitCode
+	if len(inputs) > 1 {
+		batch := newBatchResult(results)
+		if showSection(sectionMatrix) && len(batch.Matrix.Apps) > 0 {
+			printAppMatrix(stdout, batch.Matrix)
+		}
+		report, exitCode = batch, batch.ExitCode
 	}
 
-	// Ensure the directory path ends with a separator
-	if !strings.HasSuffix(fileDir, string(os.PathSeparator)) {
-		fileDir += string(os.PathSeparator)
+	if *jsonFlag != "" {
+		if err := writeJSONReport(*jsonFlag, report); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			stopProfiling()
+			closePager()
+			stop()
+			os.Exit(exitToolFailure)
+		}
+	}
+	if *iocFlag != "" {
+		if err := writeIOCFeed(*iocFlag, *iocFormatFlag, results); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			stopProfiling()
+			closePager()
+			stop()
+			os.Exit(exitToolFailure)
+		}
+	}
+	if *defectDojoFlag != "" {
+		if err := writeJSONReport(*defectDojoFlag, newDefectDojoReport(results)); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			stopProfiling()
+			closePager()
+			stop()
+			os.Exit(exitToolFailure)
+		}
+	}
+	if *gitLabFlag != "" {
+		if err := writeJSONReport(*gitLabFlag, newGitLabReport(results)); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			stopProfiling()
+			closePager()
+			stop()
+			os.Exit(exitToolFailure)
+		}
+	}
+	if *fastlaneFlag {
+		if err := writeJSONReport("-", newFastlaneOutput(newBatchResult(results))); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			stopProfiling()
+			closePager()
+			stop()
+			os.Exit(exitToolFailure)
+		}
+	}
+	if recipient != nil {
+		for _, result := range results {
+			out, err := writeEncryptedArchive(ctx, result, recipient)
+			if err != nil {
+				activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+				stopProfiling()
+				closePager()
+				stop()
+				os.Exit(exitToolFailure)
+			}
+			activeTheme.success.Fprintln(stdout, tr("EncryptedArchiveWritten", "Path", out))
+			if exitCode == exitClean || exitCode == exitFindings {
+				exitCode = result.ExitCode
+			}
+		}
+	}
+
+	stopProfiling()
+	closePager()
+	stop()
+	os.Exit(exitCode)
+}
+
+// run extracts the IPA at filePath next to it and runs every analyzer over the result.
+// Fatal problems end the run early; analyzer failures are recorded and the run continues.
+// If ctx is cancelled the partially written output directory is removed.
+func run(ctx context.Context, filePath string, prog *progressReporter) *scanResult {
+	result := newScanResult(filePath)
+	result.out = prog.output()
+	fail := func(code, stage string, err error) *scanResult {
+		return failScan(ctx, result, code, stage, err)
+	}
+	prog.startInput()
+
+	if !strings.HasSuffix(filePath, ".ipa") {
+		return fail(errCodeInvalidInput, "input", trError("ErrNotIPA"))
+	}
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fail(errCodeInvalidInput, "input", trError("ErrNotExist"))
+	}
+	sum, err := fileSHA256(filePath)
+	if err != nil {
+		return fail(errCodeIO, "input", err)
+	}
+	result.SHA256 = sum
+	if err := verifyInputSHA256(result); err != nil {
+		return fail(errCodeInvalidInput, "verify", err)
+	}
+	if analysisOpts.dsymPath != "" {
+		if _, err := os.Stat(analysisOpts.dsymPath); err != nil {
+			return fail(errCodeInvalidInput, "input", trError("ErrDSYM", "Path", analysisOpts.dsymPath, "Err", err))
+		}
 	}
 
-	// Construct the full path to Info.plist
-	plistPath := filepath.Join(fileDir, "Info.plist")
+	// The IPA is read in place: copies are only made in the scratch directory
+	var fileDir string
+	if analysisOpts.noWrite {
+		// Extract where nothing outlives the run
+		if fileDir, err = newScratchDir(); err != nil {
+			return fail(errCodeIO, "extract", trError("ErrCreateDir", "Err", err))
+		}
+		defer os.RemoveAll(fileDir)
+	} else {
+		fileDir = strings.TrimSuffix(filePath, filepath.Ext(filePath))
+		if activeWorkspace != nil {
+			fileDir = activeWorkspace.extractionDir(filePath)
+			if activeWorkspace.extracted(sum, fileDir) {
+				if showSection(sectionLog) {
+					activeTheme.success.Fprintln(prog.output(), tr("WorkspaceReused", "Dir", fileDir))
+				}
+				return reanalyze(ctx, result, fileDir, filePath, prog)
+			}
+		}
+		if err := os.Mkdir(fileDir, 0755); err != nil {
+			return fail(errCodeIO, "extract", trError("ErrCreateDir", "Err", err))
+		}
+		defer func() {
+			if ctx.Err() != nil {
+				os.RemoveAll(fileDir)
+			}
+		}()
+	}
 
-	// Debug: Print the path being used to open the file
-	fmt.Println("Attempting to open:", plistPath)
+	// Unzip the file
+	prog.stageStart("extract", progressExtractStart)
+	var limitErr *extractLimitError
+	if err := unzip(ctx, filePath, fileDir, prog); errors.As(err, &limitErr) {
+		// The entries that fit were extracted; report on those
+		result.addError(errCodeInvalidInput, "extract", "", trError("ErrUnzip", "Err", err), false)
+	} else if err != nil {
+		return fail(extractErrorCode(err), "extract", trError("ErrUnzip", "Err", err))
+	}
 
-	// Attempt to highlight keys in the Info.plist file
-	err = highlightKeysInFile(plistPath)
+	extraEntries, err := unexpectedArchiveEntries(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		result.addError(extractErrorCode(err), "extract", "", err, false)
+	}
+	return reportExtraction(ctx, result, fileDir, extraEntries, prog)
+}
+
+// failScan records a fatal error on the result, as an interruption when ctx
+// was cancelled, and returns the result
+func failScan(ctx context.Context, result *scanResult, code, stage string, err error) *scanResult {
+	if ctx.Err() != nil {
+		code = errCodeInterrupted
+	}
+	result.addError(code, stage, "", err, true)
+	return result
+}
+
+// reportExtraction reports on every app in the Payload directory of an IPA
+// extracted to fileDir. extraEntries are the archive's entries outside Payload.
+func reportExtraction(ctx context.Context, result *scanResult, fileDir string, extraEntries []string, prog *progressReporter) *scanResult {
+	fail := func(code, stage string, err error) *scanResult {
+		return failScan(ctx, result, code, stage, err)
+	}
+	result.Extraction = fileDir
+	out := prog.output()
+
+	// In a workspace the derived files go to artifacts/ instead of the extraction
+	artifactDir := fileDir
+	if activeWorkspace != nil {
+		var err error
+		if artifactDir, err = activeWorkspace.artifactDir(fileDir); err != nil {
+			return fail(errCodeIO, "report", trError("ErrCreateDir", "Err", err))
+		}
 	}
 
-	// Assuming standard IPA structure for finding .app directories
-	appDirs, err := filepath.Glob(filepath.Join(fileDir, "Payload", "*.app"))
+	// Find every .app in the payload and the extensions, watch apps and App Clips nested in them
+	prog.stageStart("plist", progressExtractEnd)
+	bundles, err := findBundles(filepath.Join(fileDir, "Payload"))
 	if err != nil {
-		color.Red("Error finding .app directories: %v", err)
-		os.Exit(1)
+		return fail(errCodeIO, "report", trError("ErrFindApps", "Err", err))
 	}
-	if len(appDirs) == 0 {
-		color.Red("No .app directories found.")
-		os.Exit(1)
+	if len(bundles) == 0 {
+		return fail(errCodeInvalidInput, "report", trError("ErrNoApps"))
 	}
 
-	// Loop through each .app directory
-	for _, appDir := range appDirs {
-		// Construct the expected main binary name (same as the .app directory, minus the extension)
-		appName := filepath.Base(appDir)                                 // Get the .app directory name
-		binaryName := strings.TrimSuffix(appName, filepath.Ext(appName)) // Remove .app extension
-		binaryPath := filepath.Join(appDir, binaryName)                  // Assume binary is directly inside .app folder
+	// Ensure the directory path ends with a separator
+	if !strings.HasSuffix(fileDir, string(os.PathSeparator)) {
+		fileDir += string(os.PathSeparator)
+	}
+
+	for i, bundle := range bundles {
+		if err := ctx.Err(); err != nil {
+			return fail(errCodeInterrupted, "report", err)
+		}
+
+		appPercent := progressBinaryStart + (100-progressBinaryStart)*i/len(bundles)
+		appName := bundle.Name()
+
+		// Convert the bundle's Info.plist to XML next to the extracted IPA
+		prog.stageStart("plist", appPercent)
+		plistPath := filepath.Join(artifactDir, bundle.plistCopyName(i == 0))
+		if err := convertPlistToXML(ctx, out, bundleInfoPlist(bundle.Dir), plistPath); err != nil {
+			result.addError(toolErrorCode(err), "plist", appName, err, false)
+		} else {
+			result.Artifacts = append(result.Artifacts, plistPath)
+			if showSection(sectionPlist) {
+				// Debug: Print the path being used to open the file
+				fmt.Fprintln(out, tr("AttemptingOpen", "Path", plistPath))
+
+				// Attempt to highlight keys in the Info.plist file
+				if err := highlightKeysInFile(out, plistPath); err != nil {
+					result.addError(errCodeIO, "plist", appName, err, false)
+				}
+			}
+		}
+
+		// Summarize metadata, capabilities, schemes, entitlements, frameworks and findings
+		prog.stageStart("report", appPercent)
+		report, err := buildAppReport(bundle.Dir, bundle.BinaryPath)
+		if err != nil {
+			result.addError(errCodeParse, "report", appName, trError("ErrAppReport", "App", appName, "Err", err), false)
+		} else {
+			report.Bundle = bundle.Rel
+			report.Parent = bundle.Parent
+			report.recorder.record(bundleAnalyzer, report)
+			if bundle.Parent == "" {
+				report.Findings = append(report.Findings, archiveFindings(extraEntries)...)
+				report.recorder.record("archive", report)
+				if analysisOpts.frida != "" {
+					prog.stageStart("frida", appPercent)
+					if err := runFridaPass(ctx, report, analysisOpts.frida); err != nil {
+						result.addError(toolErrorCode(err), "frida", appName, trError("ErrFrida", "Err", err), false)
+					}
+					report.recorder.record("frida", report)
+				}
+				if analysisOpts.appStore != "" {
+					prog.stageStart("appstore", appPercent)
+					if err := runAppStorePass(ctx, report, analysisOpts.appStore); err != nil {
+						code := errCodeFetch
+						if ctx.Err() != nil {
+							code = errCodeInterrupted
+						}
+						result.addError(code, "appstore", appName, trError("ErrAppStore", "Err", err), false)
+					}
+					report.recorder.record("appstore", report)
+				}
+			}
+			if historyPath != "" {
+				findings, err := signerHistoryFindings(historyPath, report)
+				if err != nil {
+					result.addError(errCodeIO, "history", appName, trError("ErrHistory", "Err", err), false)
+				}
+				report.Findings = append(report.Findings, findings...)
+				report.recorder.record("signer", report)
+			}
+			attachEvidenceContext(report)
+			report.Findings = dedupeFindings(report.Findings)
+			applyConfidence(report, activeBaseline)
+			redactReport(report)
+			result.Apps = append(result.Apps, report)
+			prog.appDone(report)
+			printReport(out, report)
+			// Under --no-write the export would only land in the temporary directory
+			if !analysisOpts.noWrite {
+				if path, err := exportGraphQL(report, artifactDir); err != nil {
+					result.addError(errCodeIO, "graphql", appName, err, false)
+				} else if path != "" {
+					result.Artifacts = append(result.Artifacts, path)
+					if showSection(sectionLog) {
+						activeTheme.success.Fprintln(out, tr("GraphQLExported", "Path", path))
+					}
+				}
+			}
+			prog.addFindings(len(report.Findings))
+		}
 
 		// First, run Radare2 command as before
-		if err := runRadare2Command(appDir); err != nil {
-			color.Red("Error running Radare2 command: %v", err)
-			os.Exit(1)
+		if showSection(sectionApplinks) {
+			prog.stageStart("radare2", appPercent)
+			prog.file(bundle.BinaryPath, appPercent)
+			if err := runRadare2Command(ctx, out, bundle.BinaryPath); err != nil {
+				result.addError(toolErrorCode(err), "radare2", appName, trError("ErrRadare2Step", "Err", err), false)
+			}
 		}
 
 		// Next, run strings and grep on the app binary
-		if err := runStringsAndGrep(binaryPath); err != nil {
-			color.Red("Error running strings and grep on the binary: %v", err)
-			os.Exit(1)
+		if showSection(sectionStrings) {
+			prog.stageStart("strings", appPercent)
+			prog.file(bundle.BinaryPath, appPercent)
+			if err := printBinaryStrings(out, bundle.BinaryPath); err != nil {
+				result.addError(errCodeIO, "strings", appName, trError("ErrStringsStep", "Err", err), false)
+			}
 		}
 	}
 
-	color.Green("File successfully extracted and Info.plist converted to XML format in: %s", fileDir)
+	if err := ctx.Err(); err != nil {
+		return fail(errCodeInterrupted, "report", err)
+	}
+
+	prog.stageStart("done", 100)
+
+	if analysisOpts.noWrite {
+		activeTheme.success.Fprintln(out, tr("DoneNoWrite"))
+	} else {
+		activeTheme.success.Fprintln(out, tr("Done", "Dir", fileDir))
+	}
+	return result
 }
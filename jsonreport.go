@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// scanResult is the outcome of one run over an input artifact, serialized by --json
+type scanResult struct {
+	Tool           string       `json:"tool"`
+	Version        string       `json:"version"`
+	Input          string       `json:"input"`
+	SHA256         string       `json:"sha256,omitempty"`          // digest of the scanned IPA
+	ExpectedSHA256 string       `json:"expected_sha256,omitempty"` // digest --expect-sha256 required
+	StartedAt      time.Time    `json:"started_at"`
+	Status         string       `json:"status"` // clean, findings, partial, failed or interrupted
+	ExitCode       int          `json:"exit_code"`
+	Apps           []*appReport `json:"apps"`
+	Errors         []scanError  `json:"errors"`
+	Artifacts      []string     `json:"-"` // files the scan wrote next to the input, for --encrypt-output
+	Extraction     string       `json:"-"` // directory the IPA was extracted to
+	out            io.Writer    // human-readable output of the scan, see output
+}
+
+// newScanResult starts an empty result for the given input path
+func newScanResult(input string) *scanResult {
+	return &scanResult{
+		Tool:      "iosdumper",
+		Version:   version,
+		Input:     input,
+		StartedAt: time.Now().UTC(),
+		Apps:      []*appReport{},
+		Errors:    []scanError{},
+	}
+}
+
+// addError records a problem, printing it for human readers as it happens
+func (r *scanResult) addError(code, stage, app string, err error, fatal bool) {
+	r.Errors = append(r.Errors, scanError{Code: code, Stage: stage, App: app, Message: err.Error(), Fatal: fatal})
+	if !fatal {
+		activeTheme.failure.Fprintln(r.output(), tr("ErrGeneric", "Err", err))
+	}
+}
+
+// output is where the scan's human-readable output goes, see progressReporter.output
+func (r *scanResult) output() io.Writer {
+	if r.out != nil {
+		return r.out
+	}
+	return stdout
+}
+
+// finish computes the exit code and status from the recorded errors and findings
+func (r *scanResult) finish() {
+	r.ExitCode = exitCodeForErrors(r.Errors)
+	if r.ExitCode < 0 {
+		r.ExitCode = exitClean
+		for _, app := range r.Apps {
+			for _, f := range app.Findings {
+				if f.Severity != severityInfo {
+					r.ExitCode = exitFindings
+				}
+			}
+		}
+	}
+
+	switch r.ExitCode {
+	case exitClean:
+		r.Status = "clean"
+	case exitFindings:
+		r.Status = "findings"
+	case exitPartial:
+		r.Status = "partial"
+	case exitInterrupted:
+		r.Status = "interrupted"
+	default:
+		r.Status = "failed"
+	}
+}
+
+// batchResult is the outcome of scanning several inputs in one invocation
+type batchResult struct {
+	Tool     string        `json:"tool"`
+	Version  string        `json:"version"`
+	Status   string        `json:"status"`
+	ExitCode int           `json:"exit_code"`
+	Scans    []*scanResult `json:"scans"`
+	Matrix   appMatrix     `json:"matrix"`
+}
+
+// newBatchResult combines finished scans. The batch exit code is the most
+// severe of the individual ones: interruption, then failures, then bad input,
+// partial analysis and findings.
+func newBatchResult(results []*scanResult) *batchResult {
+	b := &batchResult{
+		Tool:     "iosdumper",
+		Version:  version,
+		ExitCode: exitClean,
+		Status:   "clean",
+		Scans:    results,
+		Matrix:   buildAppMatrix(matrixApps(results)),
+	}
+	rank := map[int]int{exitClean: 0, exitFindings: 1, exitPartial: 2, exitBadInput: 3, exitToolFailure: 4, exitInterrupted: 5}
+	for _, r := range results {
+		if rank[r.ExitCode] > rank[b.ExitCode] {
+			b.ExitCode, b.Status = r.ExitCode, r.Status
+		}
+	}
+	return b
+}
+
+// writeJSONReport writes a scan or batch result as indented JSON to path, or to stdout when path is "-"
+func writeJSONReport(path string, r interface{}) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+)
+
+// keychainAPIs are the Security framework functions that store and read keychain items
+var keychainAPIs = []string{
+	"SecItemAdd",
+	"SecItemUpdate",
+	"SecItemCopyMatching",
+	"SecItemDelete",
+	"SecAccessControlCreateWithFlags",
+}
+
+// keychainAccessibility grades each kSecAttrAccessible class. Only the
+// classes that leave items readable while the device is locked produce findings.
+var keychainAccessibility = map[string]struct {
+	severity string
+	note     string
+}{
+	"kSecAttrAccessibleAlways":                         {severityMedium, "readable at any time, even before first unlock, and migrates to other devices through backups (deprecated)"},
+	"kSecAttrAccessibleAlwaysThisDeviceOnly":           {severityMedium, "readable at any time, even before first unlock (deprecated)"},
+	"kSecAttrAccessibleAfterFirstUnlock":               {severityInfo, "readable while locked once the device has been unlocked after boot, and migrates through backups"},
+	"kSecAttrAccessibleAfterFirstUnlockThisDeviceOnly": {severityInfo, "readable while locked once the device has been unlocked after boot"},
+	"kSecAttrAccessibleWhenUnlocked":                   {severityInfo, "readable only while unlocked"},
+	"kSecAttrAccessibleWhenUnlockedThisDeviceOnly":     {severityInfo, "readable only while unlocked, never leaves the device"},
+	"kSecAttrAccessibleWhenPasscodeSetThisDeviceOnly":  {severityInfo, "readable only while unlocked on a device with a passcode"},
+}
+
+// keychainReport summarizes how the app uses the keychain
+type keychainReport struct {
+	APIs           []string `json:"apis,omitempty"`
+	Accessibility  []string `json:"accessibility,omitempty"` // kSecAttrAccessible classes referenced
+	Synchronizable bool     `json:"synchronizable"`          // kSecAttrSynchronizable referenced, items may sync to iCloud
+}
+
+// keychainAnalyzer records keychain accessibility constants named in the
+// binary. Swift and Objective-C code reference them as imported symbols,
+// while keychain wrapper libraries often carry them as plain strings.
+type keychainAnalyzer struct {
+	seen map[string]bool
+}
+
+func newKeychainAnalyzer() stringAnalyzer {
+	return &keychainAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *keychainAnalyzer) visit(s string, offset int64) {
+	if !strings.HasPrefix(s, "kSecAttr") && !strings.HasPrefix(s, "_kSecAttr") {
+		return
+	}
+	name := strings.TrimPrefix(s, "_")
+	if _, ok := keychainAccessibility[name]; ok || name == "kSecAttrSynchronizable" {
+		a.seen[name] = true
+	}
+}
+
+func (a *keychainAnalyzer) findings(r *appReport) []finding {
+	seen := make(map[string]bool, len(a.seen))
+	for name := range a.seen {
+		seen[name] = true
+	}
+	apis := make(map[string]bool)
+	for _, sym := range r.Imports {
+		name := strings.TrimPrefix(sym, "_")
+		if containsString(keychainAPIs, name) {
+			apis[name] = true
+		}
+		if _, ok := keychainAccessibility[name]; ok || name == "kSecAttrSynchronizable" {
+			seen[name] = true
+		}
+	}
+
+	k := keychainReport{APIs: sortedSet(apis), Synchronizable: seen["kSecAttrSynchronizable"]}
+	delete(seen, "kSecAttrSynchronizable")
+	k.Accessibility = sortedSet(seen)
+	r.Keychain = k
+
+	// Accessibility constants on their own may only be read back, not used to store
+	if !apis["SecItemAdd"] && !apis["SecItemUpdate"] {
+		return nil
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, class := range k.Accessibility {
+		grade := keychainAccessibility[class]
+		if grade.severity == severityInfo {
+			continue
+		}
+		findings = append(findings, finding{
+			Rule:        "keychain-weak-accessibility",
+			Severity:    grade.severity,
+			Title:       "Keychain items stored with weak accessibility class",
+			Evidence:    class + ": " + grade.note,
+			Location:    location,
+			Remediation: "Store secrets with kSecAttrAccessibleWhenUnlockedThisDeviceOnly, or kSecAttrAccessibleAfterFirstUnlockThisDeviceOnly when background access is required.",
+		})
+	}
+	if len(k.Accessibility) == 0 {
+		findings = append(findings, finding{
+			Rule:     "keychain-default-accessibility",
+			Severity: severityInfo,
+			Title:    "Keychain items stored without an explicit accessibility class",
+			Evidence: strings.Join(k.APIs, ", "),
+			Location: location,
+			Remediation: "Set kSecAttrAccessible explicitly on every item so its protection class is a deliberate choice " +
+				"rather than the default, kSecAttrAccessibleWhenUnlocked.",
+		})
+	}
+	if k.Synchronizable {
+		findings = append(findings, finding{
+			Rule:        "keychain-synchronizable",
+			Severity:    severityInfo,
+			Title:       "Keychain items may sync through iCloud Keychain",
+			Evidence:    "kSecAttrSynchronizable",
+			Location:    location,
+			Remediation: "Keep device-bound secrets such as refresh tokens out of iCloud Keychain by leaving kSecAttrSynchronizable unset.",
+		})
+	}
+	return findings
+}
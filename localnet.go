@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxServiceTypes bounds how many mDNS service types are kept from the binary
+const maxServiceTypes = 30
+
+// serviceTypePattern matches a DNS-SD service type such as _airplay._tcp,
+// optionally with the trailing dot or .local. domain
+var serviceTypePattern = regexp.MustCompile(`^(_[A-Za-z0-9][A-Za-z0-9-]{0,14}\._(?:tcp|udp))\.?(?:local\.?)?$`)
+
+// Info.plist keys, entitlements and imports for local network access
+const (
+	keyLocalNetworkUsage = "NSLocalNetworkUsageDescription"
+	keyBonjourServices   = "NSBonjourServices"
+	entMulticast         = "com.apple.developer.networking.multicast"
+	symNetServiceBrowser = "_OBJC_CLASS_$_NSNetServiceBrowser"
+	symNetService        = "_OBJC_CLASS_$_NSNetService"
+	symNWBrowser         = "_nw_browser_create"
+	symDNSServiceBrowse  = "_DNSServiceBrowse"
+	symDNSServiceReg     = "_DNSServiceRegister"
+)
+
+// localNetworkReport is the app's LAN surface: the Bonjour services it
+// declares and the ones its binary names
+type localNetworkReport struct {
+	UsageDescription string   `json:"usage_description,omitempty"`
+	Declared         []string `json:"declared_services,omitempty"` // NSBonjourServices
+	Referenced       []string `json:"referenced_services,omitempty"`
+	APIs             []string `json:"apis,omitempty"`
+	Multicast        bool     `json:"multicast"` // multicast networking entitlement
+}
+
+// String summarizes the report, e.g. "3 Bonjour services, multicast"
+func (l localNetworkReport) String() string {
+	services := make(map[string]bool)
+	for _, s := range append(append([]string(nil), l.Declared...), l.Referenced...) {
+		services[s] = true
+	}
+	var parts []string
+	if len(services) > 0 {
+		parts = append(parts, fmt.Sprintf("%d Bonjour services", len(services)))
+	}
+	if l.Multicast {
+		parts = append(parts, "multicast")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// localNetworkAnalyzer records the mDNS service types named in the binary
+type localNetworkAnalyzer struct {
+	types map[string]bool
+}
+
+func newLocalNetworkAnalyzer() stringAnalyzer {
+	return &localNetworkAnalyzer{types: make(map[string]bool)}
+}
+
+func (a *localNetworkAnalyzer) visit(s string, offset int64) {
+	if len(a.types) >= maxServiceTypes || !strings.HasPrefix(s, "_") {
+		return
+	}
+	if m := serviceTypePattern.FindStringSubmatch(s); m != nil {
+		a.types[strings.ToLower(m[1])] = true
+	}
+}
+
+func (a *localNetworkAnalyzer) findings(r *appReport) []finding {
+	l := &r.LocalNetwork
+	l.UsageDescription = plistString(r.InfoPlist, keyLocalNetworkUsage)
+	declared := make(map[string]bool)
+	for _, s := range plistStrings(r.InfoPlist, keyBonjourServices) {
+		s = strings.ToLower(strings.TrimSuffix(s, "."))
+		declared[s] = true
+	}
+	l.Declared = sortedSet(declared)
+	l.Referenced = sortedSet(a.types)
+	for _, sym := range []string{symNetServiceBrowser, symNetService, symNWBrowser, symDNSServiceBrowse, symDNSServiceReg} {
+		if containsString(r.Imports, sym) {
+			l.APIs = append(l.APIs, strings.TrimPrefix(strings.TrimPrefix(sym, "_OBJC_CLASS_$_"), "_"))
+		}
+	}
+	l.Multicast = plistBool(r.Entitlements, entMulticast)
+
+	var undeclared []string
+	for _, s := range l.Referenced {
+		if !declared[s] {
+			undeclared = append(undeclared, s)
+		}
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if len(l.Declared) > 0 || len(l.Referenced) > 0 || l.Multicast {
+		var evidence []string
+		if len(l.Declared) > 0 {
+			evidence = append(evidence, "declared: "+sampleList(l.Declared))
+		}
+		if len(l.Referenced) > 0 {
+			evidence = append(evidence, "binary: "+sampleList(l.Referenced))
+		}
+		if l.Multicast {
+			evidence = append(evidence, entMulticast)
+		}
+		findings = append(findings, finding{
+			Rule:     "local-network-services",
+			Severity: severityInfo,
+			Title:    "Local network protocols",
+			Evidence: strings.Join(evidence, "; "),
+			Location: location,
+			Remediation: "Any device on the same Wi-Fi can reach these services; authenticate peers and encrypt traffic rather than " +
+				"trusting the LAN.",
+		})
+	}
+	// Browsing for a type missing from NSBonjourServices fails on iOS 14 and
+	// later; an app that still names one either targets older systems or
+	// carries dead code
+	if len(undeclared) > 0 && len(l.APIs) > 0 {
+		findings = append(findings, finding{
+			Rule:        "bonjour-undeclared",
+			Severity:    severityLow,
+			Title:       "Bonjour service types not declared in NSBonjourServices",
+			Evidence:    sampleList(undeclared),
+			Location:    location,
+			Remediation: "List every service type the app browses for or advertises in NSBonjourServices, or remove the code using it.",
+		})
+	}
+	if (len(l.Declared) > 0 || l.Multicast) && l.UsageDescription == "" {
+		findings = append(findings, finding{
+			Rule:        "local-network-usage-missing",
+			Severity:    severityLow,
+			Title:       "Local network access without NSLocalNetworkUsageDescription",
+			Evidence:    "Bonjour services or multicast declared",
+			Location:    bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: "Add NSLocalNetworkUsageDescription explaining why the app talks to devices on the local network.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// loggingImports maps imported symbols to the logging API they belong to
+var loggingImports = map[string]string{
+	"_NSLog":             "NSLog",
+	"_NSLogv":            "NSLog",
+	"_os_log_impl":       "os_log",
+	"_os_log_error_impl": "os_log",
+	"_os_log_fault_impl": "os_log",
+	"_os_log_debug_impl": "os_log",
+	"_os_log_create":     "os_log",
+	"_$ss5print_9separator10terminatoryypd_S2StF":      "print",
+	"_$ss9debugPrint_9separator10terminatoryypd_S2StF": "debugPrint",
+	"_printf":  "printf",
+	"_puts":    "printf",
+	"_fprintf": "printf",
+	"_syslog":  "syslog",
+}
+
+var (
+	// logFormatPattern matches printf and os_log conversion specifiers, including os_log privacy modifiers
+	logFormatPattern = regexp.MustCompile(`%(?:\d+\$)?(?:\{(?:public|private|sensitive)[^}]*\})?[-+ #0]*\d*(?:\.\d+)?(?:hh|h|ll|l|q|z|t|j)?[@sdiuxXfegcp]`)
+	// sensitiveLogPattern matches words suggesting the logged value is a credential or personal data
+	sensitiveLogPattern = regexp.MustCompile(`(?i)\b(?:access[ _-]?token|refresh[ _-]?token|id[ _-]?token|token|bearer|authorization|session[ _-]?id|api[ _-]?key|secret|password|passwd|passcode|e-?mail|card[ _-]?number|cardnumber|pan|cvv|cvc|ssn)\b`)
+)
+
+// maxLoggingSamples bounds how many format strings are kept as samples
+const maxLoggingSamples = 10
+
+// loggingReport quantifies the app's logging
+type loggingReport struct {
+	APIs          []string `json:"apis,omitempty"`
+	FormatStrings int      `json:"format_strings"`
+	Samples       []string `json:"samples,omitempty"`
+	Sensitive     []string `json:"sensitive,omitempty"`
+}
+
+// String summarizes the APIs and format string count, e.g. "NSLog, os_log (143 format strings)"
+func (l loggingReport) String() string {
+	if len(l.APIs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d format strings)", strings.Join(l.APIs, ", "), l.FormatStrings)
+}
+
+// loggingAnalyzer counts format strings in the binary, keeps a sample, and
+// collects those that mention credentials or personal data
+type loggingAnalyzer struct {
+	count     int
+	samples   []string
+	sensitive map[string]bool
+}
+
+func newLoggingAnalyzer() stringAnalyzer {
+	return &loggingAnalyzer{sensitive: make(map[string]bool)}
+}
+
+func (a *loggingAnalyzer) visit(s string, offset int64) {
+	if len(s) > maxFormatStringLength || !strings.Contains(s, "%") {
+		return
+	}
+	verbs := logFormatPattern.FindAllString(s, -1)
+	if len(verbs) == 0 {
+		return
+	}
+	a.count++
+	if len(a.samples) < maxLoggingSamples {
+		a.samples = append(a.samples, s)
+	}
+	if !sensitiveLogPattern.MatchString(s) {
+		return
+	}
+	// Values os_log redacts on its own are not a leak
+	for _, v := range verbs {
+		if !strings.Contains(v, "{private") && !strings.Contains(v, "{sensitive") {
+			addSample(a.sensitive, s)
+			return
+		}
+	}
+}
+
+func (a *loggingAnalyzer) findings(r *appReport) []finding {
+	apis := make(map[string]bool)
+	for _, sym := range r.Imports {
+		if api, ok := loggingImports[sym]; ok {
+			apis[api] = true
+		}
+	}
+	r.Logging = loggingReport{
+		APIs:          sortedSet(apis),
+		FormatStrings: a.count,
+		Samples:       a.samples,
+		Sensitive:     sortedSet(a.sensitive),
+	}
+	if len(apis) == 0 {
+		return nil
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, s := range r.Logging.Sensitive {
+		severity := severityLow
+		if strings.Contains(s, "{public") {
+			severity = severityMedium
+		}
+		findings = append(findings, finding{
+			Rule:     "sensitive-logging",
+			Severity: severity,
+			Title:    "Format string appears to log credentials or personal data",
+			Evidence: s,
+			Location: location,
+			Remediation: "Do not log tokens, passwords, email addresses or card numbers. If the value is needed for debugging, " +
+				"log it with os_log and the %{private} modifier so it is redacted outside development.",
+		})
+	}
+	return findings
+}
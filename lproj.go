@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxLocaleLeaks bounds how many localized strings are reported
+const maxLocaleLeaks = 50
+
+var (
+	// debugTextPattern matches placeholder and debug text left in a translation
+	debugTextPattern = regexp.MustCompile(`(?i)\b(?:debug|todo|fixme|xxx+|lorem ipsum|placeholder|internal only|do not ship|staging|test build)\b`)
+	// internalHostPattern matches host names of internal and pre-production environments
+	internalHostPattern = regexp.MustCompile(`(?i)(?:^|[.-])(?:dev|qa|uat|stage|staging|preprod|sandbox|internal|intranet|corp)(?:[.-]|\d|$)|\.(?:local|lan|test|internal|corp)$`)
+)
+
+// localeLeak is a localized Info.plist string that differs from the base
+// locale in a way worth a look
+type localeLeak struct {
+	Locale string `json:"locale"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Kind   string `json:"kind"` // display-name, url, internal-url, debug-text or undeclared-key
+}
+
+// localizationReport lists the app's localizations and what their
+// InfoPlist.strings add to the base Info.plist
+type localizationReport struct {
+	Locales []string     `json:"locales,omitempty"`
+	Leaks   []localeLeak `json:"leaks,omitempty"`
+}
+
+// analyzeLocalizations compares every <locale>.lproj/InfoPlist.strings with
+// the base locale, meaning Info.plist and Base.lproj, and records alternate
+// display names, URLs the base does not contain, debug text, and keys
+// Info.plist does not declare
+func analyzeLocalizations(r *appReport) error {
+	entries, err := os.ReadDir(r.Path)
+	if err != nil {
+		return err
+	}
+	base := make(map[string]string)
+	for key, v := range r.InfoPlist {
+		if s, ok := v.(string); ok {
+			base[key] = s
+		}
+	}
+	baseURLs := make(map[string]bool)
+	locales := make(map[string]map[string]string)
+	for _, e := range entries {
+		if !e.IsDir() || filepath.Ext(e.Name()) != ".lproj" {
+			continue
+		}
+		locale := strings.TrimSuffix(e.Name(), ".lproj")
+		r.Localizations.Locales = append(r.Localizations.Locales, locale)
+		if !scopeOpts.inScope(e.Name() + "/InfoPlist.strings") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.Path, e.Name(), "InfoPlist.strings"))
+		if err != nil {
+			continue
+		}
+		strs, err := parseStringsFile(data)
+		if err != nil {
+			continue
+		}
+		if locale == "Base" {
+			for _, v := range strs {
+				for _, u := range endpointPattern.FindAllString(v, -1) {
+					baseURLs[u] = true
+				}
+			}
+			continue
+		}
+		locales[locale] = strs
+	}
+	for _, v := range base {
+		for _, u := range endpointPattern.FindAllString(v, -1) {
+			baseURLs[u] = true
+		}
+	}
+
+	baseName := base["CFBundleDisplayName"]
+	if baseName == "" {
+		baseName = base["CFBundleName"]
+	}
+	for _, locale := range sortedKeysOf(locales) {
+		strs := locales[locale]
+		for _, key := range sortedKeysOf(strs) {
+			value := strs[key]
+			add := func(kind, value string) {
+				if len(r.Localizations.Leaks) < maxLocaleLeaks {
+					r.Localizations.Leaks = append(r.Localizations.Leaks, localeLeak{Locale: locale, Key: key, Value: snippet(value, 0, 120), Kind: kind})
+				}
+			}
+			if (key == "CFBundleDisplayName" || key == "CFBundleName") && value != baseName && value != "" {
+				add("display-name", value)
+			}
+			for _, u := range endpointPattern.FindAllString(value, -1) {
+				if baseURLs[u] {
+					continue
+				}
+				if host := endpointHost(u); internalHostPattern.MatchString(host) || isPrivateHost(host) {
+					add("internal-url", u)
+				} else {
+					add("url", u)
+				}
+			}
+			if debugTextPattern.MatchString(value) && !debugTextPattern.MatchString(base[key]) {
+				add("debug-text", value)
+			}
+			if _, declared := r.InfoPlist[key]; !declared && key != "CFBundleDisplayName" && key != "CFBundleName" {
+				add("undeclared-key", value)
+			}
+		}
+	}
+	return nil
+}
+
+// isPrivateHost reports whether host is a loopback, link-local or private IP address
+func isPrivateHost(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast())
+}
+
+// checkLocalizations reports the localized strings analyzeLocalizations recorded
+func checkLocalizations(r *appReport) []finding {
+	var findings []finding
+	var names []string
+	for _, l := range r.Localizations.Leaks {
+		location := l.Locale + ".lproj/InfoPlist.strings"
+		evidence := fmt.Sprintf("%s = %s", l.Key, l.Value)
+		switch l.Kind {
+		case "display-name":
+			names = append(names, l.Locale+": "+l.Value)
+		case "internal-url":
+			findings = append(findings, finding{
+				Rule:        "locale-internal-url",
+				Severity:    severityMedium,
+				Title:       "Localized Info.plist string points to an internal or pre-production host",
+				Evidence:    evidence,
+				Location:    location,
+				Remediation: "Remove internal endpoints from translations; localized resources are readable by anyone with the IPA.",
+			})
+		case "url":
+			findings = append(findings, finding{
+				Rule:        "locale-url",
+				Severity:    severityLow,
+				Title:       "Localized Info.plist string contains a URL the base locale does not",
+				Evidence:    evidence,
+				Location:    location,
+				Remediation: "Check that the URL is meant to be public; per-locale URLs sometimes reveal unreleased sites or services.",
+			})
+		case "debug-text":
+			findings = append(findings, finding{
+				Rule:        "locale-debug-text",
+				Severity:    severityLow,
+				Title:       "Debug or placeholder text in a localized Info.plist string",
+				Evidence:    evidence,
+				Location:    location,
+				Remediation: "Replace placeholder translations before release; users of this locale see the text in system prompts.",
+			})
+		case "undeclared-key":
+			findings = append(findings, finding{
+				Rule:        "locale-undeclared-key",
+				Severity:    severityInfo,
+				Title:       "Localized key that Info.plist does not declare",
+				Evidence:    evidence,
+				Location:    location,
+				Remediation: "Drop stale keys from InfoPlist.strings; they may belong to removed features or another build of the app.",
+			})
+		}
+	}
+	if len(names) > 0 {
+		findings = append(findings, finding{
+			Rule:        "locale-display-name",
+			Severity:    severityInfo,
+			Title:       "Localized display names differ from the base name",
+			Evidence:    sampleList(names),
+			Location:    "InfoPlist.strings",
+			Remediation: "Review alternate names; a name that is not a translation may be unreleased branding.",
+		})
+	}
+	return findings
+}
+
+// parseStringsFile decodes a .strings file: a binary plist, as Xcode
+// compiles them, or the text format of "key" = "value"; pairs in UTF-8 or
+// UTF-16
+func parseStringsFile(data []byte) (map[string]string, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		value, err := parsePlist(data)
+		if err != nil {
+			return nil, err
+		}
+		dict, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("top-level object is not a dictionary")
+		}
+		strs := make(map[string]string, len(dict))
+		for k, v := range dict {
+			if s, ok := v.(string); ok {
+				strs[k] = s
+			}
+		}
+		return strs, nil
+	}
+	return parseTextStrings(decodeStringsText(data))
+}
+
+// decodeStringsText converts UTF-16 text, detected by its byte order mark,
+// to UTF-8 and strips a UTF-8 byte order mark
+func decodeStringsText(data []byte) string {
+	var bigEndian bool
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		bigEndian = true
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+	default:
+		return strings.TrimPrefix(string(data), "\uFEFF")
+	}
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// parseTextStrings parses "key" = "value"; pairs, skipping comments. Keys
+// may be unquoted identifiers.
+func parseTextStrings(text string) (map[string]string, error) {
+	strs := make(map[string]string)
+	p := 0
+	skip := func() {
+		for p < len(text) {
+			switch {
+			case strings.ContainsRune(" \t\r\n", rune(text[p])):
+				p++
+			case strings.HasPrefix(text[p:], "//"):
+				if i := strings.IndexByte(text[p:], '\n'); i >= 0 {
+					p += i + 1
+				} else {
+					p = len(text)
+				}
+			case strings.HasPrefix(text[p:], "/*"):
+				if i := strings.Index(text[p+2:], "*/"); i >= 0 {
+					p += i + 4
+				} else {
+					p = len(text)
+				}
+			default:
+				return
+			}
+		}
+	}
+	token := func() (string, error) {
+		if p >= len(text) {
+			return "", errors.New("unexpected end of file")
+		}
+		if text[p] != '"' {
+			start := p
+			for p < len(text) && isStringsIdentByte(text[p]) {
+				p++
+			}
+			if p == start {
+				return "", fmt.Errorf("unexpected %q at offset %d", text[p], p)
+			}
+			return text[start:p], nil
+		}
+		var b strings.Builder
+		for p++; p < len(text); p++ {
+			c := text[p]
+			switch {
+			case c == '"':
+				p++
+				return b.String(), nil
+			case c == '\\' && p+1 < len(text):
+				p++
+				switch text[p] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				case 'U', 'u':
+					var r rune
+					if p+4 < len(text) {
+						if _, err := fmt.Sscanf(text[p+1:p+5], "%04x", &r); err == nil {
+							b.WriteRune(r)
+							p += 4
+							continue
+						}
+					}
+					b.WriteByte(text[p])
+				default:
+					b.WriteByte(text[p])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", errors.New("unterminated string")
+	}
+
+	for {
+		skip()
+		if p >= len(text) {
+			return strs, nil
+		}
+		key, err := token()
+		if err != nil {
+			return nil, err
+		}
+		skip()
+		if p >= len(text) || text[p] != '=' {
+			return nil, fmt.Errorf("expected = after %q", key)
+		}
+		p++
+		skip()
+		value, err := token()
+		if err != nil {
+			return nil, err
+		}
+		skip()
+		if p >= len(text) || text[p] != ';' {
+			return nil, fmt.Errorf("expected ; after value of %q", key)
+		}
+		p++
+		strs[key] = value
+	}
+}
+
+// isStringsIdentByte reports whether c may appear in an unquoted .strings token
+func isStringsIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_.$:/-", c) >= 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errWrapperManyApps = errors.New("more than one app in Wrapper")
+
+// isAppDir reports whether input is an .app directory on disk, such as a
+// Mac Catalyst app or an iOS app installed on an Apple silicon Mac
+func isAppDir(input string) bool {
+	st, err := os.Stat(input)
+	return err == nil && st.IsDir() && strings.HasSuffix(strings.TrimSuffix(input, string(filepath.Separator)), ".app")
+}
+
+// wrappedApp returns the iOS app inside the Wrapper directory of an iOS app
+// installed on an Apple silicon Mac, or "" when app is not such a wrapper
+func wrappedApp(app string) (string, error) {
+	inner, err := filepath.Glob(filepath.Join(app, "Wrapper", "*.app"))
+	switch {
+	case err != nil:
+		return "", err
+	case len(inner) > 1:
+		return "", fmt.Errorf("%w: %s", errWrapperManyApps, filepath.Base(app))
+	case len(inner) == 1:
+		return inner[0], nil
+	}
+	return "", nil
+}
+
+// analyzeAppDir reports on an .app directory in place. Like an IPA it gets
+// an extraction directory, next to it or a scratch one under --no-write,
+// but its Payload only links to the app, so analyze --from can report on it
+// again. An iOS app installed on a Mac is analyzed from its Wrapper
+// directory; a Mac Catalyst app keeps its Contents layout, which the
+// analyzers resolve through bundleContents.
+func analyzeAppDir(ctx context.Context, app string, prog *progressReporter) *scanResult {
+	app = filepath.Clean(app)
+	result := newScanResult(app)
+	result.out = prog.output()
+	fail := func(code, stage string, err error) *scanResult {
+		return failScan(ctx, result, code, stage, err)
+	}
+	prog.startInput()
+	if err := verifyInputSHA256(result); err != nil {
+		return fail(errCodeInvalidInput, "verify", err)
+	}
+	if inner, err := wrappedApp(app); err != nil {
+		return fail(extractErrorCode(err), "input", err)
+	} else if inner != "" {
+		app = inner
+	}
+	target, err := filepath.Abs(app)
+	if err != nil {
+		return fail(errCodeIO, "input", err)
+	}
+
+	var fileDir string
+	if analysisOpts.noWrite {
+		if fileDir, err = newScratchDir(); err != nil {
+			return fail(errCodeIO, "extract", trError("ErrCreateDir", "Err", err))
+		}
+		defer os.RemoveAll(fileDir)
+	} else {
+		fileDir = strings.TrimSuffix(result.Input, ".app")
+		if activeWorkspace != nil {
+			fileDir = activeWorkspace.extractionDir(result.Input)
+		}
+		if err := os.Mkdir(fileDir, 0755); err != nil {
+			return fail(errCodeIO, "extract", trError("ErrCreateDir", "Err", err))
+		}
+		defer func() {
+			if ctx.Err() != nil {
+				os.RemoveAll(fileDir)
+			}
+		}()
+	}
+	payload := filepath.Join(fileDir, "Payload")
+	if err := os.Mkdir(payload, 0755); err != nil {
+		return fail(errCodeIO, "extract", trError("ErrCreateDir", "Err", err))
+	}
+	if err := os.Symlink(target, filepath.Join(payload, filepath.Base(target))); err != nil {
+		return fail(errCodeIO, "extract", err)
+	}
+	return reportExtraction(ctx, result, fileDir, nil, prog)
+}
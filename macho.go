@@ -0,0 +1,323 @@
+package main
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Load command and code signature constants not exported by debug/macho
+const (
+	lcUUID               = 0x1b
+	lcCodeSignature      = 0x1d
+	lcVersionMinMacOSX   = 0x24
+	lcVersionMinIPhoneOS = 0x25
+	lcVersionMinTVOS     = 0x2f
+	lcVersionMinWatchOS  = 0x30
+	lcSourceVersion      = 0x2a
+	lcBuildVersion       = 0x32
+	cpuSubtypeMask       = 0x00ffffff
+	cpuSubtypeARM64E     = 2
+	cpuSubtypeARMV7      = 9
+	cpuSubtypeARMV7S     = 11
+
+	csMagicEmbeddedSignature = 0xfade0cc0
+	csMagicEntitlements      = 0xfade7171
+
+	// maxSignatureSize bounds how much of LC_CODE_SIGNATURE is read into memory
+	maxSignatureSize = 64 << 20
+)
+
+// machoSlice is one architecture of a thin or universal Mach-O along with
+// the offset of its header within the file
+type machoSlice struct {
+	*macho.File
+	offset int64
+}
+
+// machoSlices parses every architecture in r. Thin files yield a single slice at offset 0.
+func machoSlices(r io.ReaderAt) ([]machoSlice, error) {
+	fat, err := macho.NewFatFile(r)
+	if err == nil {
+		slices := make([]machoSlice, len(fat.Arches))
+		for i, arch := range fat.Arches {
+			slices[i] = machoSlice{File: arch.File, offset: int64(arch.Offset)}
+		}
+		return slices, nil
+	}
+	if !errors.Is(err, macho.ErrNotFat) {
+		return nil, err
+	}
+
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return []machoSlice{{File: f}}, nil
+}
+
+// codeSignatureBlob returns the payload of the blob with the given magic from
+// the slice's embedded code signature, or nil if the slice has none
+func codeSignatureBlob(r io.ReaderAt, s machoSlice, magic uint32) ([]byte, error) {
+	blobs, err := codeSignatureBlobs(r, s, magic)
+	if err != nil || len(blobs) == 0 {
+		return nil, err
+	}
+	return blobs[0], nil
+}
+
+// codeSignatureBlobs returns the payloads of every blob with the given magic,
+// in index order. Signatures may carry several CodeDirectories, one per hash type.
+func codeSignatureBlobs(r io.ReaderAt, s machoSlice, magic uint32) ([][]byte, error) {
+	for _, load := range s.Loads {
+		raw := load.Raw()
+		if len(raw) < 16 || s.ByteOrder.Uint32(raw) != lcCodeSignature {
+			continue
+		}
+		dataOff := s.ByteOrder.Uint32(raw[8:])
+		dataSize := s.ByteOrder.Uint32(raw[12:])
+		if dataSize > maxSignatureSize {
+			return nil, fmt.Errorf("code signature too large (%d bytes)", dataSize)
+		}
+
+		sig := make([]byte, dataSize)
+		if _, err := r.ReadAt(sig, s.offset+int64(dataOff)); err != nil {
+			return nil, fmt.Errorf("reading code signature: %v", err)
+		}
+		return superBlobEntries(sig, magic)
+	}
+	return nil, nil
+}
+
+// superBlobEntries finds the blobs with the given magic inside a code signature
+// SuperBlob. Code signature structures are always big-endian.
+func superBlobEntries(sig []byte, magic uint32) ([][]byte, error) {
+	be := binary.BigEndian
+	if len(sig) < 12 || be.Uint32(sig) != csMagicEmbeddedSignature {
+		return nil, errors.New("code signature is not an embedded signature SuperBlob")
+	}
+
+	var blobs [][]byte
+	count := be.Uint32(sig[8:])
+	for i := uint32(0); i < count; i++ {
+		idx := 12 + int(i)*8
+		if idx+8 > len(sig) {
+			break
+		}
+		off := int(be.Uint32(sig[idx+4:]))
+		if off < 0 || off+8 > len(sig) || be.Uint32(sig[off:]) != magic {
+			continue
+		}
+		length := int(be.Uint32(sig[off+4:]))
+		if length < 8 || off+length > len(sig) {
+			return nil, errors.New("code signature blob truncated")
+		}
+		blobs = append(blobs, sig[off+8:off+length])
+	}
+	return blobs, nil
+}
+
+// readEntitlements returns the entitlements plist embedded in the binary's
+// code signature. A binary without signed entitlements yields a nil map.
+func readEntitlements(binaryPath string) (map[string]interface{}, error) {
+	f, err := os.Open(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range slices {
+		blob, err := codeSignatureBlob(f, s, csMagicEntitlements)
+		if err != nil {
+			return nil, err
+		}
+		if blob == nil {
+			continue
+		}
+		value, err := parsePlist(blob)
+		if err != nil {
+			return nil, fmt.Errorf("parsing entitlements: %v", err)
+		}
+		dict, _ := value.(map[string]interface{})
+		return dict, nil
+	}
+	return nil, nil
+}
+
+// platformNames maps LC_BUILD_VERSION platform identifiers to names
+var platformNames = map[uint32]string{
+	1:  "macOS",
+	2:  "iOS",
+	3:  "tvOS",
+	4:  "watchOS",
+	5:  "bridgeOS",
+	6:  "Mac Catalyst",
+	7:  "iOS Simulator",
+	8:  "tvOS Simulator",
+	9:  "watchOS Simulator",
+	10: "DriverKit",
+	11: "visionOS",
+	12: "visionOS Simulator",
+}
+
+// sliceInfo describes one architecture slice of a Mach-O binary
+type sliceInfo struct {
+	Arch          string `json:"arch"`
+	Platform      string `json:"platform,omitempty"`
+	UUID          string `json:"uuid,omitempty"`
+	MinimumOS     string `json:"minimum_os,omitempty"`
+	SDK           string `json:"sdk,omitempty"`
+	SourceVersion string `json:"source_version,omitempty"`
+}
+
+// readSlices describes every architecture slice of the Mach-O at path
+func readSlices(path string) ([]sliceInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]sliceInfo, len(slices))
+	for i, s := range slices {
+		infos[i] = describeSlice(s)
+	}
+	return infos, nil
+}
+
+// describeSlice reads the platform, deployment target, SDK, UUID and source
+// version load commands of one slice, the fields crash logs and symbol
+// servers key on
+func describeSlice(s machoSlice) sliceInfo {
+	info := sliceInfo{
+		Arch:     archName(s.Cpu, s.SubCpu),
+		Platform: slicePlatform(s),
+		UUID:     sliceUUIDString(s),
+	}
+	for _, load := range s.Loads {
+		raw := load.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+		switch s.ByteOrder.Uint32(raw) {
+		case lcBuildVersion:
+			if len(raw) >= 20 {
+				info.MinimumOS = formatPackedVersion(s.ByteOrder.Uint32(raw[12:]))
+				info.SDK = formatPackedVersion(s.ByteOrder.Uint32(raw[16:]))
+			}
+		case lcVersionMinIPhoneOS, lcVersionMinMacOSX, lcVersionMinTVOS, lcVersionMinWatchOS:
+			if len(raw) >= 16 && info.MinimumOS == "" {
+				info.MinimumOS = formatPackedVersion(s.ByteOrder.Uint32(raw[8:]))
+				info.SDK = formatPackedVersion(s.ByteOrder.Uint32(raw[12:]))
+			}
+		case lcSourceVersion:
+			if len(raw) >= 16 {
+				info.SourceVersion = formatSourceVersion(s.ByteOrder.Uint64(raw[8:]))
+			}
+		}
+	}
+	return info
+}
+
+// sliceUUIDString formats the slice's LC_UUID the way dwarfdump and crash logs do
+func sliceUUIDString(s machoSlice) string {
+	for _, load := range s.Loads {
+		raw := load.Raw()
+		if len(raw) < 24 || s.ByteOrder.Uint32(raw) != lcUUID {
+			continue
+		}
+		u := raw[8:24]
+		return fmt.Sprintf("%X-%X-%X-%X-%X", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+	}
+	return ""
+}
+
+// formatSourceVersion renders an LC_SOURCE_VERSION packed as a24.b10.c10.d10.e10,
+// dropping trailing zero components. A zero version means none was recorded.
+func formatSourceVersion(v uint64) string {
+	if v == 0 {
+		return ""
+	}
+	parts := []uint64{v >> 40, (v >> 30) & 0x3ff, (v >> 20) & 0x3ff, (v >> 10) & 0x3ff, v & 0x3ff}
+	n := len(parts)
+	for n > 1 && parts[n-1] == 0 {
+		n--
+	}
+	out := fmt.Sprint(parts[0])
+	for _, p := range parts[1:n] {
+		out += fmt.Sprintf(".%d", p)
+	}
+	return out
+}
+
+// archName returns the conventional architecture name used by lipo and Xcode
+func archName(cpu macho.Cpu, subCpu uint32) string {
+	switch cpu {
+	case macho.CpuArm64:
+		if subCpu&cpuSubtypeMask == cpuSubtypeARM64E {
+			return "arm64e"
+		}
+		return "arm64"
+	case macho.CpuArm:
+		switch subCpu & cpuSubtypeMask {
+		case cpuSubtypeARMV7:
+			return "armv7"
+		case cpuSubtypeARMV7S:
+			return "armv7s"
+		}
+		return "arm"
+	case macho.CpuAmd64:
+		return "x86_64"
+	case macho.Cpu386:
+		return "i386"
+	}
+	return cpu.String()
+}
+
+// slicePlatform reads the target platform from LC_BUILD_VERSION, falling back
+// to the older LC_VERSION_MIN_* commands. It returns "" if neither is present.
+func slicePlatform(s machoSlice) string {
+	for _, load := range s.Loads {
+		raw := load.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+		switch s.ByteOrder.Uint32(raw) {
+		case lcBuildVersion:
+			if len(raw) < 12 {
+				continue
+			}
+			id := s.ByteOrder.Uint32(raw[8:])
+			if name, ok := platformNames[id]; ok {
+				return name
+			}
+			return fmt.Sprintf("platform %d", id)
+		case lcVersionMinIPhoneOS:
+			// Before LC_BUILD_VERSION, simulator builds were iOS builds for Intel
+			if s.Cpu == macho.CpuAmd64 || s.Cpu == macho.Cpu386 {
+				return "iOS Simulator"
+			}
+			return "iOS"
+		case lcVersionMinMacOSX:
+			return "macOS"
+		case lcVersionMinTVOS:
+			return "tvOS"
+		case lcVersionMinWatchOS:
+			return "watchOS"
+		}
+	}
+	return ""
+}
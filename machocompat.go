@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"debug/macho"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dylib load commands otool -L lists, and the note it adds after the versions
+var dylibCommands = map[uint32]string{
+	0xd:        "",           // LC_ID_DYLIB
+	0xc:        "",           // LC_LOAD_DYLIB
+	0x80000018: ", weak",     // LC_LOAD_WEAK_DYLIB
+	0x8000001f: ", reexport", // LC_REEXPORT_DYLIB
+	0x20:       ", lazy",     // LC_LAZY_LOAD_DYLIB
+	0x80000023: ", upward",   // LC_LOAD_UPWARD_DYLIB
+}
+
+// Symbol table type bits, see <mach-o/nlist.h>
+const (
+	nStab = 0xe0
+	nType = 0x0e
+	nExt  = 0x01
+	nUndf = 0x0
+	nAbs  = 0x2
+	nIndr = 0xa
+	nPbud = 0xc
+	nSect = 0xe
+)
+
+// machoCompatCommands print in the format of the Apple tool they are named
+// after, without the banner, so scripts can swap the tool for iOSDumper on
+// any platform
+var machoCompatCommands = []string{"otool", "nm", "codesign"}
+
+// runOtool implements `macho otool -L <binary>...`
+func runOtool(args []string) int {
+	fs := flag.NewFlagSet("macho otool", flag.ContinueOnError)
+	libs := fs.Bool("L", false, "Print the shared libraries used")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if !*libs || fs.NArg() == 0 {
+		activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", trError("ErrMachoCommand")))
+		return exitBadInput
+	}
+	return eachMachoSlice(fs.Args(), func(w io.Writer, path string, s machoSlice, fat bool) {
+		if fat {
+			fmt.Fprintf(w, "%s (architecture %s):\n", path, archName(s.Cpu, s.SubCpu))
+		} else {
+			fmt.Fprintf(w, "%s:\n", path)
+		}
+		for _, load := range s.Loads {
+			raw := load.Raw()
+			if len(raw) < 24 {
+				continue
+			}
+			note, ok := dylibCommands[s.ByteOrder.Uint32(raw)]
+			if !ok {
+				continue
+			}
+			name := raw[min(int(s.ByteOrder.Uint32(raw[8:])), len(raw)):]
+			if i := bytes.IndexByte(name, 0); i >= 0 {
+				name = name[:i]
+			}
+			fmt.Fprintf(w, "\t%s (compatibility version %s, current version %s%s)\n",
+				name, dylibVersion(s.ByteOrder.Uint32(raw[20:])), dylibVersion(s.ByteOrder.Uint32(raw[16:])), note)
+		}
+	})
+}
+
+// dylibVersion formats a packed xxxx.yy.zz dylib version as otool does
+func dylibVersion(v uint32) string {
+	return fmt.Sprintf("%d.%d.%d", v>>16, v>>8&0xff, v&0xff)
+}
+
+// runNm implements `macho nm [-g] <binary>...`: symbols sorted by name, with
+// nm's one-letter types
+func runNm(args []string) int {
+	fs := flag.NewFlagSet("macho nm", flag.ContinueOnError)
+	external := fs.Bool("g", false, "Only print external symbols")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() == 0 {
+		activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", trError("ErrMachoCommand")))
+		return exitBadInput
+	}
+	multiple := fs.NArg() > 1
+	return eachMachoSlice(fs.Args(), func(w io.Writer, path string, s machoSlice, fat bool) {
+		if fat {
+			fmt.Fprintf(w, "\n%s (for architecture %s):\n", path, archName(s.Cpu, s.SubCpu))
+		} else if multiple {
+			fmt.Fprintf(w, "\n%s:\n", path)
+		}
+		if s.Symtab == nil {
+			return
+		}
+		width := 8
+		if s.Magic == macho.Magic64 {
+			width = 16
+		}
+		syms := make([]macho.Symbol, 0, len(s.Symtab.Syms))
+		for _, sym := range s.Symtab.Syms {
+			if sym.Type&nStab == 0 && (!*external || sym.Type&nExt != 0) {
+				syms = append(syms, sym)
+			}
+		}
+		sort.SliceStable(syms, func(i, j int) bool { return syms[i].Name < syms[j].Name })
+		for _, sym := range syms {
+			typ := nmType(s, sym)
+			if typ == 'U' || typ == 'u' {
+				fmt.Fprintf(w, "%*s %c %s\n", width, "", typ, sym.Name)
+			} else {
+				fmt.Fprintf(w, "%0*x %c %s\n", width, sym.Value, typ, sym.Name)
+			}
+		}
+	})
+}
+
+// nmType returns nm's letter for a symbol, upper-cased when it is external
+func nmType(s machoSlice, sym macho.Symbol) byte {
+	typ := byte('?')
+	switch sym.Type & nType {
+	case nUndf:
+		typ = 'u'
+		if sym.Value != 0 {
+			typ = 'c'
+		}
+	case nPbud:
+		typ = 'u'
+	case nAbs:
+		typ = 'a'
+	case nIndr:
+		typ = 'i'
+	case nSect:
+		typ = 's'
+		if i := int(sym.Sect) - 1; i >= 0 && i < len(s.Sections) {
+			switch sect := s.Sections[i]; sect.Seg + "," + sect.Name {
+			case "__TEXT,__text":
+				typ = 't'
+			case "__DATA,__data":
+				typ = 'd'
+			case "__DATA,__bss":
+				typ = 'b'
+			}
+		}
+	}
+	if sym.Type&nExt != 0 {
+		typ -= 'a' - 'A'
+	}
+	return typ
+}
+
+// runCodesign implements `macho codesign -d --entitlements - <binary>`,
+// writing the entitlements plist as signed, like codesign's :- form. Like
+// codesign it names the executable on stderr and writes nothing for a
+// binary without entitlements.
+func runCodesign(args []string) int {
+	fs := flag.NewFlagSet("macho codesign", flag.ContinueOnError)
+	display := fs.Bool("d", false, "Display information about the code")
+	target := fs.String("entitlements", "", "Write the entitlements to this file, or - for stdout")
+	fs.Bool("xml", false, "Accepted for compatibility; entitlements are always XML")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if !*display || *target == "" || fs.NArg() != 1 {
+		activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", trError("ErrMachoCommand")))
+		return exitBadInput
+	}
+	path := fs.Arg(0)
+
+	f, err := os.Open(path)
+	if err != nil {
+		activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", err))
+		return exitBadInput
+	}
+	defer f.Close()
+	slices, err := machoSlices(f)
+	if err != nil {
+		activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", fmt.Errorf("%s: %w", path, err)))
+		return exitBadInput
+	}
+	var blob []byte
+	for _, s := range slices {
+		if blob, err = codeSignatureBlob(f, s, csMagicEntitlements); err != nil || blob != nil {
+			break
+		}
+	}
+	if err != nil {
+		activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", fmt.Errorf("%s: %w", path, err)))
+		return exitBadInput
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	fmt.Fprintf(os.Stderr, "Executable=%s\n", path)
+	if blob == nil {
+		return exitClean
+	}
+	if out := strings.TrimPrefix(*target, ":"); out != "-" {
+		if err := os.WriteFile(out, blob, 0644); err != nil {
+			activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", err))
+			return exitToolFailure
+		}
+		return exitClean
+	}
+	os.Stdout.Write(blob)
+	return exitClean
+}
+
+// eachMachoSlice calls print for every slice of every binary, writing to
+// stdout. An unreadable binary is reported on stderr and the rest are still
+// printed, as the Apple tools do.
+func eachMachoSlice(paths []string, print func(w io.Writer, path string, s machoSlice, fat bool)) int {
+	code := exitClean
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", err))
+			code = exitBadInput
+			continue
+		}
+		slices, err := machoSlices(f)
+		if err != nil {
+			f.Close()
+			activeTheme.failure.Fprintln(os.Stderr, tr("ErrGeneric", "Err", fmt.Errorf("%s: %w", path, err)))
+			code = exitBadInput
+			continue
+		}
+		// Slices of a universal binary start past the fat header
+		for _, s := range slices {
+			print(os.Stdout, path, s, s.offset != 0)
+		}
+		f.Close()
+	}
+	return code
+}
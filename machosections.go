@@ -0,0 +1,205 @@
+package main
+
+import (
+	"debug/macho"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Section types whose contents are not stored in the file
+const (
+	sectionTypeMask            = 0xff
+	sectionZerofill            = 0x1
+	sectionGBZerofill          = 0xc
+	sectionThreadLocalZerofill = 0x12
+)
+
+// runMacho implements the `iosdumper macho` subcommands, which inspect a
+// single Mach-O binary. It returns the process exit code.
+func runMacho(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "sections":
+			return runMachoSections(args[1:])
+		case "otool":
+			return runOtool(args[1:])
+		case "nm":
+			return runNm(args[1:])
+		case "codesign":
+			return runCodesign(args[1:])
+		}
+	}
+	activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrMachoCommand")))
+	return exitBadInput
+}
+
+// runMachoSections implements `macho sections [--arch a] [--hex seg,sect] <binary>`:
+// it lists every segment and section of each slice and optionally hex dumps
+// one section, for triage without otool or radare2
+func runMachoSections(args []string) int {
+	fs := flag.NewFlagSet("macho sections", flag.ContinueOnError)
+	arch := fs.String("arch", "", "Only show this architecture, e.g. arm64")
+	hexSection := fs.String("hex", "", "Hex dump this section, as __TEXT,__cstring or __cstring")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() != 1 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrMachoCommand")))
+		return exitBadInput
+	}
+	path := fs.Arg(0)
+
+	f, err := os.Open(path)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitBadInput
+	}
+	defer f.Close()
+	slices, err := machoSlices(f)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", fmt.Errorf("%s: %w", path, err)))
+		return exitBadInput
+	}
+
+	shown := 0
+	for _, s := range slices {
+		name := archName(s.Cpu, s.SubCpu)
+		if *arch != "" && name != *arch {
+			continue
+		}
+		shown++
+		printTable(stdout, tr("TableSections", "Binary", filepath.Base(path), "Arch", name),
+			[]string{tr("ColSegment"), tr("ColSection"), tr("ColAddress"), tr("ColOffset"), tr("ColSize"), tr("ColProtection")},
+			sectionRows(s))
+		if *hexSection == "" {
+			continue
+		}
+		sect := findSection(s, *hexSection)
+		if sect == nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrSectionNotFound", "Section", *hexSection, "Arch", name)))
+			return exitBadInput
+		}
+		if isZerofill(sect) {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrSectionZerofill", "Section", *hexSection)))
+			return exitBadInput
+		}
+		activeTheme.title.Fprintln(stdout, tr("SectionHexDump", "Section", sect.Seg+","+sect.Name, "Arch", name))
+		if err := hexDump(stdout, sect.Open(), sect.Addr); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+			return exitToolFailure
+		}
+		fmt.Fprintln(stdout)
+	}
+	if shown == 0 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrNoSlice", "Arch", *arch, "Path", path)))
+		return exitBadInput
+	}
+	return exitClean
+}
+
+// sectionRows lists each segment followed by its sections. Offsets are from
+// the start of the file, so they stay valid within universal binaries.
+func sectionRows(s machoSlice) [][]string {
+	var rows [][]string
+	for _, load := range s.Loads {
+		seg, ok := load.(*macho.Segment)
+		if !ok {
+			continue
+		}
+		rows = append(rows, []string{
+			seg.Name, "",
+			fmt.Sprintf("0x%x", seg.Addr),
+			fmt.Sprintf("0x%x", uint64(s.offset)+seg.Offset),
+			formatBytes(int64(seg.Memsz)),
+			protectionString(seg.Prot) + "/" + protectionString(seg.Maxprot),
+		})
+		for _, sect := range s.Sections {
+			if sect.Seg != seg.Name {
+				continue
+			}
+			offset := "-"
+			if !isZerofill(sect) {
+				offset = fmt.Sprintf("0x%x", s.offset+int64(sect.Offset))
+			}
+			rows = append(rows, []string{"", sect.Name, fmt.Sprintf("0x%x", sect.Addr), offset, formatBytes(int64(sect.Size)), ""})
+		}
+	}
+	return rows
+}
+
+// findSection looks a section up as segment,section or by section name alone
+func findSection(s machoSlice, name string) *macho.Section {
+	seg, sect, qualified := strings.Cut(name, ",")
+	if !qualified {
+		return s.Section(name)
+	}
+	for _, candidate := range s.Sections {
+		if candidate.Seg == seg && candidate.Name == sect {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// isZerofill reports whether a section only reserves memory, like __bss
+func isZerofill(sect *macho.Section) bool {
+	switch sect.Flags & sectionTypeMask {
+	case sectionZerofill, sectionGBZerofill, sectionThreadLocalZerofill:
+		return true
+	}
+	return false
+}
+
+// protectionString renders VM protection bits as rwx
+func protectionString(prot uint32) string {
+	out := []byte("---")
+	for i, c := range "rwx" {
+		if prot&(1<<i) != 0 {
+			out[i] = byte(c)
+		}
+	}
+	return string(out)
+}
+
+// hexDump writes r in the layout of hexdump -C, numbering lines by virtual
+// address from addr
+func hexDump(w io.Writer, r io.Reader, addr uint64) error {
+	buf := make([]byte, 16)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			var line strings.Builder
+			fmt.Fprintf(&line, "%016x  ", addr)
+			for i := 0; i < len(buf); i++ {
+				if i < n {
+					fmt.Fprintf(&line, "%02x ", buf[i])
+				} else {
+					line.WriteString("   ")
+				}
+				if i == 7 {
+					line.WriteByte(' ')
+				}
+			}
+			line.WriteString(" |")
+			for _, b := range buf[:n] {
+				if b < 0x20 || b > 0x7e {
+					b = '.'
+				}
+				line.WriteByte(b)
+			}
+			line.WriteString("|")
+			fmt.Fprintln(w, line.String())
+			addr += uint64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
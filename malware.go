@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxSchemeQueries is how many LSApplicationQueriesSchemes an app needs
+// before the list looks like fingerprinting the other apps installed
+const maxSchemeQueries = 50
+
+var (
+	// schemeAbusePattern matches sms:, tel: and FaceTime links with a hardcoded number
+	schemeAbusePattern = regexp.MustCompile(`(?i)\b(?:sms|tel|telprompt|facetime(?:-audio)?):(?://)?\+?[0-9*#][0-9*#\-]{2,}`)
+)
+
+// hiddenCodeDirs are bundle directories where nested code belongs
+var hiddenCodeDirs = []string{"Frameworks/", "PlugIns/", "Extensions/", "Watch/", "AppClips/", "SystemExtensions/"}
+
+// machOMagics are the first four bytes of thin and fat Mach-O files, read big-endian
+var machOMagics = []uint32{0xfeedface, 0xfeedfacf, 0xcefaedfe, 0xcffaedfe, 0xcafebabe, 0xcafebabf}
+
+// malwareReport collects the indicators --malware looks for
+type malwareReport struct {
+	ProfileInstall    []string `json:"profile_install,omitempty"`
+	SchemeAbuse       []string `json:"scheme_abuse,omitempty"`
+	HiddenExecutables []string `json:"hidden_executables,omitempty"`
+}
+
+// malwareAnalyzers run over the binary in addition to stringAnalyzers when
+// --malware is set
+var malwareAnalyzers = []func() stringAnalyzer{
+	newMalwareAnalyzer,
+}
+
+// malwareAnalyzer looks for behavior App Review would reject but sideloaded
+// and enterprise-signed apps get away with. It runs after the OTA analyzer
+// and reuses its matches.
+type malwareAnalyzer struct {
+	schemes map[string]bool
+}
+
+func newMalwareAnalyzer() stringAnalyzer {
+	return &malwareAnalyzer{
+		schemes: make(map[string]bool),
+	}
+}
+
+func (a *malwareAnalyzer) visit(s string, offset int64) {
+	if m := schemeAbusePattern.FindString(s); m != "" {
+		addSample(a.schemes, m)
+	}
+}
+
+func (a *malwareAnalyzer) findings(r *appReport) []finding {
+	m := &malwareReport{
+		ProfileInstall: r.OTAIndicators,
+		SchemeAbuse:    sortedSet(a.schemes),
+	}
+	if queries := plistStrings(r.InfoPlist, "LSApplicationQueriesSchemes"); len(queries) > maxSchemeQueries {
+		m.SchemeAbuse = append(m.SchemeAbuse, fmt.Sprintf("LSApplicationQueriesSchemes lists %d schemes", len(queries)))
+	}
+	r.Malware = m
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	add := func(rule, severity, title string, evidence []string, remediation string) {
+		if len(evidence) == 0 {
+			return
+		}
+		findings = append(findings, finding{
+			Rule:        rule,
+			Severity:    severity,
+			Title:       title,
+			Evidence:    sampleList(evidence),
+			Location:    location,
+			Remediation: remediation,
+		})
+	}
+	add("malware-profile-install", severityHigh, "Configuration profile or enterprise app installation strings", m.ProfileInstall,
+		"Find out what profile or app the binary installs; a profile can add root certificates, VPNs or MDM enrollment that give a third party control of the device.")
+	add("malware-scheme-abuse", severityMedium, "Hardcoded SMS, call or FaceTime targets, or scheme sniffing", m.SchemeAbuse,
+		"Check whether the app contacts premium numbers or probes installed apps; neither is needed to link to another app.")
+	return findings
+}
+
+// checkHiddenExecutables flags Mach-O files outside the directories nested
+// code belongs in, such as a binary named like an image, when --malware is set
+func checkHiddenExecutables(r *appReport) []finding {
+	if !analysisOpts.malware {
+		return nil
+	}
+	var hidden []string
+	filepath.Walk(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || path == r.BinaryPath || info.Size() < 4 {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if hasAnyPrefix(rel, hiddenCodeDirs) || strings.HasSuffix(rel, ".dylib") {
+			return nil
+		}
+		if isMachOFile(path) {
+			hidden = append(hidden, rel)
+		}
+		return nil
+	})
+	if len(hidden) == 0 {
+		return nil
+	}
+	sort.Strings(hidden)
+	if r.Malware == nil {
+		r.Malware = &malwareReport{}
+	}
+	r.Malware.HiddenExecutables = hidden
+	return []finding{{
+		Rule:        "malware-hidden-executable",
+		Severity:    severityHigh,
+		Title:       "Executable hidden among bundle resources",
+		Evidence:    sampleList(hidden),
+		Location:    r.Name,
+		Remediation: "Nested code belongs in Frameworks or PlugIns; a Mach-O among resources is usually a payload meant to be copied out and loaded later.",
+	}}
+}
+
+// isMachOFile reports whether the file at path starts with a Mach-O magic number
+func isMachOFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var magic [4]byte
+	if _, err := f.Read(magic[:]); err != nil {
+		return false
+	}
+	v := binary.BigEndian.Uint32(magic[:])
+	for _, m := range machOMagics {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
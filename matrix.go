@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// appMatrix compares several apps side by side, one row per attribute
+type appMatrix struct {
+	Apps []string    `json:"apps"`
+	Rows []matrixRow `json:"rows"`
+}
+
+// matrixRow is one attribute with a value per app, in the order of appMatrix.Apps
+type matrixRow struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values"`
+}
+
+// buildAppMatrix compares capabilities, SDKs, permissions, ATS posture,
+// hardening flags and finding counts across apps from a batch scan
+func buildAppMatrix(apps []*appReport) appMatrix {
+	m := appMatrix{Apps: make([]string, len(apps))}
+	for i, r := range apps {
+		m.Apps[i] = r.Name
+		if v := versionString(r.Metadata); v != "" {
+			m.Apps[i] += " " + v
+		}
+	}
+
+	row := func(attribute string, value func(r *appReport) string) {
+		values := make([]string, len(apps))
+		for i, r := range apps {
+			values[i] = value(r)
+		}
+		m.Rows = append(m.Rows, matrixRow{Attribute: attribute, Values: values})
+	}
+	mark := func(on bool) string {
+		if on {
+			return "✓"
+		}
+		return "-"
+	}
+
+	row(tr("MetaBundleID"), func(r *appReport) string { return r.Metadata.BundleID })
+	row(tr("MetaMinimumOS"), func(r *appReport) string { return r.Metadata.MinimumOS })
+	row(tr("MetaSDK"), func(r *appReport) string { return r.Metadata.SDK })
+	row(tr("MetaXcode"), func(r *appReport) string { return r.Metadata.Xcode })
+	row(tr("MetaArchitectures"), func(r *appReport) string { return sliceSummary(r.Slices) })
+	row(tr("MetaSignature"), func(r *appReport) string { return r.Signature.String() })
+	row(tr("MetaATS"), func(r *appReport) string { return r.ATS.String() })
+	row(tr("MatrixFrameworks"), func(r *appReport) string { return fmt.Sprint(len(r.Frameworks)) })
+
+	for _, p := range []struct {
+		name string
+		on   func(m memoryReport) bool
+	}{
+		{"PIE", func(m memoryReport) bool { return m.PIE }},
+		{"Stack canary", func(m memoryReport) bool { return m.StackCanary }},
+		{"ARC", func(m memoryReport) bool { return m.ARC }},
+		{"PAC", func(m memoryReport) bool { return m.PointerAuth }},
+	} {
+		p := p
+		row(p.name, func(r *appReport) string { return mark(p.on(r.Memory)) })
+	}
+
+	for _, rule := range capabilityRules {
+		name := rule.Name
+		row(name, func(r *appReport) string {
+			for _, c := range r.Capabilities {
+				if c.Name == name {
+					return mark(c.Enabled)
+				}
+			}
+			return mark(false)
+		})
+	}
+
+	permissions := make(map[string]bool)
+	for _, r := range apps {
+		for _, p := range r.Permissions {
+			permissions[p] = true
+		}
+	}
+	for _, p := range sortedSet(permissions) {
+		p := p
+		row(p, func(r *appReport) string { return mark(containsString(r.Permissions, p)) })
+	}
+
+	for _, severity := range severityOrder {
+		severity := severity
+		row(severity, func(r *appReport) string {
+			n := 0
+			for _, f := range r.Findings {
+				if f.Severity == severity {
+					n++
+				}
+			}
+			return fmt.Sprint(n)
+		})
+	}
+	return m
+}
+
+// printAppMatrix writes the comparison matrix as a table with one column per app
+func printAppMatrix(w io.Writer, m appMatrix) {
+	headers := append([]string{tr("ColAttribute")}, m.Apps...)
+	rows := make([][]string, len(m.Rows))
+	for i, r := range m.Rows {
+		rows[i] = append([]string{r.Attribute}, r.Values...)
+	}
+	printTable(w, tr("TableMatrix", "Count", len(m.Apps)), headers, rows)
+}
+
+// matrixApps flattens the apps of every scan in a batch
+func matrixApps(results []*scanResult) []*appReport {
+	var apps []*appReport
+	for _, r := range results {
+		apps = append(apps, r.Apps...)
+	}
+	return apps
+}
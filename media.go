@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// maxMediaScanSize skips media files too large to read whole
+	maxMediaScanSize = 16 << 20
+	// maxMediaFiles bounds how many files with metadata are reported
+	maxMediaFiles = 200
+)
+
+// Kinds of media metadata fields
+const (
+	mediaGPS    = "gps"    // location the picture was taken
+	mediaPerson = "person" // author, artist or camera owner, or a path naming a user
+	mediaTool   = "tool"   // authoring software, camera model or font vendor
+)
+
+var (
+	// xmpFieldPattern matches XMP properties worth reporting, as an element or attribute
+	xmpFieldPattern = regexp.MustCompile(`(xmp:CreatorTool|dc:creator|photoshop:AuthorsPosition|exif:GPSLatitude|pdf:Producer)(?:="([^"]*)"|>\s*(?:<rdf:Seq>\s*<rdf:li[^>]*>)?([^<]*))`)
+	// pdfInfoPattern matches literal strings of a PDF document information dictionary
+	pdfInfoPattern = regexp.MustCompile(`/(Author|Creator|Producer)\s*\(((?:\\.|[^\\)])*)\)`)
+	// pdfStringReplacer undoes the escapes of a PDF literal string
+	pdfStringReplacer = strings.NewReplacer(`\(`, "(", `\)`, ")", `\\`, `\`)
+	// homePathPattern matches paths into a user's home directory
+	homePathPattern = regexp.MustCompile(`(?:/Users/|/home/|[A-Z]:\\Users\\)[^/\\\s]+`)
+)
+
+// mediaField is one metadata property of a bundled media file
+type mediaField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Kind  string `json:"kind"` // gps, person or tool
+}
+
+// mediaMetadata is the metadata of one bundled image, PDF or font
+type mediaMetadata struct {
+	File   string       `json:"file"`
+	Fields []mediaField `json:"fields"`
+}
+
+// exifTags are the TIFF and Exif tags reported, with their kind
+var exifTags = map[uint16]mediaField{
+	0x010F: {Name: "Make", Kind: mediaTool},
+	0x0110: {Name: "Model", Kind: mediaTool},
+	0x0131: {Name: "Software", Kind: mediaTool},
+	0x013B: {Name: "Artist", Kind: mediaPerson},
+	0xA430: {Name: "CameraOwnerName", Kind: mediaPerson},
+	0xA431: {Name: "BodySerialNumber", Kind: mediaTool},
+}
+
+// pngTextKinds are the kinds of reported PNG text chunk keywords
+var pngTextKinds = map[string]string{
+	"Author": mediaPerson, "Software": mediaTool, "Source": mediaTool, "Comment": mediaTool,
+}
+
+// fontNameIDs are the reported records of a font's name table. Font
+// designers are credited publicly, so they are not treated as personal data.
+var fontNameIDs = map[uint16]mediaField{
+	8: {Name: "Manufacturer", Kind: mediaTool},
+	9: {Name: "Designer", Kind: mediaTool},
+}
+
+// analyzeMediaMetadata reads the metadata of bundled JPEG and PNG images,
+// PDFs and fonts
+func analyzeMediaMetadata(r *appReport) error {
+	return walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() > maxMediaScanSize || len(r.MediaMetadata) >= maxMediaFiles {
+			return nil
+		}
+		var read func([]byte) []mediaField
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jpg", ".jpeg":
+			read = jpegMetadata
+		case ".png":
+			read = pngMetadata
+		case ".pdf":
+			read = pdfMetadata
+		case ".ttf", ".otf":
+			read = fontMetadata
+		default:
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fields := read(data)
+		for i := range fields {
+			fields[i].Value = strings.TrimSpace(snippet(fields[i].Value, 0, 120))
+			if fields[i].Kind == mediaTool && homePathPattern.MatchString(fields[i].Value) {
+				fields[i].Kind = mediaPerson
+			}
+		}
+		if len(fields) > 0 {
+			r.MediaMetadata = append(r.MediaMetadata, mediaMetadata{File: filepath.ToSlash(rel), Fields: fields})
+		}
+		return nil
+	})
+}
+
+// jpegMetadata reads the Exif and XMP segments of a JPEG
+func jpegMetadata(data []byte) []mediaField {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil
+	}
+	var fields []mediaField
+	for p := 2; p+4 <= len(data) && data[p] == 0xFF; {
+		marker := data[p+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[p+2:]))
+		if n < 2 || p+2+n > len(data) {
+			break
+		}
+		segment := data[p+4 : p+2+n]
+		if marker == 0xE1 {
+			switch {
+			case bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+				fields = append(fields, tiffMetadata(segment[6:])...)
+			case bytes.HasPrefix(segment, []byte("http://ns.adobe.com/xap/1.0/\x00")):
+				fields = append(fields, xmpMetadata(string(segment))...)
+			}
+		}
+		p += 2 + n
+	}
+	return fields
+}
+
+// tiffMetadata reads the reported tags of a TIFF structure's first IFD and
+// its Exif sub-IFD, and whether its GPS IFD holds a latitude
+func tiffMetadata(data []byte) []mediaField {
+	if len(data) < 8 {
+		return nil
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+	var fields []mediaField
+	var readIFD func(offset uint32, depth int)
+	readIFD = func(offset uint32, depth int) {
+		if depth > 2 || int64(offset)+2 > int64(len(data)) {
+			return
+		}
+		count := int(order.Uint16(data[offset:]))
+		for i := 0; i < count; i++ {
+			e := int(offset) + 2 + 12*i
+			if e+12 > len(data) {
+				return
+			}
+			tag, typ, n := order.Uint16(data[e:]), order.Uint16(data[e+2:]), order.Uint32(data[e+4:])
+			value := order.Uint32(data[e+8:])
+			switch {
+			case tag == 0x8769: // Exif IFD
+				readIFD(value, depth+1)
+			case tag == 0x8825: // GPS IFD
+				if gpsHasLatitude(data, order, value) {
+					fields = append(fields, mediaField{Name: "GPSLatitude", Value: "present", Kind: mediaGPS})
+				}
+			case typ == 2 && exifTags[tag].Name != "": // ASCII
+				var s []byte
+				if n <= 4 {
+					s = data[e+8 : e+8+int(n)]
+				} else if int64(value)+int64(n) <= int64(len(data)) {
+					s = data[value : value+n]
+				}
+				if v := strings.TrimRight(string(s), "\x00 "); v != "" {
+					f := exifTags[tag]
+					f.Value = v
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+	readIFD(order.Uint32(data[4:]), 0)
+	return fields
+}
+
+// gpsHasLatitude reports whether the GPS IFD at offset has a GPSLatitude entry
+func gpsHasLatitude(data []byte, order binary.ByteOrder, offset uint32) bool {
+	if int64(offset)+2 > int64(len(data)) {
+		return false
+	}
+	count := int(order.Uint16(data[offset:]))
+	for i := 0; i < count; i++ {
+		e := int(offset) + 2 + 12*i
+		if e+12 > len(data) {
+			return false
+		}
+		if order.Uint16(data[e:]) == 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// xmpMetadata extracts the reported properties of an XMP packet
+func xmpMetadata(text string) []mediaField {
+	var fields []mediaField
+	for _, m := range xmpFieldPattern.FindAllStringSubmatch(text, -1) {
+		value := strings.TrimSpace(m[2] + m[3])
+		if value == "" {
+			continue
+		}
+		kind := mediaTool
+		switch m[1] {
+		case "dc:creator", "photoshop:AuthorsPosition":
+			kind = mediaPerson
+		case "exif:GPSLatitude":
+			kind = mediaGPS
+		}
+		fields = append(fields, mediaField{Name: m[1], Value: value, Kind: kind})
+	}
+	return fields
+}
+
+// pngMetadata reads a PNG's text, compressed text, international text and eXIf chunks
+func pngMetadata(data []byte) []mediaField {
+	if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return nil
+	}
+	var fields []mediaField
+	for p := 8; p+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		typ := string(data[p+4 : p+8])
+		if n < 0 || p+12+n > len(data) {
+			break
+		}
+		chunk := data[p+8 : p+8+n]
+		p += 12 + n
+
+		if typ == "eXIf" {
+			fields = append(fields, tiffMetadata(chunk)...)
+			continue
+		}
+		if typ != "tEXt" && typ != "zTXt" && typ != "iTXt" {
+			continue
+		}
+		keyword, rest, ok := bytes.Cut(chunk, []byte{0})
+		if !ok {
+			continue
+		}
+		var text []byte
+		switch typ {
+		case "tEXt":
+			text = rest
+		case "zTXt":
+			if len(rest) > 0 {
+				text = inflate(rest[1:])
+			}
+		case "iTXt":
+			if len(rest) < 2 {
+				continue
+			}
+			compressed := rest[0] == 1
+			// skip the language tag and translated keyword
+			_, rest, _ = bytes.Cut(rest[2:], []byte{0})
+			_, text, _ = bytes.Cut(rest, []byte{0})
+			if compressed {
+				text = inflate(text)
+			}
+		}
+		if string(keyword) == "XML:com.adobe.xmp" {
+			fields = append(fields, xmpMetadata(string(text))...)
+		} else if kind, ok := pngTextKinds[string(keyword)]; ok && len(text) > 0 {
+			fields = append(fields, mediaField{Name: string(keyword), Value: string(text), Kind: kind})
+		}
+	}
+	return fields
+}
+
+// inflate decompresses zlib data, returning at most maxMediaScanSize bytes
+// and nil when the data is corrupt
+func inflate(data []byte) []byte {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	out, _ := io.ReadAll(io.LimitReader(zr, maxMediaScanSize))
+	return out
+}
+
+// pdfMetadata reads the literal strings of a PDF's document information
+// dictionary and an uncompressed XMP packet
+func pdfMetadata(data []byte) []mediaField {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil
+	}
+	var fields []mediaField
+	seen := make(map[string]bool)
+	for _, m := range pdfInfoPattern.FindAllSubmatch(data, -1) {
+		name, value := string(m[1]), strings.TrimSpace(pdfStringReplacer.Replace(string(m[2])))
+		if value == "" || seen[name+value] {
+			continue
+		}
+		seen[name+value] = true
+		kind := mediaTool
+		if name == "Author" {
+			kind = mediaPerson
+		}
+		fields = append(fields, mediaField{Name: name, Value: value, Kind: kind})
+	}
+	if start := bytes.Index(data, []byte("<x:xmpmeta")); start >= 0 {
+		if end := bytes.Index(data[start:], []byte("</x:xmpmeta>")); end > 0 {
+			fields = append(fields, xmpMetadata(string(data[start:start+end]))...)
+		}
+	}
+	return fields
+}
+
+// fontMetadata reads the manufacturer and designer records of a TrueType or
+// OpenType font's name table
+func fontMetadata(data []byte) []mediaField {
+	if len(data) < 12 {
+		return nil
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil
+		}
+		if string(data[rec:rec+4]) != "name" {
+			continue
+		}
+		offset, length := binary.BigEndian.Uint32(data[rec+8:]), binary.BigEndian.Uint32(data[rec+12:])
+		if int64(offset)+int64(length) > int64(len(data)) || length < 6 {
+			return nil
+		}
+		return fontNames(data[offset : offset+length])
+	}
+	return nil
+}
+
+// fontNames decodes the reported English records of a name table, preferring
+// the Windows (UTF-16) records and falling back to Macintosh Roman ones
+func fontNames(table []byte) []mediaField {
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	found := make(map[uint16]string)
+	for i := 0; i < count; i++ {
+		rec := 6 + 12*i
+		if rec+12 > len(table) {
+			break
+		}
+		platform, nameID := binary.BigEndian.Uint16(table[rec:]), binary.BigEndian.Uint16(table[rec+6:])
+		length, offset := int(binary.BigEndian.Uint16(table[rec+8:])), int(binary.BigEndian.Uint16(table[rec+10:]))
+		if fontNameIDs[nameID].Name == "" || storage+offset+length > len(table) {
+			continue
+		}
+		raw := table[storage+offset : storage+offset+length]
+		switch platform {
+		case 3: // Windows, UTF-16BE
+			units := make([]uint16, len(raw)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(raw[2*j:])
+			}
+			found[nameID] = string(utf16.Decode(units))
+		case 1: // Macintosh
+			if _, ok := found[nameID]; !ok {
+				found[nameID] = string(raw)
+			}
+		}
+	}
+	var fields []mediaField
+	for _, id := range []uint16{8, 9} {
+		if v := strings.TrimSpace(found[id]); v != "" {
+			f := fontNameIDs[id]
+			f.Value = v
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// checkMediaMetadata flags location data and personal names left in bundled
+// media, and lists the authoring tools they name
+func checkMediaMetadata(r *appReport) []finding {
+	var people, tools []string
+	gps := make(map[string]bool)
+	for _, m := range r.MediaMetadata {
+		for _, f := range m.Fields {
+			entry := fmt.Sprintf("%s: %s = %s", m.File, f.Name, f.Value)
+			switch f.Kind {
+			case mediaGPS:
+				gps[m.File] = true
+			case mediaPerson:
+				people = append(people, entry)
+			default:
+				tools = append(tools, entry)
+			}
+		}
+	}
+	var findings []finding
+	if files := sortedSet(gps); len(files) > 0 {
+		findings = append(findings, finding{
+			Rule:        "media-gps",
+			Severity:    severityMedium,
+			Title:       "Bundled images carry GPS coordinates",
+			Evidence:    sampleList(files),
+			Location:    files[0],
+			Remediation: "Strip Exif and XMP metadata from assets before adding them to the project; GPS tags reveal where the pictures were taken.",
+		})
+	}
+	if len(people) > 0 {
+		findings = append(findings, finding{
+			Rule:        "media-author",
+			Severity:    severityLow,
+			Title:       "Bundled media names authors or user accounts",
+			Evidence:    sampleList(people),
+			Location:    strings.SplitN(people[0], ":", 2)[0],
+			Remediation: "Strip author, artist and owner metadata from assets, for example with exiftool -all= or an asset pipeline step.",
+		})
+	}
+	if len(tools) > 0 {
+		sort.Strings(tools)
+		findings = append(findings, finding{
+			Rule:        "media-metadata",
+			Severity:    severityInfo,
+			Title:       "Bundled media name the tools and devices that produced them",
+			Evidence:    sampleList(tools),
+			Location:    strings.SplitN(tools[0], ":", 2)[0],
+			Remediation: "Strip authoring metadata from assets if the tool names, camera models or serial numbers are not meant to be public.",
+		})
+	}
+	return findings
+}
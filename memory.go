@@ -0,0 +1,160 @@
+package main
+
+import (
+	"debug/macho"
+	"os"
+	"strings"
+)
+
+// Mach-O header flags relevant to memory protections
+const (
+	mhAllowStackExecution = 0x20000
+	mhPIE                 = 0x200000
+	mhNoHeapExecution     = 0x1000000
+)
+
+// mallocDebugVariables are libmalloc environment toggles that weaken or
+// instrument the heap when set by the app itself
+var mallocDebugVariables = []string{
+	"MallocStackLogging",
+	"MallocStackLoggingNoCompact",
+	"MallocScribble",
+	"MallocPreScribble",
+	"MallocGuardEdges",
+	"MallocCheckHeapStart",
+	"MallocCheckHeapEach",
+	"MallocErrorAbort",
+	"MallocNanoZone",
+}
+
+// insecureMemoryAPIs are libc functions without bounds checking, keyed by
+// imported symbol name, with the safer replacement
+var insecureMemoryAPIs = map[string]string{
+	"_strcpy":   "strlcpy",
+	"_strcat":   "strlcat",
+	"_sprintf":  "snprintf",
+	"_vsprintf": "vsnprintf",
+	"_gets":     "fgets",
+	"_memcpy":   "memcpy with a checked length, or memcpy_s",
+	"_strncpy":  "strlcpy",
+	"_alloca":   "malloc",
+	"_scanf":    "a bounded field width",
+	"_sscanf":   "a bounded field width",
+	"_strtok":   "strtok_r",
+	"_realpath": "realpath with a PATH_MAX buffer",
+}
+
+// memoryReport describes the binary's memory protections and allocator hardening
+type memoryReport struct {
+	PIE                 bool     `json:"pie"`
+	StackCanary         bool     `json:"stack_canary"`
+	ARC                 bool     `json:"arc"`
+	PointerAuth         bool     `json:"pointer_authentication"`
+	NoHeapExecution     bool     `json:"no_heap_execution"`
+	AllowStackExecution bool     `json:"allow_stack_execution"`
+	MallocDebug         []string `json:"malloc_debug,omitempty"`
+	InsecureAPIs        []string `json:"insecure_apis,omitempty"`
+}
+
+// memoryAnalyzer records libmalloc debug toggles named in the binary and,
+// once the scan is over, combines them with header flags and imported
+// symbols into the memory protections report
+type memoryAnalyzer struct {
+	toggles map[string]bool
+}
+
+func newMemoryAnalyzer() stringAnalyzer {
+	return &memoryAnalyzer{toggles: make(map[string]bool)}
+}
+
+func (a *memoryAnalyzer) visit(s string, offset int64) {
+	if !strings.Contains(s, "Malloc") {
+		return
+	}
+	for _, name := range mallocDebugVariables {
+		if s == name || strings.HasPrefix(s, name+"=") {
+			a.toggles[name] = true
+		}
+	}
+}
+
+func (a *memoryAnalyzer) findings(r *appReport) []finding {
+	m := memoryReport{MallocDebug: sortedSet(a.toggles)}
+	if err := readHeaderProtections(r.BinaryPath, &m); err != nil {
+		return nil
+	}
+
+	insecure := make(map[string]bool)
+	for _, sym := range r.Imports {
+		switch {
+		case sym == "___stack_chk_fail" || sym == "___stack_chk_guard":
+			m.StackCanary = true
+		case sym == "_objc_release" || sym == "_swift_release":
+			m.ARC = true
+		}
+		if _, ok := insecureMemoryAPIs[sym]; ok {
+			insecure[strings.TrimPrefix(sym, "_")] = true
+		}
+	}
+	m.InsecureAPIs = sortedSet(insecure)
+	r.Memory = m
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	add := func(rule, severity, title, evidence, remediation string) {
+		findings = append(findings, finding{
+			Rule:        rule,
+			Severity:    severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    location,
+			Remediation: remediation,
+		})
+	}
+
+	if !m.PIE {
+		add("binary-not-pie", severityMedium, "Binary is not position independent", "MH_PIE not set",
+			"Link with -pie (the default for iOS targets) so ASLR can randomize the executable's load address.")
+	}
+	if m.AllowStackExecution {
+		add("executable-stack", severityHigh, "Binary allows stack execution", "MH_ALLOW_STACK_EXECUTION set",
+			"Remove -allow_stack_execute from the linker flags.")
+	}
+	if !m.StackCanary {
+		add("missing-stack-canary", severityLow, "No stack protector found", "___stack_chk_fail not imported",
+			"Build with -fstack-protector-all (Xcode: Other C Flags) to detect stack buffer overflows.")
+	}
+	if len(m.MallocDebug) > 0 {
+		add("malloc-debug-toggle", severityLow, "libmalloc debug toggles referenced", strings.Join(m.MallocDebug, ", "),
+			"Remove malloc debugging environment variables from release builds; they change allocator behavior and leak allocation history.")
+	}
+	for _, api := range m.InsecureAPIs {
+		add("insecure-memory-api", severityLow, "Unbounded memory API imported", api,
+			"Replace "+api+" with "+insecureMemoryAPIs["_"+api]+".")
+	}
+	return findings
+}
+
+// readHeaderProtections reads PIE, heap and stack execution flags from every
+// slice. A protection only counts when all slices have it.
+func readHeaderProtections(path string, m *memoryReport) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return err
+	}
+
+	m.PIE, m.NoHeapExecution, m.PointerAuth = len(slices) > 0, len(slices) > 0, len(slices) > 0
+	for _, s := range slices {
+		m.PIE = m.PIE && s.Flags&mhPIE != 0
+		m.NoHeapExecution = m.NoHeapExecution && s.Flags&mhNoHeapExecution != 0
+		m.AllowStackExecution = m.AllowStackExecution || s.Flags&mhAllowStackExecution != 0
+		m.PointerAuth = m.PointerAuth && s.Cpu == macho.CpuArm64 && s.SubCpu&cpuSubtypeMask == cpuSubtypeARM64E
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxSchemeURLs bounds how many custom-scheme URL strings are kept
+const maxSchemeURLs = 200
+
+var (
+	// schemeURLPattern matches a URL with a custom scheme, as in
+	// com.example.app:/oauth2redirect or myapp://callback
+	schemeURLPattern = regexp.MustCompile(`\b([A-Za-z][A-Za-z0-9+.\-]*):/{1,2}[^\s"'<>]*`)
+	// googleClientIDPattern matches a Google OAuth client ID; the reversed
+	// form is the redirect scheme Google Sign-In registers
+	googleClientIDPattern = regexp.MustCompile(`\b(\d{6,}-[a-z0-9]{32})\.apps\.googleusercontent\.com\b`)
+	// clientIDParamPattern matches a client_id query parameter
+	clientIDParamPattern = regexp.MustCompile(`\bclient_id=([A-Za-z0-9._\-]{8,})`)
+	// redirectParamPattern matches a redirect_uri query parameter
+	redirectParamPattern = regexp.MustCompile(`\bredirect_uri=([^&\s"']+)`)
+	// oauthPathPattern matches the path of a custom-scheme URL used as an
+	// OAuth redirect
+	oauthPathPattern = regexp.MustCompile(`(?i)oauth|callback|redirect|auth`)
+)
+
+// Imports and strings showing how the app runs its OAuth flows
+const (
+	symWebAuthSession = "_OBJC_CLASS_$_ASWebAuthenticationSession"
+	symSFAuthSession  = "_OBJC_CLASS_$_SFAuthenticationSession"
+	pkceParameter     = "code_challenge"
+)
+
+// oauthReport correlates the app's URL schemes with the OAuth clients and
+// redirect URIs found in the binary and Info.plist
+type oauthReport struct {
+	Redirects   []oauthRedirect `json:"redirects,omitempty"`
+	ClientIDs   []string        `json:"client_ids,omitempty"`
+	PKCE        bool            `json:"pkce"`         // code_challenge is sent
+	AuthSession bool            `json:"auth_session"` // ASWebAuthenticationSession or SFAuthenticationSession
+}
+
+// oauthRedirect is a URL scheme the app registers that receives OAuth
+// authorization responses
+type oauthRedirect struct {
+	Scheme   string   `json:"scheme"`
+	Provider string   `json:"provider,omitempty"`
+	Evidence []string `json:"evidence"`
+}
+
+// oauthAnalyzer collects custom-scheme URLs, redirect_uri and client_id
+// parameters and Google client IDs
+type oauthAnalyzer struct {
+	schemeURLs map[string]bool
+	clientIDs  map[string]bool
+	pkce       bool
+}
+
+func newOAuthAnalyzer() stringAnalyzer {
+	return &oauthAnalyzer{schemeURLs: make(map[string]bool), clientIDs: make(map[string]bool)}
+}
+
+func (a *oauthAnalyzer) visit(s string, offset int64) {
+	if len(s) > maxFormatStringLength {
+		return
+	}
+	if strings.Contains(s, pkceParameter) {
+		a.pkce = true
+	}
+	if strings.Contains(s, "googleusercontent") {
+		for _, m := range googleClientIDPattern.FindAllStringSubmatch(s, -1) {
+			a.clientIDs[m[1]+".apps.googleusercontent.com"] = true
+		}
+	}
+	if strings.Contains(s, "client_id=") {
+		for _, m := range clientIDParamPattern.FindAllStringSubmatch(s, -1) {
+			a.clientIDs[m[1]] = true
+		}
+	}
+	if strings.Contains(s, "redirect_uri=") {
+		for _, m := range redirectParamPattern.FindAllStringSubmatch(s, -1) {
+			if u, err := url.QueryUnescape(m[1]); err == nil && len(a.schemeURLs) < maxSchemeURLs {
+				a.schemeURLs[u] = true
+			}
+		}
+	}
+	if strings.Contains(s, ":/") {
+		for _, u := range schemeURLPattern.FindAllString(s, -1) {
+			if _, ok := endpointSchemes[endpointScheme(u)]; !ok && len(a.schemeURLs) < maxSchemeURLs {
+				a.schemeURLs[u] = true
+			}
+		}
+	}
+}
+
+func (a *oauthAnalyzer) findings(r *appReport) []finding {
+	o := &r.OAuth
+	if id := plistString(r.InfoPlist, "GIDClientID"); id != "" {
+		a.clientIDs[id] = true
+	}
+	o.ClientIDs = sortedSet(a.clientIDs)
+	o.PKCE = a.pkce
+	o.AuthSession = containsString(r.Imports, symWebAuthSession) || containsString(r.Imports, symSFAuthSession)
+
+	reversed := make(map[string]string)
+	for _, id := range o.ClientIDs {
+		if m := googleClientIDPattern.FindStringSubmatch(id); m != nil {
+			reversed["com.googleusercontent.apps."+m[1]] = id
+		}
+	}
+	facebookID := plistString(r.InfoPlist, "FacebookAppID")
+	urls := sortedSet(a.schemeURLs)
+
+	for _, s := range r.Schemes {
+		for _, scheme := range s.Schemes {
+			lower := strings.ToLower(scheme)
+			redirect := oauthRedirect{Scheme: scheme}
+			switch {
+			case reversed[lower] != "":
+				redirect.Provider = "Google"
+				redirect.Evidence = append(redirect.Evidence, "client ID "+reversed[lower])
+			case facebookID != "" && lower == "fb"+facebookID:
+				redirect.Provider = "Facebook"
+				redirect.Evidence = append(redirect.Evidence, "FacebookAppID "+facebookID)
+			case strings.HasPrefix(lower, "msauth."):
+				redirect.Provider = "Microsoft"
+				redirect.Evidence = append(redirect.Evidence, "MSAL redirect scheme")
+			}
+			for _, u := range urls {
+				rest, ok := strings.CutPrefix(strings.ToLower(u), lower+":")
+				if ok && oauthPathPattern.MatchString(rest) {
+					redirect.Evidence = append(redirect.Evidence, u)
+				}
+			}
+			if len(redirect.Evidence) > 0 {
+				o.Redirects = append(o.Redirects, redirect)
+			}
+		}
+	}
+	sort.Slice(o.Redirects, func(i, j int) bool { return o.Redirects[i].Scheme < o.Redirects[j].Scheme })
+
+	severity, remediation := severityMedium, "Another app can register the same scheme and receive the authorization code. Use a claimed https redirect "+
+		"(universal link) or ASWebAuthenticationSession, and send a PKCE code_challenge so an intercepted code cannot be redeemed."
+	if o.PKCE {
+		severity, remediation = severityLow, "The app sends a PKCE code_challenge, so an intercepted code cannot be redeemed on its own; prefer a claimed "+
+			"https redirect or ASWebAuthenticationSession so the response cannot be intercepted at all."
+	}
+	var findings []finding
+	for _, redirect := range o.Redirects {
+		title := "URL scheme handles OAuth redirects"
+		if redirect.Provider != "" {
+			title = redirect.Provider + " sign-in redirect handled by a URL scheme"
+		}
+		findings = append(findings, finding{
+			Rule:        "oauth-redirect-scheme",
+			Severity:    severity,
+			Title:       title,
+			Evidence:    redirect.Scheme + ": " + sampleList(redirect.Evidence),
+			Location:    bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: remediation,
+		})
+	}
+	return findings
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// minRecoveredLength is the shortest recovered string worth reporting
+	minRecoveredLength = 8
+	// maxRecoveredStrings bounds how many recovered strings are reported per binary
+	maxRecoveredStrings = 25
+	// maxXORRunLength skips runs too long to be a single encoded string
+	maxXORRunLength = 256
+	// maxXORScanSize bounds how many bytes of constant data are brute forced
+	maxXORScanSize = 4 << 20
+	// maxChunkLength is the longest string treated as a fragment of a split literal
+	maxChunkLength = 7
+	// minReportedConfidence is the confidence below which recovered strings
+	// are kept in the JSON report but not raised as findings
+	minReportedConfidence = 0.6
+	// stackWindowGap ends a stack string once this many instructions pass
+	// without a move-immediate or store
+	stackWindowGap = 4
+)
+
+// obfuscationMarkers are substrings that make a recovered candidate
+// credible: random bytes rarely decode to any of them
+var obfuscationMarkers = []string{
+	"http", "://", "www.", ".com", ".net", ".io", "api", "key", "token", "secret", "passw",
+	"admin", "debug", "jailbr", "cydia", "frida", "substrate", "/bin/", "/var/", "/Applications/", "/Library/",
+}
+
+// recoveredString is a string the app hides from strings(1) that could be reconstructed
+type recoveredString struct {
+	Technique  string  `json:"technique"` // xor, stack or chunked
+	Value      string  `json:"value"`
+	Key        string  `json:"key,omitempty"` // the XOR key
+	Offset     int64   `json:"offset"`        // file offset of the encoded bytes or building code
+	Confidence float64 `json:"confidence"`    // 0-1, how unlikely the value is to be noise
+}
+
+// obfuscationAnalyzer recovers strings hidden by simple obfuscation. Split
+// literals are rejoined from adjacent short strings as they stream past;
+// single-byte XOR and arm64 stack strings are recovered from the Mach-O
+// sections once the string scan is over.
+type obfuscationAnalyzer struct {
+	chunks    []string
+	chunkEnd  int64
+	recovered []recoveredString
+	seen      map[string]bool
+}
+
+func newObfuscationAnalyzer() stringAnalyzer {
+	return &obfuscationAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *obfuscationAnalyzer) visit(s string, offset int64) {
+	// Fragments are laid out one after the other, separated by a single NUL
+	if len(s) > maxChunkLength || offset != a.chunkEnd+1 {
+		a.flushChunks()
+	}
+	if len(s) <= maxChunkLength {
+		a.chunks = append(a.chunks, s)
+		a.chunkEnd = offset + int64(len(s))
+	}
+	if len(s) > maxChunkLength {
+		a.chunkEnd = -2
+	}
+}
+
+// flushChunks reports the pending fragments when, joined, they spell a URL
+// scheme that none of them contains on its own
+func (a *obfuscationAnalyzer) flushChunks() {
+	chunks := a.chunks
+	a.chunks = nil
+	if len(chunks) < 3 {
+		return
+	}
+	joined := strings.Join(chunks, "")
+	lower := strings.ToLower(joined)
+	for _, m := range []string{"://", "http"} {
+		if !strings.Contains(lower, m) {
+			continue
+		}
+		split := true
+		for _, c := range chunks {
+			if strings.Contains(strings.ToLower(c), m) {
+				split = false
+			}
+		}
+		if split {
+			a.add(recoveredString{Technique: "chunked", Value: joined, Offset: a.chunkEnd - int64(len(joined)+len(chunks)-1), Confidence: 0.7})
+			return
+		}
+	}
+}
+
+// add records a candidate once, keeping the list bounded
+func (a *obfuscationAnalyzer) add(rs recoveredString) {
+	if len(a.recovered) >= maxRecoveredStrings || a.seen[rs.Value] {
+		return
+	}
+	a.seen[rs.Value] = true
+	a.recovered = append(a.recovered, rs)
+}
+
+func (a *obfuscationAnalyzer) findings(r *appReport) []finding {
+	a.flushChunks()
+	if err := a.scanSections(r.BinaryPath); err != nil {
+		return nil
+	}
+	sort.SliceStable(a.recovered, func(i, j int) bool { return a.recovered[i].Confidence > a.recovered[j].Confidence })
+	r.RecoveredStrings = a.recovered
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, rs := range a.recovered {
+		if rs.Confidence < minReportedConfidence {
+			continue
+		}
+		severity := severityInfo
+		if rs.Confidence >= 0.8 {
+			severity = severityLow
+		}
+		how := rs.Technique
+		if rs.Key != "" {
+			how += " key " + rs.Key
+		}
+		findings = append(findings, finding{
+			Rule:        "obfuscated-string",
+			Severity:    severity,
+			Confidence:  confidenceLevel(rs.Confidence),
+			Title:       "Obfuscated string recovered",
+			Evidence:    fmt.Sprintf("%s at 0x%x, confidence %.2f: %s", how, rs.Offset, rs.Confidence, rs.Value),
+			Location:    location,
+			Remediation: "String obfuscation only delays an attacker. Keep secrets and sensitive endpoints off the device rather than hiding them in the binary.",
+		})
+	}
+	return findings
+}
+
+// scanSections brute forces single-byte XOR over constant data and rebuilds
+// stack strings from arm64 code in the first slice that has them
+func (a *obfuscationAnalyzer) scanSections(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return err
+	}
+	for _, s := range slices {
+		if s.Cpu != macho.CpuArm64 {
+			continue
+		}
+		budget := maxXORScanSize
+		for _, sec := range s.Sections {
+			if sec.Name != "__const" && sec.Name != "__data" {
+				continue
+			}
+			if int(sec.Size) > budget || sec.Offset == 0 {
+				continue
+			}
+			data, err := sec.Data()
+			if err != nil {
+				return err
+			}
+			budget -= len(data)
+			a.scanXOR(data, s.offset+int64(sec.Offset))
+		}
+		if text := s.Section("__text"); text != nil && text.Size <= maxTextScanSize {
+			code, err := text.Data()
+			if err != nil {
+				return err
+			}
+			a.scanStackStrings(code, s.offset+int64(text.Offset))
+		}
+		return nil
+	}
+	return nil
+}
+
+// scanXOR tries every single-byte key on runs of non-printable, non-zero
+// bytes, where XOR-encoded text lives, and keeps credible decodings
+func (a *obfuscationAnalyzer) scanXOR(data []byte, base int64) {
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] != 0 && !isPrintableByte(data[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minRecoveredLength && i-start <= maxXORRunLength {
+			a.tryXOR(data[start:i], base+int64(start))
+		}
+		start = -1
+	}
+}
+
+// tryXOR decodes run with each key and records the best credible result
+func (a *obfuscationAnalyzer) tryXOR(run []byte, offset int64) {
+	buf := make([]byte, len(run))
+	best, bestKey, bestScore := "", 0, 0.0
+	for key := 1; key < 256; key++ {
+		printable := true
+		for i, b := range run {
+			buf[i] = b ^ byte(key)
+			if !isPrintableByte(buf[i]) {
+				printable = false
+				break
+			}
+		}
+		if !printable {
+			continue
+		}
+		if score := credibility(string(buf)); score > bestScore {
+			best, bestKey, bestScore = string(buf), key, score
+		}
+	}
+	if bestScore > 0 {
+		a.add(recoveredString{Technique: "xor", Value: best, Key: fmt.Sprintf("0x%02x", bestKey), Offset: offset, Confidence: bestScore})
+	}
+}
+
+// credibility scores how likely s is real text rather than noise: a marker is
+// strong evidence, long runs of word characters are weaker evidence
+func credibility(s string) float64 {
+	lower := strings.ToLower(s)
+	for _, m := range obfuscationMarkers {
+		if strings.Contains(lower, m) {
+			return 0.9
+		}
+	}
+	if len(s) < 12 {
+		return 0
+	}
+	word, vowels := 0, 0
+	for _, c := range lower {
+		switch {
+		case c >= 'a' && c <= 'z', c == ' ', c == '.', c == '/', c == '_', c == '-':
+			word++
+		}
+		if strings.ContainsRune("aeiou", c) {
+			vowels++
+		}
+	}
+	if float64(word) >= 0.9*float64(len(s)) && vowels*5 >= len(s) {
+		return 0.6
+	}
+	return 0
+}
+
+// scanStackStrings rebuilds strings that arm64 code assembles on the stack
+// with MOVZ/MOVK immediates followed by stores, e.g.
+//
+//	mov  w8, #0x7468      ; "ht"
+//	movk w8, #0x7074, lsl #16
+//	str  w8, [sp, #8]
+func (a *obfuscationAnalyzer) scanStackStrings(code []byte, base int64) {
+	regs := make(map[uint32]uint64)
+	stores := make(map[int64][]byte)
+	start, gap := -1, 0
+
+	flush := func() {
+		if len(stores) > 0 {
+			a.assembleStack(stores, base+int64(start))
+		}
+		clear(regs)
+		clear(stores)
+		start, gap = -1, 0
+	}
+
+	for i := 0; i+4 <= len(code); i += 4 {
+		ins := binary.LittleEndian.Uint32(code[i:])
+		if decodeMoveWide(ins, regs) {
+			if start < 0 {
+				start = i
+			}
+			gap = 0
+			continue
+		}
+		if decodeStackStore(ins, regs, stores) {
+			gap = 0
+			continue
+		}
+		if start >= 0 {
+			gap++
+			if gap > stackWindowGap {
+				flush()
+			}
+		}
+	}
+	flush()
+}
+
+// assembleStack lays out stored bytes by stack offset and keeps printable runs
+func (a *obfuscationAnalyzer) assembleStack(stores map[int64][]byte, offset int64) {
+	offsets := make([]int64, 0, len(stores))
+	for off := range stores {
+		offsets = append(offsets, off)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	var run []byte
+	next := offsets[0]
+	emit := func() {
+		s := strings.TrimRight(string(run), "\x00")
+		if len(s) >= minRecoveredLength && isPrintableString(s) {
+			confidence := credibility(s)
+			if confidence == 0 && letterRatio(s) >= 0.6 {
+				confidence = 0.5
+			}
+			if confidence > 0 {
+				a.add(recoveredString{Technique: "stack", Value: s, Offset: offset, Confidence: confidence})
+			}
+		}
+		run = nil
+	}
+	for _, off := range offsets {
+		if off != next {
+			emit()
+		}
+		run = append(run, stores[off]...)
+		next = off + int64(len(stores[off]))
+	}
+	emit()
+}
+
+// decodeMoveWide applies a MOVZ or MOVK instruction to regs, returning false for anything else
+func decodeMoveWide(ins uint32, regs map[uint32]uint64) bool {
+	if (ins>>23)&0x3f != 0x25 {
+		return false
+	}
+	opc := (ins >> 29) & 3
+	hw := (ins >> 21) & 3
+	imm := uint64((ins >> 5) & 0xffff)
+	rd := ins & 31
+	shift := 16 * hw
+	switch opc {
+	case 2: // MOVZ
+		regs[rd] = imm << shift
+	case 3: // MOVK
+		regs[rd] = regs[rd]&^(0xffff<<shift) | imm<<shift
+	default:
+		return false
+	}
+	return true
+}
+
+// decodeStackStore records a STR, STUR, STRB, STRH or STP of registers with
+// known immediate values, returning false for anything else
+func decodeStackStore(ins uint32, regs map[uint32]uint64, stores map[int64][]byte) bool {
+	put := func(rt uint32, off int64, size int) {
+		v, ok := regs[rt]
+		if !ok {
+			return
+		}
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, v)
+		stores[off] = b[:size]
+	}
+	// Only stores relative to sp or the frame pointer build stack strings
+	if rn := (ins >> 5) & 31; rn != 31 && rn != 29 {
+		return false
+	}
+	rt := ins & 31
+	switch {
+	case ins&0xbfc00000 == 0xb9000000: // STR (unsigned offset), 32 or 64 bit
+		size := 4 << ((ins >> 30) & 1)
+		put(rt, int64((ins>>10)&0xfff)*int64(size), size)
+	case ins&0xbfe00c00 == 0xb8000000: // STUR
+		size := 4 << ((ins >> 30) & 1)
+		put(rt, signExtend((ins>>12)&0x1ff, 9), size)
+	case ins&0xffc00000 == 0x39000000: // STRB
+		put(rt, int64((ins>>10)&0xfff), 1)
+	case ins&0xffc00000 == 0x79000000: // STRH
+		put(rt, int64((ins>>10)&0xfff)*2, 2)
+	case ins&0x7fc00000 == 0x29000000: // STP (signed offset), 32 or 64 bit
+		size := 4 << (ins >> 31)
+		off := signExtend((ins>>15)&0x7f, 7) * int64(size)
+		put(rt, off, size)
+		put((ins>>10)&31, off+int64(size), size)
+	default:
+		return false
+	}
+	return true
+}
+
+// signExtend interprets the low bits of v as a two's complement number
+func signExtend(v uint32, bits uint) int64 {
+	shift := 64 - bits
+	return int64(uint64(v)<<shift) >> shift
+}
+
+// letterRatio is the fraction of s that is ASCII letters
+func letterRatio(s string) float64 {
+	letters := 0
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c >= 'a' && c <= 'z' {
+			letters++
+		}
+	}
+	return float64(letters) / float64(len(s))
+}
+
+func isPrintableByte(b byte) bool {
+	return b >= 0x20 && b < 0x7f
+}
+
+func isPrintableString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isPrintableByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
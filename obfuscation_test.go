@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// xorEncode returns s with every byte XORed with key, padded with NULs the
+// way a constant sits between others in __const
+func xorEncode(s string, key byte) []byte {
+	data := make([]byte, 4, len(s)+8)
+	for i := 0; i < len(s); i++ {
+		data = append(data, s[i]^key)
+	}
+	return append(data, 0, 0, 0, 0)
+}
+
+// arm64 encodings of the instructions stack strings are built with
+func movz(rd, imm, hw uint32) uint32 { return 0xd2800000 | hw<<21 | imm<<5 | rd }
+func movk(rd, imm, hw uint32) uint32 { return 0xf2800000 | hw<<21 | imm<<5 | rd }
+
+// str stores the 64-bit register rt at [rn, #off]
+func str(rt, rn, off uint32) uint32 { return 0xf9000000 | (off/8)<<10 | rn<<5 | rt }
+
+// stackString returns code that builds s, a multiple of 8 bytes, in x8 and
+// stores it 8 bytes at a time relative to rn
+func stackString(s string, rn uint32) []byte {
+	var code []byte
+	emit := func(ins uint32) { code = binary.LittleEndian.AppendUint32(code, ins) }
+	for i := 0; i < len(s); i += 8 {
+		v := binary.LittleEndian.Uint64([]byte(s[i : i+8]))
+		emit(movz(8, uint32(v&0xffff), 0))
+		for hw := uint32(1); hw < 4; hw++ {
+			emit(movk(8, uint32(v>>(16*hw)&0xffff), hw))
+		}
+		emit(str(8, rn, uint32(i)))
+	}
+	return code
+}
+
+func TestObfuscationXOR(t *testing.T) {
+	tests := []struct {
+		name, plain string
+		key         byte
+		want        bool
+	}{
+		{"URL", "https://api.example.com/v1", 0x80, true},
+		{"jailbreak path", "/Applications/Cydia.app", 0xa5, true},
+		{"plain words", "the quick brown fox jumps", 0x80, true},
+		{"too short", "key", 0x80, false},
+		{"noise", "x7#q!k9z@p", 0x80, false},
+		{"symbols", "{}[]|~^%$#@!<>?=+*&{}[]|", 0x80, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newObfuscationAnalyzer().(*obfuscationAnalyzer)
+			a.scanXOR(xorEncode(tt.plain, tt.key), 0x1000)
+			if !tt.want {
+				if len(a.recovered) != 0 {
+					t.Errorf("recovered %+v, want nothing", a.recovered)
+				}
+				return
+			}
+			if len(a.recovered) != 1 {
+				t.Fatalf("recovered %+v, want %q", a.recovered, tt.plain)
+			}
+			rs := a.recovered[0]
+			if rs.Technique != "xor" || rs.Value != tt.plain || rs.Offset != 0x1004 {
+				t.Errorf("recovered %+v, want %q at 0x1004", rs, tt.plain)
+			}
+		})
+	}
+}
+
+func TestObfuscationXORKey(t *testing.T) {
+	a := newObfuscationAnalyzer().(*obfuscationAnalyzer)
+	a.scanXOR(xorEncode("https://api.example.com/v1", 0x80), 0)
+	if len(a.recovered) != 1 || a.recovered[0].Key != "0x80" || a.recovered[0].Confidence < minReportedConfidence {
+		t.Errorf("recovered %+v, want key 0x80 with a reportable confidence", a.recovered)
+	}
+}
+
+func TestObfuscationStackStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		want string // "" when nothing should be recovered
+	}{
+		{"sp relative", stackString("https://evil.io/", 31), "https://evil.io/"},
+		{"frame pointer relative", stackString("secretkey123abcd", 29), "secretkey123abcd"},
+		{"letters without markers", stackString("Hellosomeletters", 31), "Hellosomeletters"},
+		{"other base register", stackString("https://evil.io/", 1), ""},
+		{"digits", stackString("0123456789012345", 31), ""},
+		{"moves without stores", stackString("https://evil.io/", 31)[:16], ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newObfuscationAnalyzer().(*obfuscationAnalyzer)
+			a.scanStackStrings(tt.code, 0x4000)
+			if tt.want == "" {
+				if len(a.recovered) != 0 {
+					t.Errorf("recovered %+v, want nothing", a.recovered)
+				}
+				return
+			}
+			if len(a.recovered) != 1 || a.recovered[0].Technique != "stack" || a.recovered[0].Value != tt.want {
+				t.Errorf("recovered %+v, want %q", a.recovered, tt.want)
+			}
+		})
+	}
+}
+
+func TestObfuscationChunks(t *testing.T) {
+	type piece struct {
+		s      string
+		offset int64
+	}
+	tests := []struct {
+		name   string
+		pieces []piece
+		want   string
+	}{
+		{"split URL", []piece{{"htt", 100}, {"ps:", 104}, {"//ex", 108}, {"am.io", 113}}, "https://exam.io"},
+		{"too few fragments", []piece{{"http", 100}, {"s://x", 105}}, ""},
+		{"gap between fragments", []piece{{"htt", 100}, {"p", 104}, {"s:", 110}, {"//x", 113}}, ""},
+		{"fragment holds the scheme", []piece{{"https", 100}, {"://", 106}, {"ex", 110}, {"am", 113}}, ""},
+		{"long string in between", []piece{{"htt", 100}, {"ps:", 104}, {"a longer string", 108}, {"//ex", 124}}, ""},
+		{"no scheme", []piece{{"ab", 100}, {"cd", 103}, {"ef", 106}, {"gh", 109}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newObfuscationAnalyzer().(*obfuscationAnalyzer)
+			for _, p := range tt.pieces {
+				a.visit(p.s, p.offset)
+			}
+			a.flushChunks()
+			if tt.want == "" {
+				if len(a.recovered) != 0 {
+					t.Errorf("recovered %+v, want nothing", a.recovered)
+				}
+				return
+			}
+			if len(a.recovered) != 1 || a.recovered[0].Technique != "chunked" || a.recovered[0].Value != tt.want || a.recovered[0].Offset != 100 {
+				t.Errorf("recovered %+v, want %q at 100", a.recovered, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredibility(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"https://x", 0.9},
+		{"api_TOKEN", 0.9},
+		{"/var/mobile", 0.9},
+		{"lorem ipsum dolor", 0.6},
+		{"short words", 0},
+		{"qwrtzpsdfghjklmnb", 0},
+		{"12345678901234567890", 0},
+	}
+	for _, tt := range tests {
+		if got := credibility(tt.s); got != tt.want {
+			t.Errorf("credibility(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
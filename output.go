@@ -0,0 +1,493 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// stdout receives all human-readable output. It is switched to stderr when
+// a machine-readable report is written to standard output.
+var stdout io.Writer = os.Stdout
+
+// Report sections that can be toggled with --summary and --show
+const (
+	sectionMetadata     = "metadata"
+	sectionCapabilities = "capabilities"
+	sectionCounts       = "counts"
+	sectionSlices       = "slices"
+	sectionSchemes      = "schemes"
+	sectionEntitlements = "entitlements"
+	sectionFrameworks   = "frameworks"
+	sectionResources    = "resources"
+	sectionFindings     = "findings"
+	sectionDSYM         = "dsym"
+	sectionInjection    = "injection"
+	sectionWebView      = "webview"
+	sectionEndpoints    = "endpoints"
+	sectionTrackers     = "trackers"
+	sectionDataLeak     = "dataleak"
+	sectionPlist        = "plist"
+	sectionApplinks     = "applinks"
+	sectionStrings      = "strings"
+	sectionLog          = "log"
+	sectionMatrix       = "matrix"
+)
+
+// allSections lists every section in the order it is printed
+var allSections = []string{
+	sectionLog, sectionPlist, sectionMetadata, sectionCapabilities, sectionCounts,
+	sectionSlices, sectionSchemes, sectionEntitlements, sectionFrameworks, sectionResources, sectionDSYM,
+	sectionInjection, sectionWebView, sectionEndpoints, sectionTrackers, sectionDataLeak, sectionFindings, sectionApplinks, sectionStrings,
+	sectionMatrix,
+}
+
+// summarySections are printed in --summary mode without needing --show
+var summarySections = map[string]bool{
+	sectionMetadata:     true,
+	sectionCapabilities: true,
+	sectionCounts:       true,
+	sectionMatrix:       true,
+}
+
+// sectionList is a flag.Value collecting comma-separated, repeatable --show arguments
+type sectionList map[string]bool
+
+func (s sectionList) String() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (s sectionList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, section := range allSections {
+			if section == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return trError("ErrUnknownSection", "Section", name, "Sections", strings.Join(allSections, ", "))
+		}
+		s[name] = true
+	}
+	return nil
+}
+
+// sectionOptions selects which report sections are printed
+type sectionOptions struct {
+	summary bool
+	show    sectionList
+}
+
+// sectionOpts is set from --summary and --show
+var sectionOpts = sectionOptions{show: sectionList{}}
+
+// showSection reports whether a section should be printed. Everything is
+// printed by default; --summary narrows output to the summary sections plus any --show'n ones.
+func showSection(name string) bool {
+	if !sectionOpts.summary {
+		return true
+	}
+	return summarySections[name] || sectionOpts.show[name]
+}
+
+// printReport renders the enabled sections of an app report
+func printReport(w io.Writer, r *appReport) {
+	if showSection(sectionMetadata) {
+		printMetadata(w, r)
+	}
+
+	if showSection(sectionCapabilities) {
+		var rows [][]string
+		for _, c := range r.Capabilities {
+			mark := "-"
+			if c.Enabled {
+				mark = "✓"
+			}
+			rows = append(rows, []string{c.Name, mark, c.sourceList()})
+		}
+		printTable(w, tr("TableCapabilities", "App", r.Name), []string{tr("ColCapability"), tr("ColEnabled"), tr("ColSource")}, rows)
+	}
+
+	if showSection(sectionCounts) {
+		printTable(w, tr("TableCounts", "App", r.Name), []string{tr("ColSeverity"), tr("ColCount")}, severityCountRows(r.Findings))
+	}
+
+	if showSection(sectionSlices) {
+		var rows [][]string
+		for _, s := range r.Slices {
+			rows = append(rows, []string{s.Arch, s.Platform, s.MinimumOS, s.SDK, s.UUID, s.SourceVersion})
+		}
+		printTable(w, tr("TableSlices", "App", r.Name), []string{tr("ColArch"), tr("ColPlatform"), tr("ColMinimumOS"), tr("ColSDK"), tr("ColUUID"), tr("ColSourceVersion")}, rows)
+	}
+
+	if showSection(sectionSchemes) {
+		var rows [][]string
+		for _, s := range r.Schemes {
+			rows = append(rows, []string{s.Name, s.Role, strings.Join(s.Schemes, ", ")})
+		}
+		printTable(w, tr("TableSchemes", "App", r.Name), []string{tr("ColName"), tr("ColRole"), tr("ColSchemes")}, rows)
+	}
+
+	if showSection(sectionEntitlements) {
+		var rows [][]string
+		for _, key := range sortedKeys(r.Entitlements) {
+			rows = append(rows, []string{key, formatPlistValue(r.Entitlements[key])})
+		}
+		printTable(w, tr("TableEntitlements", "App", r.Name), []string{tr("ColKey"), tr("ColValue")}, rows)
+	}
+
+	if showSection(sectionFrameworks) {
+		var rows [][]string
+		for _, fw := range r.Frameworks {
+			rows = append(rows, []string{fw.Name, fw.Kind, fw.Version, fw.BundleID, fw.Signature.String()})
+		}
+		printTable(w, tr("TableFrameworks", "App", r.Name), []string{tr("ColName"), tr("ColKind"), tr("ColVersion"), tr("ColBundleID"), tr("ColSignature")}, rows)
+	}
+
+	if showSection(sectionResources) && len(r.Resources.Types) > 0 {
+		rows := make([][]string, len(r.Resources.Types))
+		for i, t := range r.Resources.Types {
+			rows[i] = []string{t.Type, fmt.Sprint(t.Files), formatBytes(t.Bytes)}
+		}
+		printTable(w, tr("TableResources", "App", r.Name), []string{tr("ColType"), tr("ColFiles"), tr("ColSize")}, rows)
+	}
+
+	if showSection(sectionDSYM) && len(r.DSYM) > 0 {
+		var rows [][]string
+		for _, m := range r.DSYM {
+			dsym := tr("NoMatch")
+			if m.DSYM != "" {
+				dsym = m.DSYM
+			}
+			rows = append(rows, []string{m.Binary, m.Arch, m.UUID, dsym})
+		}
+		printTable(w, tr("TableDSYM", "App", r.Name), []string{tr("ColBinary"), tr("ColArch"), tr("ColUUID"), tr("ColDSYM")}, rows)
+	}
+
+	if showSection(sectionInjection) && len(r.InjectionSurface) > 0 {
+		var rows [][]string
+		for _, g := range r.InjectionSurface {
+			for _, e := range g.Evidence {
+				rows = append(rows, []string{g.Category, e})
+			}
+		}
+		printTable(w, tr("TableInjection", "App", r.Name), []string{tr("ColCategory"), tr("ColEvidence")}, rows)
+	}
+
+	if showSection(sectionWebView) {
+		var rows [][]string
+		wv := r.WebView
+		for _, g := range []struct {
+			kind  string
+			items []string
+		}{
+			{tr("WebViewClasses"), wv.Classes},
+			{tr("WebViewSettings"), wv.Settings},
+			{tr("WebViewSchemeHandlers"), wv.SchemeHandlers},
+			{tr("WebViewMessageHandlers"), wv.MessageHandlers},
+			{tr("WebViewLocalLoading"), wv.LocalLoading},
+			{tr("WebViewLocalContent"), wv.LocalContent},
+		} {
+			for _, item := range g.items {
+				rows = append(rows, []string{g.kind, item})
+			}
+		}
+		if len(rows) > 0 {
+			printTable(w, tr("TableWebView", "App", r.Name), []string{tr("ColKind"), tr("ColEvidence")}, rows)
+		}
+	}
+
+	if showSection(sectionEndpoints) && len(r.Endpoints) > 0 {
+		rows := make([][]string, len(r.Endpoints))
+		for i, u := range r.Endpoints {
+			rows[i] = []string{endpointScheme(u), u}
+		}
+		printTable(w, tr("TableEndpoints", "App", r.Name), []string{tr("ColProtocol"), tr("ColURL")}, rows)
+	}
+
+	if showSection(sectionTrackers) && len(r.Trackers) > 0 {
+		rows := make([][]string, len(r.Trackers))
+		for i, t := range r.Trackers {
+			rows[i] = []string{t.Name, strings.Join(t.Categories, ", "), sampleList(append(append([]string(nil), t.Domains...), t.SDKs...))}
+		}
+		printTable(w, tr("TableTrackers", "App", r.Name), []string{tr("ColName"), tr("ColCategory"), tr("ColEvidence")}, rows)
+	}
+
+	if showSection(sectionDataLeak) {
+		var rows [][]string
+		c := r.Clipboard
+		if c.UniversalClipboard {
+			rows = append(rows, []string{tr("DataLeakUniversalClipboard"), tr("DataLeakUniversalClipboardEvidence")})
+		}
+		for _, g := range []struct {
+			kind  string
+			items []string
+		}{
+			{tr("DataLeakNamedPasteboard"), c.NamedPasteboards},
+			{tr("DataLeakAppGroup"), c.AppGroups},
+			{tr("DataLeakHandoff"), c.Handoff},
+			{tr("DataLeakActivityType"), c.ActivityTypes},
+			{tr("DataLeakContinuation"), c.ContinuationDomains},
+		} {
+			for _, item := range g.items {
+				rows = append(rows, []string{g.kind, item})
+			}
+		}
+		if len(rows) > 0 {
+			printTable(w, tr("TableDataLeak", "App", r.Name), []string{tr("ColKind"), tr("ColEvidence")}, rows)
+		}
+	}
+
+	if showSection(sectionFindings) {
+		// The component column only appears when embedded code contributed findings
+		components := false
+		for _, f := range r.Findings {
+			components = components || f.Component != ""
+		}
+		shown, omitted := limitFindingsPerRule(r.Findings)
+		var rows [][]string
+		for _, f := range shown {
+			if components {
+				rows = append(rows, []string{f.Severity, f.Rule, f.Component, findingTitle(f), findingEvidence(f)})
+			} else {
+				rows = append(rows, []string{f.Severity, f.Rule, findingTitle(f), findingEvidence(f)})
+			}
+		}
+		headers := []string{tr("ColSeverity"), tr("ColRule"), tr("ColTitle"), tr("ColEvidence")}
+		if components {
+			headers = []string{tr("ColSeverity"), tr("ColRule"), tr("ColComponent"), tr("ColTitle"), tr("ColEvidence")}
+		}
+		printTable(w, tr("TableFindings", "App", r.Name), headers, rows)
+		if len(omitted) > 0 {
+			for _, rule := range sortedKeysOf(omitted) {
+				fmt.Fprintln(w, "  "+tr("FindingsOmitted", "Count", omitted[rule], "Rule", rule))
+			}
+			fmt.Fprintln(w)
+		}
+		if len(r.FalsePositives) > 0 {
+			fmt.Fprintln(w, "  "+tr("FindingsFalsePositives", "Count", len(r.FalsePositives)))
+			fmt.Fprintln(w)
+		}
+		printEvidenceContext(w, shown)
+		printRemediations(w, r.Findings)
+	}
+}
+
+// findingTitle is the title column of a finding, noting when a heuristic
+// is less than confident
+func findingTitle(f finding) string {
+	switch f.Confidence {
+	case confidenceMedium:
+		return tr("TitleMediumConfidence", "Title", f.Title)
+	case confidenceLow:
+		return tr("TitleLowConfidence", "Title", f.Title)
+	}
+	return f.Title
+}
+
+// findingEvidence is the evidence column of a finding, noting repeats
+func findingEvidence(f finding) string {
+	if f.Occurrences > 1 {
+		return tr("EvidenceRepeated", "Evidence", f.Evidence, "Count", f.Occurrences)
+	}
+	return f.Evidence
+}
+
+// printRemediations lists the remediation advice of the findings, once per rule and advice
+func printRemediations(w io.Writer, findings []finding) {
+	seen := make(map[string]bool)
+	printed := false
+	for _, f := range findings {
+		if f.Remediation == "" || seen[f.Rule+f.Remediation] {
+			continue
+		}
+		seen[f.Rule+f.Remediation] = true
+		if !printed {
+			activeTheme.title.Fprintln(w, tr("Remediation"))
+			printed = true
+		}
+		fmt.Fprintf(w, "  %s %s\n", activeTheme.option.Sprint(f.Rule+":"), f.Remediation)
+	}
+	if printed {
+		fmt.Fprintln(w)
+	}
+}
+
+// printMetadata writes the identifying fields of the app as an aligned key/value block
+func printMetadata(w io.Writer, r *appReport) {
+	m := r.Metadata
+	fields := [][2]string{
+		{tr("MetaName"), m.DisplayName},
+		{tr("MetaBundleID"), m.BundleID},
+		{tr("MetaVersion"), versionString(m)},
+		{tr("MetaExecutable"), m.Executable},
+		{tr("MetaMinimumOS"), m.MinimumOS},
+		{tr("MetaSDK"), m.SDK},
+		{tr("MetaPlatform"), r.Platform},
+		{tr("MetaDevices"), strings.Join(m.DeviceFamily, ", ")},
+		{tr("MetaArchitectures"), sliceSummary(r.Slices)},
+		{tr("MetaSignature"), r.Signature.String()},
+		{tr("MetaAppStore"), r.AppStore.String()},
+		{tr("MetaStrings"), r.Strings.String()},
+		{tr("MetaProvisioning"), r.Provisioning.String()},
+		{tr("MetaATS"), r.ATS.String()},
+		{tr("MetaXcode"), m.Xcode},
+		{tr("MetaBuildTools"), strings.Join(r.Toolchain.BuildTools, ", ")},
+		{tr("MetaCompilers"), strings.Join(r.Toolchain.Compilers, "; ")},
+		{tr("MetaClasses"), classSummary(r.Classes)},
+		{tr("MetaProtections"), protectionSummary(r.Memory)},
+		{tr("MetaAntiDebug"), antiDebugSummary(r.AntiDebug)},
+		{tr("MetaKeychain"), keychainSummary(r.Keychain)},
+		{tr("MetaDataProtection"), r.DataProtection.String()},
+		{tr("MetaLogging"), r.Logging.String()},
+		{tr("MetaSQL"), r.SQL.String()},
+		{tr("MetaReceipt"), r.Receipt.String()},
+		{tr("MetaGraphQL"), r.GraphQL.String()},
+		{tr("MetaFeatureFlags"), r.FeatureFlags.String()},
+		{tr("MetaConsent"), r.Consent.String()},
+		{tr("MetaRequiredReasons"), requiredReasonSummary(r.RequiredReasons)},
+		{tr("MetaRadio"), r.Radio.String()},
+		{tr("MetaLocalNetwork"), r.LocalNetwork.String()},
+		{tr("MetaVPN"), r.VPN.String()},
+	}
+
+	width := 0
+	for _, f := range fields {
+		if len([]rune(f[0])) > width {
+			width = len([]rune(f[0]))
+		}
+	}
+
+	activeTheme.title.Fprintln(w, tr("TableMetadata", "App", r.Name))
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		label := f[0] + strings.Repeat(" ", width-len([]rune(f[0])))
+		fmt.Fprintf(w, "  %s  %s\n", activeTheme.option.Sprint(label), f[1])
+	}
+	fmt.Fprintln(w)
+}
+
+// sliceSummary lists slice architectures, e.g. "arm64, x86_64 (iOS Simulator)"
+func sliceSummary(slices []sliceInfo) string {
+	parts := make([]string, len(slices))
+	for i, s := range slices {
+		parts[i] = s.Arch
+		if s.Platform != "" && s.Platform != "iOS" {
+			parts[i] += " (" + s.Platform + ")"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatBytes formats a size with a binary unit, e.g. "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// versionString formats the marketing version and build number as "1.2 (34)"
+func versionString(m appMetadata) string {
+	switch {
+	case m.Build == "" || m.Build == m.Version:
+		return m.Version
+	case m.Version == "":
+		return m.Build
+	default:
+		return m.Version + " (" + m.Build + ")"
+	}
+}
+
+// severityOrder lists severities from most to least urgent
+var severityOrder = []string{severityCritical, severityHigh, severityMedium, severityLow, severityInfo}
+
+// severityCountRows tallies findings per severity, most urgent first
+func severityCountRows(findings []finding) [][]string {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	rows := make([][]string, 0, len(severityOrder)+1)
+	for _, sev := range severityOrder {
+		rows = append(rows, []string{sev, fmt.Sprint(counts[sev])})
+	}
+	rows = append(rows, []string{tr("CountTotal"), fmt.Sprint(len(findings))})
+	return rows
+}
+
+// classSummary describes how many Objective-C classes and methods were recovered
+func classSummary(classes []objcClass) string {
+	if len(classes) == 0 {
+		return ""
+	}
+	methods := 0
+	for _, c := range classes {
+		methods += len(c.Methods)
+	}
+	return tr("ClassCount", "Classes", len(classes), "Methods", methods)
+}
+
+// antiDebugSummary lists the anti-debugging techniques detected in the binary
+func antiDebugSummary(measures []antiDebugMeasure) string {
+	names := make([]string, len(measures))
+	for i, m := range measures {
+		names[i] = m.Technique
+	}
+	return strings.Join(names, ", ")
+}
+
+// keychainSummary lists the keychain accessibility classes referenced, e.g.
+// "AfterFirstUnlock, WhenUnlockedThisDeviceOnly (iCloud sync)"
+func keychainSummary(k keychainReport) string {
+	if len(k.APIs) == 0 {
+		return ""
+	}
+	classes := make([]string, len(k.Accessibility))
+	for i, c := range k.Accessibility {
+		classes[i] = strings.TrimPrefix(c, "kSecAttrAccessible")
+	}
+	summary := strings.Join(classes, ", ")
+	if summary == "" {
+		summary = "default"
+	}
+	if k.Synchronizable {
+		summary += " (iCloud sync)"
+	}
+	return summary
+}
+
+// protectionSummary lists the memory protections the binary was built with
+func protectionSummary(m memoryReport) string {
+	var names []string
+	for _, p := range []struct {
+		name string
+		on   bool
+	}{{"PIE", m.PIE}, {"stack canary", m.StackCanary}, {"ARC", m.ARC}, {"PAC", m.PointerAuth}, {"NX heap", m.NoHeapExecution}} {
+		if p.on {
+			names = append(names, p.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
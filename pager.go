@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// outputOptions bound how much the terminal report prints. The JSON report
+// always has everything.
+type outputOptions struct {
+	maxStrings         int          // --max-strings: lines of the strings section, 0 for all
+	stringsMinLength   int          // --strings-min-length: shortest string the strings section prints
+	stringsEncodings   encodingList // --strings-encoding: encodings the strings section prints, empty for all
+	maxFindingsPerRule int          // --max-findings-per-rule: findings table rows per rule, 0 for all
+	pager              bool         // --pager: page the report through $PAGER
+}
+
+// outputOpts is set from --max-strings, --strings-min-length,
+// --strings-encoding, --max-findings-per-rule and --pager
+var outputOpts = outputOptions{stringsEncodings: encodingList{}}
+
+// defaultPager is used when $PAGER is not set; -R passes colors through
+const defaultPager = "less -R"
+
+// pagerCmd is the running pager, nil when output goes straight to the terminal
+var pagerCmd *exec.Cmd
+
+// startPager pipes stdout through $PAGER when --pager is set and stdout is a
+// terminal. It does nothing when the report is redirected or the pager
+// cannot be started.
+func startPager() {
+	if !outputOpts.pager || stdout != io.Writer(os.Stdout) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
+	command := strings.Fields(os.Getenv("PAGER"))
+	if len(command) == 0 {
+		command = strings.Fields(defaultPager)
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	pagerCmd = cmd
+	stdout = pipe
+}
+
+// closePager ends the pager's input and waits for the user to quit it
+func closePager() {
+	if pagerCmd == nil {
+		return
+	}
+	if c, ok := stdout.(io.Closer); ok {
+		c.Close()
+	}
+	pagerCmd.Wait()
+	stdout = os.Stdout
+	pagerCmd = nil
+}
+
+// limitFindingsPerRule returns the findings to print, at most
+// --max-findings-per-rule of each rule, and how many of each were left out
+func limitFindingsPerRule(findings []finding) ([]finding, map[string]int) {
+	if outputOpts.maxFindingsPerRule <= 0 {
+		return findings, nil
+	}
+	shown := make([]finding, 0, len(findings))
+	perRule := make(map[string]int)
+	omitted := make(map[string]int)
+	for _, f := range findings {
+		perRule[f.Rule]++
+		if perRule[f.Rule] > outputOpts.maxFindingsPerRule {
+			omitted[f.Rule]++
+			continue
+		}
+		shown = append(shown, f)
+	}
+	return shown, omitted
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+)
+
+// pasteboardWriteSelectors put data on a UIPasteboard
+var pasteboardWriteSelectors = []string{
+	"setString:",
+	"setStrings:",
+	"setURL:",
+	"setImage:",
+	"setItems:",
+	"setItems:options:",
+	"setData:forPasteboardType:",
+	"addItems:",
+}
+
+// pasteboardLimitSymbols keep pasteboard contents from lingering or syncing
+// through Universal Clipboard
+var pasteboardLimitSymbols = []string{
+	"_UIPasteboardOptionExpirationDate",
+	"_UIPasteboardOptionLocalOnly",
+}
+
+// screenCaptureIndicators are symbols and selectors apps use to notice
+// screenshots or recording, or to hide content when sent to the background
+var screenCaptureIndicators = []string{
+	"_UIApplicationUserDidTakeScreenshotNotification",
+	"_UIScreenCapturedDidChangeNotification",
+	"_UISceneCaptureStateDidChangeNotification",
+	"isCaptured",
+	"sceneCaptureState",
+	"_OBJC_CLASS_$_UIVisualEffectView",
+}
+
+// privacyHygieneReport records pasteboard use and screen capture defenses
+type privacyHygieneReport struct {
+	Pasteboard        bool     `json:"pasteboard"` // writes to a UIPasteboard
+	PasteboardLimits  []string `json:"pasteboard_limits,omitempty"`
+	CaptureIndicators []string `json:"capture_indicators,omitempty"`
+}
+
+// pasteboardAnalyzer records pasteboard and screen capture selectors. These
+// are heuristics: they show what the binary can do, not what every screen does.
+type pasteboardAnalyzer struct {
+	seen map[string]bool
+}
+
+func newPasteboardAnalyzer() stringAnalyzer {
+	return &pasteboardAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *pasteboardAnalyzer) visit(s string, offset int64) {
+	if s == "generalPasteboard" || containsString(pasteboardWriteSelectors, s) || containsString(screenCaptureIndicators, s) {
+		a.seen[s] = true
+	}
+}
+
+func (a *pasteboardAnalyzer) findings(r *appReport) []finding {
+	imports := make(map[string]bool, len(r.Imports))
+	for _, sym := range r.Imports {
+		imports[sym] = true
+	}
+
+	p := &r.PrivacyHygiene
+	writes := false
+	for _, sel := range pasteboardWriteSelectors {
+		writes = writes || a.seen[sel]
+	}
+	p.Pasteboard = imports["_OBJC_CLASS_$_UIPasteboard"] && a.seen["generalPasteboard"] && writes
+	for _, sym := range pasteboardLimitSymbols {
+		if imports[sym] {
+			p.PasteboardLimits = append(p.PasteboardLimits, strings.TrimPrefix(sym, "_"))
+		}
+	}
+	for _, ind := range screenCaptureIndicators {
+		if imports[ind] || a.seen[ind] {
+			p.CaptureIndicators = append(p.CaptureIndicators, strings.TrimPrefix(ind, "_"))
+		}
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if p.Pasteboard && len(p.PasteboardLimits) == 0 {
+		findings = append(findings, finding{
+			Rule:     "pasteboard-no-expiration",
+			Severity: severityLow,
+			Title:    "General pasteboard written without expiration or local-only option",
+			Evidence: "UIPasteboard generalPasteboard",
+			Location: location,
+			Remediation: "Copy sensitive values with setItems:options: and UIPasteboardOptionExpirationDate plus UIPasteboardOptionLocalOnly, " +
+				"so they expire and do not sync to the user's other devices, or use a named app pasteboard.",
+		})
+	}
+	if len(p.CaptureIndicators) == 0 {
+		findings = append(findings, finding{
+			Rule:     "screenshot-privacy",
+			Severity: severityInfo,
+			Title:    "No screenshot or screen recording defenses found",
+			Evidence: "no capture notifications, isCaptured checks or blur views referenced",
+			Location: location,
+			Remediation: "Hide sensitive screens when the app resigns active (the app switcher snapshot) and when " +
+				"UIScreen.isCaptured is true, and react to UIApplicationUserDidTakeScreenshotNotification where appropriate.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxProductCandidates bounds the reverse-DNS strings kept while scanning,
+	// before they are matched against the bundle ID
+	maxProductCandidates = 5000
+	// maxPaywallSamples bounds how many products and flags are reported
+	maxPaywallSamples = 50
+)
+
+var (
+	// reverseDNSPattern matches identifiers shaped like StoreKit product IDs
+	reverseDNSPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+){2,}$`)
+	// productWordPattern matches words product IDs not prefixed by the bundle ID usually contain
+	productWordPattern = regexp.MustCompile(`(?i)monthly|yearly|annual|weekly|lifetime|premium|subscription|coins?\b|gems?\b|\.pro\b|\.vip\b`)
+	// premiumFlagPattern matches a normalized (lower case, no separators) key
+	// or identifier naming a premium, purchase or paywall switch
+	premiumFlagPattern = regexp.MustCompile(`^(?:is|has|did|user)?(?:premium|pro|vip|paid|purchased|subscribed|subscriber|unlocked|unlockall|unlockedall|fullversion|adsremoved|removeads|paywall)(?:user|enabled|disabled|unlocked|active|status|mode|version|features?)?$`)
+)
+
+// paywallReport lists what the client knows about purchases and premium state
+type paywallReport struct {
+	Products []string `json:"products,omitempty"` // StoreKit product identifiers
+	Flags    []string `json:"flags,omitempty"`    // premium and paywall switch names in the binary
+	Config   []string `json:"config,omitempty"`   // premium switches set in bundled plists and JSON, as "file: key = value"
+}
+
+// paywallAnalyzer collects product identifier candidates and premium flag
+// names from the binary
+type paywallAnalyzer struct {
+	candidates map[string]bool
+	flags      map[string]bool
+}
+
+func newPaywallAnalyzer() stringAnalyzer {
+	return &paywallAnalyzer{candidates: make(map[string]bool), flags: make(map[string]bool)}
+}
+
+func (a *paywallAnalyzer) visit(s string, offset int64) {
+	if len(s) > 100 {
+		return
+	}
+	if reverseDNSPattern.MatchString(s) {
+		if len(a.candidates) < maxProductCandidates {
+			a.candidates[s] = true
+		}
+		return
+	}
+	if len(s) <= 40 && isPremiumFlag(s) && len(a.flags) < maxPaywallSamples {
+		a.flags[s] = true
+	}
+}
+
+func (a *paywallAnalyzer) findings(r *appReport) []finding {
+	products := make(map[string]bool)
+	prefix := r.Metadata.BundleID + "."
+	for s := range a.candidates {
+		if strings.HasPrefix(s, "com.apple.") || len(products) >= maxPaywallSamples {
+			continue
+		}
+		if (r.Metadata.BundleID != "" && strings.HasPrefix(s, prefix)) || productWordPattern.MatchString(s) {
+			products[s] = true
+		}
+	}
+	r.Paywall.Products = sortedSet(products)
+	r.Paywall.Flags = sortedSet(a.flags)
+	return nil
+}
+
+// isPremiumFlag reports whether an identifier such as isPremium, has_pro or
+// removeAds names a premium switch
+func isPremiumFlag(s string) bool {
+	if strings.ContainsAny(s, " /:.") {
+		return false
+	}
+	normalized := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	return premiumFlagPattern.MatchString(normalized)
+}
+
+// analyzePaywallResources adds the products of bundled StoreKit configuration
+// files, and premium switches set in bundled plists and JSON, to the report
+func analyzePaywallResources(r *appReport) error {
+	products := make(map[string]bool)
+	for _, p := range r.Paywall.Products {
+		products[p] = true
+	}
+	err := walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() > maxResourceScanSize {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		var value interface{}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".storekit", ".json":
+			data, err := os.ReadFile(path)
+			if err != nil || json.Unmarshal(data, &value) != nil {
+				return nil
+			}
+		case ".plist":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			if value, err = parsePlist(data); err != nil {
+				return nil
+			}
+		default:
+			return nil
+		}
+		walkDecodedValue(value, func(key string, v interface{}) {
+			switch {
+			case key == "productID" || key == "productId" || key == "product_id":
+				if id, ok := v.(string); ok && len(products) < maxPaywallSamples {
+					products[id] = true
+				}
+			case isPremiumFlag(key) && len(r.Paywall.Config) < maxPaywallSamples:
+				if _, ok := v.(bool); ok {
+					r.Paywall.Config = append(r.Paywall.Config, fmt.Sprintf("%s: %s = %v", rel, key, v))
+				}
+			}
+		})
+		return nil
+	})
+	r.Paywall.Products = sortedSet(products)
+	sort.Strings(r.Paywall.Config)
+	return err
+}
+
+// walkDecodedValue calls fn for every key and value in a decoded plist or JSON document
+func walkDecodedValue(v interface{}, fn func(key string, v interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			fn(key, child)
+			walkDecodedValue(child, fn)
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkDecodedValue(child, fn)
+		}
+	}
+}
+
+// checkPaywall lists the purchase surface and flags premium state the client
+// decides on its own, which patched binaries and edited preferences flip
+func checkPaywall(r *appReport) []finding {
+	p := r.Paywall
+	var findings []finding
+	if len(p.Products) > 0 {
+		findings = append(findings, finding{
+			Rule:        "iap-products",
+			Severity:    severityInfo,
+			Title:       fmt.Sprintf("%d in-app purchase product identifiers", len(p.Products)),
+			Evidence:    sampleList(p.Products),
+			Location:    bundleLocation(r, r.BinaryPath),
+			Remediation: "Check that every product unlocks content through a server-verified entitlement rather than a client-side switch.",
+		})
+	}
+	if len(p.Config) > 0 {
+		findings = append(findings, finding{
+			Rule:        "paywall-config-flag",
+			Severity:    severityMedium,
+			Title:       "Premium or paywall switch set in a bundled file",
+			Evidence:    sampleList(p.Config),
+			Location:    strings.SplitN(p.Config[0], ":", 2)[0],
+			Remediation: "Do not ship premium state in the bundle; a repackaged app only has to flip the value. Derive it from verified transactions.",
+		})
+	}
+	if len(p.Flags) > 0 && (len(p.Products) > 0 || r.Receipt.Purchases || r.Receipt.StoreKit2) {
+		findings = append(findings, finding{
+			Rule:     "paywall-local-flag",
+			Severity: severityLow,
+			Title:    "Premium state may be kept in a local flag",
+			Evidence: sampleList(p.Flags),
+			Location: bundleLocation(r, r.BinaryPath),
+			Remediation: "If these names are UserDefaults keys or properties gating paid features, derive them from verified receipts or StoreKit 2 " +
+				"transactions on each launch; a local boolean is flipped with a plist editor or a one-instruction patch.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,68 @@
+package main
+
+import "strings"
+
+// Platforms an app bundle can target, named as slicePlatform names them
+const (
+	platformIOS      = "iOS"
+	platformTVOS     = "tvOS"
+	platformWatchOS  = "watchOS"
+	platformVisionOS = "visionOS"
+
+	platformMacCatalyst = "Mac Catalyst"
+	platformMacOS       = "macOS"
+)
+
+// plistPlatforms maps the platform names Xcode writes to DTPlatformName and
+// CFBundleSupportedPlatforms onto targets
+var plistPlatforms = map[string]string{
+	"iphoneos":         platformIOS,
+	"iphonesimulator":  platformIOS,
+	"appletvos":        platformTVOS,
+	"appletvsimulator": platformTVOS,
+	"watchos":          platformWatchOS,
+	"watchsimulator":   platformWatchOS,
+	"xros":             platformVisionOS,
+	"xrsimulator":      platformVisionOS,
+	"macosx":           platformMacOS,
+}
+
+// appPlatform is the OS a bundle runs on: the platform LC_BUILD_VERSION names
+// in its main binary, then the one Info.plist names, then iOS. Only the
+// slices tell Mac Catalyst apps from macOS ones. Simulator slices count for
+// their OS, so a simulator build is still checked as one. Watch apps and
+// their extensions inside an iOS IPA are watchOS bundles.
+func appPlatform(slices []sliceInfo, info map[string]interface{}) string {
+	for _, s := range slices {
+		switch p := strings.TrimSuffix(s.Platform, " Simulator"); p {
+		case platformIOS, platformTVOS, platformWatchOS, platformVisionOS, platformMacCatalyst, platformMacOS:
+			return p
+		}
+	}
+	names := append([]string{plistString(info, "DTPlatformName")}, plistStrings(info, "CFBundleSupportedPlatforms")...)
+	for _, name := range names {
+		if p, ok := plistPlatforms[strings.ToLower(name)]; ok {
+			return p
+		}
+	}
+	return platformIOS
+}
+
+// isMacPlatform reports whether platform runs on the Mac, where Intel slices
+// are not simulator builds and Catalyst and macOS code mix
+func isMacPlatform(platform string) bool {
+	return platform == platformMacCatalyst || platform == platformMacOS
+}
+
+// onPlatform reports whether a rule limited to platforms applies on platform;
+// an empty list applies everywhere
+func onPlatform(platform string, platforms []string) bool {
+	return len(platforms) == 0 || containsString(platforms, platform)
+}
+
+// atsApplies reports whether App Transport Security governs the bundle's
+// networking. WatchKit extensions have none of their own, so their
+// NSAppTransportSecurity settings are left out rather than reported as strict.
+func atsApplies(r *appReport) bool {
+	return r.Platform != platformWatchOS || !strings.HasSuffix(r.Name, ".appex")
+}
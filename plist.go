@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Plist values decode to these Go types:
+//
+//	dict    map[string]interface{}
+//	array   []interface{}
+//	string  string
+//	integer int64 (uint64 when it does not fit)
+//	real    float64
+//	bool    bool
+//	data    []byte
+//	date    time.Time
+
+const (
+	// maxPlistDepth bounds container nesting so hostile files cannot exhaust the stack
+	maxPlistDepth = 128
+	// maxPlistObjects bounds how many values a binary plist decodes to. Objects
+	// can be referenced many times, so a small file can otherwise expand
+	// exponentially.
+	maxPlistObjects = 1 << 20
+	// maxPlistFileSize skips plists larger than any real configuration file
+	maxPlistFileSize = 64 << 20
+)
+
+// plistEpoch is the reference date binary plists count seconds from
+var plistEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// readPlistFile parses a binary or XML plist whose top-level object is a dictionary
+func readPlistFile(path string) (map[string]interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxPlistFileSize {
+		return nil, fmt.Errorf("%s: plist larger than %d bytes", path, maxPlistFileSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parsePlist(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: top-level object is not a dictionary", path)
+	}
+	return dict, nil
+}
+
+// parsePlist decodes a plist in either binary (bplist00) or XML format
+func parsePlist(data []byte) (interface{}, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlist(data)
+	}
+	return parseXMLPlist(data)
+}
+
+// plistString returns dict[key] when it is a string
+func plistString(dict map[string]interface{}, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+// plistStrings returns dict[key] when it is an array, keeping its string elements
+func plistStrings(dict map[string]interface{}, key string) []string {
+	array, _ := dict[key].([]interface{})
+	var out []string
+	for _, v := range array {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// plistBool returns dict[key] when it is a boolean
+func plistBool(dict map[string]interface{}, key string) bool {
+	b, _ := dict[key].(bool)
+	return b
+}
+
+// plistDict returns dict[key] when it is a dictionary
+func plistDict(dict map[string]interface{}, key string) map[string]interface{} {
+	d, _ := dict[key].(map[string]interface{})
+	return d
+}
+
+// sortedKeys returns the keys of a plist dictionary in lexical order
+func sortedKeys(dict map[string]interface{}) []string {
+	keys := make([]string, 0, len(dict))
+	for k := range dict {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatPlistValue renders a decoded plist value on a single line for tables and reports
+func formatPlistValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = formatPlistValue(e)
+		}
+		return strings.Join(parts, ", ")
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + "=" + formatPlistValue(v[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// XML plists
+
+func parseXMLPlist(data []byte) (interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("no plist element found: %v", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "plist" {
+			value, err := nextXMLStart(dec)
+			if err != nil {
+				return nil, err
+			}
+			return parseXMLValue(dec, value, 0)
+		}
+	}
+}
+
+// errXMLEnd is returned by nextXMLStart when a container closes before another value starts
+var errXMLEnd = errors.New("end of element")
+
+// nextXMLStart skips character data and comments up to the next start element
+func nextXMLStart(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, errXMLEnd
+		}
+	}
+}
+
+// xmlText reads the character data of the current element up to its end tag
+func xmlText(dec *xml.Decoder) (string, error) {
+	var sb strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			return sb.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("unexpected <%s> inside text element", t.Name.Local)
+		}
+	}
+}
+
+func parseXMLValue(dec *xml.Decoder, start xml.StartElement, depth int) (interface{}, error) {
+	if depth > maxPlistDepth {
+		return nil, errors.New("plist nesting too deep")
+	}
+
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		for {
+			keyElem, err := nextXMLStart(dec)
+			if err == errXMLEnd {
+				return dict, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if keyElem.Name.Local != "key" {
+				return nil, fmt.Errorf("expected <key> in dict, got <%s>", keyElem.Name.Local)
+			}
+			key, err := xmlText(dec)
+			if err != nil {
+				return nil, err
+			}
+			valueElem, err := nextXMLStart(dec)
+			if err != nil {
+				return nil, fmt.Errorf("missing value for key %q", key)
+			}
+			value, err := parseXMLValue(dec, valueElem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = value
+		}
+	case "array":
+		array := []interface{}{}
+		for {
+			elem, err := nextXMLStart(dec)
+			if err == errXMLEnd {
+				return array, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseXMLValue(dec, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := xmlText(dec)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string", "key":
+		return text, nil
+	case "integer":
+		text = strings.TrimSpace(text)
+		if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return i, nil
+		}
+		u, err := strconv.ParseUint(text, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", text)
+		}
+		return u, nil
+	case "real":
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid real %q", text)
+		}
+		return f, nil
+	case "data":
+		clean := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+				return -1
+			}
+			return r
+		}, text)
+		b, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data: %v", err)
+		}
+		return b, nil
+	case "date":
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", text)
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown plist element <%s>", start.Name.Local)
+	}
+}
+
+// Binary plists
+
+// binaryPlist holds the decoding state for a bplist00 document
+type binaryPlist struct {
+	data       []byte
+	offsets    []uint64
+	refSize    int
+	inProgress map[uint64]bool // objects on the current decode path, to reject reference cycles
+	decoded    int             // values decoded so far, bounded by maxPlistObjects
+}
+
+func parseBinaryPlist(data []byte) (interface{}, error) {
+	if len(data) < 8+32 {
+		return nil, errors.New("binary plist too short")
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 {
+		return nil, errors.New("invalid binary plist trailer")
+	}
+	tableEnd := uint64(len(data) - 32)
+	if numObjects == 0 || topObject >= numObjects || tableOffset > tableEnd ||
+		numObjects > (tableEnd-tableOffset)/uint64(offsetSize) {
+		return nil, errors.New("invalid binary plist offset table")
+	}
+
+	p := &binaryPlist{data: data, refSize: refSize, inProgress: make(map[uint64]bool)}
+	p.offsets = make([]uint64, numObjects)
+	for i := range p.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		p.offsets[i] = readBigEndian(data[start : start+uint64(offsetSize)])
+		if p.offsets[i] >= tableOffset {
+			return nil, errors.New("binary plist object offset out of range")
+		}
+	}
+
+	return p.object(topObject, 0)
+}
+
+// readBigEndian decodes an unsigned big-endian integer of 1 to 8 bytes
+func readBigEndian(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// slice returns data[off:off+n] or an error when it runs past the end
+func (p *binaryPlist) slice(off, n uint64) ([]byte, error) {
+	if n > uint64(len(p.data)) || off > uint64(len(p.data))-n {
+		return nil, errors.New("binary plist object truncated")
+	}
+	return p.data[off : off+n], nil
+}
+
+// length decodes the count stored in a marker's low nibble, or in the integer that follows it when the nibble is 0xF.
+// It returns the count and the offset of the object's payload.
+func (p *binaryPlist) length(off uint64, marker byte) (uint64, uint64, error) {
+	n := uint64(marker & 0x0f)
+	if n != 0x0f {
+		return n, off + 1, nil
+	}
+	head, err := p.slice(off+1, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if head[0]>>4 != 0x1 {
+		return 0, 0, errors.New("binary plist length is not an integer")
+	}
+	size := uint64(1) << (head[0] & 0x0f)
+	if size > 8 {
+		return 0, 0, errors.New("binary plist length too large")
+	}
+	b, err := p.slice(off+2, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readBigEndian(b), off + 2 + size, nil
+}
+
+// refs reads n object references starting at off
+func (p *binaryPlist) refs(off, n uint64) ([]uint64, error) {
+	if n > uint64(len(p.data))/uint64(p.refSize) {
+		return nil, errors.New("binary plist container too large")
+	}
+	b, err := p.slice(off, n*uint64(p.refSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, n)
+	for i := range refs {
+		refs[i] = readBigEndian(b[i*p.refSize : (i+1)*p.refSize])
+	}
+	return refs, nil
+}
+
+func (p *binaryPlist) object(ref uint64, depth int) (interface{}, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, errors.New("binary plist reference out of range")
+	}
+	if depth > maxPlistDepth {
+		return nil, errors.New("plist nesting too deep")
+	}
+	if p.inProgress[ref] {
+		return nil, errors.New("binary plist contains a reference cycle")
+	}
+	p.decoded++
+	if p.decoded > maxPlistObjects {
+		return nil, errors.New("binary plist expands to too many objects")
+	}
+
+	off := p.offsets[ref]
+	head, err := p.slice(off, 1)
+	if err != nil {
+		return nil, err
+	}
+	marker := head[0]
+
+	switch marker >> 4 {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		size := uint64(1) << (marker & 0x0f)
+		if size == 16 {
+			// 128-bit integers store the value in the low 8 bytes
+			b, err := p.slice(off+9, 8)
+			if err != nil {
+				return nil, err
+			}
+			return binary.BigEndian.Uint64(b), nil
+		}
+		if size > 8 {
+			return nil, errors.New("binary plist integer too large")
+		}
+		b, err := p.slice(off+1, size)
+		if err != nil {
+			return nil, err
+		}
+		// 1, 2 and 4 byte integers are unsigned, 8 byte ones signed; both fit int64
+		return int64(readBigEndian(b)), nil
+	case 0x2:
+		size := uint64(1) << (marker & 0x0f)
+		b, err := p.slice(off+1, size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, errors.New("unsupported binary plist real size")
+	case 0x3:
+		b, err := p.slice(off+1, 8)
+		if err != nil {
+			return nil, err
+		}
+		secs := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return plistEpoch.Add(time.Duration(secs * float64(time.Second))), nil
+	case 0x4, 0x5:
+		n, start, err := p.length(off, marker)
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.slice(start, n)
+		if err != nil {
+			return nil, err
+		}
+		if marker>>4 == 0x5 {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case 0x6:
+		n, start, err := p.length(off, marker)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(p.data))/2 {
+			return nil, errors.New("binary plist object truncated")
+		}
+		b, err := p.slice(start, n*2)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0x8:
+		b, err := p.slice(off+1, uint64(marker&0x0f)+1)
+		if err != nil {
+			return nil, err
+		}
+		return readBigEndian(b), nil
+	case 0xa, 0xc:
+		n, start, err := p.length(off, marker)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := p.refs(start, n)
+		if err != nil {
+			return nil, err
+		}
+		p.inProgress[ref] = true
+		defer delete(p.inProgress, ref)
+		array := make([]interface{}, 0, len(refs))
+		for _, r := range refs {
+			v, err := p.object(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+		return array, nil
+	case 0xd:
+		n, start, err := p.length(off, marker)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(p.data)) {
+			return nil, errors.New("binary plist container too large")
+		}
+		refs, err := p.refs(start, n*2)
+		if err != nil {
+			return nil, err
+		}
+		p.inProgress[ref] = true
+		defer delete(p.inProgress, ref)
+		dict := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := p.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, errors.New("binary plist dictionary key is not a string")
+			}
+			v, err := p.object(refs[n+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("unknown binary plist marker 0x%02x", marker)
+}
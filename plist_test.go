@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// binaryPlistSeed assembles a bplist00 document from encoded objects, using
+// one-byte offsets and references, with objects[top] as the root
+func binaryPlistSeed(top int, objects ...[]byte) []byte {
+	data := []byte("bplist00")
+	var offsets []byte
+	for _, o := range objects {
+		offsets = append(offsets, byte(len(data)))
+		data = append(data, o...)
+	}
+	tableOffset := len(data)
+	data = append(data, offsets...)
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = 1, 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[16:], uint64(top))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(tableOffset))
+	return append(data, trailer...)
+}
+
+func FuzzParsePlist(f *testing.F) {
+	f.Add([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>CFBundleIdentifier</key><string>com.example.app</string>
+<key>UIRequiredDeviceCapabilities</key><array><string>arm64</string></array>
+<key>Count</key><integer>3</integer><key>Flag</key><true/>
+<key>Data</key><data>AAEC</data><key>Date</key><date>2024-01-01T00:00:00Z</date>
+</dict></plist>`))
+	// {"k": ["v", 1, true]}
+	f.Add(binaryPlistSeed(0,
+		[]byte{0xd1, 1, 2},
+		[]byte{0x51, 'k'},
+		[]byte{0xa3, 3, 4, 5},
+		[]byte{0x51, 'v'},
+		[]byte{0x10, 1},
+		[]byte{0x09},
+	))
+	// Each array holds the previous one twice, so the root expands to 2^60 values
+	bomb := [][]byte{{0x51, 'a'}}
+	for i := 1; i <= 60; i++ {
+		bomb = append(bomb, []byte{0xa2, byte(i - 1), byte(i - 1)})
+	}
+	f.Add(binaryPlistSeed(len(bomb)-1, bomb...))
+	// An array that contains itself
+	f.Add(binaryPlistSeed(0, []byte{0xa1, 0}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, err := parsePlist(data)
+		if err == nil {
+			formatPlistValue(value)
+		}
+	})
+}
+
+func TestParsePlist(t *testing.T) {
+	real := make([]byte, 9)
+	real[0] = 0x23
+	binary.BigEndian.PutUint64(real[1:], math.Float64bits(1.5))
+	date := make([]byte, 9)
+	date[0] = 0x33
+	binary.BigEndian.PutUint64(date[1:], math.Float64bits(86400))
+
+	tests := []struct {
+		name string
+		data []byte
+		want interface{}
+	}{
+		{"XML Info.plist", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<!-- generated by Xcode -->
+	<key>CFBundleIdentifier</key>
+	<string>com.example.app</string>
+	<key>UIBackgroundModes</key>
+	<array>
+		<string>fetch</string>
+		<string>remote-notification</string>
+	</array>
+	<key>NSAppTransportSecurity</key>
+	<dict>
+		<key>NSAllowsArbitraryLoads</key>
+		<true/>
+	</dict>
+	<key>Count</key>
+	<integer>-3</integer>
+	<key>Big</key>
+	<integer>18446744073709551615</integer>
+	<key>Ratio</key>
+	<real>0.5</real>
+	<key>Blob</key>
+	<data>
+	AAEC
+	</data>
+	<key>Built</key>
+	<date>2024-01-01T00:00:00Z</date>
+	<key>Empty</key>
+	<string></string>
+</dict>
+</plist>`), map[string]interface{}{
+			"CFBundleIdentifier":     "com.example.app",
+			"UIBackgroundModes":      []interface{}{"fetch", "remote-notification"},
+			"NSAppTransportSecurity": map[string]interface{}{"NSAllowsArbitraryLoads": true},
+			"Count":                  int64(-3),
+			"Big":                    uint64(math.MaxUint64),
+			"Ratio":                  0.5,
+			"Blob":                   []byte{0, 1, 2},
+			"Built":                  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			"Empty":                  "",
+		}},
+		{"XML top-level array", []byte(`<plist><array><false/><integer>0x10</integer></array></plist>`),
+			[]interface{}{false, int64(16)}},
+		// {"id": "com.example.app", "n": 300, "ok": false, "r": 1.5, "d": <2001-01-02>, "b": <dead>, "u": "é", "a": [1]}
+		{"binary dict", binaryPlistSeed(0,
+			[]byte{0xd8, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			[]byte{0x52, 'i', 'd'},
+			[]byte{0x51, 'n'},
+			[]byte{0x52, 'o', 'k'},
+			[]byte{0x51, 'r'},
+			[]byte{0x51, 'd'},
+			[]byte{0x51, 'b'},
+			[]byte{0x51, 'u'},
+			[]byte{0x51, 'a'},
+			append([]byte{0x5f, 0x10, 15}, "com.example.app"...),
+			[]byte{0x11, 0x01, 0x2c},
+			[]byte{0x08},
+			real,
+			date,
+			[]byte{0x42, 0xde, 0xad},
+			[]byte{0x61, 0x00, 0xe9},
+			[]byte{0xa1, 17},
+			[]byte{0x10, 1},
+		), map[string]interface{}{
+			"id": "com.example.app",
+			"n":  int64(300),
+			"ok": false,
+			"r":  1.5,
+			"d":  time.Date(2001, 1, 2, 0, 0, 0, 0, time.UTC),
+			"b":  []byte{0xde, 0xad},
+			"u":  "é",
+			"a":  []interface{}{int64(1)},
+		}},
+		{"binary 128-bit integer", binaryPlistSeed(0,
+			[]byte{0x14, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		), uint64(math.MaxUint64)},
+		{"binary shared reference", binaryPlistSeed(0, []byte{0xa2, 1, 1}, []byte{0x51, 'x'}),
+			[]interface{}{"x", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlist(tt.data)
+			if err != nil {
+				t.Fatalf("parsePlist: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePlistMalformed(t *testing.T) {
+	valid := binaryPlistSeed(0, []byte{0xa1, 1}, []byte{0x51, 'x'})
+	badTrailer := append([]byte(nil), valid...)
+	badTrailer[len(badTrailer)-32+6] = 0
+	badTop := append([]byte(nil), valid...)
+	badTop[len(badTop)-1-8] = 5
+
+	bomb := [][]byte{{0x51, 'a'}}
+	for i := 1; i <= 60; i++ {
+		bomb = append(bomb, []byte{0xa2, byte(i - 1), byte(i - 1)})
+	}
+	deep := strings.Repeat("<array>", maxPlistDepth+2) + strings.Repeat("</array>", maxPlistDepth+2)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "no plist element"},
+		{"not a plist", []byte("PK\x03\x04 zip data"), "no plist element"},
+		{"XML unknown element", []byte(`<plist><dict><key>k</key><blob/></dict></plist>`), "unknown plist element <blob>"},
+		{"XML value without key", []byte(`<plist><dict><string>v</string></dict></plist>`), "expected <key> in dict"},
+		{"XML key without value", []byte(`<plist><dict><key>k</key></dict></plist>`), `missing value for key "k"`},
+		{"XML invalid integer", []byte(`<plist><integer>twelve</integer></plist>`), `invalid integer "twelve"`},
+		{"XML invalid real", []byte(`<plist><real>1.2.3</real></plist>`), "invalid real"},
+		{"XML invalid data", []byte(`<plist><data>!!!</data></plist>`), "invalid data"},
+		{"XML invalid date", []byte(`<plist><date>yesterday</date></plist>`), "invalid date"},
+		{"XML element inside string", []byte(`<plist><string>a<b/>c</string></plist>`), "unexpected <b> inside text element"},
+		{"XML truncated", []byte(`<plist><dict><key>k</key><array><string>v</string>`), "EOF"},
+		{"XML nesting too deep", []byte("<plist>" + deep + "</plist>"), "plist nesting too deep"},
+		{"binary too short", []byte("bplist00\x00"), "binary plist too short"},
+		{"binary bad trailer", badTrailer, "invalid binary plist trailer"},
+		{"binary top object out of range", badTop, "invalid binary plist offset table"},
+		{"binary truncated string", binaryPlistSeed(0, []byte{0x5f, 0x10, 200}), "binary plist object truncated"},
+		{"binary reference out of range", binaryPlistSeed(0, []byte{0xa1, 9}), "binary plist reference out of range"},
+		{"binary reference cycle", binaryPlistSeed(0, []byte{0xa1, 0}), "binary plist contains a reference cycle"},
+		{"binary object bomb", binaryPlistSeed(len(bomb)-1, bomb...), "binary plist expands to too many objects"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlist(tt.data)
+			if err == nil {
+				t.Fatalf("got %#v, want an error", got)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want it to mention %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadPlistFileNotDict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Info.plist")
+	if err := os.WriteFile(path, []byte(`<plist><array/></plist>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPlistFile(path); err == nil || !strings.Contains(err.Error(), "not a dictionary") {
+		t.Errorf("got error %v, want the top-level object rejected", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// hardeningFlags maps the names a policy may require to the memory report field
+var hardeningFlags = map[string]func(m memoryReport) bool{
+	"pie":                    func(m memoryReport) bool { return m.PIE },
+	"stack_canary":           func(m memoryReport) bool { return m.StackCanary },
+	"arc":                    func(m memoryReport) bool { return m.ARC },
+	"pointer_authentication": func(m memoryReport) bool { return m.PointerAuth },
+	"no_heap_execution":      func(m memoryReport) bool { return m.NoHeapExecution },
+}
+
+// policy is an organization profile the app is evaluated against, loaded with --policy
+type policy struct {
+	Name string `json:"name"`
+	// Severity of violations; defaults to high so they fail CI
+	Severity string `json:"severity,omitempty"`
+	// AllowedSDKs lists the embedded frameworks that may ship, as glob patterns.
+	// An empty list allows any.
+	AllowedSDKs []string `json:"allowed_sdks,omitempty"`
+	// ForbiddenPermissions are Info.plist usage description keys the app may not declare
+	ForbiddenPermissions []string `json:"forbidden_permissions,omitempty"`
+	// RequiredHardening names memory protections every binary must have
+	RequiredHardening []string `json:"required_hardening,omitempty"`
+	// MaxATSExceptions caps NSExceptionDomains entries; nil means no limit
+	MaxATSExceptions *int `json:"max_ats_exceptions,omitempty"`
+	// AllowArbitraryLoads permits NSAllowsArbitraryLoads
+	AllowArbitraryLoads bool `json:"allow_arbitrary_loads,omitempty"`
+}
+
+// activePolicy is evaluated by checkPolicy; nil when --policy is not given
+var activePolicy *policy
+
+// loadPolicy reads and validates a policy file. Unknown fields are rejected
+// so that a typo cannot silently disable a rule.
+func loadPolicy(file string) (*policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	p := &policy{}
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("parsing policy %s: %v", file, err)
+	}
+
+	if p.Name == "" {
+		p.Name = file
+	}
+	switch p.Severity {
+	case "":
+		p.Severity = severityHigh
+	case severityInfo, severityLow, severityMedium, severityHigh, severityCritical:
+	default:
+		return nil, fmt.Errorf("policy %s: unknown severity %q", file, p.Severity)
+	}
+	for _, flag := range p.RequiredHardening {
+		if _, ok := hardeningFlags[flag]; !ok {
+			return nil, fmt.Errorf("policy %s: unknown hardening flag %q (available: %s)", file, flag, strings.Join(sortedKeysOf(hardeningFlags), ", "))
+		}
+	}
+	for _, pattern := range p.AllowedSDKs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("policy %s: bad SDK pattern %q: %v", file, pattern, err)
+		}
+	}
+	return p, nil
+}
+
+// checkPolicy evaluates the app against the active policy
+func checkPolicy(r *appReport) []finding {
+	p := activePolicy
+	if p == nil {
+		return nil
+	}
+
+	var findings []finding
+	violation := func(title, evidence, location string) {
+		findings = append(findings, finding{
+			Rule:        "policy-violation",
+			Severity:    p.Severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    location,
+			Remediation: "Bring the app in line with the " + p.Name + " policy or request an exception from its owners.",
+		})
+	}
+
+	if len(p.AllowedSDKs) > 0 {
+		for _, fw := range r.Frameworks {
+			if !matchesAny(fw.Name, p.AllowedSDKs) {
+				violation("SDK not on the allow list", fw.Name, "Frameworks/"+fw.Name)
+			}
+		}
+	}
+
+	for _, key := range p.ForbiddenPermissions {
+		if containsString(r.Permissions, key) {
+			violation("Forbidden permission declared", key, "Info.plist")
+		}
+	}
+
+	for _, flag := range p.RequiredHardening {
+		if !hardeningFlags[flag](r.Memory) {
+			violation("Required hardening flag missing", flag, r.Metadata.Executable)
+		}
+	}
+
+	if r.ATS.ArbitraryLoads && !p.AllowArbitraryLoads {
+		violation("ATS arbitrary loads not allowed", "NSAllowsArbitraryLoads", "Info.plist")
+	}
+	if p.MaxATSExceptions != nil && len(r.ATS.ExceptionDomains) > *p.MaxATSExceptions {
+		violation("Too many ATS exception domains",
+			fmt.Sprintf("%d exception domains, policy allows %d", len(r.ATS.ExceptionDomains), *p.MaxATSExceptions), "Info.plist")
+	}
+	return findings
+}
+
+// matchesAny reports whether name matches one of the glob patterns
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeysOf returns the keys of m in lexical order
+func sortedKeysOf[V any](m map[string]V) []string {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return sortedSet(set)
+}
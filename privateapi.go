@@ -0,0 +1,126 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// privateAPI is a known Apple private API. What it does decides how alarming
+// its use is beyond the App Review rejection itself.
+type privateAPI struct {
+	name       string
+	kind       string // class, function or selector
+	framework  string
+	capability string
+}
+
+var privateAPIs = []privateAPI{
+	{"LSApplicationWorkspace", "class", "MobileCoreServices", "enumerate, open and uninstall other apps"},
+	{"LSApplicationProxy", "class", "MobileCoreServices", "read other apps' metadata"},
+	{"MCProfileConnection", "class", "ManagedConfiguration", "inspect and install configuration profiles"},
+	{"FBSSystemService", "class", "FrontBoardServices", "launch apps and read the foreground app"},
+	{"BKSProcessAssertion", "class", "BackBoardServices", "keep running in the background"},
+	{"CTMessageCenter", "class", "CoreTelephony", "send SMS without the compose sheet"},
+	{"RadiosPreferences", "class", "AppSupport", "toggle airplane mode"},
+	{"AVSystemController", "class", "Celestial", "change the system volume"},
+	{"UIKeyboardImpl", "class", "UIKit", "observe keyboard input"},
+	{"_SBSLaunchApplicationWithIdentifier", "function", "SpringBoardServices", "launch other apps"},
+	{"_SBSCopyFrontmostApplicationDisplayIdentifier", "function", "SpringBoardServices", "read the foreground app"},
+	{"_MobileInstallationInstall", "function", "MobileInstallation", "install apps"},
+	{"_MobileInstallationUninstall", "function", "MobileInstallation", "uninstall apps"},
+	{"_MobileInstallationLookup", "function", "MobileInstallation", "list installed apps"},
+	{"_MGCopyAnswer", "function", "libMobileGestalt", "read the serial number, UDID and other device identifiers"},
+	{"_CTServerConnectionCreate", "function", "CoreTelephony", "read SIM, call and cellular state"},
+	{"_CTTelephonyCenterAddObserver", "function", "CoreTelephony", "observe calls and SMS"},
+	{"_CTSIMSupportGetSIMStatus", "function", "CoreTelephony", "read SIM status"},
+	{"_IOServiceGetMatchingService", "function", "IOKit", "read hardware identifiers"},
+	{"_IORegistryEntryCreateCFProperty", "function", "IOKit", "read hardware identifiers"},
+	{"_WiFiManagerClientCreate", "function", "MobileWiFi", "scan and join Wi-Fi networks"},
+	{"_GSSendEvent", "function", "GraphicsServices", "inject touch and key events"},
+	{"openApplicationWithBundleID:", "selector", "MobileCoreServices", "launch other apps"},
+	{"allInstalledApplications", "selector", "MobileCoreServices", "list installed apps"},
+	{"installProfileData:outError:", "selector", "ManagedConfiguration", "install configuration profiles"},
+	{"terminateWithSuccess", "selector", "UIKit", "quit the app programmatically"},
+}
+
+// privateFrameworkPattern matches paths into Apple's private frameworks
+var privateFrameworkPattern = regexp.MustCompile(`/System/Library/PrivateFrameworks/([A-Za-z0-9_]+)\.framework`)
+
+// privateAPIUse is one private API the binary references
+type privateAPIUse struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"` // class, function, selector or framework
+	Framework  string `json:"framework"`
+	Capability string `json:"capability,omitempty"`
+}
+
+// privateAPIAnalyzer matches imported symbols and selector and class name
+// strings against known private APIs. Classes usually appear as strings
+// because private ones are reached with NSClassFromString rather than linked.
+type privateAPIAnalyzer struct {
+	names      map[string]bool
+	frameworks map[string]bool
+}
+
+func newPrivateAPIAnalyzer() stringAnalyzer {
+	return &privateAPIAnalyzer{names: make(map[string]bool), frameworks: make(map[string]bool)}
+}
+
+func (a *privateAPIAnalyzer) visit(s string, offset int64) {
+	if strings.Contains(s, "/PrivateFrameworks/") {
+		if m := privateFrameworkPattern.FindStringSubmatch(s); m != nil {
+			addSample(a.frameworks, m[1])
+		}
+		return
+	}
+	for _, api := range privateAPIs {
+		if api.kind != "function" && s == api.name {
+			a.names[s] = true
+			return
+		}
+	}
+}
+
+func (a *privateAPIAnalyzer) findings(r *appReport) []finding {
+	for _, sym := range r.Imports {
+		a.names[strings.TrimPrefix(sym, "_OBJC_CLASS_$_")] = true
+	}
+
+	r.PrivateAPIs = nil
+	for _, api := range privateAPIs {
+		if a.names[api.name] {
+			r.PrivateAPIs = append(r.PrivateAPIs, privateAPIUse{
+				Name:       strings.TrimPrefix(api.name, "_"),
+				Kind:       api.kind,
+				Framework:  api.framework,
+				Capability: api.capability,
+			})
+		}
+	}
+	for _, fw := range sortedSet(a.frameworks) {
+		r.PrivateAPIs = append(r.PrivateAPIs, privateAPIUse{Name: fw + ".framework", Kind: "framework", Framework: fw})
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, use := range r.PrivateAPIs {
+		title := "Private API referenced: " + use.Name
+		evidence := use.Kind + " " + use.Name + " from " + use.Framework
+		if use.Capability != "" {
+			title += " (" + use.Capability + ")"
+		}
+		if use.Kind == "framework" {
+			evidence = "/System/Library/PrivateFrameworks/" + use.Name
+		}
+		findings = append(findings, finding{
+			Rule:     "private-api",
+			Severity: severityMedium,
+			Title:    title,
+			Evidence: evidence,
+			Location: location,
+			Remediation: "App Review rejects apps that use non-public APIs (ITMS-90338). If a third-party SDK brings the reference in, update or drop it; " +
+				"in an app from outside the App Store, treat the capability as intended behavior.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// profileRecorder accumulates wall time per pipeline stage, report step,
+// check and string analyzer for --profile
+type profileRecorder struct {
+	dir     string
+	cpu     *os.File
+	mu      sync.Mutex
+	timings map[string]*profileTiming
+	stage   string
+	started time.Time
+}
+
+// profileTiming is the time spent in one named part of the pipeline
+type profileTiming struct {
+	Name  string        `json:"name"` // e.g. stage:extract, step:analyzeVPN, analyzer:secretAnalyzer
+	Calls int           `json:"calls"`
+	Total time.Duration `json:"total_ns"`
+}
+
+// profiler is set by --profile; nil disables every measurement
+var profiler *profileRecorder
+
+// startProfiling creates dir and starts the CPU profile
+func startProfiling(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	profiler = &profileRecorder{dir: dir, cpu: f, timings: make(map[string]*profileTiming)}
+	return nil
+}
+
+// stopProfiling ends the CPU profile, writes the heap profile and the
+// timings, and prints the timings table
+func stopProfiling() {
+	p := profiler
+	if p == nil {
+		return
+	}
+	profiler = nil
+	p.stageDone()
+	pprof.StopCPUProfile()
+	p.cpu.Close()
+
+	runtime.GC()
+	if f, err := os.Create(filepath.Join(p.dir, "heap.pprof")); err == nil {
+		pprof.WriteHeapProfile(f)
+		f.Close()
+	}
+	timings := p.sorted()
+	if data, err := json.MarshalIndent(timings, "", "  "); err == nil {
+		os.WriteFile(filepath.Join(p.dir, "timings.json"), append(data, '\n'), 0644)
+	}
+	printProfile(stdout, timings)
+	activeTheme.success.Fprintln(stdout, tr("ProfileWritten", "Dir", p.dir))
+}
+
+// add records one call of d to name
+func (p *profileRecorder) add(name string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := p.timings[name]
+	if t == nil {
+		t = &profileTiming{Name: name}
+		p.timings[name] = t
+	}
+	t.Calls++
+	t.Total += d
+}
+
+// time starts timing name and returns the function that stops it, for use
+// as defer profiler.time("step:x")()
+func (p *profileRecorder) time(name string) func() {
+	if p == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { p.add(name, time.Since(start)) }
+}
+
+// stageStart ends the current pipeline stage and starts timing the next
+func (p *profileRecorder) stageStart(stage string) {
+	if p == nil {
+		return
+	}
+	p.stageDone()
+	p.mu.Lock()
+	p.stage, p.started = stage, time.Now()
+	p.mu.Unlock()
+}
+
+func (p *profileRecorder) stageDone() {
+	p.mu.Lock()
+	stage, started := p.stage, p.started
+	p.stage = ""
+	p.mu.Unlock()
+	if stage != "" {
+		p.add("stage:"+stage, time.Since(started))
+	}
+}
+
+// sorted returns the timings, slowest first
+func (p *profileRecorder) sorted() []profileTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]profileTiming, 0, len(p.timings))
+	for _, t := range p.timings {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// funcName returns the unqualified name of a function value, e.g. checkVPN
+func funcName(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// typeName returns the unqualified type name of v, e.g. secretAnalyzer
+func typeName(v interface{}) string {
+	name := fmt.Sprintf("%T", v)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// printProfile prints the timings table
+func printProfile(w io.Writer, timings []profileTiming) {
+	var rows [][]string
+	for _, t := range timings {
+		rows = append(rows, []string{t.Name, fmt.Sprint(t.Calls), t.Total.Round(time.Microsecond).String()})
+	}
+	printTable(w, tr("TableProfile"), []string{tr("ColName"), tr("ColCalls"), tr("ColTime")}, rows)
+}
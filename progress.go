@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Overall percentages at which the pipeline stages begin
+const (
+	progressExtractStart = 5
+	progressExtractEnd   = 50
+	progressBinaryStart  = 60
+)
+
+// progressEvent is a single line of the --progress jsonl stream
+type progressEvent struct {
+	Time     time.Time `json:"time"`
+	Stage    string    `json:"stage"`
+	Percent  int       `json:"percent"`
+	File     string    `json:"file,omitempty"`
+	Findings int       `json:"findings"`
+}
+
+// progressReporter tracks the current stage of a scan and, when enabled,
+// writes every change as a newline-delimited JSON event. The findings count
+// is that of the input being scanned: the findings of its app reports.
+type progressReporter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder // nil when progress output is disabled
+	sink     func(progressEvent)
+	apps     func(*appReport) // receives every app report as soon as it is complete
+	out      io.Writer        // the scan's human-readable output, stdout when nil
+	stage    string
+	percent  int
+	findings int
+}
+
+// newProgressReporter returns a reporter for the given --progress format.
+// An empty format yields a reporter that tracks state but writes nothing.
+func newProgressReporter(format string, w io.Writer) (*progressReporter, error) {
+	switch format {
+	case "":
+		return &progressReporter{}, nil
+	case "jsonl":
+		return &progressReporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, trError("ErrProgressFormat", "Format", format)
+	}
+}
+
+// newProgressSink returns a reporter that hands every event to sink and every
+// finished app report to apps, as the gRPC service does to stream progress
+// and findings back to its caller while the scan runs. The scan's
+// human-readable output goes to out.
+func newProgressSink(out io.Writer, sink func(progressEvent), apps func(*appReport)) *progressReporter {
+	return &progressReporter{out: out, sink: sink, apps: apps}
+}
+
+// output is where the scan's human-readable output goes: the report, logs
+// and non-fatal errors
+func (p *progressReporter) output() io.Writer {
+	if p.out != nil {
+		return p.out
+	}
+	return stdout
+}
+
+// stageStart records the beginning of a pipeline stage at the given overall percentage
+func (p *progressReporter) stageStart(stage string, percent int) {
+	profiler.stageStart(stage)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stage = stage
+	p.percent = percent
+	p.emit("")
+}
+
+// file reports the file currently being processed, optionally advancing the percentage
+func (p *progressReporter) file(name string, percent int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if percent > p.percent {
+		p.percent = percent
+	}
+	p.emit(name)
+}
+
+// startInput starts over for the next input of a batch, whose stages,
+// percentage and findings are counted on their own
+func (p *progressReporter) startInput() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stage, p.percent, p.findings = "", 0, 0
+}
+
+// addFindings adds n findings of a finished app report to the input's count
+func (p *progressReporter) addFindings(n int) {
+	if n == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.findings += n
+	p.emit("")
+}
+
+// appDone hands r, whose findings are final, to the app sink if there is one
+func (p *progressReporter) appDone(r *appReport) {
+	if p.apps != nil {
+		p.apps(r)
+	}
+}
+
+// emit writes the current state; callers must hold p.mu
+func (p *progressReporter) emit(file string) {
+	if p.enc == nil && p.sink == nil {
+		return
+	}
+	ev := progressEvent{
+		Time:     time.Now().UTC(),
+		Stage:    p.stage,
+		Percent:  p.percent,
+		File:     file,
+		Findings: p.findings,
+	}
+	if p.enc != nil {
+		p.enc.Encode(ev)
+	}
+	if p.sink != nil {
+		p.sink(ev)
+	}
+}
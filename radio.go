@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxBluetoothUUIDs bounds how many service and characteristic UUIDs are kept
+const maxBluetoothUUIDs = 30
+
+// bluetoothUUIDPattern matches the 128-bit UUID strings passed to CBUUID
+// UUIDWithString:. 16-bit short forms are too common in other strings to use.
+var bluetoothUUIDPattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+
+// Imports, Info.plist keys and entitlements that reveal Bluetooth, NFC and
+// ultra-wideband use
+const (
+	symCBUUIDClass            = "_OBJC_CLASS_$_CBUUID"
+	symNISessionClass         = "_OBJC_CLASS_$_NISession"
+	entNFCFormats             = "com.apple.developer.nfc.readersession.formats"
+	keyISO7816Identifiers     = "com.apple.developer.nfc.readersession.iso7816.select-identifiers"
+	keyFeliCaSystemCodes      = "com.apple.developer.nfc.readersession.felica.systemcodes"
+	keyNearbyInteractionUsage = "NSNearbyInteractionUsageDescription"
+)
+
+// bluetoothRoles maps CoreBluetooth manager classes to the role they give the app
+var bluetoothRoles = []struct {
+	Symbol string
+	Role   string
+}{
+	{"_OBJC_CLASS_$_CBCentralManager", "central"},
+	{"_OBJC_CLASS_$_CBPeripheralManager", "peripheral"},
+}
+
+// nfcSessions maps Core NFC reader session classes to what they read
+var nfcSessions = []struct {
+	Symbol string
+	Kind   string
+}{
+	{"_OBJC_CLASS_$_NFCNDEFReaderSession", "NDEF"},
+	{"_OBJC_CLASS_$_NFCTagReaderSession", "tag"},
+	{"_OBJC_CLASS_$_NFCVASReaderSession", "VAS"},
+}
+
+// radioReport is the app's hardware-adjacent surface: Bluetooth LE, NFC and
+// ultra-wideband
+type radioReport struct {
+	BluetoothRoles    []string `json:"bluetooth_roles,omitempty"` // central, peripheral
+	BluetoothUUIDs    []string `json:"bluetooth_uuids,omitempty"`
+	NFCSessions       []string `json:"nfc_sessions,omitempty"`
+	NFCFormats        []string `json:"nfc_formats,omitempty"` // from the entitlement
+	NFCAIDs           []string `json:"nfc_aids,omitempty"`    // ISO 7816 application identifiers
+	FeliCaSystemCodes []string `json:"felica_system_codes,omitempty"`
+	NearbyInteraction bool     `json:"nearby_interaction"`
+}
+
+// String summarizes the surface, e.g. "Bluetooth central (3 UUIDs), NFC NDEF (2 AIDs), UWB"
+func (rr radioReport) String() string {
+	var parts []string
+	if len(rr.BluetoothRoles) > 0 {
+		part := "Bluetooth " + strings.Join(rr.BluetoothRoles, "/")
+		if len(rr.BluetoothUUIDs) > 0 {
+			part += fmt.Sprintf(" (%d UUIDs)", len(rr.BluetoothUUIDs))
+		}
+		parts = append(parts, part)
+	}
+	if len(rr.NFCSessions) > 0 || len(rr.NFCFormats) > 0 {
+		part := "NFC"
+		if len(rr.NFCSessions) > 0 {
+			part += " " + strings.Join(rr.NFCSessions, "/")
+		}
+		if len(rr.NFCAIDs) > 0 {
+			part += fmt.Sprintf(" (%d AIDs)", len(rr.NFCAIDs))
+		}
+		parts = append(parts, part)
+	}
+	if rr.NearbyInteraction {
+		parts = append(parts, "UWB")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// radioAnalyzer records Bluetooth UUID strings. They only count when the
+// binary imports CBUUID, since UUIDs appear in binaries for many reasons.
+type radioAnalyzer struct {
+	uuids map[string]bool
+}
+
+func newRadioAnalyzer() stringAnalyzer {
+	return &radioAnalyzer{uuids: make(map[string]bool)}
+}
+
+func (a *radioAnalyzer) visit(s string, offset int64) {
+	if len(a.uuids) < maxBluetoothUUIDs && bluetoothUUIDPattern.MatchString(s) {
+		a.uuids[strings.ToUpper(s)] = true
+	}
+}
+
+func (a *radioAnalyzer) findings(r *appReport) []finding {
+	imports := make(map[string]bool, len(r.Imports))
+	for _, sym := range r.Imports {
+		imports[sym] = true
+	}
+
+	rr := &r.Radio
+	for _, b := range bluetoothRoles {
+		if imports[b.Symbol] {
+			rr.BluetoothRoles = append(rr.BluetoothRoles, b.Role)
+		}
+	}
+	if imports[symCBUUIDClass] {
+		rr.BluetoothUUIDs = sortedSet(a.uuids)
+	}
+	for _, s := range nfcSessions {
+		if imports[s.Symbol] {
+			rr.NFCSessions = append(rr.NFCSessions, s.Kind)
+		}
+	}
+	rr.NFCFormats = plistStrings(r.Entitlements, entNFCFormats)
+	rr.NFCAIDs = plistStrings(r.InfoPlist, keyISO7816Identifiers)
+	rr.FeliCaSystemCodes = plistStrings(r.InfoPlist, keyFeliCaSystemCodes)
+	rr.NearbyInteraction = imports[symNISessionClass] || plistString(r.InfoPlist, keyNearbyInteractionUsage) != ""
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if len(rr.BluetoothRoles) > 0 {
+		evidence := "CoreBluetooth " + strings.Join(rr.BluetoothRoles, ", ")
+		if len(rr.BluetoothUUIDs) > 0 {
+			evidence += "; UUIDs: " + sampleList(rr.BluetoothUUIDs)
+		}
+		findings = append(findings, finding{
+			Rule:     "bluetooth-surface",
+			Severity: severityInfo,
+			Title:    "Bluetooth LE interface",
+			Evidence: evidence,
+			Location: location,
+			Remediation: "Review the GATT services the app talks to or advertises: require pairing and encryption for characteristics carrying " +
+				"commands or personal data, and validate every value a peer writes.",
+		})
+	}
+	if len(rr.NFCSessions) > 0 || len(rr.NFCFormats) > 0 {
+		var evidence []string
+		if len(rr.NFCFormats) > 0 {
+			evidence = append(evidence, "formats: "+strings.Join(rr.NFCFormats, ", "))
+		}
+		if len(rr.NFCAIDs) > 0 {
+			evidence = append(evidence, "AIDs: "+sampleList(rr.NFCAIDs))
+		}
+		if len(rr.FeliCaSystemCodes) > 0 {
+			evidence = append(evidence, "FeliCa: "+sampleList(rr.FeliCaSystemCodes))
+		}
+		if len(rr.NFCSessions) > 0 {
+			evidence = append(evidence, "sessions: "+strings.Join(rr.NFCSessions, ", "))
+		}
+		findings = append(findings, finding{
+			Rule:     "nfc-surface",
+			Severity: severityInfo,
+			Title:    "NFC reader interface",
+			Evidence: strings.Join(evidence, "; "),
+			Location: location,
+			Remediation: "Treat tag and card responses as untrusted input; the AIDs list the smart card applications the app selects, " +
+				"which tells an attacker what to emulate.",
+		})
+	}
+	if rr.NearbyInteraction {
+		var evidence []string
+		if imports[symNISessionClass] {
+			evidence = append(evidence, "NISession")
+		}
+		if plistString(r.InfoPlist, keyNearbyInteractionUsage) != "" {
+			evidence = append(evidence, keyNearbyInteractionUsage)
+		}
+		findings = append(findings, finding{
+			Rule:        "uwb-surface",
+			Severity:    severityInfo,
+			Title:       "Ultra-wideband Nearby Interaction",
+			Evidence:    strings.Join(evidence, ", "),
+			Location:    location,
+			Remediation: "Check how discovery tokens are exchanged; distance and direction to a peer are location data and should only be shared with authenticated peers.",
+		})
+	}
+	return findings
+}
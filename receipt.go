@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+)
+
+// receiptVerifiers are symbols and class names of code that checks the
+// receipt's PKCS#7 signature and parses its ASN.1 payload, mapped to a label
+var receiptVerifiers = map[string]string{
+	"_PKCS7_verify":               "OpenSSL PKCS7_verify",
+	"PKCS7_verify":                "OpenSSL PKCS7_verify",
+	"_d2i_PKCS7":                  "OpenSSL PKCS#7",
+	"d2i_PKCS7_bio":               "OpenSSL PKCS#7",
+	"_ASN1_get_object":            "OpenSSL ASN.1",
+	"ASN1_get_object":             "OpenSSL ASN.1",
+	"AppleIncRootCertificate":     "Apple root CA pinned",
+	"AppleIncRootCertificate.cer": "Apple root CA pinned",
+	"TPInAppReceipt":              "TPInAppReceipt",
+	"RMAppReceipt":                "RMStore",
+	"RMStoreAppReceiptVerifier":   "RMStore",
+	"KvittoReceipt":               "Kvitto",
+	"InAppReceipt":                "TPInAppReceipt",
+}
+
+// storeKitPurchaseSymbols show the app sells in-app purchases
+var storeKitPurchaseSymbols = []string{"_OBJC_CLASS_$_SKPaymentQueue", "_OBJC_CLASS_$_SKProductsRequest"}
+
+// storeKit2Markers are StoreKit 2 APIs whose transactions arrive as
+// JWS that StoreKit verifies before handing them to the app
+var storeKit2Markers = []string{"AppTransaction", "currentEntitlements", "VerificationResult"}
+
+// receiptReport describes how the app validates App Store receipts
+type receiptReport struct {
+	Purchases    bool     `json:"purchases"` // sells in-app purchases through the original StoreKit API
+	Reads        bool     `json:"reads"`     // reads appStoreReceiptURL
+	Refreshes    bool     `json:"refreshes"` // refreshes the receipt with SKReceiptRefreshRequest
+	StoreKit2    bool     `json:"storekit2"` // uses StoreKit 2 verified transactions
+	Verification []string `json:"verification,omitempty"`
+	VerifyURLs   []string `json:"verify_urls,omitempty"` // Apple's verifyReceipt endpoint called from the device
+}
+
+// String summarizes receipt handling, e.g. "read, verified with OpenSSL PKCS7_verify"
+func (rr receiptReport) String() string {
+	var parts []string
+	if rr.Reads {
+		parts = append(parts, "read")
+	}
+	if len(rr.Verification) > 0 {
+		parts = append(parts, "verified with "+strings.Join(rr.Verification, ", "))
+	}
+	if len(rr.VerifyURLs) > 0 {
+		parts = append(parts, "verifyReceipt from device")
+	}
+	if rr.StoreKit2 {
+		parts = append(parts, "StoreKit 2")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// receiptAnalyzer looks for receipt access, local validation code and calls
+// to Apple's verifyReceipt endpoint
+type receiptAnalyzer struct {
+	seen       map[string]bool
+	verifiers  map[string]bool
+	verifyURLs map[string]bool
+}
+
+func newReceiptAnalyzer() stringAnalyzer {
+	return &receiptAnalyzer{seen: make(map[string]bool), verifiers: make(map[string]bool), verifyURLs: make(map[string]bool)}
+}
+
+func (a *receiptAnalyzer) visit(s string, offset int64) {
+	switch {
+	case s == "appStoreReceiptURL" || containsString(storeKit2Markers, s):
+		a.seen[s] = true
+	case receiptVerifiers[s] != "":
+		a.verifiers[receiptVerifiers[s]] = true
+	case strings.Contains(s, "itunes.apple.com/verifyReceipt"):
+		addSample(a.verifyURLs, s)
+	}
+}
+
+func (a *receiptAnalyzer) findings(r *appReport) []finding {
+	rr := receiptReport{Reads: a.seen["appStoreReceiptURL"]}
+	for _, m := range storeKit2Markers {
+		rr.StoreKit2 = rr.StoreKit2 || a.seen[m]
+	}
+	for _, sym := range r.Imports {
+		switch {
+		case containsString(storeKitPurchaseSymbols, sym):
+			rr.Purchases = true
+		case sym == "_OBJC_CLASS_$_SKReceiptRefreshRequest":
+			rr.Refreshes = true
+		case receiptVerifiers[sym] != "":
+			a.verifiers[receiptVerifiers[sym]] = true
+		}
+	}
+	rr.Verification = sortedSet(a.verifiers)
+	rr.VerifyURLs = sortedSet(a.verifyURLs)
+	r.Receipt = rr
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if len(rr.VerifyURLs) > 0 {
+		findings = append(findings, finding{
+			Rule:     "receipt-verify-from-device",
+			Severity: severityMedium,
+			Title:    "App calls Apple's verifyReceipt endpoint directly",
+			Evidence: sampleList(rr.VerifyURLs),
+			Location: location,
+			Remediation: "Validate receipts on your server, or with App Store Server API; a device talking to verifyReceipt can be pointed at a fake " +
+				"endpoint, and the endpoint is deprecated.",
+		})
+	}
+	switch {
+	case rr.Reads && len(rr.Verification) == 0 && len(rr.VerifyURLs) == 0:
+		findings = append(findings, finding{
+			Rule:     "receipt-naive-validation",
+			Severity: severityLow,
+			Title:    "Receipt read without local signature verification",
+			Evidence: "appStoreReceiptURL without PKCS#7, ASN.1 or receipt library symbols",
+			Location: location,
+			Remediation: "Unless the receipt is only forwarded to your server, check its PKCS#7 signature against Apple's root CA and compare the " +
+				"bundle ID, version and device hash; checking only the bundle ID is defeated by any forged receipt.",
+		})
+	case rr.Purchases && !rr.Reads && !rr.StoreKit2:
+		findings = append(findings, finding{
+			Rule:        "receipt-not-validated",
+			Severity:    severityInfo,
+			Title:       "In-app purchases without receipt validation in the app",
+			Evidence:    "SKPaymentQueue without appStoreReceiptURL",
+			Location:    location,
+			Remediation: "Make sure purchases are validated on a server; otherwise the app trusts the transaction state it is told.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// urlScheme is one CFBundleURLTypes entry from Info.plist
+type urlScheme struct {
+	Name    string   `json:"name"`
+	Role    string   `json:"role,omitempty"`
+	Schemes []string `json:"schemes"`
+}
+
+// framework is a dynamic library or framework shipped inside the app's Frameworks directory
+type framework struct {
+	Name       string        `json:"name"`
+	Kind       string        `json:"kind"` // "framework" or "dylib"
+	BundleID   string        `json:"bundle_id,omitempty"`
+	Version    string        `json:"version,omitempty"`
+	BinaryPath string        `json:"-"`
+	Slices     []sliceInfo   `json:"slices,omitempty"`
+	Signature  signatureInfo `json:"signature"`
+	Libraries  []string      `json:"linked_libraries,omitempty"`
+}
+
+// appMetadata is the identifying information from an app's Info.plist
+type appMetadata struct {
+	BundleID     string   `json:"bundle_id"`
+	DisplayName  string   `json:"display_name,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	Build        string   `json:"build,omitempty"`
+	Executable   string   `json:"executable,omitempty"`
+	MinimumOS    string   `json:"minimum_os,omitempty"`
+	SDK          string   `json:"sdk,omitempty"`
+	Platform     string   `json:"platform,omitempty"`
+	Xcode        string   `json:"xcode,omitempty"`
+	DeviceFamily []string `json:"device_family,omitempty"`
+}
+
+// appReport collects everything learned about one .app bundle
+type appReport struct {
+	Name             string                    `json:"name"`
+	Bundle           string                    `json:"bundle,omitempty"` // path inside Payload, e.g. Foo.app/PlugIns/Share.appex
+	Parent           string                    `json:"parent,omitempty"` // containing app of an extension, watch app or App Clip
+	Path             string                    `json:"path"`
+	BinaryPath       string                    `json:"binary_path"`
+	BinarySize       int64                     `json:"binary_size"`
+	BinarySHA256     string                    `json:"binary_sha256,omitempty"`
+	InfoPlist        map[string]interface{}    `json:"-"`
+	Metadata         appMetadata               `json:"metadata"`
+	Platform         string                    `json:"platform"` // iOS, tvOS, watchOS or visionOS, see appPlatform
+	Capabilities     []capability              `json:"capabilities"`
+	Slices           []sliceInfo               `json:"slices"`
+	Signature        signatureInfo             `json:"signature"`
+	Provisioning     *provisioningProfile      `json:"provisioning,omitempty"`
+	OTAIndicators    []string                  `json:"ota_indicators,omitempty"`
+	Libraries        []string                  `json:"linked_libraries,omitempty"`
+	Imports          []string                  `json:"-"`
+	Memory           memoryReport              `json:"memory"`
+	Keychain         keychainReport            `json:"keychain"`
+	Biometrics       biometricReport           `json:"biometrics"`
+	DataProtection   dataProtectionReport      `json:"data_protection"`
+	PrivacyHygiene   privacyHygieneReport      `json:"privacy_hygiene"`
+	Snapshot         snapshotReport            `json:"snapshot"`
+	Clipboard        clipboardReport           `json:"clipboard"`
+	Radio            radioReport               `json:"radio"`
+	LocalNetwork     localNetworkReport        `json:"local_network"`
+	VPN              vpnReport                 `json:"vpn"`
+	OAuth            oauthReport               `json:"oauth"`
+	DeepLinks        deepLinkReport            `json:"deep_links"`
+	Logging          loggingReport             `json:"logging"`
+	DecodedBlobs     []decodedBlob             `json:"decoded_blobs,omitempty"`
+	RecoveredStrings []recoveredString         `json:"recovered_strings,omitempty"`
+	Strings          stringStats               `json:"strings"`
+	InjectionSurface []injectionSurface        `json:"injection_surface,omitempty"`
+	SQL              sqlReport                 `json:"sql"`
+	GraphQL          graphQLReport             `json:"graphql"`
+	Receipt          receiptReport             `json:"receipt"`
+	Paywall          paywallReport             `json:"paywall"`
+	FeatureFlags     featureFlagReport         `json:"feature_flags"`
+	DynamicCode      dynamicCodeReport         `json:"dynamic_code"`
+	PrivateAPIs      []privateAPIUse           `json:"private_apis,omitempty"`
+	Malware          *malwareReport            `json:"malware,omitempty"`   // only with --malware
+	Runtime          *runtimeReport            `json:"runtime,omitempty"`   // only with --frida
+	AppStore         *appStoreInfo             `json:"app_store,omitempty"` // only with --app-store
+	WebView          webViewReport             `json:"webview"`
+	Endpoints        []string                  `json:"endpoints,omitempty"`
+	Trackers         []trackerMatch            `json:"trackers,omitempty"`
+	Consent          consentReport             `json:"consent"`
+	RequiredReasons  []requiredReasonUse       `json:"required_reason_apis,omitempty"`
+	Localizations    localizationReport        `json:"localizations"`
+	MediaMetadata    []mediaMetadata           `json:"media_metadata,omitempty"`
+	Resources        resourceReport            `json:"resources"`
+	KnownFiles       []knownFile               `json:"known_files,omitempty"` // only with --known-good or --known-bad
+	AntiDebug        []antiDebugMeasure        `json:"anti_debug,omitempty"`
+	Toolchain        toolchainInfo             `json:"toolchain"`
+	BuildPaths       buildPathReport           `json:"build_paths"`
+	ATS              atsPosture                `json:"ats"`
+	Permissions      []string                  `json:"permissions,omitempty"`
+	Schemes          []urlScheme               `json:"url_schemes"`
+	Entitlements     map[string]interface{}    `json:"entitlements"`
+	Frameworks       []framework               `json:"frameworks"`
+	Instrumentation  []instrumentationArtifact `json:"instrumentation,omitempty"`
+	Classes          []objcClass               `json:"objc_classes,omitempty"`
+	DSYM             []dsymMatch               `json:"dsym,omitempty"`
+	Findings         []finding                 `json:"findings"`
+	FalsePositives   []string                  `json:"false_positives,omitempty"` // fingerprints of findings left out as marked in the baseline
+
+	recorder *fragmentRecorder // with --fragments, which analyzer set what
+}
+
+// reportSteps analyze the bundle, in order, once the binary's strings have
+// been scanned. Later steps read what earlier ones recorded.
+var reportSteps = []func(r *appReport) error{
+	analyzeComponents,
+	analyzeVulnerableComponents,
+	analyzeWebContent,
+	analyzeSQLResources,
+	analyzeGraphQLResources,
+	analyzeOTAResources,
+	analyzeVPN,
+	analyzePushKeyResources,
+	analyzePaywallResources,
+	analyzeFeatureFlagResources,
+	analyzeConsent,
+	analyzeRequiredReasonAPIs,
+	analyzeLocalizations,
+	analyzeMediaMetadata,
+	analyzeResources,
+	analyzeKnownHashes,
+}
+
+// buildAppReport parses the Info.plist, entitlements and embedded frameworks of the bundle at appDir
+func buildAppReport(appDir, binaryPath string) (*appReport, error) {
+	r := &appReport{
+		Name:       filepath.Base(appDir),
+		Path:       appDir,
+		BinaryPath: binaryPath,
+		recorder:   newFragmentRecorder(),
+	}
+
+	info, err := readPlistFile(bundleInfoPlist(appDir))
+	if err != nil {
+		return nil, err
+	}
+	r.InfoPlist = info
+	r.Metadata = metadataFromInfoPlist(info)
+	r.Schemes = urlSchemes(info)
+	r.Permissions = usageDescriptions(info)
+
+	if st, err := os.Stat(binaryPath); err == nil {
+		r.BinarySize = st.Size()
+	}
+	r.BinarySHA256, _ = fileSHA256(binaryPath)
+
+	r.Entitlements, err = readEntitlements(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Slices, err = readSlices(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Platform = appPlatform(r.Slices, info)
+	r.Capabilities = evaluateCapabilities(info, r.Entitlements, r.Platform)
+	if atsApplies(r) {
+		r.ATS = readATSPosture(info)
+	} else {
+		r.ATS = atsPosture{NotApplicable: true}
+	}
+	r.Signature, err = readSignature(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Provisioning, err = readProvisioningProfile(appDir)
+	if err != nil {
+		return nil, err
+	}
+	r.Libraries, err = readLinkedLibraries(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Imports, err = readImportedSymbols(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Toolchain, err = readToolchain(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Frameworks, err = embeddedFrameworks(appDir)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Instrumentation = findInstrumentation(r)
+
+	r.Classes, err = readObjCSymbols(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := correlateDSYMs(r); err != nil {
+		return nil, err
+	}
+
+	for _, domain := range plistStrings(r.Entitlements, "com.apple.developer.associated-domains") {
+		r.Findings = append(r.Findings, finding{
+			Rule:     "associated-domain",
+			Severity: severityInfo,
+			Title:    "Associated domain",
+			Evidence: domain,
+			Location: bundleLocation(r, binaryPath),
+		})
+	}
+
+	r.recorder.record(bundleAnalyzer, r)
+	stop := profiler.time("step:analyzeBinaryStrings")
+	err = analyzeBinaryStrings(r, binaryPath)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	// The string analyzers share one pass over the binary and one fragment
+	r.recorder.record("strings", r)
+	for _, step := range reportSteps {
+		if !analyzerSelected(step) {
+			continue
+		}
+		stop := profiler.time("step:" + funcName(step))
+		err := step(r)
+		stop()
+		if err != nil {
+			return nil, err
+		}
+		r.recorder.record(analyzerLabel(step), r)
+	}
+
+	runChecks(r)
+	return r, nil
+}
+
+// metadataFromInfoPlist extracts identifying fields from an Info.plist
+func metadataFromInfoPlist(info map[string]interface{}) appMetadata {
+	m := appMetadata{
+		BundleID:    plistString(info, "CFBundleIdentifier"),
+		DisplayName: plistString(info, "CFBundleDisplayName"),
+		Version:     plistString(info, "CFBundleShortVersionString"),
+		Build:       plistString(info, "CFBundleVersion"),
+		Executable:  plistString(info, "CFBundleExecutable"),
+		MinimumOS:   plistString(info, "MinimumOSVersion"),
+		SDK:         plistString(info, "DTSDKName"),
+		Platform:    plistString(info, "DTPlatformName"),
+		Xcode:       plistString(info, "DTXcode"),
+	}
+	if m.DisplayName == "" {
+		m.DisplayName = plistString(info, "CFBundleName")
+	}
+
+	families, _ := info["UIDeviceFamily"].([]interface{})
+	for _, f := range families {
+		switch f {
+		case int64(1):
+			m.DeviceFamily = append(m.DeviceFamily, "iPhone")
+		case int64(2):
+			m.DeviceFamily = append(m.DeviceFamily, "iPad")
+		case int64(3):
+			m.DeviceFamily = append(m.DeviceFamily, "Apple TV")
+		case int64(4):
+			m.DeviceFamily = append(m.DeviceFamily, "Apple Watch")
+		case int64(6):
+			m.DeviceFamily = append(m.DeviceFamily, "Mac")
+		case int64(7):
+			m.DeviceFamily = append(m.DeviceFamily, "Apple Vision")
+		}
+	}
+	return m
+}
+
+// urlSchemes lists the custom URL schemes declared under CFBundleURLTypes
+func urlSchemes(info map[string]interface{}) []urlScheme {
+	types, _ := info["CFBundleURLTypes"].([]interface{})
+	var schemes []urlScheme
+	for _, t := range types {
+		entry, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		schemes = append(schemes, urlScheme{
+			Name:    plistString(entry, "CFBundleURLName"),
+			Role:    plistString(entry, "CFBundleTypeRole"),
+			Schemes: plistStrings(entry, "CFBundleURLSchemes"),
+		})
+	}
+	return schemes
+}
+
+// embeddedFrameworks lists the .framework bundles and loose .dylib files in
+// the Frameworks directory of the bundle at appDir
+func embeddedFrameworks(appDir string) ([]framework, error) {
+	dir := filepath.Join(bundleContents(appDir), "Frameworks")
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var frameworks []framework
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, ".framework"):
+			fw := framework{Name: strings.TrimSuffix(name, ".framework"), Kind: "framework"}
+			executable := fw.Name
+			if info, err := readPlistFile(bundleInfoPlist(filepath.Join(dir, name))); err == nil {
+				fw.BundleID = plistString(info, "CFBundleIdentifier")
+				fw.Version = plistString(info, "CFBundleShortVersionString")
+				if exe := plistString(info, "CFBundleExecutable"); exe != "" {
+					executable = exe
+				}
+			}
+			fw.BinaryPath = filepath.Join(dir, name, executable)
+			fw.Slices, _ = readSlices(fw.BinaryPath)
+			fw.Signature, _ = readSignature(fw.BinaryPath)
+			frameworks = append(frameworks, fw)
+		case strings.HasSuffix(name, ".dylib"):
+			fw := framework{Name: name, Kind: "dylib", BinaryPath: filepath.Join(dir, name)}
+			fw.Slices, _ = readSlices(fw.BinaryPath)
+			fw.Signature, _ = readSignature(fw.BinaryPath)
+			frameworks = append(frameworks, fw)
+		}
+	}
+
+	sort.Slice(frameworks, func(i, j int) bool { return frameworks[i].Name < frameworks[j].Name })
+	return frameworks, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// artifactRepository is an Artifactory or Nexus server that IPAs are fetched
+// from and reports are uploaded to. Both are configured from the environment
+// so that API keys never appear on the command line.
+type artifactRepository struct {
+	kind string // "artifactory" or "nexus"
+	base string // server URL up to, but excluding, the repository name
+	auth func(req *http.Request)
+}
+
+// publishReports uploads each report next to its artifact when the input came
+// from an artifact repository (--publish-report)
+var publishReports bool
+
+// reportSuffix is appended to an artifact's path to name its uploaded report
+const reportSuffix = ".iosdumper.json"
+
+// repositoryFor returns the server configured for an artifactory:// or nexus:// input
+//
+//	artifactory: ARTIFACTORY_URL (e.g. https://acme.jfrog.io/artifactory), ARTIFACTORY_API_KEY
+//	nexus:       NEXUS_URL (e.g. https://nexus.acme.com/repository), NEXUS_TOKEN as "name:passcode"
+func repositoryFor(kind string) (*artifactRepository, error) {
+	switch kind {
+	case "artifactory":
+		base, key := os.Getenv("ARTIFACTORY_URL"), os.Getenv("ARTIFACTORY_API_KEY")
+		if base == "" {
+			return nil, trError("ErrRepositoryEnv", "Var", "ARTIFACTORY_URL")
+		}
+		return &artifactRepository{kind: kind, base: strings.TrimSuffix(base, "/"), auth: func(req *http.Request) {
+			if key != "" {
+				req.Header.Set("X-JFrog-Art-Api", key)
+			}
+		}}, nil
+	case "nexus":
+		base, token := os.Getenv("NEXUS_URL"), os.Getenv("NEXUS_TOKEN")
+		if base == "" {
+			return nil, trError("ErrRepositoryEnv", "Var", "NEXUS_URL")
+		}
+		return &artifactRepository{kind: kind, base: strings.TrimSuffix(base, "/"), auth: func(req *http.Request) {
+			if name, pass, ok := strings.Cut(token, ":"); ok {
+				req.SetBasicAuth(name, pass)
+			}
+		}}, nil
+	}
+	return nil, fmt.Errorf("unknown repository type %q", kind)
+}
+
+// isRepositoryInput reports whether input is an artifactory:// or nexus:// coordinate
+func isRepositoryInput(input string) bool {
+	return strings.HasPrefix(input, "artifactory://") || strings.HasPrefix(input, "nexus://")
+}
+
+// parseRepositoryInput splits `<kind>://<repo>/<path>` into the server and the
+// artifact's path within it. The path is either a plain file path or Maven
+// coordinates, group:artifact:version[:classifier], which expand to the
+// standard layout with an .ipa extension.
+func parseRepositoryInput(input string) (*artifactRepository, string, error) {
+	kind, rest, _ := strings.Cut(input, "://")
+	repoName, loc, ok := strings.Cut(rest, "/")
+	if !ok || repoName == "" || loc == "" {
+		return nil, "", trError("ErrRepositoryInput", "Input", input)
+	}
+	if strings.Count(loc, ":") >= 2 {
+		parts := strings.Split(loc, ":")
+		if len(parts) > 4 {
+			return nil, "", trError("ErrRepositoryInput", "Input", input)
+		}
+		group, artifact, version := parts[0], parts[1], parts[2]
+		file := artifact + "-" + version
+		if len(parts) == 4 {
+			file += "-" + parts[3]
+		}
+		loc = path.Join(strings.ReplaceAll(group, ".", "/"), artifact, version, file+".ipa")
+	}
+	repo, err := repositoryFor(kind)
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, path.Join(repoName, loc), nil
+}
+
+// fileURL is the download or upload URL of the file at loc
+func (r *artifactRepository) fileURL(loc string) string {
+	return r.base + "/" + (&url.URL{Path: loc}).EscapedPath()
+}
+
+// fetchFromRepository downloads the artifact named by a repository input into dir
+func fetchFromRepository(ctx context.Context, input, dir string) (string, error) {
+	repo, loc, err := parseRepositoryInput(input)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repo.fileURL(loc), nil)
+	if err != nil {
+		return "", err
+	}
+	repo.auth(req)
+	return downloadHTTP(req, dir, path.Base(loc))
+}
+
+// publishReport uploads result's report when --publish-report is set and the
+// scan of an artifact repository input produced a report, printing any failure
+func publishReport(ctx context.Context, result *scanResult) {
+	if !publishReports || !isRepositoryInput(result.Input) || len(result.Apps) == 0 || result.ExitCode == exitInterrupted {
+		return
+	}
+	if err := publishRepositoryReport(ctx, result); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrPublishReport", "Err", err))
+		return
+	}
+	activeTheme.success.Fprintln(stdout, tr("ReportPublished", "Input", result.Input))
+}
+
+// publishRepositoryReport uploads result's JSON report next to the artifact it
+// was scanned from. On Artifactory the upload carries the build.name and
+// build.number properties from the JFrog CLI environment, if set, so the report
+// is listed with the build's other artifacts.
+func publishRepositoryReport(ctx context.Context, result *scanResult) error {
+	repo, loc, err := parseRepositoryInput(result.Input)
+	if err != nil {
+		return err
+	}
+	body, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	target := repo.fileURL(loc + reportSuffix)
+	if repo.kind == "artifactory" {
+		for _, prop := range []struct{ key, env string }{
+			{"build.name", "JFROG_CLI_BUILD_NAME"},
+			{"build.number", "JFROG_CLI_BUILD_NUMBER"},
+		} {
+			if v := os.Getenv(prop.env); v != "" {
+				target += ";" + prop.key + "=" + url.PathEscape(v)
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	repo.auth(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s: %s", repo.fileURL(loc+reportSuffix), resp.Status)
+	}
+	return nil
+}
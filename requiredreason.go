@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// requiredReasonCategory is one of Apple's required reason API categories: a
+// binary calling any of its symbols must give an approved reason for it under
+// NSPrivacyAccessedAPITypes in its privacy manifest, or App Store Connect
+// rejects the upload (ITMS-91053)
+type requiredReasonCategory struct {
+	name    string   // NSPrivacyAccessedAPIType without the NSPrivacyAccessedAPICategory prefix
+	symbols []string // imported symbols that count as a use
+	reasons []string // approved reason codes
+}
+
+var requiredReasonCategories = []requiredReasonCategory{
+	{"FileTimestamp", []string{
+		"_stat", "_fstat", "_lstat", "_fstatat", "_getattrlist", "_fgetattrlist", "_getattrlistat", "_getattrlistbulk",
+		"_NSFileCreationDate", "_NSFileModificationDate", "_NSURLContentModificationDateKey", "_NSURLCreationDateKey",
+	}, []string{"DDA9.1", "C617.1", "3B52.1", "0A2A.1"}},
+	{"SystemBootTime", []string{"_mach_absolute_time"}, []string{"35F9.1", "8FFB.1", "3D61.1"}},
+	{"DiskSpace", []string{
+		"_statfs", "_statvfs", "_fstatfs", "_fstatvfs", "_NSFileSystemFreeSize", "_NSFileSystemSize",
+		"_NSURLVolumeAvailableCapacityKey", "_NSURLVolumeAvailableCapacityForImportantUsageKey",
+		"_NSURLVolumeAvailableCapacityForOpportunisticUsageKey", "_NSURLVolumeTotalCapacityKey",
+	}, []string{"85F4.1", "E174.1", "7D9E.1", "B728.1"}},
+	{"ActiveKeyboards", []string{"_OBJC_CLASS_$_UITextInputMode"}, []string{"3EC4.1", "54BD.1"}},
+	{"UserDefaults", []string{"_OBJC_CLASS_$_NSUserDefaults"}, []string{"CA92.1", "1C8F.1", "C56D.1", "AC6B.1"}},
+}
+
+// requiredReasonAPIPrefix prefixes every category in a privacy manifest
+const requiredReasonAPIPrefix = "NSPrivacyAccessedAPICategory"
+
+// requiredReasonUse is a required reason API category one binary of the
+// bundle uses, with the reasons its privacy manifest declares for it
+type requiredReasonUse struct {
+	Binary    string   `json:"binary"`              // relative to the bundle
+	Component string   `json:"component,omitempty"` // embedded framework or dylib, empty for the app
+	Category  string   `json:"category"`            // e.g. UserDefaults
+	Symbols   []string `json:"symbols"`
+	Manifest  string   `json:"manifest,omitempty"` // privacy manifest covering the binary
+	Reasons   []string `json:"reasons,omitempty"`
+}
+
+// requiredReasonSummary lists the categories used, e.g. "FileTimestamp, UserDefaults (1 undeclared)"
+func requiredReasonSummary(uses []requiredReasonUse) string {
+	categories := make(map[string]bool)
+	undeclared := 0
+	for _, u := range uses {
+		categories[u.Category] = true
+		if len(u.Reasons) == 0 {
+			undeclared++
+		}
+	}
+	s := strings.Join(sortedSet(categories), ", ")
+	if undeclared > 0 {
+		s += fmt.Sprintf(" (%d undeclared)", undeclared)
+	}
+	return s
+}
+
+// analyzeRequiredReasonAPIs matches the imports of the app binary and of each
+// embedded framework against the required reason APIs. A framework is covered
+// by its own privacy manifest, as App Store Connect checks each SDK
+// separately; the app binary and loose dylibs by the app's.
+func analyzeRequiredReasonAPIs(r *appReport) error {
+	appManifest := filepath.Join(bundleResources(r.Path), "PrivacyInfo.xcprivacy")
+	r.RequiredReasons = requiredReasonUses(r, r.BinaryPath, "", r.Imports, appManifest)
+	for _, fw := range r.Frameworks {
+		imports, err := readImportedSymbols(fw.BinaryPath)
+		if err != nil {
+			continue
+		}
+		manifest, component := appManifest, fw.Name
+		if fw.Kind == "framework" {
+			manifest = filepath.Join(bundleResources(filepath.Dir(fw.BinaryPath)), "PrivacyInfo.xcprivacy")
+			component += ".framework"
+		}
+		r.RequiredReasons = append(r.RequiredReasons, requiredReasonUses(r, fw.BinaryPath, component, imports, manifest)...)
+	}
+	return nil
+}
+
+// requiredReasonUses returns the categories a binary's imports fall under
+func requiredReasonUses(r *appReport, binary, component string, imports []string, manifest string) []requiredReasonUse {
+	imported := make(map[string]bool, len(imports))
+	for _, sym := range imports {
+		imported[sym] = true
+	}
+	var declared map[string][]string
+	var uses []requiredReasonUse
+	for _, c := range requiredReasonCategories {
+		var symbols []string
+		for _, sym := range c.symbols {
+			if imported[sym] {
+				symbols = append(symbols, strings.TrimPrefix(strings.TrimPrefix(sym, "_OBJC_CLASS_$_"), "_"))
+			}
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+		if declared == nil {
+			declared = readDeclaredReasons(manifest)
+		}
+		u := requiredReasonUse{Component: component, Category: c.name, Symbols: symbols, Reasons: declared[c.name]}
+		u.Binary, _ = filepath.Rel(r.Path, binary)
+		u.Binary = filepath.ToSlash(u.Binary)
+		if _, err := os.Stat(manifest); err == nil {
+			u.Manifest, _ = filepath.Rel(r.Path, manifest)
+			u.Manifest = filepath.ToSlash(u.Manifest)
+		}
+		uses = append(uses, u)
+	}
+	return uses
+}
+
+// readDeclaredReasons maps each category in a privacy manifest's
+// NSPrivacyAccessedAPITypes to its reason codes. A missing or unreadable
+// manifest declares nothing.
+func readDeclaredReasons(path string) map[string][]string {
+	declared := make(map[string][]string)
+	manifest, err := readPlistFile(path)
+	if err != nil {
+		return declared
+	}
+	entries, _ := manifest["NSPrivacyAccessedAPITypes"].([]interface{})
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		category := strings.TrimPrefix(plistString(entry, "NSPrivacyAccessedAPIType"), requiredReasonAPIPrefix)
+		declared[category] = append(declared[category], plistStrings(entry, "NSPrivacyAccessedAPITypeReasons")...)
+	}
+	return declared
+}
+
+// checkRequiredReasonAPIs flags required reason API use the privacy manifest
+// does not justify with an approved reason
+func checkRequiredReasonAPIs(r *appReport) []finding {
+	var findings []finding
+	for _, u := range r.RequiredReasons {
+		var approved []string
+		for _, c := range requiredReasonCategories {
+			if c.name == u.Category {
+				approved = c.reasons
+			}
+		}
+		valid := false
+		for _, reason := range u.Reasons {
+			valid = valid || containsString(approved, reason)
+		}
+		if valid {
+			continue
+		}
+		category := requiredReasonAPIPrefix + u.Category
+		where := "the app's privacy manifest"
+		if strings.HasSuffix(u.Component, ".framework") {
+			where = u.Component + "'s own privacy manifest"
+		}
+		f := finding{
+			Rule:      "required-reason-undeclared",
+			Severity:  severityMedium,
+			Title:     "Required reason API used without a declared reason: " + u.Category,
+			Evidence:  sampleList(u.Symbols),
+			Location:  u.Binary,
+			Component: u.Component,
+			Remediation: "Add " + category + " to NSPrivacyAccessedAPITypes in " + where + " with the reasons that apply (" +
+				strings.Join(approved, ", ") + "); App Store Connect rejects uploads with undeclared use (ITMS-91053).",
+		}
+		if len(u.Reasons) > 0 {
+			f.Rule, f.Severity = "required-reason-invalid", severityLow
+			f.Title = "Privacy manifest gives no approved reason for " + u.Category
+			f.Evidence = category + ": " + strings.Join(u.Reasons, ", ")
+			f.Remediation = "Replace the reasons for " + category + " with approved codes (" + strings.Join(approved, ", ") + ")."
+			f.Location = u.Manifest
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// resourceSniffSize is how much of each file is read to detect its type
+	resourceSniffSize = 512
+	// maxConfigFileSize is the size above which a configuration file is unusual
+	maxConfigFileSize = 2 << 20
+	// maxResourceAnomalies bounds how many anomalies are recorded
+	maxResourceAnomalies = 50
+)
+
+// resourceSignatures map leading bytes to a file type, most specific first
+var resourceSignatures = []struct {
+	Type   string
+	Offset int
+	Magic  []byte
+}{
+	{"mach-o", 0, []byte{0xfe, 0xed, 0xfa, 0xce}},
+	{"mach-o", 0, []byte{0xfe, 0xed, 0xfa, 0xcf}},
+	{"mach-o", 0, []byte{0xce, 0xfa, 0xed, 0xfe}},
+	{"mach-o", 0, []byte{0xcf, 0xfa, 0xed, 0xfe}},
+	{"mach-o", 0, []byte{0xca, 0xfe, 0xba, 0xbe}},
+	{"mach-o", 0, []byte{0xca, 0xfe, 0xba, 0xbf}},
+	{"png", 0, []byte("\x89PNG\r\n\x1a\n")},
+	{"jpeg", 0, []byte{0xff, 0xd8, 0xff}},
+	{"gif", 0, []byte("GIF8")},
+	{"webp", 8, []byte("WEBP")},
+	{"heic", 4, []byte("ftypheic")},
+	{"heic", 4, []byte("ftypmif1")},
+	{"mp4", 4, []byte("ftyp")},
+	{"pdf", 0, []byte("%PDF-")},
+	{"zip", 0, []byte("PK\x03\x04")},
+	{"gzip", 0, []byte{0x1f, 0x8b}},
+	{"sqlite", 0, []byte("SQLite format 3\x00")},
+	{"bplist", 0, []byte("bplist")},
+	{"asset-catalog", 0, []byte("BOMStore")},
+	{"nib", 0, []byte("NIBArchive")},
+	{"font", 0, []byte{0x00, 0x01, 0x00, 0x00}},
+	{"font", 0, []byte("OTTO")},
+	{"font", 0, []byte("true")},
+	{"font", 0, []byte("wOFF")},
+	{"audio", 0, []byte("ID3")},
+	{"audio", 0, []byte("caff")},
+	{"audio", 8, []byte("WAVE")},
+	{"xml", 0, []byte("<?xml")},
+}
+
+// executableTypes and archiveTypes may hide code or payloads behind a benign extension
+var (
+	executableTypes = []string{"mach-o"}
+	archiveTypes    = []string{"zip", "gzip", "sqlite"}
+)
+
+// benignExtensions are extensions of media and data files that should never
+// hold code or archives
+var benignExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".heic": true, ".bmp": true, ".ico": true,
+	".mp3": true, ".m4a": true, ".wav": true, ".caf": true, ".aiff": true, ".mp4": true, ".mov": true,
+	".ttf": true, ".otf": true, ".txt": true, ".strings": true, ".css": true, ".html": true, ".js": true,
+	".json": true, ".plist": true, ".xml": true, ".yaml": true, ".yml": true, ".cfg": true, ".conf": true, ".ini": true,
+}
+
+// configExtensions are extensions of configuration files, which are normally small
+var configExtensions = map[string]bool{
+	".json": true, ".plist": true, ".xml": true, ".yaml": true, ".yml": true, ".cfg": true, ".conf": true, ".ini": true, ".config": true,
+}
+
+// resourceType summarizes the bundle files of one detected type
+type resourceType struct {
+	Type  string `json:"type"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// resourceAnomaly is a bundle file whose content does not fit its name or size
+type resourceAnomaly struct {
+	Path string `json:"path"`
+	Type string `json:"type"` // detected type
+	Size int64  `json:"size"`
+	Kind string `json:"kind"` // disguised-executable, disguised-archive or large-config
+}
+
+// resourceReport breaks the bundle down by detected file type
+type resourceReport struct {
+	Types     []resourceType    `json:"types,omitempty"`
+	Anomalies []resourceAnomaly `json:"anomalies,omitempty"`
+}
+
+// analyzeResources detects the type of every bundle file from its leading
+// bytes, tallies counts and sizes per type, and records files whose content
+// contradicts their extension or configuration files of unusual size
+func analyzeResources(r *appReport) error {
+	types := make(map[string]*resourceType)
+	buf := make([]byte, resourceSniffSize)
+	err := walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		n, _ := io.ReadFull(f, buf)
+		f.Close()
+		typ := sniffResourceType(buf[:n], rel)
+
+		t := types[typ]
+		if t == nil {
+			t = &resourceType{Type: typ}
+			types[typ] = t
+		}
+		t.Files++
+		t.Bytes += info.Size()
+
+		ext := strings.ToLower(filepath.Ext(rel))
+		anomaly := ""
+		switch {
+		case benignExtensions[ext] && containsString(executableTypes, typ):
+			anomaly = "disguised-executable"
+		case benignExtensions[ext] && containsString(archiveTypes, typ):
+			anomaly = "disguised-archive"
+		case configExtensions[ext] && info.Size() > maxConfigFileSize:
+			anomaly = "large-config"
+		}
+		if anomaly != "" && len(r.Resources.Anomalies) < maxResourceAnomalies {
+			r.Resources.Anomalies = append(r.Resources.Anomalies, resourceAnomaly{Path: rel, Type: typ, Size: info.Size(), Kind: anomaly})
+		}
+		return nil
+	})
+	for _, t := range types {
+		r.Resources.Types = append(r.Resources.Types, *t)
+	}
+	sort.Slice(r.Resources.Types, func(i, j int) bool {
+		a, b := r.Resources.Types[i], r.Resources.Types[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Type < b.Type
+	})
+	return err
+}
+
+// sniffResourceType names the type of a file from its leading bytes, falling
+// back to text or data. rel only names JavaScript, which has no signature.
+func sniffResourceType(head []byte, rel string) string {
+	for _, sig := range resourceSignatures {
+		if len(head) >= sig.Offset+len(sig.Magic) && bytes.Equal(head[sig.Offset:sig.Offset+len(sig.Magic)], sig.Magic) {
+			return sig.Type
+		}
+	}
+	if len(head) == 0 {
+		return "empty"
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "data"
+	}
+	switch trimmed := bytes.TrimSpace(head); {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return "json"
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return "xml"
+	case strings.HasSuffix(rel, ".js"):
+		return "javascript"
+	}
+	return "text"
+}
+
+// resourceAnomalyRules describes the finding raised for each anomaly kind
+var resourceAnomalyRules = []struct {
+	Kind        string
+	Rule        string
+	Severity    string
+	Title       string
+	Remediation string
+}{
+	{"disguised-executable", "resource-disguised-executable", severityHigh,
+		"Executable code behind an image, media or data extension",
+		"Find out what loads the file; code renamed to look like a resource escapes review and is typically copied out and dlopen()ed at runtime."},
+	{"disguised-archive", "resource-disguised-archive", severityMedium,
+		"Archive or database behind an image, media or data extension",
+		"Unpack the file and review its contents; renamed archives are used to ship content or code that is not meant to be noticed."},
+	{"large-config", "resource-large-config", severityLow,
+		fmt.Sprintf("Configuration files larger than %d MB", maxConfigFileSize>>20),
+		"Check what the file holds; oversized configuration often carries embedded data dumps, encoded payloads or test fixtures."},
+}
+
+// checkResources reports the anomalies analyzeResources recorded, one finding per kind
+func checkResources(r *appReport) []finding {
+	var findings []finding
+	for _, rule := range resourceAnomalyRules {
+		var evidence []string
+		location := ""
+		for _, a := range r.Resources.Anomalies {
+			if a.Kind != rule.Kind {
+				continue
+			}
+			if location == "" {
+				location = a.Path
+			}
+			evidence = append(evidence, fmt.Sprintf("%s (%s, %d bytes)", a.Path, a.Type, a.Size))
+		}
+		if len(evidence) > 0 {
+			findings = append(findings, finding{
+				Rule:        rule.Rule,
+				Severity:    rule.Severity,
+				Title:       rule.Title,
+				Evidence:    sampleList(evidence),
+				Location:    location,
+				Remediation: rule.Remediation,
+			})
+		}
+	}
+	return findings
+}
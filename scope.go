@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// globList is a flag.Value collecting comma-separated, repeatable path globs
+type globList []string
+
+func (g *globList) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globList) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return trError("ErrBadGlob", "Pattern", pattern, "Err", err)
+		}
+		*g = append(*g, pattern)
+	}
+	return nil
+}
+
+// scanScope limits which bundle files the resource scanners read. The main
+// binary, the code signature and the integrity checks always see every file.
+type scanScope struct {
+	include globList // --include: scan only files matching one of these
+	exclude globList // --exclude: skip files and directories matching one of these
+}
+
+// scopeOpts is set from --include and --exclude
+var scopeOpts scanScope
+
+// inScope reports whether the file at rel, relative to the .app, is scanned
+func (s scanScope) inScope(rel string) bool {
+	if matchesGlobs(rel, s.exclude) {
+		return false
+	}
+	return len(s.include) == 0 || matchesGlobs(rel, s.include)
+}
+
+// matchesGlobs reports whether rel matches one of the patterns. A pattern
+// without a slash matches the file name in any directory; otherwise it is
+// matched against the whole path, and a ** element matches any number of
+// directories.
+func matchesGlobs(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchGlobElems(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlobElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchGlobElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+// walkBundle is filepath.Walk over the bundle at root, skipping the files and
+// directories --include and --exclude leave out, and nested bundles, which
+// are analyzed on their own
+func walkBundle(root string, fn filepath.WalkFunc) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == root {
+			return fn(p, info, err)
+		}
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil {
+			return fn(p, info, err)
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if isNestedBundle(rel) || matchesGlobs(rel, scopeOpts.exclude) {
+				return filepath.SkipDir
+			}
+		} else if !scopeOpts.inScope(rel) {
+			return nil
+		}
+		return fn(p, info, err)
+	})
+}
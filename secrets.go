@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxEndpoints bounds how many distinct endpoints are kept for the report
+const maxEndpoints = 500
+
+// endpointPattern matches absolute URLs with any scheme, so WebSocket,
+// MQTT and custom protocol endpoints are found along with HTTP
+var endpointPattern = regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9+.\-]*://[A-Za-z0-9.\-]+(?::\d+)?(?:/[^\s"'<>\\` + "`" + `]*)?`)
+
+// endpointSchemes are the network protocols apps connect with, mapped to
+// whether the protocol encrypts the connection. Other schemes are kept as
+// custom protocol endpoints.
+var endpointSchemes = map[string]bool{
+	"http": false, "https": true,
+	"ws": false, "wss": true,
+	"mqtt": false, "mqtts": true,
+	"amqp": false, "amqps": true,
+	"stomp": false, "stomp+ssl": true,
+	"tcp": false, "tls": true, "ssl": true,
+	"ftp": false, "ftps": true, "sftp": true,
+	"rtmp": false, "rtmps": true,
+	"rtsp": false, "rtsps": true,
+	"xmpp":  false,
+	"redis": false, "rediss": true,
+	"grpc": false, "grpcs": true,
+	"coap": false, "coaps": true,
+	"nats": false,
+}
+
+// endpointSchemeNoise are schemes of local files and system links, which
+// are not endpoints the app connects to
+var endpointSchemeNoise = []string{"file", "itms", "itms-apps", "itms-services", "itms-beta", "app-settings", "prefs", "x-apple", "x-callback-url", "data", "about", "blob"}
+
+// endpointNoise are URL prefixes of XML namespaces and document type
+// identifiers, which are never contacted
+var endpointNoise = []string{
+	"http://www.w3.org/",
+	"http://www.apple.com/DTDs/",
+	"https://www.apple.com/DTDs/",
+	"http://ns.adobe.com/",
+	"http://purl.org/",
+	"http://schemas.",
+}
+
+// secretRule recognizes one kind of hardcoded credential. Named capture
+// groups in its pattern become the fields of its findings; they capture what
+// identifies a credential, never the secret part. The evidence is always
+// masked with redactSecret, so an identifier such as an AWS access key ID is
+// only reported in full in the fields.
+type secretRule struct {
+	name       string
+	severity   string
+	confidence string
+	pattern    *regexp.Regexp
+}
+
+var secretRules = []secretRule{
+	{"Private key", severityCritical, confidenceHigh, regexp.MustCompile(`-----BEGIN (?:(?P<key_type>RSA|EC|DSA|OPENSSH|ENCRYPTED) )?PRIVATE KEY-----`)},
+	{"Stripe live secret key", severityCritical, confidenceHigh, regexp.MustCompile(`\bsk_live_[0-9A-Za-z]{24,}`)},
+	// key_id is the whole match: an access key ID grants nothing without its
+	// secret access key, and analysts need all of it to find the key in IAM
+	{"AWS access key ID", severityHigh, confidenceHigh, regexp.MustCompile(`\b(?P<key_id>(?P<key_type>AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{"GitHub token", severityHigh, confidenceHigh, regexp.MustCompile(`\b(?P<token_type>gh[pousr])_[A-Za-z0-9]{36,}`)},
+	{"Slack token", severityHigh, confidenceHigh, regexp.MustCompile(`\b(?P<token_type>xox[abprs])-[A-Za-z0-9\-]{10,}`)},
+	{"Google API key", severityMedium, confidenceHigh, regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{"JSON Web Token", severityMedium, confidenceMedium, regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	{"Generic secret assignment", severityLow, confidenceLow, regexp.MustCompile(`(?i)\b(?P<name>api[_\-]?key|client[_\-]?secret|secret[_\-]?key|access[_\-]?token|password)["']?\s*[:=]\s*["'][A-Za-z0-9_\-+/=]{16,}["']`)},
+}
+
+// endpointAnalyzer collects the network endpoints named in a file
+type endpointAnalyzer struct {
+	urls map[string]bool
+}
+
+func newEndpointAnalyzer() stringAnalyzer {
+	return &endpointAnalyzer{urls: make(map[string]bool)}
+}
+
+func (a *endpointAnalyzer) visit(s string, offset int64) {
+	if !strings.Contains(s, "://") {
+		return
+	}
+	for _, u := range endpointPattern.FindAllString(s, -1) {
+		u = trimGluedScheme(u)
+		if containsString(endpointSchemeNoise, endpointScheme(u)) {
+			continue
+		}
+		if !hasAnyPrefix(u, endpointNoise) && len(a.urls) < maxEndpoints {
+			a.urls[strings.TrimRight(u, ".,;)")] = true
+		}
+	}
+}
+
+// findings merges the endpoints into the report. Endpoints are an inventory,
+// not findings in themselves, except for messaging and socket protocols
+// without TLS; cleartext HTTP is left to the ATS checks.
+func (a *endpointAnalyzer) findings(r *appReport) []finding {
+	// The app's own URL schemes are deep links into it, not endpoints
+	own := make(map[string]bool)
+	for _, s := range r.Schemes {
+		for _, name := range s.Schemes {
+			own[strings.ToLower(name)] = true
+		}
+	}
+	merged := make(map[string]bool, len(r.Endpoints)+len(a.urls))
+	for _, u := range r.Endpoints {
+		merged[u] = true
+	}
+	var cleartext []string
+	for u := range a.urls {
+		scheme := endpointScheme(u)
+		if own[scheme] {
+			continue
+		}
+		merged[u] = true
+		if encrypted, ok := endpointSchemes[scheme]; ok && !encrypted && scheme != "http" && !isLoopbackURL(u) {
+			cleartext = append(cleartext, u)
+		}
+	}
+	r.Endpoints = sortedSet(merged)
+
+	if len(cleartext) == 0 {
+		return nil
+	}
+	sort.Strings(cleartext)
+	return []finding{{
+		Rule:        "cleartext-socket",
+		Severity:    severityLow,
+		Title:       "WebSocket or messaging endpoint without TLS",
+		Evidence:    sampleList(cleartext),
+		Location:    bundleLocation(r, r.BinaryPath),
+		Remediation: "Connect with wss://, mqtts:// or the protocol's TLS variant; ATS does not cover raw sockets, so nothing else stops the traffic going out in cleartext.",
+	}}
+}
+
+// endpointScheme returns the lower-cased scheme of a URL
+func endpointScheme(u string) string {
+	scheme, _, _ := strings.Cut(u, "://")
+	return strings.ToLower(scheme)
+}
+
+// endpointHost returns the lower-cased host of an endpoint URL, without port
+// or IPv6 brackets
+func endpointHost(u string) string {
+	_, rest, _ := strings.Cut(u, "://")
+	host, _, _ := strings.Cut(rest, "/")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+// trimGluedScheme drops text run into the front of a known scheme, as in
+// Go binaries whose strings are not NUL-terminated ("errorwss://host")
+func trimGluedScheme(u string) string {
+	scheme := endpointScheme(u)
+	if _, ok := endpointSchemes[scheme]; ok {
+		return u
+	}
+	best := ""
+	for known := range endpointSchemes {
+		if strings.HasSuffix(scheme, known) && len(known) > len(best) {
+			best = known
+		}
+	}
+	if best == "" {
+		return u
+	}
+	return u[len(scheme)-len(best):]
+}
+
+// isLoopbackURL reports whether u points at the device itself
+func isLoopbackURL(u string) bool {
+	_, rest, _ := strings.Cut(u, "://")
+	host, _, _ := strings.Cut(rest, "/")
+	host, _, _ = strings.Cut(host, ":")
+	return host == "localhost" || host == "127.0.0.1" || host == "0.0.0.0"
+}
+
+// secretAnalyzer flags strings that look like hardcoded credentials
+type secretAnalyzer struct {
+	matches []finding
+	seen    map[string]bool
+}
+
+func newSecretAnalyzer() stringAnalyzer {
+	return &secretAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *secretAnalyzer) visit(s string, offset int64) {
+	for _, rule := range secretRules {
+		for _, m := range rule.pattern.FindAllStringSubmatch(s, -1) {
+			if a.seen[m[0]] {
+				continue
+			}
+			a.seen[m[0]] = true
+			a.matches = append(a.matches, finding{
+				Rule:        "hardcoded-secret",
+				Severity:    rule.severity,
+				Confidence:  rule.confidence,
+				Title:       "Hardcoded " + rule.name,
+				Evidence:    redactSecret(m[0]),
+				Fields:      captureFields(rule.pattern, m),
+				Remediation: "Move the credential to a server-side component or fetch it at runtime, and revoke the exposed value.",
+			})
+		}
+	}
+}
+
+func (a *secretAnalyzer) findings(r *appReport) []finding {
+	location := bundleLocation(r, r.BinaryPath)
+	for i := range a.matches {
+		a.matches[i].Location = location
+	}
+	return a.matches
+}
+
+// redactSecret keeps enough of a secret to identify it in a report without
+// making the report itself a leak
+func redactSecret(s string) string {
+	if len(s) <= 12 {
+		return strings.Repeat("*", len(s))
+	}
+	return fmt.Sprintf("%s…%s (%d chars)", s[:6], s[len(s)-2:], len(s))
+}
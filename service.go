@@ -0,0 +1,284 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative scanner.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serveTokenEnv holds the bearer token callers of the service must present
+const serveTokenEnv = "IOSDUMPER_SERVE_TOKEN"
+
+// scanService runs scans on behalf of gRPC callers. Each scan extracts to a
+// scratch directory of its own and writes its human-readable output to a
+// writer of its own, so scans run side by side, up to one per slot.
+type scanService struct {
+	UnimplementedScannerServer
+	root      string        // directory request paths are resolved in, "" to take uploads only
+	maxUpload int64         // bytes an upload may hold, 0 for no limit
+	slots     chan struct{} // one per scan that may run at once
+	log       io.Writer
+	historyMu sync.Mutex // serializes history appends from concurrent scans
+}
+
+func (s *scanService) Scan(stream Scanner_ScanServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	var input, name string
+	switch {
+	case req.Path != "" && req.UploadName == "":
+		if input, err = s.resolvePath(req.Path); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		name = req.Path
+	case req.UploadName != "" && req.Path == "":
+		dir, err := newScratchDir()
+		if err != nil {
+			return status.Error(codes.Internal, trError("ErrCreateDir", "Err", err).Error())
+		}
+		defer os.RemoveAll(dir)
+		if input, err = s.receiveUpload(stream, req, dir); err != nil {
+			return err
+		}
+		name = req.UploadName
+	default:
+		return status.Error(codes.InvalidArgument, tr("ErrScanInputRequired"))
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-stream.Context().Done():
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+
+	// Progress events may come from the analyzers' goroutines, and a stream
+	// takes one message at a time
+	var sendMu sync.Mutex
+	var sendErr error
+	send := func(msg *ScanResponse) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		if sendErr == nil {
+			sendErr = stream.Send(msg)
+		}
+	}
+	prog := newProgressSink(io.Discard, func(ev progressEvent) {
+		send(&ScanResponse{Message: &ScanResponse_Progress{Progress: &Progress{
+			Time:     timestamppb.New(ev.Time),
+			Stage:    ev.Stage,
+			Percent:  int32(ev.Percent),
+			File:     ev.File,
+			Findings: int32(ev.Findings),
+		}}})
+	}, func(app *appReport) {
+		for _, f := range app.Findings {
+			send(&ScanResponse{Message: &ScanResponse_Finding{Finding: findingMessage(app.Name, f)}})
+		}
+	})
+	result := scanInput(stream.Context(), input, prog)
+	result.Input = name
+	result.finish()
+	if sendErr != nil {
+		return sendErr
+	}
+	if historyPath != "" {
+		s.historyMu.Lock()
+		err := appendHistory(historyPath, result)
+		s.historyMu.Unlock()
+		if err != nil {
+			activeTheme.failure.Fprintln(s.log, tr("ErrHistory", "Err", err))
+		}
+	}
+	report, err := json.Marshal(result)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	msg := &ScanResult{Status: result.Status, ExitCode: int32(result.ExitCode), ReportJson: report}
+	for _, e := range result.Errors {
+		msg.Errors = append(msg.Errors, &ScanError{Code: e.Code, Stage: e.Stage, App: e.App, Message: e.Message, Fatal: e.Fatal})
+	}
+	return stream.Send(&ScanResponse{Message: &ScanResponse_Result{Result: msg}})
+}
+
+// resolvePath returns the input a request path names inside the root. Paths
+// that leave the root, through .. or a symlink, are refused, as is every path
+// when the service has no root.
+func (s *scanService) resolvePath(rel string) (string, error) {
+	if s.root == "" {
+		return "", trError("ErrServeNoRoot")
+	}
+	if filepath.IsAbs(rel) || !filepath.IsLocal(rel) {
+		return "", trError("ErrServeOutsideRoot", "Path", rel)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(s.root, rel))
+	if err != nil {
+		return "", trError("ErrServeInput", "Path", rel, "Err", err)
+	}
+	if path != s.root && !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", trError("ErrServeOutsideRoot", "Path", rel)
+	}
+	return path, nil
+}
+
+// receiveUpload writes the upload the first request starts, and the chunks
+// of the requests after it, to dir under the upload's base name
+func (s *scanService) receiveUpload(stream Scanner_ScanServer, req *ScanRequest, dir string) (string, error) {
+	name := filepath.Base(req.UploadName)
+	if !filepath.IsLocal(name) {
+		return "", status.Error(codes.InvalidArgument, trError("ErrServeUploadName", "Name", req.UploadName).Error())
+	}
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	defer f.Close()
+	var size int64
+	for {
+		size += int64(len(req.Chunk))
+		if s.maxUpload > 0 && size > s.maxUpload {
+			return "", status.Error(codes.ResourceExhausted, trError("ErrServeUploadSize", "Max", formatBytes(s.maxUpload)).Error())
+		}
+		if _, err := f.Write(req.Chunk); err != nil {
+			return "", status.Error(codes.Internal, err.Error())
+		}
+		if req, err = stream.Recv(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if req.Path != "" || req.UploadName != "" {
+			return "", status.Error(codes.InvalidArgument, tr("ErrScanInputRequired"))
+		}
+	}
+	if err := f.Close(); err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	return path, nil
+}
+
+// findingMessage converts a finding of app to its message
+func findingMessage(app string, f finding) *Finding {
+	return &Finding{
+		App:         app,
+		Rule:        f.Rule,
+		Severity:    f.Severity,
+		Confidence:  f.Confidence,
+		Title:       f.Title,
+		Evidence:    f.Evidence,
+		Fields:      f.Fields,
+		Location:    f.Location,
+		Component:   f.Component,
+		Remediation: f.Remediation,
+		Fingerprint: f.Fingerprint,
+		Occurrences: int32(f.Occurrences),
+	}
+}
+
+// requireToken returns an interceptor refusing calls that do not carry the
+// token as "authorization: Bearer <token>" metadata
+func requireToken(token string) grpc.StreamServerInterceptor {
+	want := []byte("Bearer " + token)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		for _, got := range md.Get("authorization") {
+			if subtle.ConstantTimeCompare([]byte(got), want) == 1 {
+				return handler(srv, ss)
+			}
+		}
+		return status.Error(codes.Unauthenticated, tr("ErrServeToken"))
+	}
+}
+
+// runServe implements `iosdumper serve [--listen addr] [--root dir]
+// [--tls-cert file --tls-key file] [--max-scans n] [--max-upload size]`,
+// exposing the scan engine as a gRPC service until ctx is cancelled. Callers
+// scan files under --root or upload them; when IOSDUMPER_SERVE_TOKEN is set
+// they must present it as a bearer token. Scans write nothing next to their
+// input, as under --no-write.
+func runServe(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", "localhost:50051", "Address to accept gRPC connections on")
+	root := fs.String("root", "", "Directory callers may scan files in (default uploads only)")
+	tlsCert := fs.String("tls-cert", "", "PEM certificate to serve TLS with")
+	tlsKey := fs.String("tls-key", "", "PEM private key of --tls-cert")
+	maxScans := fs.Int("max-scans", 2, "Scans that may run at once")
+	maxUpload := byteSize(4 << 30)
+	fs.Var(&maxUpload, "max-upload", "Largest upload accepted, e.g. 2G (0 for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() != 0 || (*tlsCert == "") != (*tlsKey == "") || *maxScans < 1 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrServeUsage")))
+		return exitBadInput
+	}
+
+	svc := &scanService{maxUpload: int64(maxUpload), slots: make(chan struct{}, *maxScans), log: stdout}
+	if *root != "" {
+		dir, err := filepath.Abs(*root)
+		if err == nil {
+			dir, err = filepath.EvalSymlinks(dir)
+		}
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrServe", "Err", err))
+			return exitBadInput
+		}
+		svc.root = dir
+	}
+	var opts []grpc.ServerOption
+	if *tlsCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrServe", "Err", err))
+			return exitBadInput
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if token := os.Getenv(serveTokenEnv); token != "" {
+		opts = append(opts, grpc.StreamInterceptor(requireToken(token)))
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrServe", "Err", err))
+		return exitBadInput
+	}
+	if host, _, _ := net.SplitHostPort(lis.Addr().String()); *tlsCert == "" && !net.ParseIP(host).IsLoopback() {
+		activeTheme.failure.Fprintln(stdout, tr("ServeInsecure", "Addr", lis.Addr()))
+	}
+	analysisOpts.noWrite = true
+	srv := grpc.NewServer(opts...)
+	RegisterScannerServer(srv, svc)
+
+	go func() {
+		<-ctx.Done()
+		srv.Stop()
+	}()
+
+	activeTheme.success.Fprintln(stdout, tr("Serving", "Addr", lis.Addr(), "Service", Scanner_ServiceDesc.ServiceName))
+	if err := srv.Serve(lis); err != nil && ctx.Err() == nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrServe", "Err", err))
+		return exitToolFailure
+	}
+	return exitClean
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// checkForeignSlices reports simulator slices and slices built for another
+// platform than the bundle's in the app binary and embedded frameworks. Device
+// IPAs should only carry device slices for the bundle's platform, such as
+// watchOS in a watch app; anything else is a build pipeline mistake that
+// bloats the bundle and ships code that was never meant to run on the device.
+func checkForeignSlices(r *appReport) []finding {
+	binaries := []struct {
+		path   string
+		slices []sliceInfo
+	}{{r.BinaryPath, r.Slices}}
+	for _, fw := range r.Frameworks {
+		binaries = append(binaries, struct {
+			path   string
+			slices []sliceInfo
+		}{fw.BinaryPath, fw.Slices})
+	}
+
+	var findings []finding
+	for _, bin := range binaries {
+		location, err := filepath.Rel(r.Path, bin.path)
+		if err != nil {
+			location = filepath.Base(bin.path)
+		}
+
+		for _, s := range bin.slices {
+			evidence := s.Arch
+			if s.Platform != "" {
+				evidence += " (" + s.Platform + ")"
+			}
+
+			switch {
+			case strings.HasSuffix(s.Platform, "Simulator") || ((s.Arch == "x86_64" || s.Arch == "i386") && !isMacPlatform(r.Platform)):
+				findings = append(findings, finding{
+					Rule:        "simulator-slice",
+					Severity:    severityMedium,
+					Title:       "Simulator slice shipped in device IPA",
+					Evidence:    evidence,
+					Location:    location,
+					Remediation: "Strip simulator architectures (lipo -remove) or ship XCFrameworks so only device slices are embedded.",
+				})
+			case s.Platform != "" && s.Platform != r.Platform && !(isMacPlatform(s.Platform) && isMacPlatform(r.Platform)):
+				// The rule keeps the name it had when only iOS bundles were checked
+				findings = append(findings, finding{
+					Rule:     "non-ios-slice",
+					Severity: severityMedium,
+					Title:    "Non-" + r.Platform + " slice shipped in " + r.Platform + " bundle",
+					Evidence: evidence,
+					Location: location,
+					Remediation: "Build the framework for " + r.Platform + " devices only; variants for other platforms belong in separate " +
+						"XCFramework slices.",
+				})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxSensitiveScreens bounds how many sensitive screen classes are kept
+const maxSensitiveScreens = 20
+
+// stateRestorationSelectors opt view controllers into UIKit state
+// restoration, which archives their state to Library/Saved Application State
+var stateRestorationSelectors = []string{
+	"application:shouldSaveApplicationState:",
+	"application:shouldSaveSecureApplicationState:",
+	"application:shouldRestoreApplicationState:",
+	"application:shouldRestoreSecureApplicationState:",
+	"encodeRestorableStateWithCoder:",
+	"setRestorationIdentifier:",
+	"stateRestorationActivityForScene:",
+}
+
+// snapshotDefenseSelectors hide the app's window before iOS takes the app
+// switcher snapshot, or stop it from being reused at launch
+var snapshotDefenseSelectors = []string{
+	"ignoreSnapshotOnNextApplicationLaunch",
+}
+
+// backgroundHandlerSelectors run when the app is about to be snapshotted
+var backgroundHandlerSelectors = []string{
+	"applicationWillResignActive:",
+	"applicationDidEnterBackground:",
+	"sceneWillResignActive:",
+	"sceneDidEnterBackground:",
+}
+
+// snapshotCoverSymbols are used to cover the window while in the background
+var snapshotCoverSymbols = []string{
+	"_OBJC_CLASS_$_UIVisualEffectView",
+	"_OBJC_CLASS_$_UIBlurEffect",
+}
+
+// snapshotInfoPlistKeys are the Info.plist keys that change how iOS
+// snapshots and restores the app
+var snapshotInfoPlistKeys = []string{
+	"UIApplicationExitsOnSuspend",
+	"UIApplicationSceneManifest",
+	"NSUserActivityTypes",
+}
+
+// sensitiveScreenPattern matches class names of screens that show credentials,
+// payment or account data
+var sensitiveScreenPattern = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_]{0,80})?(?:Login|SignIn|Password|Passcode|PIN|Pin|OTP|TwoFactor|Payment|Card|Wallet|Account|Bank|Transfer|Balance|Statement)[A-Za-z0-9_]{0,40}(?:ViewController|Controller|Screen|View)$`)
+
+// snapshotReport records state restoration and the app switcher snapshot defenses
+type snapshotReport struct {
+	StateRestoration []string `json:"state_restoration,omitempty"` // restoration selectors referenced
+	Defenses         []string `json:"defenses,omitempty"`          // snapshot suppression and background covers
+	InfoPlistKeys    []string `json:"info_plist_keys,omitempty"`
+	SensitiveScreens []string `json:"sensitive_screens,omitempty"`
+}
+
+// snapshotAnalyzer records state restoration selectors, snapshot defenses and
+// screens likely to show sensitive data. Like the pasteboard checks these are
+// heuristics: a referenced selector does not prove every screen is covered.
+type snapshotAnalyzer struct {
+	seen    map[string]bool
+	screens map[string]bool
+}
+
+func newSnapshotAnalyzer() stringAnalyzer {
+	return &snapshotAnalyzer{seen: make(map[string]bool), screens: make(map[string]bool)}
+}
+
+func (a *snapshotAnalyzer) visit(s string, offset int64) {
+	switch {
+	case containsString(stateRestorationSelectors, s), containsString(snapshotDefenseSelectors, s), containsString(backgroundHandlerSelectors, s):
+		a.seen[s] = true
+	case len(a.screens) < maxSensitiveScreens && sensitiveScreenPattern.MatchString(s):
+		a.screens[s] = true
+	}
+}
+
+func (a *snapshotAnalyzer) findings(r *appReport) []finding {
+	imports := make(map[string]bool, len(r.Imports))
+	for _, sym := range r.Imports {
+		imports[sym] = true
+	}
+
+	sr := &r.Snapshot
+	for _, sel := range stateRestorationSelectors {
+		if a.seen[sel] {
+			sr.StateRestoration = append(sr.StateRestoration, sel)
+		}
+	}
+	for _, sel := range snapshotDefenseSelectors {
+		if a.seen[sel] {
+			sr.Defenses = append(sr.Defenses, sel)
+		}
+	}
+	handler := ""
+	for _, sel := range backgroundHandlerSelectors {
+		if a.seen[sel] {
+			handler = sel
+			break
+		}
+	}
+	for _, sym := range snapshotCoverSymbols {
+		if handler != "" && imports[sym] {
+			sr.Defenses = append(sr.Defenses, handler+" + "+strings.TrimPrefix(sym, "_OBJC_CLASS_$_"))
+		}
+	}
+	for _, key := range snapshotInfoPlistKeys {
+		if _, ok := r.InfoPlist[key]; ok {
+			sr.InfoPlistKeys = append(sr.InfoPlistKeys, key)
+		}
+	}
+	if plistBool(r.InfoPlist, "UIApplicationExitsOnSuspend") {
+		sr.Defenses = append(sr.Defenses, "UIApplicationExitsOnSuspend")
+	}
+	for s := range a.screens {
+		sr.SensitiveScreens = append(sr.SensitiveScreens, s)
+	}
+	sort.Strings(sr.SensitiveScreens)
+
+	var sensitive []string
+	sensitive = append(sensitive, sr.SensitiveScreens...)
+	if r.Biometrics.LocalAuthentication {
+		sensitive = append(sensitive, "LocalAuthentication")
+	}
+	if len(sensitive) == 0 {
+		return nil
+	}
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	if len(sr.Defenses) == 0 {
+		evidence := "screens: " + sampleList(sensitive) + "; no ignoreSnapshotOnNextApplicationLaunch or blurred background cover"
+		if len(sr.InfoPlistKeys) > 0 {
+			evidence += "; Info.plist: " + strings.Join(sr.InfoPlistKeys, ", ")
+		}
+		findings = append(findings, finding{
+			Rule:     "snapshot-sensitive-screen",
+			Severity: severityMedium,
+			Title:    "Sensitive screens may be captured in the app switcher snapshot",
+			Evidence: evidence,
+			Location: location,
+			Remediation: "Cover the window in applicationWillResignActive: or sceneWillResignActive: (a blur or splash view) and remove it on becoming " +
+				"active; iOS writes the snapshot to Library/SplashBoard/Snapshots, where backups and jailbroken devices can read it.",
+		})
+	}
+	if len(sr.StateRestoration) > 0 {
+		findings = append(findings, finding{
+			Rule:     "state-restoration-sensitive",
+			Severity: severityLow,
+			Title:    "State restoration enabled in an app with sensitive screens",
+			Evidence: sampleList(sr.StateRestoration) + "; screens: " + sampleList(sensitive),
+			Location: location,
+			Remediation: "Exclude sensitive view controllers from restoration (no restorationIdentifier, or skip them in encodeRestorableStateWithCoder:) " +
+				"so their contents are not archived to Library/Saved Application State.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxSQLStatements bounds how many statements are quoted in the report;
+	// the counts cover all of them
+	maxSQLStatements = 50
+	// maxSQLFragmentGap is how far after a statement the next string may start
+	// to count as the literal that follows the spliced value
+	maxSQLFragmentGap = 16
+)
+
+// sqlKinds classifies a statement by its leading keywords, schema first so
+// CREATE TABLE ... AS SELECT counts as schema
+var sqlKinds = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"schema", regexp.MustCompile(`(?i)^\s*(?:create\s+(?:temp(?:orary)?\s+)?(?:unique\s+)?(?:table|index|view|trigger|virtual\s+table)\b|alter\s+table\b|drop\s+(?:table|index|view|trigger)\b|pragma\s+\w+)`)},
+	{"write", regexp.MustCompile(`(?i)^\s*(?:insert\s+(?:or\s+\w+\s+)?into\s+\S+|replace\s+into\s+\S+|update\s+\S+\s+set\s+\S+|delete\s+from\s+\S+)`)},
+	{"query", regexp.MustCompile(`(?i)^\s*(?:select\s+.+?\s+from\s+\S+|with\s+\w+.*?\bas\s*\(\s*select\b)`)},
+}
+
+var (
+	// sqlDanglingPattern matches a statement that stops where a value belongs,
+	// the literal half of "WHERE name = '" + name + "'"
+	sqlDanglingPattern = regexp.MustCompile(`(?i)(?:[=<>]|\blike|\bin\s*\(|\bvalues\s*\(|\bwhere|\band|\bor|\blimit|\border\s+by|,)\s*['"]?%?$`)
+	// sqlContinuationPattern matches the literal that closes a spliced value
+	sqlContinuationPattern = regexp.MustCompile(`(?i)^\s*(?:%?['"]|\)|(?:and|or|order\s+by|group\s+by|limit)\b)`)
+)
+
+// sqlResourceExtensions are bundle files scanned for SQL: scripts, and
+// prebuilt databases whose sqlite_master holds the schema
+var sqlResourceExtensions = map[string]bool{
+	".sql":     true,
+	".sqlite":  true,
+	".sqlite3": true,
+	".db":      true,
+}
+
+// sqlStatement is one SQL statement found in the binary or a resource
+type sqlStatement struct {
+	Kind      string `json:"kind"` // query, write or schema
+	Statement string `json:"statement"`
+	Source    string `json:"source"` // binary or bundle-relative resource path
+}
+
+// sqlReport counts and samples the SQL the app carries
+type sqlReport struct {
+	Queries    int            `json:"queries"`
+	Writes     int            `json:"writes"`
+	Schema     int            `json:"schema"`
+	Statements []sqlStatement `json:"statements,omitempty"`
+}
+
+// String summarizes the counts, e.g. "12 queries, 3 writes, 5 schema"
+func (s sqlReport) String() string {
+	if s.Queries+s.Writes+s.Schema == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d queries, %d writes, %d schema", s.Queries, s.Writes, s.Schema)
+}
+
+// add counts a classified statement and keeps it unless the sample is full
+func (s *sqlReport) add(kind, statement, source string) {
+	switch kind {
+	case "query":
+		s.Queries++
+	case "write":
+		s.Writes++
+	case "schema":
+		s.Schema++
+	}
+	if len(s.Statements) < maxSQLStatements {
+		s.Statements = append(s.Statements, sqlStatement{Kind: kind, Statement: statement, Source: source})
+	}
+}
+
+// sqlAnalyzer classifies SQL statements in the binary and flags those that
+// end where a value would be spliced in by string concatenation or Swift
+// interpolation, which compile to a literal on each side of the value
+type sqlAnalyzer struct {
+	report   sqlReport
+	seen     map[string]bool
+	dangling []string
+	// pending is the last dangling statement and where it ended, waiting for
+	// the literal that may follow it
+	pending    string
+	pendingEnd int64
+}
+
+func newSQLAnalyzer() stringAnalyzer {
+	return &sqlAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *sqlAnalyzer) visit(s string, offset int64) {
+	if a.pending != "" {
+		if offset-a.pendingEnd <= maxSQLFragmentGap && sqlContinuationPattern.MatchString(s) {
+			a.dangling[len(a.dangling)-1] += " … " + strings.TrimSpace(s)
+		}
+		a.pending = ""
+	}
+	if len(s) > maxFormatStringLength || a.seen[s] {
+		return
+	}
+	kind := classifySQL(s)
+	if kind == "" {
+		return
+	}
+	a.seen[s] = true
+	a.report.add(kind, strings.TrimSpace(s), "binary")
+
+	// Format strings are covered by the injection analyzer
+	if kind != "schema" && !objectFormatPattern.MatchString(s) && sqlDanglingPattern.MatchString(s) && len(a.dangling) < maxInjectionSamples {
+		a.dangling = append(a.dangling, strings.TrimSpace(s))
+		a.pending, a.pendingEnd = s, offset+int64(len(s))
+	}
+}
+
+func (a *sqlAnalyzer) findings(r *appReport) []finding {
+	r.SQL = a.report
+	if len(a.dangling) == 0 {
+		return nil
+	}
+
+	severity := severityLow
+	for _, sym := range r.Imports {
+		if injectionImports[sym] == "SQLite" {
+			severity = severityMedium
+			break
+		}
+	}
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	for _, s := range a.dangling {
+		findings = append(findings, finding{
+			Rule:        "sql-concatenation",
+			Severity:    severity,
+			Title:       "SQL statement appears to be built by string concatenation",
+			Evidence:    s,
+			Location:    location,
+			Remediation: "Use ? placeholders and sqlite3_bind_* (or the binding API of FMDB, GRDB or SQLite.swift) instead of concatenating or interpolating values into the statement.",
+		})
+	}
+	return findings
+}
+
+// classifySQL returns query, write or schema for a SQL statement, or "" for
+// anything else. The leading keyword must be all upper or all lower case so
+// UI text such as "Select a photo from your library" is not mistaken for SQL.
+func classifySQL(s string) string {
+	word := strings.Fields(s)
+	if len(word) < 2 || (word[0] != strings.ToUpper(word[0]) && word[0] != strings.ToLower(word[0])) {
+		return ""
+	}
+	for _, k := range sqlKinds {
+		if k.pattern.MatchString(s) {
+			return k.kind
+		}
+	}
+	return ""
+}
+
+// analyzeSQLResources adds the statements in bundled SQL scripts and
+// databases to the report's SQL section
+func analyzeSQLResources(r *appReport) error {
+	var files []string
+	walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && sqlResourceExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	for _, path := range files {
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		err = scanPrintableStrings(f, minStringLength, func(s string, offset int64) {
+			if kind := classifySQL(s); kind != "" && !seen[s] {
+				seen[s] = true
+				r.SQL.add(kind, strings.TrimSpace(s), filepath.ToSlash(rel))
+			}
+		})
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
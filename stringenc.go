@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings scanStrings tells apart. strings(1) only finds ASCII by default,
+// so Swift and Objective-C literals with non-ASCII characters, which the
+// compiler stores as UTF-16 in __ustring, never show up in its output.
+const (
+	encodingASCII   = "ascii"
+	encodingUTF8    = "utf-8"
+	encodingUTF16LE = "utf-16le"
+)
+
+// allEncodings is the order the encodings are listed in
+var allEncodings = []string{encodingASCII, encodingUTF8, encodingUTF16LE}
+
+// encodingAliases maps the names --strings-encoding accepts onto encodings
+var encodingAliases = map[string]string{
+	"ascii":    encodingASCII,
+	"utf-8":    encodingUTF8,
+	"utf8":     encodingUTF8,
+	"utf-16":   encodingUTF16LE,
+	"utf16":    encodingUTF16LE,
+	"utf-16le": encodingUTF16LE,
+}
+
+// encodingList is a flag.Value collecting comma-separated, repeatable
+// --strings-encoding arguments; empty means every encoding
+type encodingList map[string]bool
+
+func (e encodingList) String() string {
+	return strings.Join(sortedSet(e), ",")
+}
+
+func (e encodingList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		encoding, ok := encodingAliases[name]
+		if !ok {
+			return trError("ErrStringsEncoding", "Encoding", name, "Encodings", "ascii, utf-8, utf-16")
+		}
+		e[encoding] = true
+	}
+	return nil
+}
+
+// shows reports whether the strings section prints strings in encoding
+func (e encodingList) shows(encoding string) bool {
+	return len(e) == 0 || e[encoding]
+}
+
+// stringStats counts the strings of the main binary by encoding, and the
+// non-ASCII ones by the script they are written in
+type stringStats struct {
+	Encodings map[string]int `json:"encodings,omitempty"`
+	Scripts   map[string]int `json:"scripts,omitempty"` // of UTF-8 and UTF-16 strings, see stringScript
+}
+
+func (s stringStats) add(str, encoding string) {
+	s.Encodings[encoding]++
+	if encoding != encodingASCII {
+		if script := stringScript(str); script != "" {
+			s.Scripts[script]++
+		}
+	}
+}
+
+func (s stringStats) String() string {
+	var parts []string
+	for _, encoding := range allEncodings {
+		if n := s.Encodings[encoding]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, encoding))
+		}
+	}
+	summary := strings.Join(parts, ", ")
+	if len(s.Scripts) == 0 {
+		return summary
+	}
+	scripts := make([]string, 0, len(s.Scripts))
+	for script := range s.Scripts {
+		scripts = append(scripts, script)
+	}
+	sort.Slice(scripts, func(i, j int) bool {
+		if s.Scripts[scripts[i]] != s.Scripts[scripts[j]] {
+			return s.Scripts[scripts[i]] > s.Scripts[scripts[j]]
+		}
+		return scripts[i] < scripts[j]
+	})
+	for i, script := range scripts {
+		scripts[i] = fmt.Sprintf("%s %d", script, s.Scripts[script])
+	}
+	return summary + " (" + strings.Join(scripts, ", ") + ")"
+}
+
+// scriptTables name the writing systems stringScript recognizes. Japanese
+// is told from Chinese by its kana, as both use Han characters.
+var scriptTables = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"latin", unicode.Latin},
+	{"cyrillic", unicode.Cyrillic},
+	{"greek", unicode.Greek},
+	{"arabic", unicode.Arabic},
+	{"hebrew", unicode.Hebrew},
+	{"devanagari", unicode.Devanagari},
+	{"thai", unicode.Thai},
+	{"korean", unicode.Hangul},
+	{"japanese", unicode.Hiragana},
+	{"japanese", unicode.Katakana},
+	{"chinese", unicode.Han},
+}
+
+// stringScript names the script the letters of s are written in: latin
+// when there are only Latin letters, otherwise the one other script they
+// use, "other" for scripts not in scriptTables and "mixed" for more than
+// one. Strings without letters have no script.
+func stringScript(s string) string {
+	found := make(map[string]bool)
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		found[letterScript(r)] = true
+	}
+	if found["japanese"] {
+		delete(found, "chinese")
+	}
+	if len(found) > 1 {
+		delete(found, "latin")
+	}
+	switch len(found) {
+	case 0:
+		return ""
+	case 1:
+		for name := range found {
+			return name
+		}
+	}
+	return "mixed"
+}
+
+// letterScript names the script of the letter r. Latin letters beyond the
+// ones European and Vietnamese text uses, such as IPA, count as other.
+func letterScript(r rune) string {
+	for _, t := range scriptTables {
+		if !unicode.Is(t.table, r) {
+			continue
+		}
+		if t.name == "latin" && r >= 0x80 && !(r >= 0xc0 && r <= 0x24f) && !(r >= 0x1e00 && r <= 0x1eff) {
+			break
+		}
+		return t.name
+	}
+	return "other"
+}
+
+// scanStrings calls fn for every string of at least minLen characters in
+// f, along with its byte offset and encoding:
+//   - runs of printable ASCII, exactly as scanPrintableStrings finds them
+//   - NUL-terminated runs of printable UTF-8 with at least one non-ASCII
+//     character that read as text, see looksLikeText; their ASCII parts
+//     are reported as ASCII strings too
+//   - the UTF-16 strings of a Mach-O file's __ustring section, split at
+//     unprintable characters
+//
+// Decoding the whole file as UTF-16 turns instructions and tables into
+// plausible Chinese text, so only the section the compiler keeps UTF-16
+// literals in is read as UTF-16.
+func scanStrings(f *os.File, minLen int, fn func(s string, offset int64, encoding string)) error {
+	ascii := asciiScanner{minLen: minLen, fn: fn}
+	u8 := utf8Scanner{minLen: minLen, fn: fn}
+	buf := make([]byte, 1<<20)
+	var offset int64
+	for {
+		n, err := f.Read(buf)
+		for _, c := range buf[:n] {
+			ascii.feed(c, offset)
+			u8.feed(c, offset)
+			offset++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	ascii.flush()
+	u8.flush(false)
+	return scanUTF16Strings(f, minLen, fn)
+}
+
+// scanUTF16Strings reports the strings of the __ustring section of the
+// first slice that has one; the other slices hold the same literals. Files
+// that are not Mach-O have none.
+func scanUTF16Strings(r io.ReaderAt, minLen int, fn func(s string, offset int64, encoding string)) error {
+	slices, err := machoSlices(r)
+	if err != nil {
+		return nil
+	}
+	for _, s := range slices {
+		sect := s.Section("__ustring")
+		if sect == nil || isZerofill(sect) {
+			continue
+		}
+		data, err := sect.Data()
+		if err != nil {
+			return err
+		}
+		var run []rune
+		var start int64
+		flush := func() {
+			if len(run) >= minLen {
+				if len(run) > maxStringLength {
+					run = run[:maxStringLength]
+				}
+				fn(string(run), start, encodingUTF16LE)
+			}
+			run = run[:0]
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+		for i := 0; i < len(units); i++ {
+			r, width := rune(units[i]), 1
+			if utf16.IsSurrogate(r) && i+1 < len(units) {
+				if pair := utf16.DecodeRune(r, rune(units[i+1])); pair != unicode.ReplacementChar {
+					r, width = pair, 2
+				}
+			}
+			if r != '\t' && !unicode.IsPrint(r) {
+				flush()
+				i += width - 1
+				continue
+			}
+			if len(run) == 0 {
+				start = s.offset + int64(sect.Offset) + int64(2*i)
+			}
+			run = append(run, r)
+			i += width - 1
+		}
+		flush()
+		return nil
+	}
+	return nil
+}
+
+// asciiScanner collects runs of printable ASCII and tabs
+type asciiScanner struct {
+	minLen int
+	fn     func(s string, offset int64, encoding string)
+	run    []byte
+	n      int
+	start  int64
+}
+
+func (a *asciiScanner) feed(c byte, offset int64) {
+	if c != '\t' && (c < 0x20 || c >= 0x7f) {
+		a.flush()
+		return
+	}
+	if a.n == 0 {
+		a.start = offset
+	}
+	if len(a.run) < maxStringLength {
+		a.run = append(a.run, c)
+	}
+	a.n++
+}
+
+func (a *asciiScanner) flush() {
+	if a.n >= a.minLen {
+		a.fn(string(a.run), a.start, encodingASCII)
+	}
+	a.run = a.run[:0]
+	a.n = 0
+}
+
+// utf8Scanner collects runs of printable UTF-8 characters
+type utf8Scanner struct {
+	minLen   int
+	fn       func(s string, offset int64, encoding string)
+	run      []byte
+	n        int
+	start    int64
+	nonASCII bool
+	seq      []byte // a multibyte character read so far
+	seqStart int64
+	seqLen   int
+}
+
+func (u *utf8Scanner) feed(c byte, offset int64) {
+	if len(u.seq) > 0 {
+		if utf8.RuneStart(c) {
+			// A truncated sequence ends the run; c may start the next one
+			u.seq = u.seq[:0]
+			u.flush(false)
+		} else {
+			u.seq = append(u.seq, c)
+			if len(u.seq) < u.seqLen {
+				return
+			}
+			r, _ := utf8.DecodeRune(u.seq)
+			u.seq = u.seq[:0]
+			if r == utf8.RuneError || !unicode.IsPrint(r) {
+				u.flush(false)
+				return
+			}
+			u.append(r, u.seqStart)
+			u.nonASCII = true
+			return
+		}
+	}
+	switch {
+	case c == '\t' || (c >= 0x20 && c < 0x7f):
+		u.append(rune(c), offset)
+	case c >= 0xc2 && c <= 0xf4:
+		u.seq = append(u.seq, c)
+		u.seqStart = offset
+		u.seqLen = 2
+		if c >= 0xe0 {
+			u.seqLen++
+		}
+		if c >= 0xf0 {
+			u.seqLen++
+		}
+	case c == 0:
+		u.flush(true)
+	default:
+		u.flush(false)
+	}
+}
+
+func (u *utf8Scanner) append(r rune, offset int64) {
+	if u.n == 0 {
+		u.start = offset
+	}
+	if len(u.run)+utf8.RuneLen(r) <= maxStringLength {
+		u.run = utf8.AppendRune(u.run, r)
+	}
+	u.n++
+}
+
+func (u *utf8Scanner) flush(terminated bool) {
+	if terminated && u.nonASCII && u.n >= u.minLen && looksLikeText(string(u.run)) {
+		u.fn(string(u.run), u.start, encodingUTF8)
+	}
+	u.run = u.run[:0]
+	u.n = 0
+	u.nonASCII = false
+}
+
+// textPunctuation is the punctuation looksLikeText accepts in sentences
+const textPunctuation = ".,;:!?'\"()-…«»„“”‘’¡¿、。，！？：；「」（）"
+
+// looksLikeText reports whether s is nearly all letters, spaces and
+// sentence punctuation, with at least two non-ASCII letters of one script
+// that stringScript recognizes. Text in another script may quote English
+// words, but mostly uses its own letters and no accented Latin ones, which
+// is what bytes of code decode to beside it.
+func looksLikeText(s string) bool {
+	text, n, ascii, latin, other := 0, 0, 0, 0, 0
+	for _, r := range s {
+		n++
+		if unicode.IsMark(r) || r == ' ' || strings.ContainsRune(textPunctuation, r) {
+			text++
+		}
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		text++
+		switch {
+		case r < 0x80:
+			ascii++
+		case letterScript(r) == "latin":
+			latin++
+		default:
+			other++
+		}
+	}
+	if text*10 < n*9 || latin+other < 2 {
+		return false
+	}
+	switch stringScript(s) {
+	case "", "other", "mixed":
+		return false
+	case "latin":
+		return true
+	}
+	return latin == 0 && other > ascii
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// machoUStringOffset is where machoWithUString places the section data
+const machoUStringOffset = 32 + 72 + 80
+
+// machoWithUString assembles a thin arm64 Mach-O whose only section is a
+// __TEXT,__ustring holding units
+func machoWithUString(units []uint16) []byte {
+	data := make([]byte, 0, machoUStringOffset+2*len(units))
+	le := binary.LittleEndian
+	name := func(s string) []byte { return append([]byte(s), make([]byte, 16-len(s))...) }
+
+	// mach_header_64: magic, cputype, cpusubtype, filetype, ncmds, sizeofcmds, flags, reserved
+	for _, v := range []uint32{0xfeedfacf, 0x0100000c, 0, 2, 1, 72 + 80, 0, 0} {
+		data = le.AppendUint32(data, v)
+	}
+	// segment_command_64
+	data = le.AppendUint32(data, 0x19)
+	data = le.AppendUint32(data, 72+80)
+	data = append(data, name("__TEXT")...)
+	size := uint64(machoUStringOffset + 2*len(units))
+	for _, v := range []uint64{0, size, 0, size} { // vmaddr, vmsize, fileoff, filesize
+		data = le.AppendUint64(data, v)
+	}
+	for _, v := range []uint32{5, 5, 1, 0} { // maxprot, initprot, nsects, flags
+		data = le.AppendUint32(data, v)
+	}
+	// section_64
+	data = append(data, name("__ustring")...)
+	data = append(data, name("__TEXT")...)
+	data = le.AppendUint64(data, machoUStringOffset)
+	data = le.AppendUint64(data, uint64(2*len(units)))
+	for _, v := range []uint32{machoUStringOffset, 1, 0, 0, 0, 0, 0, 0} { // offset, align, reloff, nreloc, flags, reserved1-3
+		data = le.AppendUint32(data, v)
+	}
+	for _, u := range units {
+		data = le.AppendUint16(data, u)
+	}
+	return data
+}
+
+// ustring encodes NUL-terminated literals as the compiler stores them in __ustring
+func ustring(literals ...string) []uint16 {
+	var units []uint16
+	for _, s := range literals {
+		units = append(append(units, utf16.Encode([]rune(s))...), 0)
+	}
+	return units
+}
+
+type scannedString struct {
+	s        string
+	offset   int64
+	encoding string
+}
+
+func TestScanStrings(t *testing.T) {
+	// A high surrogate followed by a letter instead of a low surrogate
+	loneSurrogate := append(ustring("😀 smile"), 0xd83d)
+	loneSurrogate = append(loneSurrogate, ustring("abcd")...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		minLen   int
+		encoding string // only strings of this encoding are compared, all when empty
+		want     []scannedString
+	}{
+		{
+			name:   "ascii",
+			data:   []byte("\x00hello world\x00ab\x00"),
+			minLen: 4,
+			want:   []scannedString{{"hello world", 1, encodingASCII}},
+		},
+		{
+			name:   "cyrillic",
+			data:   []byte("Привет, мир\x00"),
+			minLen: 4,
+			want:   []scannedString{{"Привет, мир", 0, encodingUTF8}},
+		},
+		{
+			name:   "japanese",
+			data:   []byte("\x01設定を保存しました\x00"),
+			minLen: 4,
+			want:   []scannedString{{"設定を保存しました", 1, encodingUTF8}},
+		},
+		{
+			name:   "length counts characters, not bytes",
+			data:   []byte("日本語で\x00"),
+			minLen: 5,
+		},
+		{
+			name:   "not NUL-terminated",
+			data:   []byte("Привет мир"),
+			minLen: 4,
+		},
+		{
+			name:   "truncated sequence starts a new run",
+			data:   []byte("abcd\xd0Привет мир\x00"),
+			minLen: 4,
+			want: []scannedString{
+				{"abcd", 0, encodingASCII},
+				{"Привет мир", 5, encodingUTF8},
+			},
+		},
+		{
+			name:   "invalid byte ends a run",
+			data:   []byte("Привет\xffмир!\x00"),
+			minLen: 4,
+			want:   []scannedString{{"мир!", 13, encodingUTF8}},
+		},
+		{
+			name:   "symbols are not text",
+			data:   []byte("£¥§©®\x00"),
+			minLen: 4,
+		},
+		{
+			name:   "accented latin beside cyrillic is noise",
+			data:   []byte("Жзий é\x00"),
+			minLen: 4,
+		},
+		{
+			name:     "utf-16 literals",
+			data:     machoWithUString(ustring("Привет", "日本語テキスト", "ok")),
+			minLen:   4,
+			encoding: encodingUTF16LE,
+			want: []scannedString{
+				{"Привет", machoUStringOffset, encodingUTF16LE},
+				{"日本語テキスト", machoUStringOffset + 2*7, encodingUTF16LE},
+			},
+		},
+		{
+			name:     "utf-16 surrogates",
+			data:     machoWithUString(loneSurrogate),
+			minLen:   4,
+			encoding: encodingUTF16LE,
+			want: []scannedString{
+				{"😀 smile", machoUStringOffset, encodingUTF16LE},
+				{"abcd", machoUStringOffset + 2*10, encodingUTF16LE},
+			},
+		},
+		{
+			name:     "utf-16 only in __ustring",
+			data:     append([]byte("\x00"), []byte{'P', 0, 'r', 0, 'i', 0, 'v', 0, 'e', 0, 't', 0}...),
+			minLen:   4,
+			encoding: encodingUTF16LE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "binary")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			var got []scannedString
+			err = scanStrings(f, tt.minLen, func(s string, offset int64, encoding string) {
+				if tt.encoding == "" || encoding == tt.encoding {
+					got = append(got, scannedString{s, offset, encoding})
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringScript(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"hello", "latin"},
+		{"Ça va très bien", "latin"},
+		{"Привет", "cyrillic"},
+		{"Hello мир", "cyrillic"},
+		{"設定を保存", "japanese"},
+		{"设置", "chinese"},
+		{"한국어", "korean"},
+		{"Привет γειά", "mixed"},
+		{"ʃʒ", "other"},
+		{"123 !", ""},
+	}
+	for _, tt := range tests {
+		if got := stringScript(tt.s); got != tt.want {
+			t.Errorf("stringScript(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestLooksLikeText(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"Привет, мир!", true},
+		{"Ça va très bien", true},
+		{"設定を保存しました。", true},
+		{"«Ошибка» в файле", true},
+		{"Hello 世界", false},        // mostly English, quoting two letters
+		{"aé", false},              // a single non-ASCII letter
+		{"£¥§©", false},            // no letters
+		{"Жзий é", false},          // accented Latin beside Cyrillic
+		{"Жз#$%^&*|~", false},      // mostly symbols
+		{"ʃʒʃʒ", false},            // a script outside scriptTables
+		{"Привет γειά σου", false}, // two scripts
+	}
+	for _, tt := range tests {
+		if got := looksLikeText(tt.s); got != tt.want {
+			t.Errorf("looksLikeText(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
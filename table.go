@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// tableOptions controls how tables fit the terminal
+type tableOptions struct {
+	wide    bool // never truncate cells, even if rows overflow the terminal
+	maxCell int  // truncate cells longer than this many characters; 0 fits the terminal width
+}
+
+// tableOpts is set from --wide and --truncate
+var tableOpts tableOptions
+
+const (
+	columnGap      = "  "
+	minColumnWidth = 8
+	defaultWidth   = 120
+)
+
+// terminalWidth returns the width of stdout, falling back to $COLUMNS and then a fixed default
+func terminalWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return defaultWidth
+}
+
+// printTable writes rows as aligned columns under a title and header line.
+// Unless --wide is set, cells are shortened so each row fits the terminal.
+func printTable(w io.Writer, title string, headers []string, rows [][]string) {
+	activeTheme.title.Fprintln(w, title)
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  "+tr("TableEmpty"))
+		fmt.Fprintln(w)
+		return
+	}
+
+	widths := columnWidths(headers, rows)
+
+	fmt.Fprintln(w, formatRow(headers, widths, activeTheme.option.Sprint))
+	rule := make([]string, len(widths))
+	for i, width := range widths {
+		rule[i] = strings.Repeat("-", width)
+	}
+	fmt.Fprintln(w, strings.Join(rule, columnGap))
+	for _, row := range rows {
+		fmt.Fprintln(w, formatRow(row, widths, fmt.Sprint))
+	}
+	fmt.Fprintln(w)
+}
+
+// columnWidths sizes each column to its widest cell, then applies --truncate
+// and shrinks the widest columns until the table fits the terminal
+func columnWidths(headers []string, rows [][]string) []int {
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && utf8.RuneCountInString(cell) > widths[i] {
+				widths[i] = utf8.RuneCountInString(cell)
+			}
+		}
+	}
+	if tableOpts.wide {
+		return widths
+	}
+
+	if tableOpts.maxCell > 0 {
+		for i := range widths {
+			if widths[i] > tableOpts.maxCell {
+				widths[i] = tableOpts.maxCell
+			}
+		}
+		return widths
+	}
+
+	available := terminalWidth() - len(columnGap)*(len(widths)-1)
+	for {
+		total, widest := 0, 0
+		for i, width := range widths {
+			total += width
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if total <= available || widths[widest] <= minColumnWidth {
+			return widths
+		}
+		widths[widest]--
+	}
+}
+
+// formatRow pads each cell to its column width, truncating with an ellipsis where needed.
+// style is applied after padding so color codes do not affect alignment.
+func formatRow(cells []string, widths []int, style func(...interface{}) string) string {
+	parts := make([]string, len(widths))
+	for i, width := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = strings.ReplaceAll(cells[i], "\n", " ")
+		}
+		if n := utf8.RuneCountInString(cell); n > width {
+			cell = string([]rune(cell)[:width-1]) + "…"
+		}
+		padding := width - utf8.RuneCountInString(cell)
+		if i == len(widths)-1 {
+			padding = 0 // no trailing whitespace on the last column
+		}
+		parts[i] = style(cell) + strings.Repeat(" ", padding)
+	}
+	return strings.TrimRight(strings.Join(parts, columnGap), " ")
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ipaTopLevelEntries are the entries Xcode and the App Store put at the root of an IPA
+var ipaTopLevelEntries = map[string]bool{
+	"Payload":                             true,
+	"SwiftSupport":                        true,
+	"Symbols":                             true,
+	"BCSymbolMaps":                        true,
+	"WatchKitSupport":                     true,
+	"WatchKitSupport2":                    true,
+	"MessagesApplicationSupport":          true,
+	"MessagesApplicationExtensionSupport": true,
+	"META-INF":                            true,
+	"iTunesMetadata.plist":                true,
+	"iTunesArtwork":                       true,
+	"iTunesArtwork@2x":                    true,
+	"com.apple.ZipMetadata.plist":         true,
+	// Finder and Archive Utility noise, not evidence of tampering
+	"__MACOSX":  true,
+	".DS_Store": true,
+}
+
+// hookingLibraryPattern matches runtime hooking and instrumentation libraries
+// commonly injected into repackaged apps
+var hookingLibraryPattern = regexp.MustCompile(`(?i)substrate|substitute|libhooker|ellekit|tweakinject|frida|cycript|fishhook|sslkillswitch|libloader`)
+
+// systemLibraryPrefixes are absolute install names that resolve to the OS
+var systemLibraryPrefixes = []string{"/usr/lib/", "/System/Library/"}
+
+// maxSealedResourceSamples bounds how many modified resources are quoted as evidence
+const maxSealedResourceSamples = 3
+
+// unexpectedArchiveEntries lists top-level entries of the IPA at path that an
+// exported or App Store IPA never contains
+func unexpectedArchiveEntries(path string) ([]string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	extra := make(map[string]bool)
+	for _, f := range zr.File {
+		top := strings.SplitN(strings.TrimPrefix(f.Name, "/"), "/", 2)[0]
+		if top != "" && !ipaTopLevelEntries[top] {
+			extra[top] = true
+		}
+	}
+	return sortedSet(extra), nil
+}
+
+// archiveFindings reports unexpected top-level IPA entries as a tamper suspicion
+func archiveFindings(entries []string) []finding {
+	if len(entries) == 0 {
+		return nil
+	}
+	return []finding{{
+		Rule:        "tamper-suspicion",
+		Severity:    severityMedium,
+		Title:       "Unexpected top-level entries in the IPA",
+		Evidence:    strings.Join(entries, ", "),
+		Remediation: "Rebuild the IPA from the Xcode archive; extra files next to Payload/ are typical of re-zipped, repackaged apps.",
+	}}
+}
+
+// readLinkedLibraries returns the install names of the dylibs the first slice of the binary loads
+func readLinkedLibraries(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(slices) == 0 {
+		return nil, nil
+	}
+	return slices[0].ImportedLibraries()
+}
+
+// loadCommandFindings flags libraries a binary loads that point at hooking
+// frameworks or at dylibs outside the bundle and the OS
+func loadCommandFindings(libs []string, location string) []finding {
+	var findings []finding
+	add := func(severity, title, evidence, remediation string) {
+		findings = append(findings, finding{
+			Rule:        "tamper-suspicion",
+			Severity:    severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    location,
+			Remediation: remediation,
+		})
+	}
+	for _, lib := range libs {
+		switch {
+		case hookingLibraryPattern.MatchString(lib):
+			add(severityHigh, "Hooking library loaded by the binary", lib,
+				"Legitimate builds never link hooking frameworks; obtain the IPA from a trusted source.")
+		case strings.HasPrefix(lib, "/") && !hasAnyPrefix(lib, systemLibraryPrefixes):
+			add(severityHigh, "Load command references a dylib outside the bundle and the OS", lib,
+				"Remove the load command; absolute non-system install names are used by jailbreak tweaks.")
+		case strings.HasPrefix(lib, "@executable_path/") && !strings.HasPrefix(strings.TrimPrefix(lib, "@executable_path/"), "Frameworks/"):
+			add(severityMedium, "Load command references a dylib outside Frameworks/", lib,
+				"Embed libraries in Frameworks/ through Xcode; dylibs dropped next to the executable are a common injection technique.")
+		}
+	}
+	return findings
+}
+
+// checkTampering looks for traces repackaging tools leave behind: injected
+// load commands, an Info.plist or resources changed after signing, nested code
+// that no longer matches the sealed CDHash, and crack markers.
+func checkTampering(r *appReport) []finding {
+	var findings []finding
+	add := func(severity, title, evidence, location, remediation string) {
+		findings = append(findings, finding{
+			Rule:        "tamper-suspicion",
+			Severity:    severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    location,
+			Remediation: remediation,
+		})
+	}
+	findings = append(findings, loadCommandFindings(r.Libraries, bundleLocation(r, r.BinaryPath))...)
+
+	if identity := plistString(r.InfoPlist, "SignerIdentity"); identity != "" {
+		add(severityHigh, "Info.plist carries a SignerIdentity crack marker", identity, "Info.plist",
+			"Remove SignerIdentity; it is only added by tools that bypass code signature checks.")
+	}
+
+	if !r.Signature.Signed {
+		return findings
+	}
+
+	if modified, err := sealedHashMismatch(r.Signature, bundleInfoPlist(r.Path), r.Signature.infoPlistHash); err == nil && modified {
+		add(severityHigh, "Info.plist modified after signing", "hash differs from the code signature", "Info.plist",
+			"Re-sign the app after editing Info.plist; an unsigned change means the bundle was altered after export.")
+	}
+
+	resourcesPath := filepath.Join(bundleContents(r.Path), "_CodeSignature", "CodeResources")
+	if modified, err := sealedHashMismatch(r.Signature, resourcesPath, r.Signature.resourcesHash); err == nil && modified {
+		add(severityHigh, "Sealed resource list modified after signing", "hash differs from the code signature", "_CodeSignature/CodeResources",
+			"Re-sign the app; CodeResources no longer matches the signature that sealed it.")
+	}
+
+	seal, err := verifySealedResources(r)
+	if err != nil {
+		return findings
+	}
+	if len(seal.modified) > 0 {
+		add(severityHigh, "Bundle resources modified after signing", sampleList(seal.modified), "_CodeSignature/CodeResources",
+			"Re-sign the app after changing resources; modified sealed files indicate repackaging.")
+	}
+	if len(seal.cdhashMismatch) > 0 {
+		add(severityHigh, "Nested code does not match its sealed CDHash", sampleList(seal.cdhashMismatch), "_CodeSignature/CodeResources",
+			"Re-sign the whole bundle; frameworks or extensions were replaced after the app was sealed.")
+	}
+	if len(seal.unsealedLibraries) > 0 {
+		add(severityHigh, "Dylib present in the bundle but not sealed by the signature", sampleList(seal.unsealedLibraries), "_CodeSignature/CodeResources",
+			"Remove the library; dylibs added after signing are the usual payload of injection tools.")
+	}
+	return findings
+}
+
+// sealedHashMismatch reports whether the file at path no longer hashes to the
+// value bound into the signature. Missing hashes are not a mismatch.
+func sealedHashMismatch(sig signatureInfo, path string, want []byte) (bool, error) {
+	if want == nil {
+		return false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	got := codeHash(sig.hashType, data)
+	return !bytes.Equal(got[:len(want)], want), nil
+}
+
+// sealResult is the outcome of verifying _CodeSignature/CodeResources against the bundle
+type sealResult struct {
+	modified          []string
+	cdhashMismatch    []string
+	unsealedLibraries []string
+}
+
+// verifySealedResources checks every files2 entry of the app's CodeResources:
+// plain files against their SHA-256 (or SHA-1) hash, nested code against its
+// CDHash. Entries are relative to Contents in macOS-style bundles.
+func verifySealedResources(r *appReport) (sealResult, error) {
+	var res sealResult
+	contents := bundleContents(r.Path)
+	resources, err := readPlistFile(filepath.Join(contents, "_CodeSignature", "CodeResources"))
+	if err != nil {
+		return res, err
+	}
+	files := plistDict(resources, "files2")
+
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(contents, filepath.FromSlash(name))
+		var entry map[string]interface{}
+		switch v := files[name].(type) {
+		case map[string]interface{}:
+			entry = v
+		case []byte:
+			entry = map[string]interface{}{"hash": v}
+		default:
+			continue
+		}
+		if _, ok := entry["symlink"]; ok {
+			continue
+		}
+
+		if cdhash, ok := entry["cdhash"].([]byte); ok {
+			exe, err := bundleExecutable(path)
+			if err != nil {
+				continue
+			}
+			sig, err := readSignature(exe)
+			if err != nil || !containsString(sig.CDHashes, fmt.Sprintf("%x", cdhash)) {
+				res.cdhashMismatch = append(res.cdhashMismatch, name)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if optional, _ := entry["optional"].(bool); !optional {
+				res.modified = append(res.modified, name+" (missing)")
+			}
+			continue
+		}
+		if want, ok := entry["hash2"].([]byte); ok {
+			if got := sha256.Sum256(data); !bytes.Equal(got[:], want) {
+				res.modified = append(res.modified, name)
+			}
+		} else if want, ok := entry["hash"].([]byte); ok {
+			if got := sha1.Sum(data); !bytes.Equal(got[:], want) {
+				res.modified = append(res.modified, name)
+			}
+		}
+	}
+
+	res.unsealedLibraries = unsealedLibraries(contents, files)
+	return res, nil
+}
+
+// unsealedLibraries lists .dylib files in the bundle that CodeResources does not mention,
+// either directly or through a sealed nested bundle that contains them
+func unsealedLibraries(appDir string, files map[string]interface{}) []string {
+	var nested []string
+	for name := range files {
+		if entry, ok := files[name].(map[string]interface{}); ok {
+			if _, ok := entry["cdhash"]; ok {
+				nested = append(nested, name+"/")
+			}
+		}
+	}
+
+	var unsealed []string
+	filepath.Walk(appDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".dylib") {
+			return nil
+		}
+		rel, err := filepath.Rel(appDir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := files[rel]; ok || hasAnyPrefix(rel, nested) {
+			return nil
+		}
+		unsealed = append(unsealed, rel)
+		return nil
+	})
+	sort.Strings(unsealed)
+	return unsealed
+}
+
+// bundleExecutable resolves nested code to its Mach-O: a file is returned as
+// is, a bundle directory through its Info.plist CFBundleExecutable
+func bundleExecutable(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+	plist, err := readPlistFile(bundleInfoPlist(path))
+	if err != nil {
+		return "", err
+	}
+	exe := plistString(plist, "CFBundleExecutable")
+	if exe == "" {
+		return "", errors.New("bundle has no CFBundleExecutable")
+	}
+	return filepath.Join(bundleExecutableDir(path), exe), nil
+}
+
+// sampleList quotes the first few names and the total count
+func sampleList(names []string) string {
+	if len(names) <= maxSealedResourceSamples {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxSealedResourceSamples], ", "), len(names)-maxSealedResourceSamples)
+}
+
+// hasAnyPrefix reports whether s starts with one of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxTelemetryCandidates bounds how many bare hex keys are kept per service;
+// past that the strings are more likely digests than one configured key
+const maxTelemetryCandidates = 5
+
+var (
+	// sentryDSNPattern matches a Sentry DSN: the public key, an optional
+	// legacy secret key, the host and the numeric project ID
+	sentryDSNPattern = regexp.MustCompile(`\bhttps?://([0-9a-f]{32})(:[0-9a-f]{32})?@([A-Za-z0-9.\-]+(?::\d+)?)/(?:[\w\-]+/)*(\d+)\b`)
+	// datadogTokenPattern matches a Datadog client token
+	datadogTokenPattern = regexp.MustCompile(`\bpub[0-9a-f]{32}\b`)
+	// bugsnagKeyPattern and crashlyticsKeyPattern match bare API keys, which
+	// are only attributed when the service's SDK is embedded
+	bugsnagKeyPattern     = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	crashlyticsKeyPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// telemetryCredential is a crash reporting or monitoring SDK key
+type telemetryCredential struct {
+	Service  string
+	Kind     string // DSN, API key or client token
+	Value    string
+	Location string
+}
+
+// telemetryAnalyzer extracts Sentry DSNs, Datadog client tokens and Bugsnag
+// and Crashlytics API keys. It runs after trackerAnalyzer, whose SDK matches
+// decide whether a bare hex string is a Bugsnag or Crashlytics key.
+type telemetryAnalyzer struct {
+	seen        map[string]bool // hex key candidates
+	reported    map[string]bool
+	credentials []telemetryCredential
+	bugsnag     []string
+	crashlytics []string
+}
+
+func newTelemetryAnalyzer() stringAnalyzer {
+	return &telemetryAnalyzer{seen: make(map[string]bool), reported: make(map[string]bool)}
+}
+
+func (a *telemetryAnalyzer) visit(s string, offset int64) {
+	switch {
+	case bugsnagKeyPattern.MatchString(s):
+		if len(a.bugsnag) < maxTelemetryCandidates && !a.seen[s] {
+			a.seen[s] = true
+			a.bugsnag = append(a.bugsnag, s)
+		}
+		return
+	case crashlyticsKeyPattern.MatchString(s):
+		if len(a.crashlytics) < maxTelemetryCandidates && !a.seen[s] {
+			a.seen[s] = true
+			a.crashlytics = append(a.crashlytics, s)
+		}
+		return
+	}
+	if strings.Contains(s, "@") {
+		for _, m := range sentryDSNPattern.FindAllString(s, -1) {
+			a.add(telemetryCredential{Service: "Sentry", Kind: "DSN", Value: m})
+		}
+	}
+	if strings.Contains(s, "pub") {
+		for _, m := range datadogTokenPattern.FindAllString(s, -1) {
+			a.add(telemetryCredential{Service: "Datadog", Kind: "client token", Value: m})
+		}
+	}
+}
+
+func (a *telemetryAnalyzer) add(c telemetryCredential) {
+	if !a.reported[c.Value] {
+		a.reported[c.Value] = true
+		a.credentials = append(a.credentials, c)
+	}
+}
+
+func (a *telemetryAnalyzer) findings(r *appReport) []finding {
+	location := bundleLocation(r, r.BinaryPath)
+	for i := range a.credentials {
+		a.credentials[i].Location = location
+	}
+	sdks := make(map[string]bool)
+	for _, t := range r.Trackers {
+		if len(t.SDKs) > 0 {
+			sdks[t.Name] = true
+		}
+	}
+
+	// Keys configured in Info.plist are attributed by the key name alone
+	bugsnag := plistDict(r.InfoPlist, "bugsnag")
+	for _, key := range []string{plistString(bugsnag, "apiKey"), plistString(r.InfoPlist, "BugsnagAPIKey")} {
+		if bugsnagKeyPattern.MatchString(key) {
+			a.add(telemetryCredential{Service: "Bugsnag", Kind: "API key", Value: key, Location: bundleLocation(r, bundleInfoPlist(r.Path))})
+		}
+	}
+	if key := plistString(plistDict(r.InfoPlist, "Fabric"), "APIKey"); crashlyticsKeyPattern.MatchString(key) {
+		a.add(telemetryCredential{Service: "Crashlytics", Kind: "API key", Value: key, Location: bundleLocation(r, bundleInfoPlist(r.Path))})
+	}
+	if sdks["Bugsnag"] {
+		for _, key := range a.bugsnag {
+			a.add(telemetryCredential{Service: "Bugsnag", Kind: "API key", Value: key, Location: location})
+		}
+	}
+	if sdks["Google Crashlytics"] {
+		for _, key := range a.crashlytics {
+			a.add(telemetryCredential{Service: "Crashlytics", Kind: "API key", Value: key, Location: location})
+		}
+	}
+
+	var findings []finding
+	for _, c := range a.credentials {
+		findings = append(findings, finding{
+			Rule:     "telemetry-credential",
+			Severity: severityMedium,
+			Title:    "Exposed " + c.Service + " " + c.Kind,
+			Evidence: c.Service + ": " + redactTelemetryCredential(c),
+			Location: c.Location,
+			Remediation: "Anyone can use this key to send forged crash reports and events, polluting the project or running up its quota; " +
+				"restrict the key to ingestion, rate-limit it, and rotate it if it can read data.",
+		})
+	}
+	return findings
+}
+
+// redactTelemetryCredential redacts the key but keeps a DSN's host and
+// project, which identify the Sentry project without granting access to it
+func redactTelemetryCredential(c telemetryCredential) string {
+	if c.Kind != "DSN" {
+		return redactSecret(c.Value)
+	}
+	m := sentryDSNPattern.FindStringSubmatch(c.Value)
+	if m == nil {
+		return redactSecret(c.Value)
+	}
+	scheme, _, _ := strings.Cut(c.Value, "://")
+	key := redactSecret(m[1])
+	if m[2] != "" {
+		key += ":" + redactSecret(m[2][1:])
+	}
+	return scheme + "://" + key + "@" + m[3] + "/" + m[4]
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ascBaseURL is the App Store Connect API root
+const ascBaseURL = "https://api.appstoreconnect.apple.com/v1"
+
+// ascTokenLifetime is how long each API token is valid; Apple rejects anything over 20 minutes
+const ascTokenLifetime = 15 * time.Minute
+
+// ascClient calls the App Store Connect API with a team API key
+type ascClient struct {
+	keyID  string
+	issuer string
+	key    *ecdsa.PrivateKey
+}
+
+// testFlightBuild is one build uploaded to App Store Connect
+type testFlightBuild struct {
+	ID              string    `json:"id"`
+	Version         string    `json:"version"` // CFBundleShortVersionString
+	Build           string    `json:"build"`   // CFBundleVersion
+	Uploaded        time.Time `json:"uploaded"`
+	ProcessingState string    `json:"processing_state"`
+	Expired         bool      `json:"expired"`
+	MinimumOS       string    `json:"minimum_os,omitempty"`
+}
+
+// newASCClient loads the .p8 API key issued in App Store Connect
+func newASCClient(keyID, issuer, keyPath string) (*ascClient, error) {
+	if keyID == "" || issuer == "" || keyPath == "" {
+		return nil, trError("ErrASCCredentials")
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", keyPath)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyPath, err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ECDSA key", keyPath)
+	}
+	return &ascClient{keyID: keyID, issuer: issuer, key: key}, nil
+}
+
+// token returns a freshly signed ES256 JWT for the API
+func (c *ascClient) token() (string, error) {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": c.keyID, "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss": c.issuer,
+		"iat": now.Unix(),
+		"exp": now.Add(ascTokenLifetime).Unix(),
+		"aud": "appstoreconnect-v1",
+	})
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signing))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS wants the raw 64-byte r||s form rather than ASN.1
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// get decodes the JSON response for path and query into v
+func (c *ascClient) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	tok, err := c.token()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ascBaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Errors []struct {
+				Detail string `json:"detail"`
+			} `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && len(apiErr.Errors) > 0 {
+			return fmt.Errorf("App Store Connect: %s: %s", resp.Status, apiErr.Errors[0].Detail)
+		}
+		return fmt.Errorf("App Store Connect: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// appID resolves a bundle ID to the App Store Connect app ID; numeric IDs pass through
+func (c *ascClient) appID(ctx context.Context, app string) (string, error) {
+	if _, err := strconv.ParseUint(app, 10, 64); err == nil {
+		return app, nil
+	}
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := c.get(ctx, "/apps", url.Values{"filter[bundleId]": {app}, "fields[apps]": {"bundleId"}}, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", trError("ErrASCNoApp", "App", app)
+	}
+	return resp.Data[0].ID, nil
+}
+
+// builds lists the most recent builds of an app, newest first
+func (c *ascClient) builds(ctx context.Context, appID string) ([]testFlightBuild, error) {
+	var resp struct {
+		Data []struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				Version         string    `json:"version"`
+				UploadedDate    time.Time `json:"uploadedDate"`
+				ProcessingState string    `json:"processingState"`
+				Expired         bool      `json:"expired"`
+				MinOSVersion    string    `json:"minOsVersion"`
+			} `json:"attributes"`
+			Relationships struct {
+				PreReleaseVersion struct {
+					Data struct {
+						ID string `json:"id"`
+					} `json:"data"`
+				} `json:"preReleaseVersion"`
+			} `json:"relationships"`
+		} `json:"data"`
+		Included []struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				Version string `json:"version"`
+			} `json:"attributes"`
+		} `json:"included"`
+	}
+	query := url.Values{
+		"filter[app]":                {appID},
+		"sort":                       {"-uploadedDate"},
+		"limit":                      {"50"},
+		"include":                    {"preReleaseVersion"},
+		"fields[builds]":             {"version,uploadedDate,processingState,expired,minOsVersion,preReleaseVersion"},
+		"fields[preReleaseVersions]": {"version"},
+	}
+	if err := c.get(ctx, "/builds", query, &resp); err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string)
+	for _, inc := range resp.Included {
+		versions[inc.ID] = inc.Attributes.Version
+	}
+	var builds []testFlightBuild
+	for _, d := range resp.Data {
+		builds = append(builds, testFlightBuild{
+			ID:              d.ID,
+			Version:         versions[d.Relationships.PreReleaseVersion.Data.ID],
+			Build:           d.Attributes.Version,
+			Uploaded:        d.Attributes.UploadedDate,
+			ProcessingState: d.Attributes.ProcessingState,
+			Expired:         d.Attributes.Expired,
+			MinimumOS:       d.Attributes.MinOSVersion,
+		})
+	}
+	return builds, nil
+}
+
+// runTestFlight implements `iosdumper testflight --app <id|bundle-id> [--build N] [file.ipa]`.
+//
+// The App Store Connect API lists builds but has no endpoint that serves the
+// uploaded binary, so a build cannot be downloaded from it. Without an IPA the
+// command lists the app's builds. With one, it scans the IPA and gates the
+// result on the IPA being the selected TestFlight build: the build number
+// (--build, or the IPA's own CFBundleVersion) must exist, have finished
+// processing and not have expired, and the IPA's versions must match it.
+func runTestFlight(ctx context.Context, args []string, prog *progressReporter, jsonPath string) int {
+	fs := flag.NewFlagSet("testflight", flag.ContinueOnError)
+	app := fs.String("app", "", "App Store Connect app ID or bundle ID")
+	buildNumber := fs.String("build", "", "Build number (CFBundleVersion) the IPA must match")
+	keyID := fs.String("key-id", os.Getenv("ASC_KEY_ID"), "API key ID")
+	issuer := fs.String("issuer", os.Getenv("ASC_ISSUER_ID"), "API key issuer ID")
+	keyPath := fs.String("key", os.Getenv("ASC_KEY_PATH"), "Path to the AuthKey_<id>.p8 private key")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if *app == "" || fs.NArg() > 1 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrTestFlightUsage")))
+		return exitBadInput
+	}
+
+	client, err := newASCClient(*keyID, *issuer, *keyPath)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitBadInput
+	}
+	id, err := client.appID(ctx, *app)
+	if err == nil {
+		var builds []testFlightBuild
+		if builds, err = client.builds(ctx, id); err == nil {
+			if fs.NArg() == 0 {
+				printTestFlightBuilds(builds)
+				return exitClean
+			}
+			return gateTestFlightBuild(ctx, fs.Arg(0), *buildNumber, builds, prog, jsonPath)
+		}
+	}
+	activeTheme.failure.Fprintln(stdout, tr("ErrASC", "Err", err))
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	return exitToolFailure
+}
+
+// printTestFlightBuilds prints one row per build
+func printTestFlightBuilds(builds []testFlightBuild) {
+	var rows [][]string
+	for _, b := range builds {
+		expired := ""
+		if b.Expired {
+			expired = "✓"
+		}
+		rows = append(rows, []string{b.Version, b.Build, b.Uploaded.Format("2006-01-02 15:04"), b.ProcessingState, expired, b.MinimumOS})
+	}
+	printTable(stdout, tr("TestFlightBuilds"), []string{
+		tr("ColVersion"), tr("ColBuild"), tr("ColUploaded"), tr("ColState"), tr("ColExpired"), tr("ColMinimumOS"),
+	}, rows)
+}
+
+// gateTestFlightBuild scans ipa and fails the scan unless it matches a usable TestFlight build
+func gateTestFlightBuild(ctx context.Context, ipa, buildNumber string, builds []testFlightBuild, prog *progressReporter, jsonPath string) int {
+	result := scanInput(ctx, ipa, prog)
+	if len(result.Apps) > 0 {
+		meta := result.Apps[0].Metadata
+		if buildNumber == "" {
+			buildNumber = meta.Build
+		}
+		var match *testFlightBuild
+		for i := range builds {
+			if builds[i].Build == buildNumber {
+				match = &builds[i]
+				break
+			}
+		}
+		var gateErr error
+		switch {
+		case match == nil:
+			gateErr = trError("ErrTestFlightNoBuild", "Build", buildNumber)
+		case match.Build != meta.Build || (match.Version != "" && match.Version != meta.Version):
+			gateErr = trError("ErrTestFlightMismatch", "Version", match.Version, "Build", match.Build, "IPAVersion", meta.Version, "IPABuild", meta.Build)
+		case match.Expired || match.ProcessingState != "VALID":
+			gateErr = trError("ErrTestFlightUnusable", "Build", match.Build, "State", match.ProcessingState, "Expired", match.Expired)
+		default:
+			activeTheme.success.Fprintln(stdout, tr("TestFlightMatch", "Version", match.Version, "Build", match.Build, "State", match.ProcessingState))
+		}
+		if gateErr != nil {
+			result.addError(errCodeInvalidInput, "testflight", result.Apps[0].Name, gateErr, true)
+		}
+	}
+	result.finish()
+
+	for _, e := range result.Errors {
+		if e.Fatal {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", e.Message))
+		}
+	}
+	if historyPath != "" {
+		if err := appendHistory(historyPath, result); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrHistory", "Err", err))
+		}
+	}
+	if jsonPath != "" {
+		if err := writeJSONReport(jsonPath, result); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			return exitToolFailure
+		}
+	}
+	return result.ExitCode
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// theme maps each role a highlighter plays to the color used to render it
+type theme struct {
+	success *color.Color // status lines reporting a completed step
+	failure *color.Color // errors and missing artifacts
+	banner  *color.Color
+	title   *color.Color // help text headings
+	option  *color.Color // flag names in help text
+	match   *color.Color // interesting hits inside analyzer output (applinks:, path strings)
+	noMatch *color.Color // analyzer output lines that did not match
+	keys    [6]*color.Color
+}
+
+// themes holds every palette selectable with --theme
+var themes = map[string]theme{
+	"default": {
+		success: color.New(color.FgGreen),
+		failure: color.New(color.FgRed),
+		banner:  color.New(color.FgYellow),
+		title:   color.New(color.FgCyan, color.Bold),
+		option:  color.New(color.FgYellow),
+		match:   color.New(color.FgGreen),
+		noMatch: color.New(color.FgRed),
+		keys: [6]*color.Color{
+			color.New(color.FgCyan),
+			color.New(color.FgGreen),
+			color.New(color.FgYellow),
+			color.New(color.FgMagenta),
+			color.New(color.FgRed),
+			color.New(color.FgBlue),
+		},
+	},
+	// colorblind avoids red/green pairs and keeps matches distinguishable by
+	// blue/orange contrast plus weight, which holds up for the common color vision deficiencies
+	"colorblind": {
+		success: color.New(color.FgHiBlue),
+		failure: color.New(color.FgHiYellow, color.Bold),
+		banner:  color.New(color.FgHiBlue),
+		title:   color.New(color.FgHiBlue, color.Bold),
+		option:  color.New(color.FgHiYellow),
+		match:   color.New(color.FgHiBlue, color.Bold),
+		noMatch: color.New(color.Faint),
+		keys: [6]*color.Color{
+			color.New(color.FgHiBlue),
+			color.New(color.FgHiYellow),
+			color.New(color.FgHiBlue, color.Underline),
+			color.New(color.FgHiYellow, color.Underline),
+			color.New(color.FgHiWhite, color.Bold),
+			color.New(color.FgHiBlue, color.Bold),
+		},
+	},
+	// mono uses text attributes only, for terminals or reports without color
+	"mono": {
+		success: color.New(color.Bold),
+		failure: color.New(color.Bold, color.Underline),
+		banner:  color.New(color.Reset),
+		title:   color.New(color.Bold),
+		option:  color.New(color.Underline),
+		match:   color.New(color.Bold),
+		noMatch: color.New(color.Reset),
+		keys: [6]*color.Color{
+			color.New(color.Bold),
+			color.New(color.Underline),
+			color.New(color.Bold, color.Underline),
+			color.New(color.Italic),
+			color.New(color.Bold, color.Italic),
+			color.New(color.ReverseVideo),
+		},
+	},
+	"high-contrast": {
+		success: color.New(color.FgHiWhite, color.BgGreen, color.Bold),
+		failure: color.New(color.FgHiWhite, color.BgRed, color.Bold),
+		banner:  color.New(color.FgHiWhite, color.Bold),
+		title:   color.New(color.FgBlack, color.BgHiWhite, color.Bold),
+		option:  color.New(color.FgHiYellow, color.Bold),
+		match:   color.New(color.FgBlack, color.BgHiYellow, color.Bold),
+		noMatch: color.New(color.FgHiWhite),
+		keys: [6]*color.Color{
+			color.New(color.FgBlack, color.BgHiCyan),
+			color.New(color.FgBlack, color.BgHiGreen),
+			color.New(color.FgBlack, color.BgHiYellow),
+			color.New(color.FgBlack, color.BgHiMagenta),
+			color.New(color.FgHiWhite, color.BgRed),
+			color.New(color.FgHiWhite, color.BgBlue),
+		},
+	},
+}
+
+// activeTheme is the palette used by every highlighter, selected with --theme
+var activeTheme = themes["default"]
+
+// setTheme switches activeTheme to the named palette
+func setTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return trError("ErrUnknownTheme", "Theme", name, "Themes", themeNames())
+	}
+	activeTheme = t
+	return nil
+}
+
+// themeNames lists the selectable palettes in a stable order for help and error text
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ticketTrackers are the values --tickets accepts
+var ticketTrackers = []string{"jira", "github"}
+
+// ticketExporter is the tracker chosen with --tickets; nil disables the exporter
+var ticketExporter ticketTracker
+
+// ticketSeverities are the severities that get a ticket
+var ticketSeverities = map[string]bool{severityHigh: true, severityCritical: true}
+
+// ticketLabel marks every ticket the exporter opens
+const ticketLabel = "iosdumper"
+
+// ticket is the tracker issue for one finding of one app
+type ticket struct {
+	Key     string // bundle ID and finding fingerprint, see ticketKey
+	Summary string
+	Body    string
+}
+
+// ticketTracker opens and updates issues in Jira or GitHub. Both are
+// configured from the environment so that tokens never appear on the command
+// line.
+type ticketTracker interface {
+	// existing maps the keys of the exporter's earlier tickets to their issue IDs
+	existing(ctx context.Context) (map[string]string, error)
+	create(ctx context.Context, t ticket) error
+	update(ctx context.Context, id string, t ticket) error
+}
+
+// trackerFor returns the tracker named by --tickets
+//
+//	jira:   JIRA_URL (e.g. https://acme.atlassian.net), JIRA_PROJECT, JIRA_USER, JIRA_API_TOKEN, JIRA_ISSUE_TYPE (default Bug)
+//	github: GITHUB_REPOSITORY as "owner/repo", GITHUB_TOKEN, GITHUB_API_URL (default https://api.github.com)
+func trackerFor(name string) (ticketTracker, error) {
+	switch name {
+	case "jira":
+		j := &jiraTracker{}
+		err := readTrackerEnv([]trackerEnv{
+			{&j.base, "JIRA_URL", ""}, {&j.project, "JIRA_PROJECT", ""}, {&j.user, "JIRA_USER", ""},
+			{&j.token, "JIRA_API_TOKEN", ""}, {&j.issueType, "JIRA_ISSUE_TYPE", "Bug"},
+		})
+		j.base = strings.TrimSuffix(j.base, "/")
+		return j, err
+	case "github":
+		g := &githubTracker{}
+		err := readTrackerEnv([]trackerEnv{
+			{&g.repo, "GITHUB_REPOSITORY", ""}, {&g.token, "GITHUB_TOKEN", ""}, {&g.api, "GITHUB_API_URL", "https://api.github.com"},
+		})
+		g.api = strings.TrimSuffix(g.api, "/")
+		return g, err
+	}
+	return nil, trError("ErrTicketTracker", "Tracker", name, "Trackers", strings.Join(ticketTrackers, ", "))
+}
+
+// trackerEnv is an environment variable a tracker reads, with its default;
+// variables without a default are required
+type trackerEnv struct {
+	dst      *string
+	key, def string
+}
+
+func readTrackerEnv(vars []trackerEnv) error {
+	for _, v := range vars {
+		*v.dst = os.Getenv(v.key)
+		if *v.dst == "" {
+			if v.def == "" {
+				return trError("ErrTicketEnv", "Var", v.key)
+			}
+			*v.dst = v.def
+		}
+	}
+	return nil
+}
+
+// ticketKey identifies a finding's ticket across scans. The fingerprint alone
+// is shared by every app that embeds the same SDK, so the bundle ID is part of it.
+func ticketKey(r *appReport, f finding) string {
+	return r.Metadata.BundleID + "-" + f.Fingerprint
+}
+
+// newTicket describes a finding for the tracker
+func newTicket(r *appReport, f finding) ticket {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", f.Title)
+	fmt.Fprintf(&b, "App: %s (%s) %s\n", r.Name, r.Metadata.BundleID, versionString(r.Metadata))
+	fmt.Fprintf(&b, "Severity: %s\n", f.Severity)
+	fmt.Fprintf(&b, "Rule: %s\n", f.Rule)
+	if f.Location != "" {
+		fmt.Fprintf(&b, "Location: %s\n", f.Location)
+	}
+	if f.Component != "" {
+		fmt.Fprintf(&b, "Component: %s\n", f.Component)
+	}
+	fmt.Fprintf(&b, "Evidence: %s\n", findingEvidence(f))
+	if f.Remediation != "" {
+		fmt.Fprintf(&b, "\nRemediation: %s\n", f.Remediation)
+	}
+	fmt.Fprintf(&b, "\nFingerprint: %s\n", ticketKey(r, f))
+	return ticket{
+		Key:     ticketKey(r, f),
+		Summary: fmt.Sprintf("[%s] %s: %s", f.Severity, r.Name, f.Title),
+		Body:    b.String(),
+	}
+}
+
+// exportTickets opens a ticket for each high or critical finding of the scan,
+// or updates the one opened by an earlier scan, printing the outcome
+func exportTickets(ctx context.Context, result *scanResult) {
+	if ticketExporter == nil || len(result.Apps) == 0 || result.ExitCode == exitInterrupted {
+		return
+	}
+	created, updated, err := syncTickets(ctx, result)
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrTickets", "Err", err))
+		return
+	}
+	activeTheme.success.Fprintln(stdout, tr("TicketsExported", "Created", created, "Updated", updated))
+}
+
+// syncTickets creates or updates the tickets of a scan and counts each
+func syncTickets(ctx context.Context, result *scanResult) (created, updated int, err error) {
+	existing, err := ticketExporter.existing(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, app := range result.Apps {
+		for _, f := range app.Findings {
+			if !ticketSeverities[f.Severity] {
+				continue
+			}
+			t := newTicket(app, f)
+			if id, ok := existing[t.Key]; ok {
+				if err := ticketExporter.update(ctx, id, t); err != nil {
+					return created, updated, err
+				}
+				updated++
+				continue
+			}
+			if err := ticketExporter.create(ctx, t); err != nil {
+				return created, updated, err
+			}
+			created++
+		}
+	}
+	return created, updated, nil
+}
+
+// trackerRequest sends a JSON request and decodes a JSON response into out,
+// when out is not nil
+func trackerRequest(ctx context.Context, method, target string, auth func(*http.Request), body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	auth(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", method, target, resp.Status)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("%s %s: %v", method, target, err)
+		}
+	}
+	return nil
+}
+
+// jiraTracker files tickets through the Jira REST API. The ticket key is
+// stored as a label, which JQL can search.
+type jiraTracker struct {
+	base, project, user, token, issueType string
+}
+
+func (j *jiraTracker) auth(req *http.Request) {
+	req.SetBasicAuth(j.user, j.token)
+}
+
+// label turns a ticket key into a Jira label, which cannot contain spaces
+func (j *jiraTracker) label(key string) string {
+	return ticketLabel + "-" + strings.ReplaceAll(key, " ", "_")
+}
+
+func (j *jiraTracker) existing(ctx context.Context) (map[string]string, error) {
+	keys := make(map[string]string)
+	jql := fmt.Sprintf("project = %q AND labels = %q", j.project, ticketLabel)
+	for start := 0; ; {
+		var page struct {
+			Total  int `json:"total"`
+			Issues []struct {
+				Key    string `json:"key"`
+				Fields struct {
+					Labels []string `json:"labels"`
+				} `json:"fields"`
+			} `json:"issues"`
+		}
+		q := url.Values{"jql": {jql}, "fields": {"labels"}, "startAt": {fmt.Sprint(start)}, "maxResults": {"100"}}
+		if err := trackerRequest(ctx, http.MethodGet, j.base+"/rest/api/2/search?"+q.Encode(), j.auth, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, issue := range page.Issues {
+			for _, label := range issue.Fields.Labels {
+				if key, ok := strings.CutPrefix(label, ticketLabel+"-"); ok {
+					keys[key] = issue.Key
+				}
+			}
+		}
+		start += len(page.Issues)
+		if len(page.Issues) == 0 || start >= page.Total {
+			return keys, nil
+		}
+	}
+}
+
+func (j *jiraTracker) create(ctx context.Context, t ticket) error {
+	body := map[string]interface{}{"fields": map[string]interface{}{
+		"project":     map[string]string{"key": j.project},
+		"issuetype":   map[string]string{"name": j.issueType},
+		"summary":     t.Summary,
+		"description": t.Body,
+		"labels":      []string{ticketLabel, j.label(t.Key)},
+	}}
+	return trackerRequest(ctx, http.MethodPost, j.base+"/rest/api/2/issue", j.auth, body, nil)
+}
+
+func (j *jiraTracker) update(ctx context.Context, id string, t ticket) error {
+	body := map[string]interface{}{"fields": map[string]interface{}{
+		"summary":     t.Summary,
+		"description": t.Body,
+	}}
+	return trackerRequest(ctx, http.MethodPut, j.base+"/rest/api/2/issue/"+url.PathEscape(id), j.auth, body, nil)
+}
+
+// githubTracker files tickets as GitHub issues labelled iosdumper. The ticket
+// key is the Fingerprint line of the issue body.
+type githubTracker struct {
+	repo, token, api string
+}
+
+// githubKeyPattern finds the ticket key in an issue body written by newTicket
+var githubKeyPattern = regexp.MustCompile(`(?m)^Fingerprint: (\S+)$`)
+
+func (g *githubTracker) auth(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+}
+
+func (g *githubTracker) existing(ctx context.Context) (map[string]string, error) {
+	keys := make(map[string]string)
+	for page := 1; ; page++ {
+		var issues []struct {
+			Number int    `json:"number"`
+			Body   string `json:"body"`
+		}
+		q := url.Values{"labels": {ticketLabel}, "state": {"all"}, "per_page": {"100"}, "page": {fmt.Sprint(page)}}
+		if err := trackerRequest(ctx, http.MethodGet, g.api+"/repos/"+g.repo+"/issues?"+q.Encode(), g.auth, nil, &issues); err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if m := githubKeyPattern.FindStringSubmatch(issue.Body); m != nil {
+				keys[m[1]] = fmt.Sprint(issue.Number)
+			}
+		}
+		if len(issues) < 100 {
+			return keys, nil
+		}
+	}
+}
+
+func (g *githubTracker) create(ctx context.Context, t ticket) error {
+	body := map[string]interface{}{"title": t.Summary, "body": t.Body, "labels": []string{ticketLabel}}
+	return trackerRequest(ctx, http.MethodPost, g.api+"/repos/"+g.repo+"/issues", g.auth, body, nil)
+}
+
+// update refreshes the title and body and leaves the state alone, so an issue
+// the team closed as accepted risk stays closed
+func (g *githubTracker) update(ctx context.Context, id string, t ticket) error {
+	body := map[string]interface{}{"title": t.Summary, "body": t.Body}
+	return trackerRequest(ctx, http.MethodPatch, g.api+"/repos/"+g.repo+"/issues/"+id, g.auth, body, nil)
+}
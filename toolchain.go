@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// buildToolNames maps LC_BUILD_VERSION tool identifiers to names
+var buildToolNames = map[uint32]string{
+	1:    "clang",
+	2:    "swift",
+	3:    "ld",
+	4:    "lld",
+	1024: "metal",
+	1025: "airlld",
+	1026: "airnt",
+	1027: "airnt-plugin",
+	1028: "airpack",
+	1031: "gpuarchiver",
+	1032: "metal-framebuffer",
+}
+
+// compilerVersionPattern matches compiler identification strings embedded in binaries and bitcode
+var compilerVersionPattern = regexp.MustCompile(`(?:Apple )?(?:clang|LLVM|Swift) version [0-9][0-9A-Za-z.\-]*(?: \([^)\x00]{1,80}\))?`)
+
+// toolchainScanSections are the data sections searched for compiler strings
+var toolchainScanSections = map[string]bool{
+	"__cstring": true,
+	"__const":   true,
+	"__cmdline": true,
+	"__bundle":  true,
+}
+
+// maxToolchainSectionSize bounds how much of a single section is scanned for compiler strings
+const maxToolchainSectionSize = 32 << 20
+
+// toolchainInfo is the build provenance recovered from a Mach-O binary
+type toolchainInfo struct {
+	Bitcode      bool     `json:"bitcode"`                 // an __LLVM segment with embedded bitcode is present
+	BitcodeBytes uint64   `json:"bitcode_bytes,omitempty"` // size of the __LLVM segment
+	BuildTools   []string `json:"build_tools,omitempty"`   // tools recorded in LC_BUILD_VERSION, e.g. "ld 1015.7"
+	Compilers    []string `json:"compilers,omitempty"`     // compiler version strings found in the binary
+	CommandLine  string   `json:"command_line,omitempty"`  // compiler flags recorded in __LLVM,__cmdline
+}
+
+// readToolchain extracts bitcode presence, build tool versions and compiler
+// strings from the first slice of the Mach-O at path
+func readToolchain(path string) (toolchainInfo, error) {
+	var info toolchainInfo
+
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	slices, err := machoSlices(f)
+	if err != nil {
+		return info, err
+	}
+	if len(slices) == 0 {
+		return info, nil
+	}
+	s := slices[0]
+
+	if seg := s.Segment("__LLVM"); seg != nil {
+		info.BitcodeBytes = seg.Filesz
+		// Stripped bitcode leaves a one-byte marker section behind
+		info.Bitcode = seg.Filesz > 1
+	}
+
+	for _, load := range s.Loads {
+		raw := load.Raw()
+		if len(raw) < 24 || s.ByteOrder.Uint32(raw) != lcBuildVersion {
+			continue
+		}
+		ntools := int(s.ByteOrder.Uint32(raw[20:]))
+		for i := 0; i < ntools && 24+i*8+8 <= len(raw); i++ {
+			tool := s.ByteOrder.Uint32(raw[24+i*8:])
+			name, ok := buildToolNames[tool]
+			if !ok {
+				name = fmt.Sprintf("tool %d", tool)
+			}
+			info.BuildTools = append(info.BuildTools, name+" "+formatPackedVersion(s.ByteOrder.Uint32(raw[28+i*8:])))
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, sect := range s.Sections {
+		if !toolchainScanSections[sect.Name] || sect.Size > maxToolchainSectionSize {
+			continue
+		}
+		data, err := sect.Data()
+		if err != nil {
+			continue
+		}
+		if sect.Seg == "__LLVM" && sect.Name == "__cmdline" {
+			info.CommandLine = string(nulToSpace(data))
+		}
+		for _, m := range compilerVersionPattern.FindAll(data, 32) {
+			if !seen[string(m)] {
+				seen[string(m)] = true
+				info.Compilers = append(info.Compilers, string(m))
+			}
+		}
+	}
+	sort.Strings(info.Compilers)
+
+	return info, nil
+}
+
+// formatPackedVersion renders an xxxx.yy.zz nibble-packed Mach-O version
+func formatPackedVersion(v uint32) string {
+	major, minor, patch := v>>16, (v>>8)&0xff, v&0xff
+	if patch == 0 {
+		return fmt.Sprintf("%d.%d", major, minor)
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, patch)
+}
+
+// nulToSpace replaces NUL separators, as used between recorded compiler flags, with spaces
+func nulToSpace(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c == 0 {
+			c = ' '
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// checkToolchain reports embedded bitcode and recorded compiler command lines
+func checkToolchain(r *appReport) []finding {
+	var findings []finding
+	location := bundleLocation(r, r.BinaryPath)
+
+	if r.Toolchain.Bitcode {
+		findings = append(findings, finding{
+			Rule:        "embedded-bitcode",
+			Severity:    severityLow,
+			Title:       "LLVM bitcode embedded in the shipped binary",
+			Evidence:    fmt.Sprintf("__LLVM segment, %d bytes", r.Toolchain.BitcodeBytes),
+			Location:    location,
+			Remediation: "Disable ENABLE_BITCODE; bitcode is deprecated and hands reverse engineers the app's LLVM IR.",
+		})
+	}
+	if r.Toolchain.CommandLine != "" {
+		findings = append(findings, finding{
+			Rule:        "embedded-compiler-flags",
+			Severity:    severityLow,
+			Title:       "Compiler command line recorded in the binary",
+			Evidence:    r.Toolchain.CommandLine,
+			Location:    location,
+			Remediation: "Build release configurations without -embed-bitcode / -gmodules style flags that record the command line.",
+		})
+	}
+	return findings
+}
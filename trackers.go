@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tracker is an analytics, advertising or profiling service. Built-in
+// trackers also carry iOS SDK signatures; trackers loaded from an Exodus
+// database only have the network signature, since Exodus code signatures
+// are Android package names.
+type tracker struct {
+	Name       string
+	Categories []string
+	Network    *regexp.Regexp // matched against endpoint hosts
+	Frameworks []string       // embedded framework names
+	Classes    []string       // Objective-C class names
+}
+
+// builtinTrackers covers the SDKs most often found in iOS apps
+var builtinTrackers = []tracker{
+	{"Google Firebase Analytics", []string{"Analytics"}, regexp.MustCompile(`app-measurement\.com|firebaselogging\.googleapis\.com`), []string{"FirebaseAnalytics", "GoogleAppMeasurement"}, []string{"FIRAnalytics"}},
+	{"Google AdMob", []string{"Advertisement"}, regexp.MustCompile(`googleads\.g\.doubleclick\.net|admob\.com|googlesyndication\.com`), []string{"GoogleMobileAds"}, []string{"GADMobileAds", "GADRequest"}},
+	{"Google Analytics", []string{"Analytics"}, regexp.MustCompile(`google-analytics\.com`), []string{"GoogleAnalytics"}, []string{"GAIDictionaryBuilder"}},
+	{"Google Crashlytics", []string{"Crash reporting"}, regexp.MustCompile(`crashlyticsreports-pa\.googleapis\.com|crashlytics\.com`), []string{"FirebaseCrashlytics", "Crashlytics"}, []string{"FIRCrashlytics", "Crashlytics"}},
+	{"Facebook Analytics", []string{"Analytics", "Advertisement"}, regexp.MustCompile(`graph\.facebook\.com|connect\.facebook\.net`), []string{"FBSDKCoreKit", "FBAudienceNetwork"}, []string{"FBSDKAppEvents", "FBAdView"}},
+	{"AppsFlyer", []string{"Analytics", "Identification"}, regexp.MustCompile(`appsflyer\.com|appsflyersdk\.com`), []string{"AppsFlyerLib"}, []string{"AppsFlyerLib"}},
+	{"Adjust", []string{"Analytics", "Identification"}, regexp.MustCompile(`adjust\.com|adjust\.io`), []string{"AdjustSdk"}, []string{"ADJConfig"}},
+	{"Branch", []string{"Analytics", "Identification"}, regexp.MustCompile(`branch\.io|(?:^|\.)app\.link$`), []string{"Branch", "BranchSDK"}, []string{"BNCConfig", "BranchUniversalObject"}},
+	{"Mixpanel", []string{"Analytics"}, regexp.MustCompile(`mixpanel\.com`), []string{"Mixpanel"}, []string{"Mixpanel"}},
+	{"Amplitude", []string{"Analytics"}, regexp.MustCompile(`amplitude\.com`), []string{"Amplitude", "AmplitudeSwift"}, []string{"Amplitude"}},
+	{"Segment", []string{"Analytics"}, regexp.MustCompile(`segment\.io|segment\.com`), []string{"Segment"}, []string{"SEGAnalytics"}},
+	{"Flurry", []string{"Analytics", "Advertisement"}, regexp.MustCompile(`flurry\.com`), []string{"Flurry_iOS_SDK"}, []string{"Flurry"}},
+	{"Sentry", []string{"Crash reporting"}, regexp.MustCompile(`sentry\.io`), []string{"Sentry"}, []string{"SentrySDK"}},
+	{"Bugsnag", []string{"Crash reporting"}, regexp.MustCompile(`bugsnag\.com`), []string{"Bugsnag"}, []string{"Bugsnag"}},
+	{"OneSignal", []string{"Analytics"}, regexp.MustCompile(`onesignal\.com`), []string{"OneSignal", "OneSignalFramework"}, []string{"OneSignal"}},
+	{"Braze", []string{"Analytics", "Profiling"}, regexp.MustCompile(`braze\.com|appboy\.com`), []string{"Appboy_iOS_SDK", "BrazeKit"}, []string{"Appboy"}},
+	{"CleverTap", []string{"Analytics", "Profiling"}, regexp.MustCompile(`clevertap-prod\.com|wzrkt\.com`), []string{"CleverTapSDK"}, []string{"CleverTap"}},
+	{"Kochava", []string{"Analytics", "Identification"}, regexp.MustCompile(`kochava\.com`), []string{"KochavaTracker", "KochavaCore"}, []string{"KochavaTracker"}},
+	{"Singular", []string{"Analytics", "Identification"}, regexp.MustCompile(`singular\.net`), []string{"Singular"}, nil},
+	{"AppLovin", []string{"Advertisement"}, regexp.MustCompile(`applovin\.com|applvn\.com`), []string{"AppLovinSDK"}, []string{"ALSdk"}},
+	{"Unity Ads", []string{"Advertisement"}, regexp.MustCompile(`unityads\.unity3d\.com`), []string{"UnityAds"}, []string{"UnityAds"}},
+	{"ironSource", []string{"Advertisement"}, regexp.MustCompile(`supersonicads\.com|ironsrc\.mobi|(?:^|\.)is\.com$`), []string{"IronSource"}, []string{"IronSource"}},
+	{"Vungle", []string{"Advertisement"}, regexp.MustCompile(`vungle\.com`), []string{"VungleSDK"}, []string{"VungleSDK"}},
+	{"Chartboost", []string{"Advertisement"}, regexp.MustCompile(`chartboost\.com`), []string{"Chartboost", "ChartboostSDK"}, []string{"Chartboost"}},
+	{"comScore", []string{"Analytics"}, regexp.MustCompile(`scorecardresearch\.com|comscore\.com`), []string{"ComScore"}, []string{"SCORAnalytics"}},
+	{"New Relic", []string{"Analytics", "Crash reporting"}, regexp.MustCompile(`nr-data\.net|newrelic\.com`), []string{"NewRelic", "NewRelicAgent"}, []string{"NewRelic"}},
+	{"Heap", []string{"Analytics"}, regexp.MustCompile(`heapanalytics\.com`), []string{"Heap"}, nil},
+	{"Smartlook", []string{"Analytics", "Profiling"}, regexp.MustCompile(`smartlook\.com|smartlook\.cloud`), []string{"Smartlook", "SmartlookAnalytics"}, []string{"Smartlook"}},
+	{"Datadog", []string{"Analytics", "Crash reporting"}, regexp.MustCompile(`datadoghq\.com|datadoghq\.eu|ddog-gov\.com`), []string{"DatadogCore", "DatadogRUM", "DatadogObjc", "Datadog"}, []string{"DDDatadog"}},
+	{"FullStory", []string{"Analytics", "Profiling"}, regexp.MustCompile(`fullstory\.com`), []string{"FullStory"}, []string{"FSExternalLog"}},
+}
+
+// activeTrackers are matched by trackerAnalyzer; --trackers adds an Exodus database
+var activeTrackers = builtinTrackers
+
+// loadTrackers reads an Exodus tracker database, as served by
+// https://reports.exodus-privacy.eu.org/api/trackers, and returns the
+// built-in trackers followed by every database entry with a network
+// signature. An entry named like a built-in tracker replaces its network
+// signature.
+func loadTrackers(file string) ([]tracker, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var db struct {
+		Trackers map[string]struct {
+			Name             string   `json:"name"`
+			NetworkSignature string   `json:"network_signature"`
+			Categories       []string `json:"categories"`
+		} `json:"trackers"`
+	}
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("parsing tracker database %s: %v", file, err)
+	}
+	if len(db.Trackers) == 0 {
+		return nil, fmt.Errorf("tracker database %s: no trackers", file)
+	}
+
+	trackers := append([]tracker(nil), builtinTrackers...)
+	builtin := make(map[string]int)
+	for i, t := range trackers {
+		builtin[strings.ToLower(t.Name)] = i
+	}
+	ids := sortedKeysOf(db.Trackers)
+	for _, id := range ids {
+		entry := db.Trackers[id]
+		if entry.NetworkSignature == "" {
+			continue
+		}
+		network, err := regexp.Compile(entry.NetworkSignature)
+		if err != nil {
+			return nil, fmt.Errorf("tracker database %s: %s: bad network signature: %v", file, entry.Name, err)
+		}
+		if i, ok := builtin[strings.ToLower(entry.Name)]; ok {
+			trackers[i].Network = network
+			continue
+		}
+		trackers = append(trackers, tracker{Name: entry.Name, Categories: entry.Categories, Network: network})
+	}
+	return trackers, nil
+}
+
+// trackerMatch is a tracker the app embeds or talks to
+type trackerMatch struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories,omitempty"`
+	Domains    []string `json:"domains,omitempty"` // endpoint hosts matching the network signature
+	SDKs       []string `json:"sdks,omitempty"`    // embedded frameworks and classes
+}
+
+// trackerAnalyzer records tracker SDK class names among the binary's strings
+// and matches endpoint hosts against each tracker's network signature. It
+// runs after secretAnalyzer, which collects the endpoints.
+type trackerAnalyzer struct {
+	known   map[string]bool // class names of activeTrackers
+	classes map[string]bool
+}
+
+func newTrackerAnalyzer() stringAnalyzer {
+	a := &trackerAnalyzer{known: make(map[string]bool), classes: make(map[string]bool)}
+	for _, t := range activeTrackers {
+		for _, c := range t.Classes {
+			a.known[c] = true
+		}
+	}
+	return a
+}
+
+func (a *trackerAnalyzer) visit(s string, offset int64) {
+	if a.known[s] {
+		a.classes[s] = true
+	}
+}
+
+func (a *trackerAnalyzer) findings(r *appReport) []finding {
+	for _, sym := range r.Imports {
+		if name := strings.TrimPrefix(sym, "_OBJC_CLASS_$_"); name != sym {
+			a.classes[name] = true
+		}
+	}
+	for _, c := range r.Classes {
+		a.classes[c.Name] = true
+	}
+	hosts := make(map[string]bool)
+	for _, u := range r.Endpoints {
+		if _, ok := endpointSchemes[endpointScheme(u)]; ok && !isLoopbackURL(u) {
+			hosts[endpointHost(u)] = true
+		}
+	}
+
+	r.Trackers = nil
+	var findings []finding
+	for _, t := range activeTrackers {
+		m := trackerMatch{Name: t.Name, Categories: t.Categories}
+		for _, fw := range r.Frameworks {
+			if containsString(t.Frameworks, strings.TrimSuffix(fw.Name, filepath.Ext(fw.Name))) {
+				m.SDKs = append(m.SDKs, fw.Name)
+			}
+		}
+		for _, c := range t.Classes {
+			if a.classes[c] {
+				m.SDKs = append(m.SDKs, c)
+			}
+		}
+		if t.Network != nil {
+			for _, h := range sortedSet(hosts) {
+				if t.Network.MatchString(h) {
+					m.Domains = append(m.Domains, h)
+				}
+			}
+		}
+		if len(m.SDKs)+len(m.Domains) == 0 {
+			continue
+		}
+		r.Trackers = append(r.Trackers, m)
+
+		title := "Embeds " + t.Name
+		if len(m.Domains) > 0 {
+			title = "Talks to " + t.Name
+		}
+		if len(t.Categories) > 0 {
+			title += " (" + strings.Join(t.Categories, ", ") + ")"
+		}
+		findings = append(findings, finding{
+			Rule:        "tracker",
+			Severity:    severityInfo,
+			Title:       title,
+			Evidence:    sampleList(append(append([]string(nil), m.Domains...), m.SDKs...)),
+			Location:    bundleLocation(r, r.BinaryPath),
+			Remediation: "Declare the data this service collects in the App Store privacy details and privacy manifest, and ask for tracking consent where required.",
+		})
+	}
+	sort.Slice(r.Trackers, func(i, j int) bool { return r.Trackers[i].Name < r.Trackers[j].Name })
+	return findings
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// trendBarWidth is the width of the longest bar in ASCII charts
+const trendBarWidth = 40
+
+// trendSeries is one metric charted across the scanned versions of an app
+type trendSeries struct {
+	Title  string
+	Unit   string
+	Labels []string
+	Values []float64
+}
+
+// trendSigner is a run of consecutive versions signed by the same team
+type trendSigner struct {
+	TeamID string
+	First  string
+	Last   string
+}
+
+// trendChart is every series for one bundle ID
+type trendChart struct {
+	App     string
+	Series  []trendSeries
+	Signers []trendSigner // more than one means the signing team changed
+}
+
+// runTrends implements `iosdumper trends [--history db] [--html out.html] [bundle-id]`.
+// It returns the process exit code.
+func runTrends(args []string) int {
+	fs := flag.NewFlagSet("trends", flag.ContinueOnError)
+	history := fs.String("history", historyPath, "Results history database (SQLite)")
+	htmlPath := fs.String("html", "", "Write an HTML chart to this file instead of ASCII charts")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if *history == "" {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrHistoryRequired")))
+		return exitBadInput
+	}
+
+	records, err := readHistory(*history, fs.Arg(0))
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrHistory", "Err", err))
+		return exitToolFailure
+	}
+	if len(records) == 0 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrNoHistory", "App", fs.Arg(0))))
+		return exitBadInput
+	}
+
+	charts := buildTrendCharts(records)
+	if *htmlPath != "" {
+		f, err := os.Create(*htmlPath)
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			return exitToolFailure
+		}
+		if err := writeTrendsHTML(f, charts); err != nil {
+			f.Close()
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			return exitToolFailure
+		}
+		if err := f.Close(); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+			return exitToolFailure
+		}
+		activeTheme.success.Fprintln(stdout, tr("TrendsWritten", "Path", *htmlPath))
+		return exitClean
+	}
+
+	for _, c := range charts {
+		printTrendChart(stdout, c)
+	}
+	return exitClean
+}
+
+// buildTrendCharts groups history records by bundle ID and turns them into
+// series of finding counts, binary size, SDK count and permissions, oldest first
+func buildTrendCharts(records []historyRecord) []trendChart {
+	byApp := make(map[string][]historyRecord)
+	for _, h := range records {
+		byApp[h.BundleID] = append(byApp[h.BundleID], h)
+	}
+	apps := make([]string, 0, len(byApp))
+	for app := range byApp {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	var charts []trendChart
+	for _, app := range apps {
+		scans := byApp[app]
+		sort.SliceStable(scans, func(i, j int) bool { return scans[i].ScannedAt.Before(scans[j].ScannedAt) })
+
+		findings := trendSeries{Title: tr("TrendFindings")}
+		size := trendSeries{Title: tr("TrendBinarySize"), Unit: "MB"}
+		sdks := trendSeries{Title: tr("TrendSDKs")}
+		permissions := trendSeries{Title: tr("TrendPermissions")}
+		var signers []trendSigner
+		for _, h := range scans {
+			label := historyLabel(h)
+			if h.TeamID != "" {
+				if n := len(signers); n > 0 && signers[n-1].TeamID == h.TeamID {
+					signers[n-1].Last = label
+				} else {
+					signers = append(signers, trendSigner{TeamID: teamLabel(h.TeamID, h.TeamName), First: label, Last: label})
+				}
+			}
+			total := 0
+			for _, n := range h.Findings {
+				total += n
+			}
+			for _, s := range []*trendSeries{&findings, &size, &sdks, &permissions} {
+				s.Labels = append(s.Labels, label)
+			}
+			findings.Values = append(findings.Values, float64(total))
+			size.Values = append(size.Values, float64(h.BinarySize)/(1<<20))
+			sdks.Values = append(sdks.Values, float64(h.SDKs))
+			permissions.Values = append(permissions.Values, float64(h.Permissions))
+		}
+		charts = append(charts, trendChart{App: app, Series: []trendSeries{findings, size, sdks, permissions}, Signers: signers})
+	}
+	return charts
+}
+
+// printTrendChart draws each series as horizontal ASCII bars, one per version
+func printTrendChart(w io.Writer, c trendChart) {
+	activeTheme.title.Fprintln(w, tr("TrendsTitle", "App", c.App))
+	for _, s := range c.Series {
+		fmt.Fprintf(w, "\n  %s\n", activeTheme.option.Sprint(s.Title))
+		labelWidth, max := 0, 0.0
+		for i, label := range s.Labels {
+			if len(label) > labelWidth {
+				labelWidth = len(label)
+			}
+			if s.Values[i] > max {
+				max = s.Values[i]
+			}
+		}
+		for i, label := range s.Labels {
+			bar := 0
+			if max > 0 {
+				bar = int(s.Values[i] / max * trendBarWidth)
+			}
+			fmt.Fprintf(w, "  %-*s  %s %s\n", labelWidth, label, activeTheme.match.Sprint(strings.Repeat("█", bar)), formatTrendValue(s.Values[i], s.Unit))
+		}
+	}
+	if len(c.Signers) > 0 {
+		fmt.Fprintf(w, "\n  %s\n", activeTheme.option.Sprint(tr("TrendSigners")))
+		spanWidth := 0
+		for _, s := range c.Signers {
+			if n := len([]rune(s.Span())); n > spanWidth {
+				spanWidth = n
+			}
+		}
+		for i, s := range c.Signers {
+			// Later teams are highlighted as changes of signer
+			span := s.Span()
+			line := "  " + span + strings.Repeat(" ", spanWidth-len([]rune(span))) + "  " + s.TeamID
+			if i > 0 {
+				activeTheme.failure.Fprintln(w, line)
+			} else {
+				fmt.Fprintln(w, line)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// Span formats the versions a signer covers, e.g. "1.0 – 1.4"
+func (s trendSigner) Span() string {
+	if s.First == s.Last {
+		return s.First
+	}
+	return s.First + " – " + s.Last
+}
+
+// formatTrendValue prints whole numbers without decimals and sizes with one
+func formatTrendValue(v float64, unit string) string {
+	if unit == "" {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f %s", v, unit)
+}
+
+// trendsTemplate renders the charts as a self-contained HTML page with CSS bars
+var trendsTemplate = template.Must(template.New("trends").Funcs(template.FuncMap{
+	"percent": func(v float64, values []float64) float64 {
+		max := 0.0
+		for _, x := range values {
+			if x > max {
+				max = x
+			}
+		}
+		if max == 0 {
+			return 0
+		}
+		return v / max * 100
+	},
+	"value": formatTrendValue,
+	"tr":    func(id string) string { return tr(id) },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>iOSDumper trends</title>
+<style>
+body { font-family: -apple-system, sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 2em; } h3 { margin-bottom: .3em; }
+.row { display: flex; align-items: center; margin: 2px 0; }
+.label { width: 12em; font-size: .9em; }
+.bar { background: #2f6fdf; height: 1em; margin-right: .5em; }
+.value { font-size: .9em; }
+.changed { color: #c62828; font-weight: bold; }
+</style></head><body>
+<h1>iOSDumper trends</h1>
+{{range .}}<h2>{{.App}}</h2>
+{{range .Series}}{{$s := .}}<h3>{{.Title}}</h3>
+{{range $i, $label := .Labels}}{{$v := index $s.Values $i}}<div class="row"><span class="label">{{$label}}</span><span class="bar" style="width: {{percent $v $s.Values}}%"></span><span class="value">{{value $v $s.Unit}}</span></div>
+{{end}}{{end}}{{if .Signers}}<h3>{{tr "TrendSigners"}}</h3>
+{{range $i, $s := .Signers}}<div class="row{{if $i}} changed{{end}}"><span class="label">{{$s.Span}}</span><span class="value">{{$s.TeamID}}</span></div>
+{{end}}{{end}}{{end}}</body></html>
+`))
+
+// writeTrendsHTML renders charts as an HTML page
+func writeTrendsHTML(w io.Writer, charts []trendChart) error {
+	return trendsTemplate.Execute(w, charts)
+}
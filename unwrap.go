@@ -0,0 +1,460 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxWrapperDepth bounds how many wrappers are peeled off an IPA, e.g. an
+// IPA in a tar.gz in the zip a CI system serves artifacts as
+const maxWrapperDepth = 4
+
+// wrapperSuffixes name the entries of a wrapper that may hold the IPA
+var wrapperSuffixes = []string{".ipa", ".zip", ".tar", ".tar.gz", ".tgz", ".gz"}
+
+var (
+	errWrapperNoIPA = errors.New("no IPA, Payload/*.app or .xcarchive found")
+	errWrapperMany  = errors.New("more than one candidate, unwrap it by hand")
+	errWrapperDepth = errors.New("wrappers nested too deeply")
+)
+
+// wrapperErrors are caused by the layout of the input rather than the filesystem
+var wrapperErrors = []error{errWrapperNoIPA, errWrapperMany, errWrapperDepth, errWrapperManyApps}
+
+// wrapperKind is the format of a file, detected from its first bytes since
+// downloaded artifacts are often misnamed
+type wrapperKind int
+
+const (
+	wrapperNone wrapperKind = iota
+	wrapperZip
+	wrapperGzip
+	wrapperTar
+)
+
+// detectWrapper sniffs the format of the file at path
+func detectWrapper(path string) (wrapperKind, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return wrapperNone, err
+	}
+	defer f.Close()
+	head := make([]byte, 262)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return wrapperNone, err
+	}
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return wrapperZip, nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return wrapperGzip, nil
+	case len(head) == 262 && string(head[257:262]) == "ustar":
+		return wrapperTar, nil
+	}
+	return wrapperNone, nil
+}
+
+// unwrapIPA peels zip, tar and gzip wrappers off the input, as CI systems and
+// distribution portals deliver them, until it reaches an IPA, a Payload/*.app
+// or an .xcarchive, which is repackaged as an IPA. Each level is unpacked
+// into its own directory under dir. It returns "" when archive is an IPA
+// already or no wrapper at all, leaving the scan to judge it.
+func unwrapIPA(ctx context.Context, archive, dir string) (string, error) {
+	current := archive
+	for depth := 1; ; depth++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		kind, err := detectWrapper(current)
+		if err != nil {
+			return "", err
+		}
+		if kind == wrapperNone {
+			if current == archive {
+				return "", nil
+			}
+			return "", fmt.Errorf("%s: %w", filepath.Base(current), errWrapperNoIPA)
+		}
+		// The last level opened is the IPA itself, not a wrapper
+		if depth > maxWrapperDepth+1 {
+			return "", errWrapperDepth
+		}
+
+		level := filepath.Join(dir, strconv.Itoa(depth))
+		if err := os.Mkdir(level, 0755); err != nil {
+			return "", err
+		}
+		var next string
+		var isIPA bool
+		switch kind {
+		case wrapperZip:
+			next, isIPA, err = unwrapZip(current, level)
+		case wrapperTar:
+			next, isIPA, err = unwrapTar(current, level)
+		case wrapperGzip:
+			next, err = gunzipFile(current, level)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", filepath.Base(current), err)
+		}
+		if !isIPA {
+			current = next
+			continue
+		}
+		// The scan only accepts .ipa names; a misnamed IPA is copied, never renamed in place
+		if next == archive && strings.HasSuffix(archive, ".ipa") {
+			return "", nil
+		}
+		if !strings.HasSuffix(next, ".ipa") {
+			named := filepath.Join(level, wrapperBase(filepath.Base(next))+".ipa")
+			if err := copyFile(next, named); err != nil {
+				return "", err
+			}
+			next = named
+		}
+		return next, nil
+	}
+}
+
+// wrapperBase strips the archive suffixes off a file name, e.g. App for
+// App.tar.gz, or a download saved as App.tar.gz.ipa
+func wrapperBase(name string) string {
+	for {
+		trimmed := name
+		for _, suffix := range wrapperSuffixes {
+			trimmed = strings.TrimSuffix(trimmed, suffix)
+		}
+		if trimmed == name || trimmed == "" {
+			return name
+		}
+		name = trimmed
+	}
+}
+
+// unwrapZip looks inside a zip. It reports the zip itself as the IPA when it
+// has a Payload directory, builds one in dir from an .xcarchive's app, or
+// extracts the single nested wrapper to dir.
+func unwrapZip(archive, dir string) (string, bool, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return "", false, err
+	}
+	defer zr.Close()
+	if err := checkArchive(zr.File); err != nil {
+		return "", false, err
+	}
+
+	names := make([]string, len(zr.File))
+	for i, f := range zr.File {
+		names[i] = f.Name
+	}
+	payload, app, nested, err := wrapperLayout(names)
+	if err != nil {
+		return "", false, err
+	}
+	switch {
+	case payload:
+		return archive, true, nil
+	case app != "":
+		ipa, err := newIPAWriter(dir, "", app)
+		if err != nil {
+			return "", false, err
+		}
+		for _, f := range zr.File {
+			if name, ok := ipa.rename(f.Name); ok {
+				// Entries are copied still compressed, and still encrypted
+				hdr := f.FileHeader
+				hdr.Name = name
+				w, err := ipa.zw.CreateRaw(&hdr)
+				if err == nil {
+					err = copyRawEntry(w, f)
+				}
+				if err != nil {
+					ipa.close()
+					return "", false, err
+				}
+			}
+		}
+		return ipa.path, true, ipa.close()
+	}
+	for _, f := range zr.File {
+		if f.Name != nested {
+			continue
+		}
+		limits := &extractLimitError{}
+		if !limits.admit(f.Name, f.UncompressedSize64, 0) {
+			return "", false, limits
+		}
+		local := filepath.Join(dir, path.Base(f.Name))
+		return local, false, extractFile(f, local)
+	}
+	return "", false, errWrapperNoIPA
+}
+
+// copyRawEntry copies the stored bytes of a zip entry
+func copyRawEntry(w io.Writer, f *zip.File) error {
+	r, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// unwrapTar looks inside a tar the way unwrapZip does a zip, except that a
+// Payload directory is also repackaged, since the scan reads IPAs as zips
+func unwrapTar(archive, dir string) (string, bool, error) {
+	var names []string
+	if err := eachTarEntry(archive, func(hdr *tar.Header, _ io.Reader) error {
+		names = append(names, hdr.Name)
+		return nil
+	}); err != nil {
+		return "", false, err
+	}
+	payload, app, nested, err := wrapperLayout(names)
+	if err != nil {
+		return "", false, err
+	}
+
+	if payload || app != "" {
+		ipa, err := newIPAWriter(dir, wrapperBase(filepath.Base(archive)), app)
+		if err != nil {
+			return "", false, err
+		}
+		limits := &extractLimitError{}
+		var total uint64
+		err = eachTarEntry(archive, func(hdr *tar.Header, r io.Reader) error {
+			name, ok := ipa.rename(hdr.Name)
+			// Only files and directories; iOS bundles have no symlinks that matter
+			if !ok || (hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir) {
+				return nil
+			}
+			if !limits.admit(hdr.Name, uint64(hdr.Size), total) {
+				return nil
+			}
+			total += uint64(hdr.Size)
+			fh := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: hdr.ModTime}
+			fh.SetMode(hdr.FileInfo().Mode())
+			if hdr.Typeflag == tar.TypeDir {
+				fh.Name, fh.Method = strings.TrimSuffix(name, "/")+"/", zip.Store
+			}
+			w, err := ipa.zw.CreateHeader(fh)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, r)
+			return err
+		})
+		if err == nil && (len(limits.tooLarge) > 0 || len(limits.overflow) > 0) {
+			err = limits
+		}
+		if err != nil {
+			ipa.close()
+			return "", false, err
+		}
+		return ipa.path, true, ipa.close()
+	}
+
+	local := ""
+	err = eachTarEntry(archive, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name != nested || local != "" {
+			return nil
+		}
+		limits := &extractLimitError{}
+		if !limits.admit(hdr.Name, uint64(hdr.Size), 0) {
+			return limits
+		}
+		local = filepath.Join(dir, path.Base(hdr.Name))
+		f, err := os.Create(local)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+	if err == nil && local == "" {
+		err = errWrapperNoIPA
+	}
+	return local, false, err
+}
+
+// eachTarEntry calls fn with every entry of the tar at path, names cleaned of
+// a leading ./
+func eachTarEntry(path string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		hdr.Name = strings.TrimPrefix(hdr.Name, "./")
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// gunzipFile decompresses a gzip file into dir, naming the result after it:
+// App.ipa.gz becomes App.ipa and build.tgz build.tar
+func gunzipFile(archive, dir string) (string, error) {
+	in, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	name := filepath.Base(archive)
+	switch {
+	case strings.HasSuffix(name, ".tgz"):
+		name = strings.TrimSuffix(name, ".tgz") + ".tar"
+	case strings.HasSuffix(name, ".gz"):
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	local := filepath.Join(dir, name)
+	out, err := os.Create(local)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	// A gzip stream carries no trustworthy size, so it is bounded as it is read
+	r := io.Reader(zr)
+	if extractOpts.maxFile > 0 {
+		r = io.LimitReader(zr, int64(extractOpts.maxFile)+1)
+	}
+	n, err := io.Copy(out, r)
+	if err != nil {
+		return "", err
+	}
+	if extractOpts.maxFile > 0 && n > int64(extractOpts.maxFile) {
+		return "", &extractLimitError{tooLarge: []string{name}}
+	}
+	return local, out.Close()
+}
+
+// wrapperLayout decides what a wrapper holds from its entry names: a
+// Payload directory, the Products/Applications/<App>.app/ prefix of an
+// .xcarchive, or else the one nested wrapper worth opening. An .ipa is
+// preferred over other archives, which are often dSYMs or logs next to it.
+func wrapperLayout(names []string) (payload bool, app, nested string, err error) {
+	apps := make(map[string]bool)
+	var ipas, others []string
+	for _, name := range names {
+		// Symbols shipped next to the app are archives too, never the app
+		if strings.HasPrefix(name, "__MACOSX/") || strings.Contains(name, ".dSYM") {
+			continue
+		}
+		if strings.HasPrefix(name, "Payload/") {
+			return true, "", "", nil
+		}
+		if i := strings.Index(name, ".xcarchive/Products/Applications/"); i >= 0 {
+			rest := name[i+len(".xcarchive/Products/Applications/"):]
+			if j := strings.Index(rest, ".app/"); j >= 0 {
+				apps[name[:len(name)-len(rest)+j+len(".app/")]] = true
+			}
+			continue
+		}
+		lower := strings.ToLower(name)
+		if strings.HasSuffix(lower, ".ipa") {
+			ipas = append(ipas, name)
+			continue
+		}
+		for _, suffix := range wrapperSuffixes {
+			if strings.HasSuffix(lower, suffix) {
+				others = append(others, name)
+				break
+			}
+		}
+	}
+	switch {
+	case len(apps) > 1:
+		return false, "", "", fmt.Errorf("%w: %s", errWrapperMany, strings.Join(sortedSet(apps), ", "))
+	case len(apps) == 1:
+		return false, sortedSet(apps)[0], "", nil
+	case len(ipas) > 1:
+		return false, "", "", fmt.Errorf("%w: %s", errWrapperMany, strings.Join(ipas, ", "))
+	case len(ipas) == 1:
+		return false, "", ipas[0], nil
+	case len(others) > 1:
+		return false, "", "", fmt.Errorf("%w: %s", errWrapperMany, strings.Join(others, ", "))
+	case len(others) == 1:
+		return false, "", others[0], nil
+	}
+	return false, "", "", errWrapperNoIPA
+}
+
+// ipaWriter repackages a Payload directory or an .xcarchive's app as an IPA
+type ipaWriter struct {
+	path   string
+	file   *os.File
+	zw     *zip.Writer
+	prefix string // stripped from entry names before Payload/ is added, "" to keep them
+}
+
+// newIPAWriter creates dir/<App>.ipa for the app whose entries start with
+// prefix, or dir/<name>.ipa for entries already under Payload/
+func newIPAWriter(dir, name, prefix string) (*ipaWriter, error) {
+	if prefix != "" {
+		name = strings.TrimSuffix(path.Base(prefix), ".app")
+	}
+	w := &ipaWriter{path: filepath.Join(dir, name+".ipa")}
+	if prefix != "" {
+		w.prefix = strings.TrimSuffix(prefix, path.Base(prefix)+"/")
+	}
+	f, err := os.Create(w.path)
+	if err != nil {
+		return nil, err
+	}
+	w.file, w.zw = f, zip.NewWriter(f)
+	return w, nil
+}
+
+// rename maps an entry of the wrapper to its name in the IPA, reporting
+// false for entries outside the app
+func (w *ipaWriter) rename(name string) (string, bool) {
+	if w.prefix == "" {
+		return name, strings.HasPrefix(name, "Payload/")
+	}
+	rest, ok := strings.CutPrefix(name, w.prefix)
+	if !ok || rest == "" {
+		return "", false
+	}
+	return "Payload/" + rest, true
+}
+
+func (w *ipaWriter) close() error {
+	err := w.zw.Close()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
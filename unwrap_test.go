@@ -0,0 +1,191 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ipaSeed is a minimal IPA
+var ipaSeed = zipSeed("Payload/", "Payload/App.app/Info.plist", "Payload/App.app/App")
+
+// zipOf builds a zip holding each named file with the given contents
+func zipOf(files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range sortedKeysOf(files) {
+		f, _ := w.Create(name)
+		f.Write(files[name])
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// tarOf builds a tar holding each named file with the given contents
+func tarOf(files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, name := range sortedKeysOf(files) {
+		w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[name]))})
+		w.Write(files[name])
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func gzipOf(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// zipNames lists the entries of the zip at path
+func zipNames(t *testing.T, path string) []string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// wrapIPA wraps the IPA in n zips, each holding the previous one
+func wrapIPA(n int) []byte {
+	data, name := ipaSeed, "App.ipa"
+	for i := 0; i < n; i++ {
+		data = zipOf(map[string][]byte{name: data})
+		name = "level" + string(rune('a'+i)) + ".zip"
+	}
+	return data
+}
+
+func TestUnwrapIPA(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		data  []byte
+		want  string   // base name of the unwrapped IPA, "" when the input is left to the scan
+		names []string // entries of the unwrapped IPA, when it was repackaged
+	}{
+		{"IPA", "App.ipa", ipaSeed, "", nil},
+		{"not an archive", "App.ipa", []byte("just some text, not an archive"), "", nil},
+		{"empty file", "App.ipa", nil, "", nil},
+		{"IPA in zip", "artifacts.zip", zipOf(map[string][]byte{"build/App.ipa": ipaSeed}), "App.ipa", nil},
+		{"misnamed IPA", "build.zip", ipaSeed, "build.ipa", nil},
+		{"IPA next to dSYMs", "artifacts.zip", zipOf(map[string][]byte{
+			"App.ipa":                    ipaSeed,
+			"App.app.dSYM.zip":           zipSeed("x"),
+			"__MACOSX/._App.ipa":         []byte("resource fork"),
+			"App.app.dSYM/Contents/Info": []byte("x"),
+		}), "App.ipa", nil},
+		{"IPA in tar.gz in zip", "artifacts.zip", zipOf(map[string][]byte{
+			"build.tar.gz": gzipOf(tarOf(map[string][]byte{"./out/App.ipa": ipaSeed})),
+		}), "App.ipa", nil},
+		{"gzipped IPA", "App.ipa.gz", gzipOf(ipaSeed), "App.ipa", nil},
+		{"Payload in tar", "build.tar", tarOf(map[string][]byte{
+			"Payload/App.app/App":        []byte("binary"),
+			"Payload/App.app/Info.plist": []byte("<plist/>"),
+		}), "build.ipa", []string{"Payload/App.app/App", "Payload/App.app/Info.plist"}},
+		{"xcarchive in zip", "archive.zip", zipOf(map[string][]byte{
+			"App.xcarchive/Info.plist":                                      []byte("<plist/>"),
+			"App.xcarchive/Products/Applications/App.app/App":               []byte("binary"),
+			"App.xcarchive/Products/Applications/App.app/Info.plist":        []byte("<plist/>"),
+			"App.xcarchive/dSYMs/App.app.dSYM/Contents/Resources/DWARF/App": []byte("dwarf"),
+		}), "App.ipa", []string{"Payload/App.app/App", "Payload/App.app/Info.plist"}},
+		{"IPA at the depth limit", "artifacts.zip", wrapIPA(maxWrapperDepth), "App.ipa", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, tt.input)
+			if err := os.WriteFile(input, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			scratch := filepath.Join(dir, "scratch")
+			if err := os.Mkdir(scratch, 0755); err != nil {
+				t.Fatal(err)
+			}
+			got, err := unwrapIPA(context.Background(), input, scratch)
+			if err != nil {
+				t.Fatalf("unwrapIPA: %v", err)
+			}
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("got %s, want the input left as it is", got)
+				}
+				return
+			}
+			if filepath.Base(got) != tt.want || !strings.HasPrefix(got, scratch) {
+				t.Fatalf("got %q, want %s under the scratch directory", got, tt.want)
+			}
+			names := zipNames(t, got)
+			if tt.names != nil && !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("got entries %q, want %q", names, tt.names)
+			}
+			if tt.names == nil && !reflect.DeepEqual(names, zipNames(t, writeTemp(t, ipaSeed))) {
+				t.Errorf("got entries %q, want those of the wrapped IPA", names)
+			}
+		})
+	}
+}
+
+func TestUnwrapIPAErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"nothing inside", zipOf(map[string][]byte{"README.txt": []byte("hello")}), errWrapperNoIPA},
+		{"non-archive inside", zipOf(map[string][]byte{"App.ipa": []byte("not a zip")}), errWrapperNoIPA},
+		{"only dSYMs", zipOf(map[string][]byte{"App.app.dSYM.zip": zipSeed("x")}), errWrapperNoIPA},
+		{"two IPAs", zipOf(map[string][]byte{"A.ipa": ipaSeed, "B.ipa": ipaSeed}), errWrapperMany},
+		{"two archives", zipOf(map[string][]byte{"a.tar": tarOf(nil), "b.zip": zipSeed("x")}), errWrapperMany},
+		{"two xcarchive apps", zipOf(map[string][]byte{
+			"X.xcarchive/Products/Applications/A.app/A": []byte("a"),
+			"X.xcarchive/Products/Applications/B.app/B": []byte("b"),
+		}), errWrapperMany},
+		{"nested too deeply", wrapIPA(maxWrapperDepth + 1), errWrapperDepth},
+		{"zip bomb of wrappers", wrapIPA(3 * maxWrapperDepth), errWrapperDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := unwrapIPA(context.Background(), writeTemp(t, tt.data), t.TempDir())
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestUnwrapIPACancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := unwrapIPA(ctx, writeTemp(t, wrapIPA(1)), t.TempDir()); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
+
+// writeTemp writes data to a file of its own and returns its path
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.zip")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// parseExpectedSHA256 normalizes the --expect-sha256 digest, given as hex
+// with or without a sha256: prefix
+func parseExpectedSHA256(s string) (string, error) {
+	digest := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "sha256:"))
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != 32 {
+		return "", trError("ErrExpectSHA256", "Digest", s)
+	}
+	return digest, nil
+}
+
+// verifyInputSHA256 compares the digest of the scanned IPA with the one
+// --expect-sha256 gave, recording both in the result for chain of custody. A
+// mismatch, or an extraction whose IPA is gone, stops the scan before any
+// analysis.
+func verifyInputSHA256(result *scanResult) error {
+	if analysisOpts.expectSHA256 == "" {
+		return nil
+	}
+	result.ExpectedSHA256 = analysisOpts.expectSHA256
+	if result.SHA256 == "" {
+		return trError("ErrSHA256Unavailable", "Input", result.Input)
+	}
+	if result.SHA256 != result.ExpectedSHA256 {
+		return trError("ErrSHA256Mismatch", "Input", result.Input, "Expected", result.ExpectedSHA256, "Actual", result.SHA256)
+	}
+	if showSection(sectionLog) {
+		activeTheme.success.Fprintln(result.output(), tr("SHA256Verified", "Digest", result.SHA256))
+	}
+	return nil
+}
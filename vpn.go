@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entitlements and imports for NetworkExtension VPN, proxy and filter providers
+const (
+	entNetworkExtension = "com.apple.developer.networking.networkextension"
+	entPersonalVPN      = "com.apple.developer.networking.vpn.api"
+)
+
+// vpnManagerClasses configure VPN tunnels from the containing app
+var vpnManagerClasses = []string{
+	"_OBJC_CLASS_$_NEVPNManager",
+	"_OBJC_CLASS_$_NETunnelProviderManager",
+	"_OBJC_CLASS_$_NEAppProxyProviderManager",
+	"_OBJC_CLASS_$_NEDNSProxyManager",
+	"_OBJC_CLASS_$_NEFilterManager",
+}
+
+// vpnSecretKeys are configuration profile keys holding a pre-shared key or
+// password for the tunnel
+var vpnSecretKeys = map[string]bool{
+	"SharedSecret":    true,
+	"XAuthPassword":   true,
+	"AuthPassword":    true,
+	"PayloadPassword": true,
+}
+
+// vpnReport is the app's NetworkExtension surface: the provider types it is
+// entitled to, the extension point of this bundle when it is a provider, and
+// tunnel configurations shipped in the bundle
+type vpnReport struct {
+	Providers      []string    `json:"providers,omitempty"`       // networkextension entitlement values
+	ExtensionPoint string      `json:"extension_point,omitempty"` // e.g. com.apple.networkextension.packet-tunnel
+	PrincipalClass string      `json:"principal_class,omitempty"`
+	PersonalVPN    bool        `json:"personal_vpn"`
+	Managers       []string    `json:"managers,omitempty"` // NE manager classes imported
+	Configs        []vpnConfig `json:"configs,omitempty"`
+}
+
+// vpnConfig is a tunnel configuration file found in the bundle
+type vpnConfig struct {
+	Path    string   `json:"path"`
+	Format  string   `json:"format"` // profile, openvpn or wireguard
+	Servers []string `json:"servers,omitempty"`
+	Secrets []string `json:"secrets,omitempty"` // keys holding a hardcoded secret
+}
+
+// String summarizes the report, e.g. "packet-tunnel provider, 2 bundled configs"
+func (v vpnReport) String() string {
+	var parts []string
+	if v.ExtensionPoint != "" {
+		parts = append(parts, strings.TrimPrefix(v.ExtensionPoint, "com.apple.networkextension.")+" provider")
+	} else if len(v.Providers) > 0 {
+		parts = append(parts, strings.Join(v.Providers, ", "))
+	}
+	if v.PersonalVPN {
+		parts = append(parts, "personal VPN")
+	}
+	if len(v.Configs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d bundled configs", len(v.Configs)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// analyzeVPN records the NetworkExtension entitlements and extension point
+// and parses VPN configuration profiles, OpenVPN and WireGuard files in the
+// bundle
+func analyzeVPN(r *appReport) error {
+	v := &r.VPN
+	v.Providers = plistStrings(r.Entitlements, entNetworkExtension)
+	v.PersonalVPN = containsString(plistStrings(r.Entitlements, entPersonalVPN), "allow-vpn")
+	ext := plistDict(r.InfoPlist, "NSExtension")
+	if point := plistString(ext, "NSExtensionPointIdentifier"); strings.HasPrefix(point, "com.apple.networkextension.") {
+		v.ExtensionPoint = point
+		v.PrincipalClass = plistString(ext, "NSExtensionPrincipalClass")
+	}
+	for _, class := range vpnManagerClasses {
+		if containsString(r.Imports, class) {
+			v.Managers = append(v.Managers, strings.TrimPrefix(class, "_OBJC_CLASS_$_"))
+		}
+	}
+
+	err := walkBundle(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() > maxResourceScanSize {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Path, path)
+		if err != nil {
+			return nil
+		}
+		var c *vpnConfig
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".mobileconfig":
+			c = readVPNProfile(path)
+		case ".ovpn":
+			c = readOpenVPNConfig(path)
+		case ".conf":
+			c = readWireGuardConfig(path)
+		}
+		if c != nil {
+			c.Path = filepath.ToSlash(rel)
+			v.Configs = append(v.Configs, *c)
+		}
+		return nil
+	})
+	sort.Slice(v.Configs, func(i, j int) bool { return v.Configs[i].Path < v.Configs[j].Path })
+	return err
+}
+
+// readVPNProfile returns the VPN payloads of a configuration profile, signed
+// or not, or nil when it has none
+func readVPNProfile(path string) *vpnConfig {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	// A signed profile is a CMS message wrapping the plist
+	if start, end := bytes.Index(data, []byte("<?xml")), bytes.Index(data, []byte("</plist>")); start >= 0 && end > start {
+		data = data[start : end+len("</plist>")]
+	}
+	value, err := parsePlist(data)
+	if err != nil {
+		return nil
+	}
+	c := &vpnConfig{Format: "profile"}
+	servers := make(map[string]bool)
+	secrets := make(map[string]bool)
+	isVPN := false
+	walkDecodedValue(value, func(key string, v interface{}) {
+		s, _ := v.(string)
+		switch {
+		case key == "PayloadType" && strings.HasPrefix(s, "com.apple.vpn.managed"):
+			isVPN = true
+		case key == "RemoteAddress" && s != "":
+			servers[s] = true
+		case vpnSecretKeys[key] && s != "":
+			secrets[key] = true
+		case key == "SharedSecret":
+			// IPSec and IKEv2 store the PSK as data rather than a string
+			if b, ok := v.([]byte); ok && len(b) > 0 {
+				secrets[key] = true
+			}
+		}
+	})
+	if !isVPN {
+		return nil
+	}
+	c.Servers = sortedSet(servers)
+	c.Secrets = sortedSet(secrets)
+	return c
+}
+
+// readOpenVPNConfig returns the remotes of an OpenVPN config and whether it
+// inlines a static key or private key
+func readOpenVPNConfig(path string) *vpnConfig {
+	c := &vpnConfig{Format: "openvpn"}
+	servers := make(map[string]bool)
+	secrets := make(map[string]bool)
+	err := scanConfigLines(path, func(line string) {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 2 && fields[0] == "remote":
+			servers[fields[1]] = true
+		case line == "<secret>", line == "<tls-auth>", line == "<tls-crypt>", line == "<key>":
+			secrets[strings.Trim(line, "<>")] = true
+		}
+	})
+	if err != nil || len(servers) == 0 {
+		return nil
+	}
+	c.Servers = sortedSet(servers)
+	c.Secrets = sortedSet(secrets)
+	return c
+}
+
+// readWireGuardConfig returns the peer endpoints of a WireGuard config and
+// the keys it hardcodes, or nil when the .conf file is not one
+func readWireGuardConfig(path string) *vpnConfig {
+	c := &vpnConfig{Format: "wireguard"}
+	servers := make(map[string]bool)
+	secrets := make(map[string]bool)
+	isWireGuard := false
+	err := scanConfigLines(path, func(line string) {
+		if line == "[Interface]" {
+			isWireGuard = true
+			return
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case key == "Endpoint" && value != "":
+			servers[value] = true
+		case (key == "PrivateKey" || key == "PresharedKey") && value != "":
+			secrets[key] = true
+		}
+	})
+	if err != nil || !isWireGuard {
+		return nil
+	}
+	c.Servers = sortedSet(servers)
+	c.Secrets = sortedSet(secrets)
+	return c
+}
+
+// scanConfigLines calls fn with every trimmed, non-comment line of a text config
+func scanConfigLines(path string, fn func(line string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		fn(line)
+	}
+	return scanner.Err()
+}
+
+// checkVPN describes the NetworkExtension surface and flags tunnel
+// configurations shipped with their secrets
+func checkVPN(r *appReport) []finding {
+	v := r.VPN
+	var findings []finding
+	if v.ExtensionPoint != "" || len(v.Providers) > 0 {
+		evidence := v.ExtensionPoint
+		if v.PrincipalClass != "" {
+			evidence += " (" + v.PrincipalClass + ")"
+		}
+		if len(v.Providers) > 0 {
+			if evidence != "" {
+				evidence += "; "
+			}
+			evidence += entNetworkExtension + ": " + strings.Join(v.Providers, ", ")
+		}
+		findings = append(findings, finding{
+			Rule:     "network-extension",
+			Severity: severityInfo,
+			Title:    "NetworkExtension provider",
+			Evidence: evidence,
+			Location: bundleLocation(r, bundleInfoPlist(r.Path)),
+			Remediation: "The provider sees the traffic it tunnels, proxies or filters; review where it sends that traffic and how it " +
+				"authenticates to its servers.",
+		})
+	}
+	for _, c := range v.Configs {
+		evidence := c.Format
+		if len(c.Servers) > 0 {
+			evidence += "; servers: " + sampleList(c.Servers)
+		}
+		if len(c.Secrets) > 0 {
+			findings = append(findings, finding{
+				Rule:     "vpn-hardcoded-secret",
+				Severity: severityHigh,
+				Title:    "VPN configuration with a hardcoded pre-shared key or private key",
+				Evidence: evidence + "; " + strings.Join(c.Secrets, ", "),
+				Location: c.Path,
+				Remediation: "Every copy of the app shares this secret, so anyone can extract it and join or impersonate the tunnel; " +
+					"provision per-user credentials or certificates at runtime and rotate the key.",
+			})
+			continue
+		}
+		findings = append(findings, finding{
+			Rule:        "vpn-embedded-config",
+			Severity:    severityLow,
+			Title:       "VPN configuration bundled with the app",
+			Evidence:    evidence,
+			Location:    c.Path,
+			Remediation: "Bundled tunnel configurations disclose the VPN infrastructure; fetch them after the user authenticates.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// osvEcosystems are the OSV ecosystems whose packages ship inside iOS apps.
+// GitHub's Swift advisories are published under SwiftURL.
+var osvEcosystems = []string{"SwiftURL"}
+
+// osvBundleURL is where OSV publishes every advisory of an ecosystem as one zip
+const osvBundleURL = "https://osv-vulnerabilities.storage.googleapis.com/%s/all.zip"
+
+// maxAdvisorySize skips advisory files too large to be one advisory
+const maxAdvisorySize = 1 << 20
+
+// vulnDB indexes OSV advisories by the lower-cased last path element of the
+// package name, e.g. alamofire for github.com/Alamofire/Alamofire, which is
+// how the package's framework is named inside an app
+type vulnDB map[string][]osvAdvisory
+
+// activeVulnDB is loaded from the offline database at startup; nil when none
+// has been downloaded
+var activeVulnDB vulnDB
+
+// osvAdvisory is the part of an OSV advisory used for matching
+type osvAdvisory struct {
+	ID               string        `json:"id"`
+	Aliases          []string      `json:"aliases"`
+	Summary          string        `json:"summary"`
+	Affected         []osvAffected `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"` // GitHub's CRITICAL, HIGH, MODERATE or LOW
+	} `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"` // SEMVER, ECOSYSTEM or GIT
+		Events []struct {
+			Introduced   string `json:"introduced"`
+			Fixed        string `json:"fixed"`
+			LastAffected string `json:"last_affected"`
+		} `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+// vulnDBDir is the offline database directory: IOSDUMPER_DB, or iosdumper/osv
+// in the user's cache directory
+func vulnDBDir() string {
+	if dir := os.Getenv("IOSDUMPER_DB"); dir != "" {
+		return dir
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "iosdumper", "osv")
+}
+
+// runDB implements `iosdumper db update [--db dir] [--from dir]`. Without
+// --from it downloads each ecosystem's bundle from OSV; with --from it
+// installs the bundles from a directory written by db update on a connected
+// machine, for air-gapped environments. It returns the process exit code.
+func runDB(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] != "update" {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrDBCommand")))
+		return exitBadInput
+	}
+	fs := flag.NewFlagSet("db update", flag.ContinueOnError)
+	dir := fs.String("db", vulnDBDir(), "Offline vulnerability database directory")
+	from := fs.String("from", "", "Install the bundles from this directory instead of downloading them")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitBadInput
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", err))
+		return exitToolFailure
+	}
+	for _, ecosystem := range osvEcosystems {
+		count, err := updateVulnBundle(ctx, *dir, *from, ecosystem)
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrDBUpdate", "Ecosystem", ecosystem, "Err", err))
+			return exitToolFailure
+		}
+		activeTheme.success.Fprintln(stdout, tr("DBUpdated", "Ecosystem", ecosystem, "Count", count, "Dir", *dir))
+	}
+	return exitClean
+}
+
+// updateVulnBundle fetches or copies one ecosystem's bundle next to its final
+// name, checks that it parses, then moves it into place so a failed update
+// leaves the previous bundle intact. It returns the number of advisories.
+func updateVulnBundle(ctx context.Context, dir, from, ecosystem string) (int, error) {
+	target := filepath.Join(dir, ecosystem+".zip")
+	tmp := target + ".tmp"
+	defer os.Remove(tmp)
+	if from != "" {
+		if err := copyFile(filepath.Join(from, ecosystem+".zip"), tmp); err != nil {
+			return 0, err
+		}
+	} else if err := downloadVulnBundle(ctx, fmt.Sprintf(osvBundleURL, ecosystem), tmp); err != nil {
+		return 0, err
+	}
+	db := make(vulnDB)
+	count, err := loadVulnBundle(tmp, db)
+	if err != nil {
+		return 0, err
+	}
+	return count, os.Rename(tmp, target)
+}
+
+func downloadVulnBundle(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// loadVulnDB reads every ecosystem bundle in dir. A missing directory is not
+// an error: matching is skipped until db update has run.
+func loadVulnDB(dir string) (vulnDB, error) {
+	db := make(vulnDB)
+	loaded := false
+	for _, ecosystem := range osvEcosystems {
+		path := filepath.Join(dir, ecosystem+".zip")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := loadVulnBundle(path, db); err != nil {
+			return nil, err
+		}
+		loaded = true
+	}
+	if !loaded {
+		return nil, nil
+	}
+	return db, nil
+}
+
+// loadVulnBundle adds the advisories of an OSV all.zip to db, returning how
+// many it read. Bundles are checked like IPAs since they may have been
+// carried in from elsewhere.
+func loadVulnBundle(path string, db vulnDB) (int, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+	if err := checkArchive(zr.File); err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	count := 0
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".json") || f.UncompressedSize64 > maxAdvisorySize {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", path, err)
+		}
+		var adv osvAdvisory
+		err = json.NewDecoder(rc).Decode(&adv)
+		rc.Close()
+		if err != nil {
+			return count, fmt.Errorf("%s: %s: %v", path, f.Name, err)
+		}
+		count++
+		names := make(map[string]bool)
+		for _, a := range adv.Affected {
+			names[osvPackageKey(a.Package.Name)] = true
+		}
+		for name := range names {
+			db[name] = append(db[name], adv)
+		}
+	}
+	return count, nil
+}
+
+// osvPackageKey reduces a package name to the lower-cased last path element,
+// without a .git suffix
+func osvPackageKey(name string) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "/"), ".git")
+	return strings.ToLower(name[strings.LastIndex(name, "/")+1:])
+}
+
+// analyzeVulnerableComponents matches each embedded framework's name and
+// version against the offline vulnerability database
+func analyzeVulnerableComponents(r *appReport) error {
+	if activeVulnDB == nil {
+		return nil
+	}
+	for _, fw := range r.Frameworks {
+		if fw.Version == "" {
+			continue
+		}
+		rel, err := filepath.Rel(r.Path, fw.BinaryPath)
+		if err != nil {
+			return err
+		}
+		component := fw.Name
+		if fw.Kind == "framework" {
+			component += ".framework"
+		}
+		for _, adv := range activeVulnDB[strings.ToLower(fw.Name)] {
+			fixed, ok := adv.affects(fw.Name, fw.Version)
+			if !ok {
+				continue
+			}
+			ids := adv.ID
+			if len(adv.Aliases) > 0 {
+				ids += " (" + strings.Join(adv.Aliases, ", ") + ")"
+			}
+			remediation := "Update " + fw.Name + " to a release that fixes " + adv.ID + "."
+			if fixed != "" {
+				remediation = "Update " + fw.Name + " to " + fixed + " or later, which fixes " + adv.ID + "."
+			}
+			r.Findings = append(r.Findings, finding{
+				Rule:        "vulnerable-component",
+				Severity:    osvSeverity(adv.DatabaseSpecific.Severity),
+				Title:       fmt.Sprintf("%s %s has a known vulnerability", fw.Name, fw.Version),
+				Evidence:    ids + ": " + adv.Summary,
+				Location:    filepath.ToSlash(rel),
+				Component:   component,
+				Remediation: remediation,
+			})
+		}
+	}
+	return nil
+}
+
+// affects reports whether version of the named package is affected, and the
+// first fixed version when the advisory gives one
+func (adv osvAdvisory) affects(name, version string) (string, bool) {
+	for _, a := range adv.Affected {
+		if osvPackageKey(a.Package.Name) != strings.ToLower(name) {
+			continue
+		}
+		if containsString(a.Versions, version) {
+			return "", true
+		}
+		for _, rng := range a.Ranges {
+			if rng.Type == "GIT" {
+				continue
+			}
+			// Events are ordered: each introduced opens a range that the next
+			// fixed or last_affected closes
+			affected := false
+			for _, e := range rng.Events {
+				switch {
+				case e.Introduced != "":
+					affected = affected || e.Introduced == "0" || compareVersions(version, e.Introduced) >= 0
+				case e.Fixed != "":
+					if affected && compareVersions(version, e.Fixed) < 0 {
+						return e.Fixed, true
+					}
+					affected = false
+				case e.LastAffected != "":
+					if affected && compareVersions(version, e.LastAffected) <= 0 {
+						return "", true
+					}
+					affected = false
+				}
+			}
+			if affected {
+				return "", true
+			}
+		}
+	}
+	return "", false
+}
+
+// compareVersions orders dotted versions numerically, e.g. 5.10 after 5.9.
+// A trailing pre-release such as -beta.1 sorts before the release.
+func compareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+	ap, bp := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		var x, y int
+		if i < len(ap) {
+			x, _ = strconv.Atoi(ap[i])
+		}
+		if i < len(bp) {
+			y, _ = strconv.Atoi(bp[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// osvSeverity maps GitHub's advisory severity onto the report's levels
+func osvSeverity(s string) string {
+	switch strings.ToUpper(s) {
+	case "CRITICAL":
+		return severityCritical
+	case "HIGH":
+		return severityHigh
+	case "LOW":
+		return severityLow
+	}
+	return severityMedium
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// webhookTimeout bounds each webhook delivery so a slow receiver cannot stall the watcher
+const webhookTimeout = 10 * time.Second
+
+// watchEvent is posted to the webhook after each scan in watch mode
+type watchEvent struct {
+	Input    string         `json:"input"`
+	Report   string         `json:"report"`
+	Status   string         `json:"status"`
+	ExitCode int            `json:"exit_code"`
+	Apps     []string       `json:"apps"`
+	Findings map[string]int `json:"findings"` // per severity, across apps
+}
+
+// runWatch implements `iosdumper watch [--reports dir] [--webhook url] [--settle d] <dir>`.
+// New IPAs dropped into dir are analyzed once their size stops changing; a
+// JSON report is written for each and the webhook, if any, is notified.
+// It runs until ctx is cancelled and returns the process exit code.
+func runWatch(ctx context.Context, args []string, prog *progressReporter) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	reportsDir := fs.String("reports", "", "Directory for JSON reports (default <dir>/reports)")
+	webhook := fs.String("webhook", "", "POST a JSON summary of each scan to this URL")
+	settle := fs.Duration("settle", 2*time.Second, "How long a file must stay unchanged before it is scanned")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() != 1 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrWatchUsage")))
+		return exitBadInput
+	}
+	dir := fs.Arg(0)
+	if *reportsDir == "" {
+		*reportsDir = filepath.Join(dir, "reports")
+	}
+	if err := os.MkdirAll(*reportsDir, 0755); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrCreateDir", "Err", err))
+		return exitToolFailure
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrWatch", "Err", err))
+		return exitToolFailure
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrWatch", "Err", err))
+		return exitBadInput
+	}
+	activeTheme.success.Fprintln(stdout, tr("Watching", "Dir", dir))
+
+	// pending maps IPAs still being written to the size seen at the last event
+	pending := make(map[string]int64)
+	ticker := time.NewTicker(*settle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return exitClean
+		case err := <-watcher.Errors:
+			activeTheme.failure.Fprintln(stdout, tr("ErrWatch", "Err", err))
+		case ev := <-watcher.Events:
+			if !strings.HasSuffix(ev.Name, ".ipa") || !ev.Has(fsnotify.Create|fsnotify.Write) {
+				continue
+			}
+			pending[ev.Name] = -1
+		case <-ticker.C:
+			for path, lastSize := range pending {
+				st, err := os.Stat(path)
+				if err != nil {
+					delete(pending, path)
+					continue
+				}
+				if st.Size() != lastSize {
+					pending[path] = st.Size()
+					continue
+				}
+				delete(pending, path)
+				watchScan(ctx, path, *reportsDir, *webhook, prog)
+			}
+		}
+	}
+}
+
+// watchScan analyzes one dropped IPA, writes its report and notifies the webhook
+func watchScan(ctx context.Context, path, reportsDir, webhook string, prog *progressReporter) {
+	result := run(ctx, path, prog)
+	result.finish()
+	for _, e := range result.Errors {
+		if e.Fatal {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", e.Message))
+		}
+	}
+	if historyPath != "" {
+		if err := appendHistory(historyPath, result); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrHistory", "Err", err))
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".ipa")
+	reportPath := filepath.Join(reportsDir, fmt.Sprintf("%s-%s.json", name, result.StartedAt.Format("20060102T150405Z")))
+	if err := writeJSONReport(reportPath, result); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrWriteReport", "Err", err))
+		return
+	}
+	activeTheme.success.Fprintln(stdout, tr("WatchReport", "Input", path, "Report", reportPath, "Status", result.Status))
+
+	if webhook == "" {
+		return
+	}
+	ev := watchEvent{
+		Input:    path,
+		Report:   reportPath,
+		Status:   result.Status,
+		ExitCode: result.ExitCode,
+		Apps:     []string{},
+		Findings: make(map[string]int),
+	}
+	for _, app := range result.Apps {
+		ev.Apps = append(ev.Apps, app.Name)
+		for _, f := range app.Findings {
+			ev.Findings[f.Severity]++
+		}
+	}
+	if err := postWebhook(ctx, webhook, ev); err != nil {
+		activeTheme.failure.Fprintln(stdout, tr("ErrWebhook", "Err", err))
+	}
+}
+
+// postWebhook delivers v as a JSON POST body
+func postWebhook(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// fileURLPattern matches file:// URLs and scripts that navigate to them
+	fileURLPattern = regexp.MustCompile(`file://[^\s"'<>)]*`)
+	// inlineEvalPattern matches JavaScript that compiles strings into code
+	inlineEvalPattern = regexp.MustCompile(`\beval\s*\(|\bnew\s+Function\s*\(|\bset(?:Timeout|Interval)\s*\(\s*["'\x60]`)
+)
+
+// webContentAnalyzers run over every bundled HTML and JavaScript file
+var webContentAnalyzers = []func() stringAnalyzer{
+	newEndpointAnalyzer,
+	newSecretAnalyzer,
+	newWebScriptAnalyzer,
+}
+
+// webScriptAnalyzer flags file:// loading and inline eval in web content
+type webScriptAnalyzer struct {
+	fileURLs []string
+	evals    []string
+}
+
+func newWebScriptAnalyzer() stringAnalyzer {
+	return &webScriptAnalyzer{}
+}
+
+func (a *webScriptAnalyzer) visit(s string, offset int64) {
+	if m := fileURLPattern.FindString(s); m != "" && len(a.fileURLs) < maxSealedResourceSamples {
+		a.fileURLs = append(a.fileURLs, m)
+	}
+	if loc := inlineEvalPattern.FindStringIndex(s); loc != nil && len(a.evals) < maxSealedResourceSamples {
+		a.evals = append(a.evals, snippet(s, loc[0], 60))
+	}
+}
+
+func (a *webScriptAnalyzer) findings(r *appReport) []finding {
+	var findings []finding
+	if len(a.fileURLs) > 0 {
+		findings = append(findings, finding{
+			Rule:        "web-file-url",
+			Severity:    severityLow,
+			Title:       "Bundled web content loads file:// URLs",
+			Evidence:    strings.Join(a.fileURLs, ", "),
+			Remediation: "Load local content through a custom URL scheme handler instead of file:// so the page cannot reach other files.",
+		})
+	}
+	if len(a.evals) > 0 {
+		findings = append(findings, finding{
+			Rule:        "web-inline-eval",
+			Severity:    severityMedium,
+			Title:       "Bundled JavaScript evaluates strings as code",
+			Evidence:    strings.Join(a.evals, " | "),
+			Remediation: "Replace eval, new Function and string timers with direct calls; combined with a message handler they turn any injected text into native access.",
+		})
+	}
+	return findings
+}
+
+// analyzeWebContent runs the endpoint, secret and script scanners over the
+// bundled web content listed in the report's web view section
+func analyzeWebContent(r *appReport) error {
+	for _, rel := range r.WebView.LocalContent {
+		findings, err := analyzeStrings(r, filepath.Join(r.Path, filepath.FromSlash(rel)), webContentAnalyzers)
+		if err != nil {
+			return err
+		}
+		for i := range findings {
+			findings[i].Location = rel
+		}
+		r.Findings = append(r.Findings, findings...)
+	}
+	return nil
+}
+
+// snippet returns up to width characters of s starting at start
+func snippet(s string, start, width int) string {
+	end := start + width
+	if end > len(s) {
+		end = len(s)
+	}
+	return strings.TrimSpace(s[start:end])
+}
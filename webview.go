@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// webViewClasses are the web view implementations an app can embed
+var webViewClasses = []string{"WKWebView", "UIWebView", "SFSafariViewController", "WKWebViewConfiguration"}
+
+// webViewIndicators map strings found in the binary to the web view feature
+// they reveal. Preference keys are usually set through KVC, so their names
+// appear verbatim.
+var webViewIndicators = map[string]string{
+	"javaScriptEnabled":                                   "settings",
+	"setJavaScriptEnabled:":                               "settings",
+	"javaScriptCanOpenWindowsAutomatically":               "settings",
+	"allowFileAccessFromFileURLs":                         "settings",
+	"allowUniversalAccessFromFileURLs":                    "settings",
+	"setURLSchemeHandler:forURLScheme:":                   "scheme-handlers",
+	"webView:startURLSchemeTask:":                         "scheme-handlers",
+	"addScriptMessageHandler:name:":                       "message-handlers",
+	"addScriptMessageHandler:contentWorld:name:":          "message-handlers",
+	"addScriptMessageHandlerWithReply:contentWorld:name:": "message-handlers",
+	"userContentController:didReceiveScriptMessage:":      "message-handlers",
+	"loadFileURL:allowingReadAccessToURL:":                "local-loading",
+	"loadHTMLString:baseURL:":                             "local-loading",
+	"loadData:MIMEType:characterEncodingName:baseURL:":    "local-loading",
+}
+
+// webContentExtensions are bundled files a web view can load locally
+var webContentExtensions = map[string]bool{".html": true, ".htm": true, ".xhtml": true, ".js": true}
+
+// webViewReport describes how the app embeds web content
+type webViewReport struct {
+	Classes         []string `json:"classes,omitempty"`
+	Settings        []string `json:"settings,omitempty"`
+	SchemeHandlers  []string `json:"scheme_handlers,omitempty"`
+	MessageHandlers []string `json:"message_handlers,omitempty"`
+	LocalLoading    []string `json:"local_loading,omitempty"`
+	LocalContent    []string `json:"local_content,omitempty"` // bundled HTML and JS files
+}
+
+// webViewAnalyzer collects web view classes, preference keys and handler selectors
+type webViewAnalyzer struct {
+	classes    map[string]bool
+	indicators map[string]bool
+}
+
+func newWebViewAnalyzer() stringAnalyzer {
+	return &webViewAnalyzer{classes: make(map[string]bool), indicators: make(map[string]bool)}
+}
+
+func (a *webViewAnalyzer) visit(s string, offset int64) {
+	if _, ok := webViewIndicators[s]; ok {
+		a.indicators[s] = true
+		return
+	}
+	for _, class := range webViewClasses {
+		if s == class || s == "_OBJC_CLASS_$_"+class {
+			a.classes[class] = true
+		}
+	}
+}
+
+func (a *webViewAnalyzer) findings(r *appReport) []finding {
+	for _, sym := range r.Imports {
+		for _, class := range webViewClasses {
+			if sym == "_OBJC_CLASS_$_"+class {
+				a.classes[class] = true
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	for _, s := range sortedSet(a.indicators) {
+		groups[webViewIndicators[s]] = append(groups[webViewIndicators[s]], s)
+	}
+	// Classes recovered from symbols name the handlers themselves
+	for _, c := range r.Classes {
+		for _, m := range c.Methods {
+			switch strings.TrimLeft(m, "-+") {
+			case "userContentController:didReceiveScriptMessage:":
+				groups["message-handlers"] = append(groups["message-handlers"], c.Name)
+			case "webView:startURLSchemeTask:":
+				groups["scheme-handlers"] = append(groups["scheme-handlers"], c.Name)
+			}
+		}
+	}
+
+	w := webViewReport{
+		Classes:         sortedSet(a.classes),
+		Settings:        groups["settings"],
+		SchemeHandlers:  groups["scheme-handlers"],
+		MessageHandlers: groups["message-handlers"],
+		LocalLoading:    groups["local-loading"],
+		LocalContent:    bundledWebContent(r.Path),
+	}
+	r.WebView = w
+
+	location := bundleLocation(r, r.BinaryPath)
+	var findings []finding
+	add := func(rule, severity, title, evidence, remediation string) {
+		findings = append(findings, finding{
+			Rule:        rule,
+			Severity:    severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    location,
+			Remediation: remediation,
+		})
+	}
+
+	if a.classes["UIWebView"] {
+		add("uiwebview", severityMedium, "Deprecated UIWebView in use", "UIWebView",
+			"Migrate to WKWebView; UIWebView runs web content in-process without site isolation and is rejected by App Review.")
+	}
+	for _, key := range w.Settings {
+		if strings.HasPrefix(key, "allow") {
+			add("webview-file-access", severityMedium, "Web view file URL access enabled", key,
+				"Leave file URL access disabled; with it, script in a local page can read other files in the container and send them anywhere.")
+		}
+	}
+	if len(w.MessageHandlers) > 0 {
+		add("webview-message-handler", severityInfo, "JavaScript message handlers exposed to web content", strings.Join(w.MessageHandlers, ", "),
+			"Validate every message body and restrict handlers to content from trusted origins.")
+	}
+	if len(w.SchemeHandlers) > 0 {
+		add("webview-scheme-handler", severityInfo, "Custom URL scheme handler serves web content", strings.Join(w.SchemeHandlers, ", "),
+			"Treat requests to the custom scheme as untrusted input and avoid exposing arbitrary files through it.")
+	}
+	if len(w.LocalContent) > 0 && len(w.LocalLoading) > 0 {
+		add("webview-local-content", severityInfo, "Bundled web content loaded locally", sampleList(w.LocalContent),
+			"Limit allowingReadAccessToURL to the web content directory rather than the whole bundle or container.")
+	}
+	return findings
+}
+
+// bundledWebContent lists HTML and JavaScript files shipped in the bundle
+func bundledWebContent(appDir string) []string {
+	var files []string
+	walkBundle(appDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !webContentExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if rel, err := filepath.Rel(appDir, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+)
+
+// checkWildcardEntitlements flags entitlements whose values use wildcards and
+// so grant more than the app needs: wildcard App IDs, team-wide keychain and
+// app group access, and wildcard associated domains
+func checkWildcardEntitlements(r *appReport) []finding {
+	if r.Entitlements == nil {
+		return nil
+	}
+
+	var findings []finding
+	add := func(rule, severity, title, evidence, remediation string) {
+		findings = append(findings, finding{
+			Rule:        rule,
+			Severity:    severity,
+			Title:       title,
+			Evidence:    evidence,
+			Location:    bundleLocation(r, r.BinaryPath),
+			Remediation: remediation,
+		})
+	}
+
+	if appID := plistString(r.Entitlements, "application-identifier"); strings.HasSuffix(appID, "*") {
+		add("wildcard-app-id", severityHigh,
+			"Signed with a wildcard application-identifier", appID,
+			"Sign release builds with an explicit App ID so capabilities and keychain scope are tied to this bundle only.")
+	}
+
+	for _, group := range plistStrings(r.Entitlements, "keychain-access-groups") {
+		if strings.Contains(group, "*") {
+			add("wildcard-keychain-group", severityMedium,
+				"Keychain access group wildcard shares items with every app of the team", group,
+				"Replace the wildcard with the specific access groups the app needs, e.g. TEAMID.com.example.shared.")
+		}
+	}
+
+	for _, group := range plistStrings(r.Entitlements, "com.apple.security.application-groups") {
+		if strings.Contains(group, "*") {
+			add("wildcard-app-group", severityMedium,
+				"App group entitlement uses a wildcard", group,
+				"Declare each shared container explicitly (group.com.example.name).")
+		}
+	}
+
+	for _, domain := range plistStrings(r.Entitlements, "com.apple.developer.associated-domains") {
+		host := domain
+		if i := strings.Index(host, ":"); i >= 0 {
+			host = host[i+1:]
+		}
+		if strings.Contains(host, "*") {
+			add("wildcard-associated-domain", severityLow,
+				"Wildcard associated domain accepts links from every matching subdomain", domain,
+				"List the hosts that serve apple-app-site-association explicitly, or make sure no subdomain can be taken over or serve attacker content.")
+		}
+	}
+
+	for _, key := range []string{"com.apple.developer.icloud-container-identifiers", "com.apple.developer.ubiquity-container-identifiers"} {
+		for _, container := range plistStrings(r.Entitlements, key) {
+			if strings.Contains(container, "*") {
+				add("wildcard-icloud-container", severityLow,
+					"iCloud container entitlement uses a wildcard", container,
+					"Name the iCloud containers the app uses explicitly.")
+			}
+		}
+	}
+	if kvs := plistString(r.Entitlements, "com.apple.developer.ubiquity-kvstore-identifier"); strings.Contains(kvs, "*") {
+		add("wildcard-icloud-container", severityLow,
+			"iCloud key-value store identifier uses a wildcard", kvs,
+			"Use TEAMID.bundle-id for the key-value store identifier.")
+	}
+
+	return findings
+}
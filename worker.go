@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// scanJob is a message on the jobs topic
+type scanJob struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`                // any remote input, see isRemoteInput
+	Callback string `json:"callback,omitempty"` // optional http(s) URL the result is POSTed to, see checkCallback
+}
+
+// jobResult is published to the results topic, and to the job's callback, for every job
+type jobResult struct {
+	ID     string      `json:"id"`
+	URL    string      `json:"url"`
+	Result *scanResult `json:"result"`
+}
+
+// runWorker implements `iosdumper worker [--brokers list] [--jobs topic] [--results topic] [--group id]
+// [--callback-hosts list] [--http-timeout duration]`. Jobs are consumed as part
+// of a Kafka consumer group, so any number of workers can share a topic.
+// Offsets are committed only after the result is published, which gives
+// at-least-once delivery: a worker that dies mid-scan leaves its job to be
+// picked up again. Anyone who can produce to the jobs topic controls what the
+// worker fetches and where it sends results, so jobs may only name remote
+// inputs, never files on the worker's host, and callbacks may be limited to
+// trusted hosts.
+func runWorker(ctx context.Context, args []string, prog *progressReporter) int {
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	brokers := fs.String("brokers", "localhost:9092", "Comma-separated Kafka bootstrap brokers")
+	jobsTopic := fs.String("jobs", "iosdumper.jobs", "Topic to consume scan jobs from")
+	resultsTopic := fs.String("results", "iosdumper.results", "Topic to publish scan results to")
+	group := fs.String("group", "iosdumper", "Consumer group shared by the worker fleet")
+	callbackHosts := fs.String("callback-hosts", "", "Comma-separated hosts job callbacks may be sent to (default any)")
+	httpTimeout := fs.Duration("http-timeout", 30*time.Minute, "Time limit of each download, upload and callback")
+	if err := fs.Parse(args); err != nil {
+		return exitBadInput
+	}
+	if fs.NArg() != 0 {
+		activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", trError("ErrWorkerUsage")))
+		return exitBadInput
+	}
+
+	httpClient = &http.Client{Timeout: *httpTimeout}
+	var allowedHosts []string
+	for _, host := range strings.Split(*callbackHosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			allowedHosts = append(allowedHosts, host)
+		}
+	}
+
+	brokerList := strings.Split(*brokers, ",")
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokerList,
+		GroupID: *group,
+		Topic:   *jobsTopic,
+	})
+	defer reader.Close()
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokerList...),
+		Topic:                  *resultsTopic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+	defer writer.Close()
+
+	activeTheme.success.Fprintln(stdout, tr("WorkerStarted", "Topic", *jobsTopic, "Brokers", *brokers))
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			return exitClean
+		}
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWorker", "Err", err))
+			return exitToolFailure
+		}
+
+		var job scanJob
+		if err := json.Unmarshal(msg.Value, &job); err != nil || job.URL == "" {
+			// A malformed job can never succeed; skip it rather than redeliver it forever
+			activeTheme.failure.Fprintln(stdout, tr("ErrJob", "Offset", msg.Offset, "Err", err))
+			reader.CommitMessages(ctx, msg)
+			continue
+		}
+		if !isRemoteInput(job.URL) {
+			activeTheme.failure.Fprintln(stdout, tr("ErrJobLocalInput", "ID", job.ID, "URL", job.URL))
+			reader.CommitMessages(ctx, msg)
+			continue
+		}
+
+		res := workerScan(ctx, job, prog)
+		if ctx.Err() != nil {
+			// Leave the offset uncommitted so another worker rescans the job
+			return exitClean
+		}
+		value, err := json.Marshal(res)
+		if err == nil {
+			err = writer.WriteMessages(ctx, kafka.Message{Key: []byte(job.ID), Value: value})
+		}
+		if err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWorker", "Err", err))
+			return exitToolFailure
+		}
+		if job.Callback != "" {
+			err := checkCallback(job.Callback, allowedHosts)
+			if err == nil {
+				err = postWebhook(ctx, job.Callback, res)
+			}
+			if err != nil {
+				activeTheme.failure.Fprintln(stdout, tr("ErrWebhook", "Err", err))
+			}
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrWorker", "Err", err))
+		}
+	}
+}
+
+// checkCallback reports why a job's results may not be POSTed to callback:
+// it must be an http or https URL, on one of hosts when any are given
+func checkCallback(callback string, hosts []string) error {
+	u, err := url.Parse(callback)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return trError("ErrCallbackScheme", "URL", u.Redacted())
+	}
+	if len(hosts) > 0 && !containsString(hosts, strings.ToLower(u.Hostname())) {
+		return trError("ErrCallbackHost", "Host", u.Hostname(), "Hosts", strings.Join(hosts, ", "))
+	}
+	return nil
+}
+
+// workerScan downloads and analyzes the IPA named by job
+func workerScan(ctx context.Context, job scanJob, prog *progressReporter) jobResult {
+	result := scanInput(ctx, job.URL, prog)
+	result.finish()
+	for _, e := range result.Errors {
+		if e.Fatal {
+			activeTheme.failure.Fprintln(stdout, tr("ErrGeneric", "Err", e.Message))
+		}
+	}
+	if historyPath != "" {
+		if err := appendHistory(historyPath, result); err != nil {
+			activeTheme.failure.Fprintln(stdout, tr("ErrHistory", "Err", err))
+		}
+	}
+	publishReport(ctx, result)
+	activeTheme.success.Fprintln(stdout, tr("JobDone", "ID", job.ID, "Status", result.Status))
+	return jobResult{ID: job.ID, URL: job.URL, Result: result}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Workspace layout: every IPA is extracted under extraction/, the files the
+// scan derives from it go to artifacts/, each scan's JSON report to reports/,
+// and cache.db indexes what has been scanned
+const (
+	workspaceExtraction = "extraction"
+	workspaceArtifacts  = "artifacts"
+	workspaceReports    = "reports"
+	workspaceIndex      = "cache.db"
+)
+
+// activeWorkspace is set by --workspace or open; nil keeps the extraction
+// next to the IPA
+var activeWorkspace *workspace
+
+// workspace is a directory the tool manages for one assessment
+type workspace struct {
+	dir string
+}
+
+// workspaceEntry is one scanned IPA in the workspace index. Paths are
+// relative to the workspace so it can be moved or shared.
+type workspaceEntry struct {
+	Input      string    `json:"input"`
+	SHA256     string    `json:"sha256,omitempty"`
+	Extraction string    `json:"extraction"`
+	Reports    []string  `json:"reports"`
+	ScannedAt  time.Time `json:"scanned_at"`
+}
+
+// openWorkspace creates the workspace layout in dir if needed
+func openWorkspace(dir string) (*workspace, error) {
+	for _, sub := range []string{workspaceExtraction, workspaceArtifacts, workspaceReports} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, trError("ErrWorkspace", "Dir", dir, "Err", err)
+		}
+	}
+	return &workspace{dir: dir}, nil
+}
+
+// entries reads the workspace index; a new workspace has none
+func (w *workspace) entries() ([]workspaceEntry, error) {
+	data, err := os.ReadFile(filepath.Join(w.dir, workspaceIndex))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []workspaceEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, trError("ErrWorkspace", "Dir", w.dir, "Err", err)
+	}
+	return entries, nil
+}
+
+// extractionDir is where the IPA at input is extracted, e.g.
+// extraction/App for App.ipa
+func (w *workspace) extractionDir(input string) string {
+	return filepath.Join(w.dir, workspaceExtraction, strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)))
+}
+
+// artifactDir creates and returns the directory for the files derived from
+// the extraction at fileDir
+func (w *workspace) artifactDir(fileDir string) (string, error) {
+	dir := filepath.Join(w.dir, workspaceArtifacts, filepath.Base(fileDir))
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// extracted reports whether the index has already extracted the IPA with
+// this digest to dir, so the scan can reuse the extraction
+func (w *workspace) extracted(sha256, dir string) bool {
+	entries, err := w.entries()
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.SHA256 == sha256 && filepath.Join(w.dir, e.Extraction) == dir {
+			_, err := os.Stat(filepath.Join(dir, "Payload"))
+			return err == nil
+		}
+	}
+	return false
+}
+
+// save writes the result's JSON report to reports/ and records the scan in
+// the index, returning the report's path
+func (w *workspace) save(result *scanResult) (string, error) {
+	if result.Extraction == "" {
+		return "", nil
+	}
+	name := filepath.Base(result.Extraction)
+	path := filepath.Join(w.dir, workspaceReports, name+"-"+result.StartedAt.Format("20060102T150405Z")+".json")
+	if err := writeJSONReport(path, result); err != nil {
+		return "", err
+	}
+
+	entries, err := w.entries()
+	if err != nil {
+		return "", err
+	}
+	extraction, _ := filepath.Rel(w.dir, result.Extraction)
+	report, _ := filepath.Rel(w.dir, path)
+	i := 0
+	for i < len(entries) && entries[i].Extraction != extraction {
+		i++
+	}
+	if i == len(entries) {
+		entries = append(entries, workspaceEntry{Input: result.Input, Extraction: extraction})
+	}
+	entries[i].SHA256 = result.SHA256
+	entries[i].Reports = append(entries[i].Reports, report)
+	entries[i].ScannedAt = result.StartedAt
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(w.dir, workspaceIndex+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp, filepath.Join(w.dir, workspaceIndex))
+}
+
+// reopenInputs lists the extractions of a workspace for `iosdumper open`
+func (w *workspace) reopenInputs() ([]string, error) {
+	entries, err := w.entries()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, trError("ErrWorkspaceEmpty", "Dir", w.dir)
+	}
+	inputs := make([]string, len(entries))
+	for i, e := range entries {
+		inputs[i] = filepath.Join(w.dir, e.Extraction)
+	}
+	return inputs, nil
+}
+
+// reopen re-analyzes one extraction of the workspace, reporting it under the
+// input it was first scanned from
+func (w *workspace) reopen(ctx context.Context, dir string, prog *progressReporter) *scanResult {
+	var entry workspaceEntry
+	entries, _ := w.entries()
+	for _, e := range entries {
+		if filepath.Join(w.dir, e.Extraction) == dir {
+			entry = e
+		}
+	}
+	// The original IPA, if it is still where it was scanned from and unchanged
+	archive := ""
+	if sum, err := fileSHA256(entry.Input); err == nil && sum == entry.SHA256 {
+		archive = entry.Input
+	}
+	result := reanalyze(ctx, newScanResult(dir), dir, archive, prog)
+	if entry.Input != "" {
+		result.Input = entry.Input
+	}
+	return result
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// zipPassword is set by --zip-password or IOSDUMPER_ZIP_PASSWORD and decrypts
+// encrypted entries of the input archive
+var zipPassword string
+
+// Encryption as found in the zip files distribution portals hand out:
+// traditional PKWARE encryption (ZipCrypto) and WinZip AES (AE-1 and AE-2)
+const (
+	zipFlagEncrypted  = 0x1
+	zipFlagDescriptor = 0x8
+	zipMethodAES      = 99
+	zipExtraAES       = 0x9901
+	zipCryptoHeader   = 12
+	zipAESVerifier    = 2
+	zipAESMACSize     = 10
+	zipAESIterations  = 1000
+)
+
+var (
+	errZipPasswordNeeded = errors.New("entry is encrypted, pass --zip-password")
+	errZipWrongPassword  = errors.New("wrong zip password")
+	errZipAuthentication = errors.New("encrypted entry failed authentication")
+)
+
+// zipPasswordErrors are caused by the archive's encryption rather than the filesystem
+var zipPasswordErrors = []error{errZipPasswordNeeded, errZipWrongPassword, errZipAuthentication}
+
+// openZipEntry opens an entry like zip.File.Open, decrypting it with
+// zipPassword when it is encrypted. The data is checked against the entry's
+// size and CRC-32, or its HMAC for AE-2, which stores no CRC.
+func openZipEntry(f *zip.File) (io.ReadCloser, error) {
+	if f.Flags&zipFlagEncrypted == 0 {
+		return f.Open()
+	}
+	if zipPassword == "" {
+		return nil, fmt.Errorf("%s: %w", f.Name, errZipPasswordNeeded)
+	}
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	method, checkCRC := f.Method, true
+	var plain io.Reader
+	if f.Method == zipMethodAES {
+		version, strength, actual, ok := parseAESExtra(f.Extra)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", f.Name, zip.ErrAlgorithm)
+		}
+		method, checkCRC = actual, version == 1
+		plain, err = newZipAESReader(raw, int64(f.CompressedSize64), strength)
+	} else {
+		// The last header byte checks the password against the CRC, or the
+		// modification time when the CRC follows the data
+		check := byte(f.CRC32 >> 24)
+		if f.Flags&zipFlagDescriptor != 0 {
+			check = byte(f.ModifiedTime >> 8)
+		}
+		plain, err = newZipCryptoReader(raw, check)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+
+	var rc io.ReadCloser
+	switch method {
+	case zip.Store:
+		rc = io.NopCloser(plain)
+	case zip.Deflate:
+		rc = flate.NewReader(plain)
+	default:
+		return nil, fmt.Errorf("%s: %w", f.Name, zip.ErrAlgorithm)
+	}
+	return &zipEntryReader{rc: rc, plain: plain, name: f.Name, size: f.UncompressedSize64, crc: f.CRC32, checkCRC: checkCRC, hash: crc32.NewIEEE()}, nil
+}
+
+// parseAESExtra reads the WinZip AES extra field: the AE version, the key
+// strength (1, 2 or 3 for 128, 192 or 256 bits) and the real compression method
+func parseAESExtra(extra []byte) (version uint16, strength byte, method uint16, ok bool) {
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			return 0, 0, 0, false
+		}
+		field := extra[4 : 4+size]
+		extra = extra[4+size:]
+		if id != zipExtraAES || size < 7 || string(field[2:4]) != "AE" {
+			continue
+		}
+		version, strength, method = binary.LittleEndian.Uint16(field), field[4], binary.LittleEndian.Uint16(field[5:])
+		return version, strength, method, strength >= 1 && strength <= 3
+	}
+	return 0, 0, 0, false
+}
+
+// zipCryptoReader decrypts traditional PKWARE encryption
+type zipCryptoReader struct {
+	r    io.Reader
+	keys [3]uint32
+}
+
+// newZipCryptoReader reads the 12-byte encryption header and checks its last
+// byte, which leaves about one wrong password in 256 undetected until the CRC
+func newZipCryptoReader(r io.Reader, check byte) (io.Reader, error) {
+	z := &zipCryptoReader{r: r, keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for i := 0; i < len(zipPassword); i++ {
+		z.update(zipPassword[i])
+	}
+	header := make([]byte, zipCryptoHeader)
+	if _, err := io.ReadFull(z, header); err != nil {
+		return nil, err
+	}
+	if header[zipCryptoHeader-1] != check {
+		return nil, errZipWrongPassword
+	}
+	return z, nil
+}
+
+func (z *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	for i := 0; i < n; i++ {
+		t := z.keys[2] | 2
+		p[i] ^= byte((t * (t ^ 1)) >> 8)
+		z.update(p[i])
+	}
+	return n, err
+}
+
+func (z *zipCryptoReader) update(b byte) {
+	z.keys[0] = crc32.IEEETable[byte(z.keys[0])^b] ^ z.keys[0]>>8
+	z.keys[1] = (z.keys[1]+z.keys[0]&0xff)*134775813 + 1
+	z.keys[2] = crc32.IEEETable[byte(z.keys[2])^byte(z.keys[1]>>24)] ^ z.keys[2]>>8
+}
+
+// zipAESReader decrypts WinZip AES: AES in CTR mode with a little-endian
+// counter, authenticated with a truncated HMAC-SHA1 of the ciphertext
+type zipAESReader struct {
+	r       io.Reader // the ciphertext, without the MAC that follows it
+	tail    io.Reader // the MAC
+	block   cipher.Block
+	mac     hash.Hash
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+// newZipAESReader reads the salt and password verifier in front of size bytes
+// of encrypted entry data
+func newZipAESReader(r io.Reader, size int64, strength byte) (io.Reader, error) {
+	keyLen := 8 + 8*int(strength)
+	saltLen := keyLen / 2
+	dataLen := size - int64(saltLen+zipAESVerifier+zipAESMACSize)
+	if dataLen < 0 {
+		return nil, zip.ErrFormat
+	}
+	header := make([]byte, saltLen+zipAESVerifier)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	keys := pbkdf2SHA1([]byte(zipPassword), header[:saltLen], zipAESIterations, 2*keyLen+zipAESVerifier)
+	if subtle.ConstantTimeCompare(keys[2*keyLen:], header[saltLen:]) != 1 {
+		return nil, errZipWrongPassword
+	}
+	block, err := aes.NewCipher(keys[:keyLen])
+	if err != nil {
+		return nil, err
+	}
+	return &zipAESReader{
+		r:     io.LimitReader(r, dataLen),
+		tail:  r,
+		block: block,
+		mac:   hmac.New(sha1.New, keys[keyLen:2*keyLen]),
+		used:  aes.BlockSize,
+	}, nil
+}
+
+func (z *zipAESReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	z.mac.Write(p[:n])
+	for i := 0; i < n; i++ {
+		if z.used == aes.BlockSize {
+			for j := range z.counter {
+				if z.counter[j]++; z.counter[j] != 0 {
+					break
+				}
+			}
+			z.block.Encrypt(z.stream[:], z.counter[:])
+			z.used = 0
+		}
+		p[i] ^= z.stream[z.used]
+		z.used++
+	}
+	if err == io.EOF {
+		want := make([]byte, zipAESMACSize)
+		if _, err := io.ReadFull(z.tail, want); err != nil {
+			return n, err
+		}
+		if !hmac.Equal(z.mac.Sum(nil)[:zipAESMACSize], want) {
+			return n, errZipAuthentication
+		}
+	}
+	return n, err
+}
+
+// pbkdf2SHA1 derives keyLen bytes from password as in RFC 8018
+func pbkdf2SHA1(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha1.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := prf.Sum(nil)
+		t := bytes.Clone(u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// zipEntryReader checks decrypted data the way zip.File.Open does: no more
+// than the header's size, and the CRC-32 at the end
+type zipEntryReader struct {
+	rc       io.ReadCloser
+	plain    io.Reader // the decrypted data rc decompresses
+	name     string
+	size     uint64
+	read     uint64
+	crc      uint32
+	checkCRC bool
+	hash     hash.Hash32
+}
+
+func (z *zipEntryReader) Read(p []byte) (int, error) {
+	n, err := z.rc.Read(p)
+	z.hash.Write(p[:n])
+	z.read += uint64(n)
+	if z.read > z.size {
+		return n, fmt.Errorf("%s: %w", z.name, zip.ErrFormat)
+	}
+	if err == io.EOF {
+		// Deflate stops at its end marker; read the rest so an AES MAC is checked
+		if _, err := io.Copy(io.Discard, z.plain); err != nil {
+			return n, fmt.Errorf("%s: %w", z.name, err)
+		}
+		if z.read != z.size {
+			return n, fmt.Errorf("%s: %w", z.name, io.ErrUnexpectedEOF)
+		}
+		if z.checkCRC && z.hash.Sum32() != z.crc {
+			if zipPassword != "" {
+				return n, fmt.Errorf("%s: %w", z.name, errZipWrongPassword)
+			}
+			return n, fmt.Errorf("%s: %w", z.name, zip.ErrChecksum)
+		}
+	}
+	return n, err
+}
+
+func (z *zipEntryReader) Close() error {
+	return z.rc.Close()
+}
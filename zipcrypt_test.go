@@ -0,0 +1,163 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+// The fixtures below were made outside this package: the ZipCrypto archive
+// with Info-ZIP's `zip -e -P s3cret` (deflated, with a data descriptor), the
+// AES entries with Python's hashlib.pbkdf2_hmac and hmac and OpenSSL's AES.
+// All of them hold zipPlain.
+const (
+	zipFixturePassword = "s3cret"
+	zipPlain           = "<plist><dict><key>CFBundleName</key></dict></plist>\n"
+	zipPlainCRC        = 0xa4a8f1e4
+
+	zipCryptoFixture = "UEsDBBQACQAIANBGT13k8aikNgAAADQAAAAKAAAASW5mby5wbGlzdK/TMdVqhimF6oH4i00EgtkFMU3h4qSbspwXlWQDvXETpIsJ4zSfz/e+L2983PSI5lIfl2A55FBLBwjk8aikNgAAADQAAABQSwECHgMUAAkACADQRk9d5PGopDYAAAA0AAAACgAAAAAAAAABAAAApIEAAAAASW5mby5wbGlzdFBLBQYAAAAAAQABADgAAABuAAAAAAA="
+
+	// Salt, password verifier, ciphertext and MAC of a stored entry
+	zipAES128Fixture = "0001020304050607e33ea808abbabdd4283b7a3e4bc044843e19f14c35cc1b90019af2c2a0afdc07471867b6d32f01380258a086d67bbde9046c84724e161acc0ee8c406a75efcf2"
+	zipAES256Fixture = "101112131415161718191a1b1c1d1e1ffc88f4321e1685bfed545959943fac7dedc72c8a35bd06424290698cc7f44c85f31f8ceb4d587897a11a41819a830a16ffcda29dc6d6a206bc8ac07d9dff2ee9"
+)
+
+// RFC 6070 test vectors
+func TestPBKDF2SHA1(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"password", "salt", 1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"password", "salt", 2, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{"password", "salt", 4096, "4b007901b765489abead49d926f721d065a429c1"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+		{"pass\x00word", "sa\x00lt", 4096, "56fa6aa75548099dcc37d7f03425e0c3"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA1([]byte(tt.password), []byte(tt.salt), tt.iterations, len(tt.want)/2))
+		if got != tt.want {
+			t.Errorf("pbkdf2SHA1(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+// aesZipEntry wraps raw AES entry data in an archive, with the extra field
+// WinZip writes for the AE version and key strength
+func aesZipEntry(t *testing.T, version uint16, strength byte, raw []byte) *zip.File {
+	t.Helper()
+	extra := binary.LittleEndian.AppendUint16(nil, zipExtraAES)
+	extra = binary.LittleEndian.AppendUint16(extra, 7)
+	extra = binary.LittleEndian.AppendUint16(extra, version)
+	extra = append(extra, 'A', 'E', strength)
+	extra = binary.LittleEndian.AppendUint16(extra, zip.Store)
+	fh := &zip.FileHeader{
+		Name:               "Info.plist",
+		Method:             zipMethodAES,
+		Flags:              zipFlagEncrypted,
+		CompressedSize64:   uint64(len(raw)),
+		UncompressedSize64: uint64(len(zipPlain)),
+		Extra:              extra,
+	}
+	if version == 1 {
+		fh.CRC32 = zipPlainCRC
+	}
+	return zipFixtureEntry(t, func(w *zip.Writer) error {
+		fw, err := w.CreateRaw(fh)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(raw)
+		return err
+	})
+}
+
+// zipFixtureEntry returns the only entry of the archive write builds
+func zipFixtureEntry(t *testing.T, write func(w *zip.Writer) error) *zip.File {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if err := write(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return readZipFixture(t, buf.Bytes())
+}
+
+func readZipFixture(t *testing.T, data []byte) *zip.File {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr.File[0]
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestOpenZipEntry(t *testing.T) {
+	zipCrypto, err := base64.StdEncoding.DecodeString(zipCryptoFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := func(fixture string) []byte {
+		raw := mustDecodeHex(t, fixture)
+		raw[len(raw)-1] ^= 0x01
+		return raw
+	}
+	tests := []struct {
+		name     string
+		entry    *zip.File
+		password string
+		wantErr  error
+	}{
+		{"ZipCrypto", readZipFixture(t, zipCrypto), zipFixturePassword, nil},
+		{"ZipCrypto wrong password", readZipFixture(t, zipCrypto), "wrong", errZipWrongPassword},
+		{"ZipCrypto no password", readZipFixture(t, zipCrypto), "", errZipPasswordNeeded},
+		{"AE-1 AES-128", aesZipEntry(t, 1, 1, mustDecodeHex(t, zipAES128Fixture)), zipFixturePassword, nil},
+		{"AE-1 wrong password", aesZipEntry(t, 1, 1, mustDecodeHex(t, zipAES128Fixture)), "wrong", errZipWrongPassword},
+		{"AE-1 tampered MAC", aesZipEntry(t, 1, 1, tampered(zipAES128Fixture)), zipFixturePassword, errZipAuthentication},
+		{"AE-2 AES-256", aesZipEntry(t, 2, 3, mustDecodeHex(t, zipAES256Fixture)), zipFixturePassword, nil},
+		{"AE-2 wrong password", aesZipEntry(t, 2, 3, mustDecodeHex(t, zipAES256Fixture)), "wrong", errZipWrongPassword},
+		{"AE-2 tampered MAC", aesZipEntry(t, 2, 3, tampered(zipAES256Fixture)), zipFixturePassword, errZipAuthentication},
+	}
+	defer func(saved string) { zipPassword = saved }(zipPassword)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zipPassword = tt.password
+			rc, err := openZipEntry(tt.entry)
+			var got []byte
+			if err == nil {
+				got, err = io.ReadAll(rc)
+				rc.Close()
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != zipPlain {
+				t.Errorf("decrypted %q, want %q", got, zipPlain)
+			}
+		})
+	}
+}